@@ -222,7 +222,10 @@ func processAddFile(cmd *cobra.Command, file string, forceOverwrite bool) bool {
 
 	noCommit, _ := cmd.Flags().GetBool("no-commit")
 	if !noCommit {
-		err = git.Commit("Added " + file)
+		err = git.Commit(git.CommitMessage(git.OpAdd, git.MessageData{Name: filepath.Base(file)}))
+		if git.DisplaySecretsError(err, interactive.ShouldPrompt(cmd, false)) {
+			return false
+		}
 		if err != nil {
 			fileops.ColorPrintfn(fileops.Red, "Error%s when adding and committing %s: %s", fileops.Reset, file, err)
 			return false
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage aliases shared by your shells",
+	Long: `Declare aliases once and have every shell define them in its own syntax.
+
+Aliases are kept in the aliases section of each shell's enabled.json, or enabled.local.json with
+--local, and are defined after the eager features load. Commands are written in POSIX sh syntax:
+bash, zsh and POSIX sh use alias, fish uses abbr, and PowerShell uses Set-Alias for a single
+command or a function that passes its arguments on. An alias a shell cannot express is left out
+of that shell with a warning.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <name> <command>",
+	Short: "Add or replace an alias",
+	Long: `Add an alias to the selected shells, or replace the alias with the same name.
+
+Examples:
+  oh-my-dot alias add ll 'ls -la'
+  oh-my-dot alias add g git --shell bash --shell powershell
+  oh-my-dot alias add k kubectl --local   # This machine only`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE:         runAliasAdd,
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:          "remove <name>",
+	Aliases:      []string{"rm"},
+	Short:        "Remove an alias",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runAliasRemove,
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the aliases of each shell",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runAliasList,
+}
+
+var (
+	flagAliasShell []string
+	flagAliasLocal bool
+)
+
+func init() {
+	aliasAddCmd.Flags().StringSliceVar(&flagAliasShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+	aliasAddCmd.Flags().BoolVar(&flagAliasLocal, "local", false, "Add the alias for this machine only, in enabled.local.json")
+	aliasRemoveCmd.Flags().StringSliceVar(&flagAliasShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+	aliasRemoveCmd.Flags().BoolVar(&flagAliasLocal, "local", false, "Remove the alias from enabled.local.json")
+	aliasListCmd.Flags().StringSliceVar(&flagAliasShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+
+	aliasCmd.AddCommand(aliasAddCmd)
+	aliasCmd.AddCommand(aliasRemoveCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
+
+// aliasShells returns the shells selected with --shell, or every shell with features
+func aliasShells(repoPath string) ([]string, error) {
+	if len(flagAliasShell) > 0 {
+		for _, shellName := range flagAliasShell {
+			if !shell.IsShellSupported(shellName) {
+				return nil, fmt.Errorf("unsupported shell: %s", shellName)
+			}
+			if !fileops.PathExists(shell.GetManifestPath(repoPath, shellName)) {
+				return nil, fmt.Errorf("%s has no features; run '%s feature add' first", shellName, assumedAlias())
+			}
+		}
+		return flagAliasShell, nil
+	}
+
+	shells, err := shell.ListShellsWithFeatures(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shells: %w", err)
+	}
+	if len(shells) == 0 {
+		return nil, fmt.Errorf("no shells have been initialized")
+	}
+	return shells, nil
+}
+
+func runAliasAdd(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+	alias := manifest.AliasConfig{Name: args[0], Command: args[1]}
+	if err := alias.Validate(); err != nil {
+		return err
+	}
+
+	shells, err := aliasShells(repoPath)
+	if err != nil {
+		return err
+	}
+
+	for _, shellName := range shells {
+		if err := shell.SetAlias(repoPath, shellName, alias, flagAliasLocal); err != nil {
+			return fmt.Errorf("failed to add alias to %s: %w", shellName, err)
+		}
+		if problem := shell.AliasProblem(shellName, alias); problem != "" {
+			fileops.ColorPrintfn(fileops.Yellow, "Warning: %s does not define '%s': %s", shellName, alias.Name, problem)
+			continue
+		}
+		fileops.ColorPrintfn(fileops.Green, "Added alias %s to %s", alias.Name, shellName)
+	}
+
+	return commitAliasChange(cmd, git.OpAliasAdd, alias.Name, shells)
+}
+
+func runAliasRemove(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+
+	shells, err := aliasShells(repoPath)
+	if err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, shellName := range shells {
+		if err := shell.RemoveAlias(repoPath, shellName, args[0], flagAliasLocal); err != nil {
+			// Without --shell, only the shells that have the alias matter
+			if len(flagAliasShell) == 0 {
+				continue
+			}
+			return fmt.Errorf("failed to remove alias from %s: %w", shellName, err)
+		}
+		removed = append(removed, shellName)
+		fileops.ColorPrintfn(fileops.Green, "Removed alias %s from %s", args[0], shellName)
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("alias '%s' not found", args[0])
+	}
+
+	return commitAliasChange(cmd, git.OpAliasRemove, args[0], removed)
+}
+
+// commitAliasChange commits changed manifests, unless the change was to enabled.local.json
+func commitAliasChange(cmd *cobra.Command, op git.Operation, name string, shells []string) error {
+	if flagAliasLocal {
+		fileops.ColorPrintln("The change is kept in enabled.local.json for this machine only.", fileops.Cyan)
+	} else {
+		_, err := git.StageAndCommitShellFeatureChanges(git.CommitMessage(op, git.MessageData{Name: name, Shell: strings.Join(shells, ", ")}))
+		exitOnSecrets(cmd, err)
+		if err != nil {
+			return fmt.Errorf("failed to commit shell feature changes: %w", err)
+		}
+	}
+
+	fileops.ColorPrintfn(fileops.Cyan, "\nRun '%s apply' or open a new shell to use it", assumedAlias())
+	return nil
+}
+
+func runAliasList(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+
+	shells, err := aliasShells(repoPath)
+	if err != nil {
+		return err
+	}
+
+	for _, shellName := range shells {
+		aliases, err := shell.LoadAliases(repoPath, shellName)
+		if err != nil {
+			return fmt.Errorf("failed to read the %s aliases: %w", shellName, err)
+		}
+
+		fileops.ColorPrintfn(fileops.Cyan, "%s:", shellName)
+		if len(aliases) == 0 {
+			fmt.Println("  (no aliases)")
+		}
+		for _, alias := range aliases {
+			line := fmt.Sprintf("  %s = %s", alias.Name, alias.Command)
+			if problem := shell.AliasProblem(shellName, alias); problem != "" {
+				line += "  " + fileops.SColorPrint("(not defined: "+problem+")", fileops.Yellow)
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
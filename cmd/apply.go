@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/externals"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/hooks"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
@@ -18,6 +19,7 @@ import (
 func init() {
 	applyCommand.Flags().BoolP("verbose", "v", false, "Prints more information about the linking process")
 	applyCommand.Flags().Bool("no-shell", false, "Skip shell hook application")
+	applyCommand.Flags().Bool("no-externals", false, "Skip fetching the externals declared in externals.yaml")
 
 	rootCmd.AddCommand(applyCommand)
 }
@@ -42,10 +44,58 @@ func createDotfileLink(sourcePath, targetPath string) error {
 	}
 }
 
+// applyLinkings links each repository file to its target, skipping targets that already exist.
+// Returns the number of files that are linked and the number that could not be applied.
+func applyLinkings(repoPath string, linkings symlink.Linkings, verbose bool) (int, int) {
+	missingFiles := 0
+	linkedFiles := 0
+
+	for file, link := range linkings {
+		file = filepath.Join(repoPath, "files", file)
+		if !fileops.IsFile(file) {
+			missingFiles++
+			fileops.ColorPrintfn(fileops.Red, "  Error: file %s does not exist", file)
+			continue
+		}
+
+		// Expand normalized path (e.g., ~/... to /home/user/...)
+		expandedLink, err := fileops.ExpandPath(link)
+		if err != nil {
+			missingFiles++
+			fileops.ColorPrintfn(fileops.Red, "  Error expanding path %s: %s", link, err)
+			continue
+		}
+
+		if fileops.IsFile(expandedLink) {
+			if verbose {
+				fileops.ColorPrintfn(fileops.Reset, "  Skipping %s: link already exists", expandedLink)
+			}
+			linkedFiles++
+			continue
+		}
+
+		if !fileops.PathExists(filepath.Dir(expandedLink)) {
+			missingFiles++
+			fileops.ColorPrintfn(fileops.Red, "  Error: target directory '%s' does not exist for link %s -> %s", filepath.Dir(expandedLink), expandedLink, file)
+			continue
+		}
+
+		err = createDotfileLink(file, expandedLink)
+		if err != nil {
+			missingFiles++
+			fileops.ColorPrintfn(fileops.Red, "  Error creating link %s -> %s: %s", expandedLink, file, err)
+			continue
+		}
+		linkedFiles++
+	}
+
+	return linkedFiles, missingFiles
+}
+
 var applyCommand = &cobra.Command{
 	Use:     "apply",
 	Short:   "Apply the dotfiles and shell hooks to the system",
-	Long:    `Applies the dotfiles to the system and installs shell integration hooks.`,
+	Long:    `Applies the dotfiles to the system, fetches the externals declared in externals.yaml and installs shell integration hooks.`,
 	GroupID: "dotfiles",
 	Run: func(cmd *cobra.Command, args []string) {
 		verbose, verr := cmd.Flags().GetBool("verbose")
@@ -55,6 +105,7 @@ var applyCommand = &cobra.Command{
 		}
 
 		noShell, _ := cmd.Flags().GetBool("no-shell")
+		noExternals, _ := cmd.Flags().GetBool("no-externals")
 		repoPath := viper.GetString("repo-path")
 
 		// Apply dotfiles
@@ -65,47 +116,7 @@ var applyCommand = &cobra.Command{
 			return
 		}
 
-		missingFiles := 0
-		linkedFiles := 0
-
-		for file, link := range linkings {
-			file = filepath.Join(repoPath, "files", file)
-			if !fileops.IsFile(file) {
-				missingFiles++
-				fileops.ColorPrintfn(fileops.Red, "  Error: file %s does not exist", file)
-				continue
-			}
-
-			// Expand normalized path (e.g., ~/... to /home/user/...)
-			expandedLink, err := fileops.ExpandPath(link)
-			if err != nil {
-				missingFiles++
-				fileops.ColorPrintfn(fileops.Red, "  Error expanding path %s: %s", link, err)
-				continue
-			}
-
-			if fileops.IsFile(expandedLink) {
-				if verbose {
-					fileops.ColorPrintfn(fileops.Reset, "  Skipping %s: link already exists", expandedLink)
-				}
-				linkedFiles++
-				continue
-			}
-
-			if !fileops.PathExists(filepath.Dir(expandedLink)) {
-				missingFiles++
-				fileops.ColorPrintfn(fileops.Red, "  Error: target directory '%s' does not exist for link %s -> %s", filepath.Dir(expandedLink), expandedLink, file)
-				continue
-			}
-
-			err = createDotfileLink(file, expandedLink)
-			if err != nil {
-				missingFiles++
-				fileops.ColorPrintfn(fileops.Red, "  Error creating link %s -> %s: %s", expandedLink, file, err)
-				continue
-			}
-			linkedFiles++
-		}
+		linkedFiles, missingFiles := applyLinkings(repoPath, linkings, verbose)
 
 		if linkedFiles > 0 {
 			fileops.ColorPrintfn(fileops.Green, "  ✓ %d files linked", linkedFiles)
@@ -115,6 +126,20 @@ var applyCommand = &cobra.Command{
 			fileops.ColorPrintfn(fileops.Yellow, "  ✗ %d files could not be applied", missingFiles)
 		}
 
+		// Apply externals if any are declared
+		if !noExternals && fileops.IsFile(externals.Path(repoPath)) {
+			fmt.Println()
+			fileops.ColorPrintln("Applying externals...", fileops.Cyan)
+
+			appliedExternals, failedExternals := applyExternals(repoPath, verbose)
+			if appliedExternals > 0 {
+				fileops.ColorPrintfn(fileops.Green, "  ✓ %d externals in place", appliedExternals)
+			}
+			if failedExternals > 0 {
+				fileops.ColorPrintfn(fileops.Yellow, "  ✗ %d externals could not be applied", failedExternals)
+			}
+		}
+
 		// Apply shell hooks if not disabled
 		if !noShell {
 			fmt.Println()
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	branchCreateCommand.Flags().StringP("upstream", "u", "", "Branch to pull shared changes from (defaults to the current branch)")
+	branchCreateCommand.Flags().Bool("no-rebase", false, "Merge the upstream on pull instead of rebasing onto it")
+	branchCreateCommand.Flags().BoolP("switch", "s", false, "Switch to the new branch after creating it")
+
+	branchCommand.AddCommand(branchCreateCommand)
+	branchCommand.AddCommand(branchSwitchCommand)
+	rootCmd.AddCommand(branchCommand)
+}
+
+var branchCommand = &cobra.Command{
+	Use:   "branch",
+	Short: "List and manage per-machine branches",
+	Long: `List and manage per-machine branches.
+A machine branch holds changes that only apply to one machine. Pulling on a machine branch
+replays its commits on top of the shared upstream branch, and "promote" copies a commit
+from the machine branch to the shared branch.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		branches, err := git.ListBranches()
+		fileops.CheckIfErrorWithMessage(err, "Error listing branches")
+
+		for _, branch := range branches {
+			marker := "  "
+			color := fileops.Reset
+			if branch.Current {
+				marker = "* "
+				color = fileops.Green
+			}
+
+			line := marker + branch.Name
+			if branch.Upstream != "" {
+				mode := "merge"
+				if branch.Rebase {
+					mode = "rebase"
+				}
+				line += fmt.Sprintf(" -> %s/%s (%s)", branch.Remote, branch.Upstream, mode)
+			}
+			fileops.ColorPrintln(line, color)
+		}
+	},
+}
+
+var branchCreateCommand = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a machine branch that tracks a shared branch",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		upstream, _ := cmd.Flags().GetString("upstream")
+		noRebase, _ := cmd.Flags().GetBool("no-rebase")
+		switchTo, _ := cmd.Flags().GetBool("switch")
+
+		if upstream == "" {
+			current, err := git.CurrentBranch()
+			fileops.CheckIfErrorWithMessage(err, "Error reading current branch")
+			upstream = current
+		}
+
+		if upstream == name {
+			fileops.ColorPrintln("A branch cannot track itself; use --upstream to pick the shared branch", fileops.Red)
+			os.Exit(exitcodes.Error)
+		}
+
+		err := git.CreateBranch(name, upstream, !noRebase)
+		fileops.CheckIfErrorWithMessage(err, "Error creating branch")
+		fileops.ColorPrintfn(fileops.Green, "Created branch %s tracking %s", name, upstream)
+
+		if switchTo {
+			err = git.SwitchBranch(name)
+			fileops.CheckIfErrorWithMessage(err, "Error switching branch")
+			fileops.ColorPrintfn(fileops.Green, "Switched to branch %s", name)
+		}
+	},
+	Example: `oh-my-dot branch create work-laptop --switch
+oh-my-dot branch create home-desktop --upstream main`,
+}
+
+var branchSwitchCommand = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Switch to another branch",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := git.SwitchBranch(args[0])
+		fileops.CheckIfErrorWithMessage(err, "Error switching branch")
+		fileops.ColorPrintfn(fileops.Green, "Switched to branch %s", args[0])
+	},
+}
@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/config"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/hosting"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -48,12 +53,18 @@ func showAllConfig() {
 		fileops.ColorPrintfn(fileops.Green, "%s", repoPath)
 	}
 
-	// Remote URL if set
-	remoteURL := viper.GetString("remote-url")
+	// Remote URL if set, with the shorthand it was resolved from
+	shorthand, remoteURL := remoteURLs()
 	if remoteURL != "" {
 		fileops.ColorPrintf(fileops.Blue, "  remote-url: ")
 		fileops.ColorPrintfn(fileops.Green, "%s", remoteURL)
 	}
+	if shorthand != "" {
+		fileops.ColorPrintf(fileops.Blue, "  remote-shorthand: ")
+		fileops.ColorPrintfn(fileops.Green, "%s", shorthand)
+	}
+	fileops.ColorPrintf(fileops.Blue, "  remote-protocol: ")
+	fileops.ColorPrintfn(fileops.Green, "%s", remoteProtocolDescription())
 
 	// Initialized status
 	initialized := viper.GetBool("initialized")
@@ -63,6 +74,71 @@ func showAllConfig() {
 	allowGHAuth := viper.GetBool(allowGHAuthConfigKey)
 	fileops.ColorPrintf(fileops.Blue, "  allow-gh-auth: ")
 	fileops.ColorPrintfn(fileops.Green, "%t", allowGHAuth)
+
+	// Commit identity, when it overrides git's user.name and user.email
+	if authorName := viper.GetString(git.CommitAuthorNameKey); authorName != "" {
+		fileops.ColorPrintf(fileops.Blue, "  author-name: ")
+		fileops.ColorPrintfn(fileops.Green, "%s", authorName)
+	}
+	if authorEmail := viper.GetString(git.CommitAuthorEmailKey); authorEmail != "" {
+		fileops.ColorPrintf(fileops.Blue, "  author-email: ")
+		fileops.ColorPrintfn(fileops.Green, "%s", authorEmail)
+	}
+
+	fileops.ColorPrintf(fileops.Blue, "  machine-trailer: ")
+	fileops.ColorPrintfn(fileops.Green, "%t", machineTrailerEnabled())
+
+	fileops.ColorPrintf(fileops.Blue, "  clone-depth: ")
+	fileops.ColorPrintfn(fileops.Green, "%s", cloneDepthDescription())
+
+	fileops.ColorPrintf(fileops.Blue, "  offline: ")
+	fileops.ColorPrintfn(fileops.Green, "%t", network.Offline())
+	fileops.ColorPrintf(fileops.Blue, "  network-timeout: ")
+	fileops.ColorPrintfn(fileops.Green, "%s (checks: %s)", network.Timeout(network.Transfer), network.Timeout(network.Check))
+
+	// State recorded by oh-my-dot itself, kept out of the config file
+	if check := config.LoadState().UpdateCheck; !check.LastSuccess.IsZero() {
+		fileops.ColorPrintf(fileops.Blue, "  last-remote-check: ")
+		fileops.ColorPrintfn(fileops.Green, "%s (%s)", check.LastSuccess.Local().Format(time.DateTime), check.RemoteState)
+	}
+}
+
+// remoteURLs returns the shorthand the remote was given as, if any, and the URL it resolves to.
+// A shorthand written to remote-url by hand is resolved with the configured protocol.
+func remoteURLs() (shorthand, resolved string) {
+	shorthand, resolved = viper.GetString(remoteShorthandKey), viper.GetString("remote-url")
+	if hosting.IsShorthand(resolved) {
+		shorthand = resolved
+		protocol, err := hosting.ParseProtocol(viper.GetString(hosting.ProtocolKey))
+		if err != nil {
+			protocol = hosting.ProtocolHTTPS
+		}
+		if url, err := hosting.ResolveRemote(resolved, protocol); err == nil {
+			resolved = url
+		}
+	}
+	return shorthand, resolved
+}
+
+// remoteProtocolDescription describes the protocol shorthand remotes resolve to.
+func remoteProtocolDescription() string {
+	if protocol := viper.GetString(hosting.ProtocolKey); protocol != "" {
+		return protocol
+	}
+	return "https (default)"
+}
+
+// cloneDepthDescription describes the default clone depth of init.
+func cloneDepthDescription() string {
+	if depth := viper.GetInt(git.CloneDepthKey); depth > 0 {
+		return fmt.Sprintf("%d", depth)
+	}
+	return "full history"
+}
+
+// machineTrailerEnabled reports whether commits get a Machine trailer; it is on unless disabled.
+func machineTrailerEnabled() bool {
+	return !viper.IsSet(git.CommitMachineTrailerKey) || viper.GetBool(git.CommitMachineTrailerKey)
 }
 
 func showConfigValue(key string) {
@@ -87,17 +163,44 @@ func showConfigValue(key string) {
 		} else {
 			fmt.Printf("%s is not set\n", key)
 		}
-	case "remote-url":
-		value := viper.GetString("remote-url")
+	case "remote-url", "remote-shorthand":
+		shorthand, value := remoteURLs()
+		if key == "remote-shorthand" {
+			value = shorthand
+		}
 		if value != "" {
 			fmt.Println(value)
 		} else {
 			fmt.Printf("%s is not set\n", key)
 		}
+	case "remote-protocol":
+		fmt.Println(remoteProtocolDescription())
 	case "allow-gh-auth":
 		fmt.Printf("%t\n", viper.GetBool(allowGHAuthConfigKey))
+	case "author-name", "author-email":
+		value := viper.GetString("commit." + key)
+		if value != "" {
+			fmt.Println(value)
+		} else {
+			fmt.Printf("%s is not set\n", key)
+		}
+	case "machine-trailer":
+		fmt.Printf("%t\n", machineTrailerEnabled())
+	case "clone-depth":
+		fmt.Println(cloneDepthDescription())
+	case "offline":
+		fmt.Printf("%t\n", network.Offline())
+	case "network-timeout":
+		fmt.Println(network.Timeout(network.Transfer))
+	case "last-remote-check":
+		check := config.LoadState().UpdateCheck
+		if check.LastSuccess.IsZero() {
+			fmt.Printf("%s is not set\n", key)
+		} else {
+			fmt.Println(check.LastSuccess.Format(time.RFC3339))
+		}
 	default:
 		fmt.Printf("Unknown config key: %s\n", key)
-		fmt.Println("Valid keys: location, dotfiles, remote-url, initialized, allow-gh-auth")
+		fmt.Println("Valid keys: location, dotfiles, remote-url, remote-shorthand, remote-protocol, initialized, allow-gh-auth, author-name, author-email, machine-trailer, clone-depth, offline, network-timeout, last-remote-check")
 	}
 }
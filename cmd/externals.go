@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/externals"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	externalsUpdateCommand.Flags().BoolP("no-commit", "n", false, "Write the new pins without committing them")
+	externalsUpdateCommand.Flags().Bool("apply", false, "Apply the updated externals after bumping their pins")
+
+	externalsCommand.AddCommand(externalsListCommand)
+	externalsCommand.AddCommand(externalsUpdateCommand)
+	rootCmd.AddCommand(externalsCommand)
+}
+
+var externalsCommand = &cobra.Command{
+	Use:   "externals",
+	Short: "Manage third-party repos and archives pinned in externals.yaml",
+	Long: `Manage third-party sources declared in externals.yaml at the root of the dotfiles repository.
+Git repositories are pinned to a commit and archives to a sha256 checksum. "oh-my-dot apply"
+fetches them into a local cache and links (git) or extracts (archives) them to their destination
+under the home directory. "oh-my-dot externals update" moves the pins forward and commits them.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		externalsListCommand.Run(externalsListCommand, args)
+	},
+}
+
+var externalsListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List externals and whether they are applied",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := externals.Load(viper.GetString("repo-path"))
+		fileops.CheckIfErrorWithMessage(err, "Error reading externals")
+
+		if len(file.Externals) == 0 {
+			fileops.ColorPrintfn(fileops.Yellow, "No externals declared in %s", externals.FileName)
+			return
+		}
+
+		cacheDir := externals.CacheDir()
+		for _, external := range file.Externals {
+			state, err := externals.Status(cacheDir, external)
+			status := fileops.SColorPrint(string(state), externalStateColor(state))
+			if err != nil {
+				status = fileops.SColorPrint(err.Error(), fileops.Red)
+			}
+			fmt.Printf("%s  %s %s @ %s -> %s  %s\n",
+				fileops.SColorPrint(external.Name, fileops.Cyan), external.Type, external.URL, external.Pin(), external.Dest, status)
+		}
+	},
+}
+
+var externalsUpdateCommand = &cobra.Command{
+	Use:   "update [name...]",
+	Short: "Bump pinned externals to their latest version and commit",
+	Long: `Move the pins of the named externals, or all externals, forward.
+Git externals follow their ref (a branch or tag, or the remote HEAD when unset) and archives
+are downloaded again and pinned to their new checksum.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		noCommit, _ := cmd.Flags().GetBool("no-commit")
+		apply, _ := cmd.Flags().GetBool("apply")
+		repoPath := viper.GetString("repo-path")
+
+		file, err := externals.Load(repoPath)
+		fileops.CheckIfErrorWithMessage(err, "Error reading externals")
+
+		changes, err := externals.Update(context.Background(), file, args)
+		fileops.CheckIfErrorWithMessage(err, "Error updating externals")
+
+		if len(changes) == 0 {
+			fileops.ColorPrintln("All externals are up to date", fileops.Green)
+			return
+		}
+
+		names := make([]string, 0, len(changes))
+		for _, change := range changes {
+			fmt.Printf("%s  %s -> %s\n", fileops.SColorPrint(change.Name, fileops.Cyan), shortPin(change.From), shortPin(change.To))
+			names = append(names, change.Name)
+		}
+
+		err = externals.Save(repoPath, file)
+		fileops.CheckIfErrorWithMessage(err, "Error writing externals")
+
+		if !noCommit {
+			err = git.StageChange(externals.FileName)
+			fileops.CheckIfErrorWithMessage(err, "Error staging externals")
+
+			err = git.Commit(git.CommitMessage(git.OpExternalsUpdate, git.MessageData{Name: strings.Join(names, ", ")}))
+			exitOnSecrets(cmd, err)
+			fileops.CheckIfErrorWithMessage(err, "Error committing externals")
+		}
+
+		fileops.ColorPrintfn(fileops.Green, "Updated %d externals", len(changes))
+
+		if apply {
+			fmt.Println()
+			if _, failed := applyExternals(repoPath, false); failed > 0 {
+				os.Exit(exitcodes.Error)
+			}
+		}
+	},
+	Example: `oh-my-dot externals update
+oh-my-dot externals update zsh-autosuggestions --apply`,
+}
+
+// applyExternals fetches and places every external, printing one line per change.
+// Returns the number of externals that are in place and the number that failed.
+func applyExternals(repoPath string, verbose bool) (int, int) {
+	results, err := externals.Apply(context.Background(), repoPath)
+	if err != nil {
+		fileops.ColorPrintfn(fileops.Red, "  Error reading externals: %s", err)
+		return 0, 1
+	}
+
+	applied, failed := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fileops.ColorPrintfn(fileops.Red, "  Error applying %s: %s", result.Name, result.Err)
+		case result.Action == externals.ActionUnchanged:
+			applied++
+			if verbose {
+				fileops.ColorPrintfn(fileops.Reset, "  Skipping %s: already at the pinned version", result.Name)
+			}
+		default:
+			applied++
+			fileops.ColorPrintfn(fileops.Green, "  %s: %s at %s ✓", result.Name, result.Action, result.Dest)
+		}
+	}
+	return applied, failed
+}
+
+func externalStateColor(state externals.State) string {
+	switch state {
+	case externals.StateApplied:
+		return fileops.Green
+	case externals.StateConflict:
+		return fileops.Red
+	default:
+		return fileops.Yellow
+	}
+}
+
+func shortPin(pin string) string {
+	if len(pin) > 12 {
+		return pin[:12]
+	}
+	return pin
+}
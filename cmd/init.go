@@ -8,6 +8,7 @@ import (
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/hosting"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,6 +24,12 @@ func init() {
 	viper.BindPFlag("repo-path", initcmd.Flags().Lookup("folder"))
 
 	initcmd.Flags().BoolP("force", "", false, "Force initialization if previously initialized") //  or if given directory is not empty?
+	initcmd.Flags().StringP("branch", "b", "", "Use a per-machine branch that rebases onto the cloned branch on pull")
+	initcmd.Flags().String("protocol", "", "Protocol for shorthand remotes like gh:username/dotfiles: ssh or https (saved as remote.protocol)")
+	initcmd.Flags().Bool("create-remote", false, "Create the remote as a private repository on GitHub, GitLab or Gitea, then push the first commit")
+	initcmd.Flags().String("provider", "", "Hosting provider for --create-remote: github, gitlab or gitea (detected for public hosts)")
+	initcmd.Flags().String("api-url", "", "API root for --create-remote on a self-hosted instance, e.g. https://git.example.com/api/v1")
+	initcmd.Flags().Int("depth", 0, "Clone only the latest N commits (default from clone.depth; 0 clones the full history)")
 	rootCmd.AddCommand(initcmd)
 }
 
@@ -32,6 +39,8 @@ var initcmd = &cobra.Command{
 	Short:   "Initialize dotfiles management",
 	Long: `Initialize dotfiles management.
 Makes a git repository and sets remote origin to the specified URL.
+Shorthands like github.com/username/dotfiles, gh:username/dotfiles and gitlab:username/dotfiles
+resolve to an SSH or HTTPS URL; choose with --protocol or the remote.protocol config.
 The clone is placed in $HOME/dotfiles by default, but can be changed with --folder <new path>`,
 	Run: func(cmd *cobra.Command, args []string) {
 		force, _ := cmd.Flags().GetBool("force")
@@ -44,6 +53,7 @@ The clone is placed in $HOME/dotfiles by default, but can be changed with --fold
 		
 		if git.IsGitRepo(viper.GetString("repo-path")) && !force {
 			git.InitFromExistingRepo(viper.GetString("repo-path"))
+			useMachineBranch(cmd)
 			fileops.ColorPrintln("Dotfiles repo initialized 🎉🎉🎉", fileops.Green)
 			viper.Set("initialized", true)
 			viper.WriteConfig()
@@ -88,8 +98,40 @@ The clone is placed in $HOME/dotfiles by default, but can be changed with --fold
 			}
 		}
 
-		_, err := git.InitGitRepo(viper.GetString("repo-path"), viper.GetString("remote-url"))
+		remote := viper.GetString("remote-url")
+		protocol := ""
+		if hosting.IsShorthand(remote) {
+			protocol = remoteProtocol(cmd)
+			viper.Set(remoteShorthandKey, remote)
+		} else if viper.GetString(remoteShorthandKey) != "" {
+			viper.Set(remoteShorthandKey, "")
+		}
+
+		createRemote, _ := cmd.Flags().GetBool("create-remote")
+		if createRemote {
+			if remote == "" {
+				fileops.ColorPrintln("--create-remote needs the repository to create, e.g. github.com/username/dotfiles", fileops.Red)
+				os.Exit(exitcodes.MissingArgs)
+			}
+			viper.Set("remote-url", createRemoteRepository(cmd, remote, protocol))
+		} else if protocol != "" {
+			resolved, err := hosting.ResolveRemote(remote, protocol)
+			fileops.CheckIfErrorWithMessage(err, "Error resolving remote URL")
+			viper.Set("remote-url", resolved)
+		}
+		if protocol != "" {
+			fileops.ColorPrintfn(fileops.Cyan, "Using %s for %s", viper.GetString("remote-url"), remote)
+		}
+
+		r, err := git.InitGitRepoWithOptions(viper.GetString("repo-path"), viper.GetString("remote-url"), git.InitOptions{Depth: cloneDepth(cmd)})
 		fileops.CheckIfErrorWithMessage(err, "Error initializing git repository")
+		if git.IsShallow(r) {
+			fileops.ColorPrintln("Shallow clone: older history is fetched when a pull needs it", fileops.Cyan)
+		}
+		if createRemote {
+			publishInitialCommit()
+		}
+		useMachineBranch(cmd)
 
 		fileops.ColorPrintln("Dotfiles repo initialized 🎉🎉🎉", fileops.Green)
 
@@ -109,5 +151,35 @@ The clone is placed in $HOME/dotfiles by default, but can be changed with --fold
 	},
 	GroupID: "basics",
 	Example: `oh-my-dot init github.com/username/dotfiles
-oh-my-dot init -r github.com/username/dotfiles -f $HOME/myCoolDotfiles`,
+oh-my-dot init -r github.com/username/dotfiles -f $HOME/myCoolDotfiles
+oh-my-dot init gh:username/dotfiles --protocol ssh
+oh-my-dot init github.com/username/dotfiles --branch work-laptop
+oh-my-dot init github.com/username/dotfiles --depth 1
+oh-my-dot init github.com/username/dotfiles --create-remote`,
+}
+
+// useMachineBranch switches to the branch given with --branch, creating it to track the cloned branch.
+func useMachineBranch(cmd *cobra.Command) {
+	branch, _ := cmd.Flags().GetString("branch")
+	if branch == "" {
+		return
+	}
+
+	err := git.CheckoutTrackingBranch(branch)
+	fileops.CheckIfErrorWithMessage(err, "Error setting up machine branch")
+	fileops.ColorPrintfn(fileops.Cyan, "Using machine branch %s", branch)
+}
+
+// cloneDepth returns the depth given with --depth, or the clone.depth config default.
+func cloneDepth(cmd *cobra.Command) int {
+	depth := viper.GetInt(git.CloneDepthKey)
+	if cmd.Flags().Changed("depth") {
+		depth, _ = cmd.Flags().GetInt("depth")
+	}
+
+	if depth < 0 {
+		fileops.ColorPrintln("Error: --depth cannot be negative", fileops.Red)
+		os.Exit(exitcodes.Error)
+	}
+	return depth
 }
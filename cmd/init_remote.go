@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/hosting"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// remoteShorthandKey records the shorthand init was given, so config can show it next to the resolved URL.
+const remoteShorthandKey = "remote.shorthand"
+
+// createRemoteRepository creates the repository named by remoteURL through its provider's API
+// and returns the URL to use as origin. A shorthand remote resolves to the clone URL for protocol.
+func createRemoteRepository(cmd *cobra.Command, remoteURL, protocol string) string {
+	repo, err := hosting.ParseRepository(remoteURL)
+	if err != nil {
+		fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+		os.Exit(exitcodes.MissingArgs)
+	}
+
+	provider, err := remoteProvider(cmd, repo)
+	if err != nil {
+		fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+		os.Exit(exitcodes.MissingArgs)
+	}
+
+	token := hosting.TokenFromEnv(provider)
+	if token == "" {
+		fileops.ColorPrintfn(fileops.Red, "Error: no %s token found", provider.Title())
+		fileops.ColorPrintfn(fileops.Yellow, "Set %s to a token that may create repositories", strings.Join(provider.TokenEnvVars(), " or "))
+		os.Exit(exitcodes.MissingArgs)
+	}
+
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	if apiURL == "" {
+		apiURL = provider.DefaultAPIURL(repo.Host)
+	}
+
+	client := &hosting.Client{Provider: provider, APIURL: apiURL, Token: token}
+	created, err := client.CreateRepository(context.Background(), repo, true)
+	switch {
+	case errors.Is(err, hosting.ErrExists):
+		fileops.ColorPrintfn(fileops.Yellow, "%s already exists on %s; using it", repo, provider.Title())
+		created = hosting.Created{HTTPSURL: repo.HTTPSURL(), SSHURL: repo.SSHURL()}
+	case err != nil:
+		fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+		os.Exit(exitcodes.Error)
+	default:
+		location := created.WebURL
+		if location == "" {
+			location = repo.String()
+		}
+		fileops.ColorPrintfn(fileops.Green, "Created private repository %s", location)
+	}
+
+	if !hosting.IsShorthand(remoteURL) {
+		return remoteURL
+	}
+	if protocol == hosting.ProtocolSSH {
+		if created.SSHURL != "" {
+			return created.SSHURL
+		}
+		return repo.SSHURL()
+	}
+	if created.HTTPSURL != "" {
+		return created.HTTPSURL
+	}
+	return repo.HTTPSURL()
+}
+
+// remoteProtocol returns the protocol shorthand remotes resolve to: --protocol, then remote.protocol.
+// Without either it offers SSH when an SSH key is set up, and otherwise uses HTTPS.
+func remoteProtocol(cmd *cobra.Command) string {
+	name, _ := cmd.Flags().GetString("protocol")
+	if name == "" {
+		name = viper.GetString(hosting.ProtocolKey)
+	}
+	if name != "" {
+		protocol, err := hosting.ParseProtocol(name)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.MissingArgs)
+		}
+		viper.Set(hosting.ProtocolKey, protocol)
+		return protocol
+	}
+
+	key := sshKeyPath()
+	if key == "" {
+		return hosting.ProtocolHTTPS
+	}
+	if !interactive.ShouldPrompt(cmd, false) {
+		fileops.ColorPrintfn(fileops.Yellow, "Found the SSH key %s; pass --protocol ssh to clone over SSH instead of HTTPS", key)
+		return hosting.ProtocolHTTPS
+	}
+
+	useSSH, err := interactive.PromptConfirm(fmt.Sprintf("Found the SSH key %s. Clone over SSH?", key))
+	if err != nil {
+		fileops.ColorPrintln("Cancelled", fileops.Yellow)
+		os.Exit(exitcodes.Error)
+	}
+	protocol := hosting.ProtocolHTTPS
+	if useSSH {
+		protocol = hosting.ProtocolSSH
+	}
+	viper.Set(hosting.ProtocolKey, protocol)
+	return protocol
+}
+
+// sshKeyPath returns the SSH key to suggest SSH for: the SSH signing key if one is configured,
+// otherwise one of ssh's default identity files.
+func sshKeyPath() string {
+	if viper.GetString(git.SigningFormatKey) == "ssh" {
+		if key, err := fileops.ExpandPath(viper.GetString(git.SigningKeyKey)); err == nil && fileops.PathExists(key) {
+			return key
+		}
+	}
+	return hosting.DefaultSSHKey()
+}
+
+// remoteProvider returns the provider given with --provider, or the one known to host the repository.
+func remoteProvider(cmd *cobra.Command, repo hosting.Repository) (hosting.Provider, error) {
+	if name, _ := cmd.Flags().GetString("provider"); name != "" {
+		return hosting.ParseProvider(name)
+	}
+
+	provider, ok := hosting.DetectProvider(repo.Host)
+	if !ok {
+		return "", errors.New("cannot tell which provider hosts " + repo.Host + "; pass --provider github, gitlab or gitea")
+	}
+	return provider, nil
+}
+
+// publishInitialCommit gives a freshly created remote its first commit.
+func publishInitialCommit() {
+	committed, err := git.CreateInitialCommit(git.CommitMessage(git.OpInit, git.MessageData{}))
+	fileops.CheckIfErrorWithMessage(err, "Error creating the initial commit")
+	if !committed {
+		return
+	}
+
+	if err := git.PushRepo(); err != nil {
+		if git.IsSSHAgentError(err) {
+			git.DisplaySSHAgentError(false)
+		}
+		fileops.ColorPrintfn(fileops.Red, "Error pushing the initial commit: %s", err)
+		fileops.ColorPrintfn(fileops.Yellow, "The repository is set up locally; run 'oh-my-dot push' once the remote is reachable")
+		return
+	}
+	fileops.ColorPrintln("Pushed the initial commit", fileops.Green)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/symlink"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	logCommand.Flags().BoolP("patch", "p", false, "Show the diff of each commit")
+	logCommand.Flags().IntP("limit", "n", 0, "Show at most this many commits")
+	logCommand.Flags().String("shell", "", "Show the history of a shell's configuration (omd-shells/<shell>)")
+
+	rootCmd.AddCommand(logCommand)
+}
+
+var logCommand = &cobra.Command{
+	Aliases: []string{"history"},
+	Use:     "log [file]",
+	Short:   "Show the change history of dotfiles",
+	Long: `Show the change history of the dotfiles repository.
+Without arguments, shows every commit that changed a dotfile, linkings.json or shell configuration.
+A file can be given by its target path (~/.zshrc) or its name in the repository (.zshrc).`,
+	GroupID: "dotfiles",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		patch, _ := cmd.Flags().GetBool("patch")
+		limit, _ := cmd.Flags().GetInt("limit")
+		shellName, _ := cmd.Flags().GetString("shell")
+
+		if shellName != "" && len(args) > 0 {
+			fileops.ColorPrintln("Specify either a file or --shell, not both", fileops.Red)
+			os.Exit(exitcodes.Error)
+		}
+
+		filter := git.HistoryFilter{
+			Paths:    []string{"linkings.json"},
+			Prefixes: []string{"files/", "omd-shells/"},
+		}
+
+		switch {
+		case shellName != "":
+			if _, ok := shell.GetShellConfig(shellName); !ok {
+				fileops.ColorPrintfn(fileops.Red, "Unsupported shell: %s", shellName)
+				os.Exit(exitcodes.Error)
+			}
+			filter = git.HistoryFilter{Prefixes: []string{"omd-shells/" + shellName + "/"}}
+		case len(args) > 0:
+			name, err := resolveTrackedFile(args[0])
+			if err != nil {
+				fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+				os.Exit(exitcodes.Error)
+			}
+			filter = git.HistoryFilter{Paths: []string{"files/" + name}, Linking: name}
+		}
+
+		entries, err := git.History(filter, limit, patch)
+		fileops.CheckIfErrorWithMessage(err, "Error reading history")
+
+		if len(entries) == 0 {
+			fileops.ColorPrintln("No history found", fileops.Yellow)
+			return
+		}
+
+		for _, entry := range entries {
+			printHistoryEntry(entry, patch)
+		}
+	},
+	Example: `oh-my-dot log
+oh-my-dot log ~/.zshrc --patch
+oh-my-dot log .gitconfig -n 5
+oh-my-dot log --shell zsh`,
+}
+
+func printHistoryEntry(entry git.HistoryEntry, patch bool) {
+	machine := entry.Machine
+	if machine == "" {
+		machine = "-"
+	}
+
+	fmt.Printf("%s  %s  %s  %s\n",
+		fileops.SColorPrint(entry.Hash.String()[:7], fileops.Yellow),
+		entry.When.Local().Format("2006-01-02 15:04"),
+		fileops.SColorPrint(machine, fileops.Cyan),
+		entry.Subject(),
+	)
+
+	if patch {
+		fmt.Println()
+		fmt.Println(strings.TrimRight(entry.Patch, "\n"))
+		fmt.Println()
+	}
+}
+
+// resolveTrackedFile maps a target path (~/.zshrc) or repository name (.zshrc, files/.zshrc)
+// to the file's name under files/.
+func resolveTrackedFile(arg string) (string, error) {
+	linkings, err := symlink.GetLinkings()
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimPrefix(arg, "files/")
+	if _, ok := linkings[name]; ok {
+		return name, nil
+	}
+
+	if expanded, err := fileops.ExpandPath(arg); err == nil {
+		if linkPath, err := symlink.BuildLinkPath(expanded); err == nil {
+			for file, link := range linkings {
+				if link == linkPath {
+					return file, nil
+				}
+			}
+		}
+	}
+
+	// Files that are no longer linked still have history under their repository name.
+	if !strings.ContainsAny(name, `/\`) {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("%s is not a tracked dotfile", arg)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	promoteCommand.Flags().StringP("to", "t", "main", "Shared branch to copy the commit to")
+	promoteCommand.Flags().BoolP("push", "p", false, "Push the shared branch after promoting")
+	rootCmd.AddCommand(promoteCommand)
+}
+
+var promoteCommand = &cobra.Command{
+	Use:   "promote [commit]",
+	Short: "Copy a commit from the machine branch to the shared branch",
+	Long: `Copy a commit from the current machine branch to the shared branch (main by default).
+The commit is cherry-picked without changing the checked out files. Defaults to the latest commit.`,
+	GroupID: "dotfiles",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		revision := "HEAD"
+		if len(args) > 0 {
+			revision = args[0]
+		}
+		target, _ := cmd.Flags().GetString("to")
+		push, _ := cmd.Flags().GetBool("push")
+
+		hash, err := git.PromoteCommit(revision, target)
+		if errors.Is(err, git.ErrNothingToPromote) {
+			fileops.ColorPrintfn(fileops.Yellow, "%s already contains these changes", target)
+			return
+		}
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error promoting commit: %s", err)
+			var conflictErr *git.ConflictError
+			if errors.As(err, &conflictErr) {
+				os.Exit(exitcodes.Conflict)
+			}
+			os.Exit(exitcodes.Error)
+		}
+
+		fileops.ColorPrintfn(fileops.Green, "Promoted %s to %s as %s", revision, target, hash.String()[:7])
+
+		if !push {
+			return
+		}
+
+		results, err := git.PushBranch(target)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error pushing %s: %s", target, err)
+			os.Exit(exitcodes.Error)
+		}
+		if !printPushResults(results) {
+			os.Exit(exitcodes.Error)
+		}
+	},
+	Example: `oh-my-dot promote
+oh-my-dot promote 1a2b3c4 --to main --push`,
+}
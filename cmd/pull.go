@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"path"
+	"strings"
 
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// errUnverifiedPull stops a pull with --verify-signatures when a pulled commit is not verified
+var errUnverifiedPull = errors.New("pulled commits are not all verified")
+
 func init() {
+	pullCommand.Flags().Bool("no-apply", false, "Don't link new files or regenerate init scripts after pulling")
+	pullCommand.Flags().StringP("strategy", "s", "", "Resolve conflicting files automatically: ours (keep local) or theirs (take remote)")
+	pullCommand.Flags().Bool("verify-signatures", false, "Check the signatures of pulled commits")
 	rootCmd.AddCommand(pullCommand)
 }
 
@@ -16,12 +30,21 @@ var pullCommand = &cobra.Command{
 	Aliases: []string{"pl"},
 	Use:     "pull",
 	Short:   "Pull changes from the remote repository",
-	Long:    `Pull changes from the remote repository.`,
+	Long: `Pull changes from the remote repository.
+When local and remote history have diverged, the changes are merged (or rebased for machine branches).
+Files changed on both sides are merged line by line, and enabled.json and linkings.json are merged by
+feature and by file. Anything left is resolved interactively, or with --strategy ours|theirs.`,
 	GroupID: "dotfiles",
 	PreRun: func(cmd *cobra.Command, args []string) {
 		git.CheckRemoteAccessWithHelp(true)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		resolve, err := conflictResolverForCommand(cmd)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+
 		state, err := git.GetRemoteSyncState()
 		if err == nil {
 			switch state {
@@ -31,26 +54,140 @@ var pullCommand = &cobra.Command{
 			case git.RemoteSyncLocalAhead:
 				fileops.ColorPrintfn(fileops.Cyan, "Local repository is ahead of remote. Nothing to pull.")
 				return
+			case git.RemoteSyncDiverged:
+				fileops.ColorPrintfn(fileops.Cyan, "Local and remote history diverged. Merging remote changes...")
 			}
 		}
 
-		updated, err := git.PullRepo()
+		opts := git.PullOptions{Resolve: resolve}
+		if verify, _ := cmd.Flags().GetBool("verify-signatures"); verify {
+			opts.Verify = verifyPulledSignatures
+		}
+
+		result, err := git.PullRepoWithOptions(opts)
 		if err != nil {
-			if git.IsSSHAgentError(err) {
+			var conflictErr *git.ConflictError
+			if errors.Is(err, errUnverifiedPull) {
+				fileops.ColorPrintfn(fileops.Yellow, "Pulled changes were not applied; the branch and files are unchanged")
+				os.Exit(exitcodes.Error)
+			} else if git.IsSSHAgentError(err) {
 				git.DisplaySSHAgentError(true)
-			} else if state == git.RemoteSyncDiverged {
-				fileops.ColorPrintfn(fileops.Red, "Local and remote history diverged. Resolve conflicts and retry pull: %s", err)
-				os.Exit(1)
+			} else if git.DisplaySecretsError(err, false) {
+				os.Exit(exitcodes.Error)
+			} else if errors.As(err, &conflictErr) {
+				fileops.ColorPrintfn(fileops.Red, "Local and remote changes conflict in: %s", strings.Join(conflictErr.Paths, ", "))
+				fileops.ColorPrintln("Run pull interactively or use --strategy ours|theirs to resolve them", fileops.Yellow)
+				os.Exit(exitcodes.Conflict)
 			}
 			fileops.ColorPrintfn(fileops.Red, "Error pulling changes: %s", err)
-			os.Exit(1)
+			os.Exit(exitcodes.Error)
 		}
 
-		if !updated {
+		if !result.Updated {
 			fileops.ColorPrintfn(fileops.Green, "Already up to date")
 			return
 		}
 
 		fileops.ColorPrintfn(fileops.Green, "Pulled latest changes from repository")
+
+		if noApply, _ := cmd.Flags().GetBool("no-apply"); noApply {
+			fileops.ColorPrintfn(fileops.Yellow, "Run '%s apply' to link new files and refresh shell integration", cmd.Root().Name())
+			return
+		}
+
+		summary, err := reconcileAfterPull(viper.GetString("repo-path"), result)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error applying pulled changes: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+		printPullReconciliation(summary)
 	},
+	Example: `oh-my-dot pull
+oh-my-dot pull --strategy theirs
+oh-my-dot pull --no-apply
+oh-my-dot pull --verify-signatures`,
+}
+
+// verifyPulledSignatures checks the signatures of the commits a pull would apply, before the branch moves
+func verifyPulledSignatures(result git.PullResult) error {
+	statuses, err := git.VerifyPulledCommits(result)
+	if err != nil {
+		return fmt.Errorf("failed to verify signatures: %w", err)
+	}
+	if !printSignatureStatuses(statuses) {
+		return errUnverifiedPull
+	}
+	return nil
+}
+
+// conflictResolverForCommand picks how conflicting files are resolved: the --strategy flag,
+// interactive prompts, or nil to fail and list the conflicting files.
+func conflictResolverForCommand(cmd *cobra.Command) (git.ConflictResolver, error) {
+	strategy, _ := cmd.Flags().GetString("strategy")
+	if strategy != "" {
+		choice, err := git.ParseConflictStrategy(strategy)
+		if err != nil {
+			return nil, err
+		}
+		return git.ResolveWith(choice), nil
+	}
+
+	if interactive.GetMode(cmd) == interactive.ModeNonInteractive {
+		return nil, nil
+	}
+
+	return promptConflictResolution, nil
+}
+
+// maxConflictPreviewLines limits how much of each side is printed before prompting.
+const maxConflictPreviewLines = 30
+
+func promptConflictResolution(conflict git.Conflict) (git.Resolution, error) {
+	fmt.Println()
+	fileops.ColorPrintfn(fileops.Yellow, "Conflict in %s", conflict.Path)
+	printConflictSide("ours (local)", conflict.Ours, fileops.Green)
+	printConflictSide("theirs (remote)", conflict.Theirs, fileops.Cyan)
+
+	options := []string{"Keep ours (local)", "Take theirs (remote)", "Edit the merged file"}
+	for {
+		choice, err := interactive.PromptSelect("Resolve "+conflict.Path, options)
+		if err != nil {
+			return git.Resolution{}, err
+		}
+
+		switch choice {
+		case 0:
+			return git.Resolution{Choice: git.ChooseOurs}, nil
+		case 1:
+			return git.Resolution{Choice: git.ChooseTheirs}, nil
+		}
+
+		edited, err := interactive.EditText(conflict.Merged, "omd-merge-*-"+path.Base(conflict.Path))
+		if err != nil {
+			return git.Resolution{}, err
+		}
+		if bytes.Contains(edited, []byte("<<<<<<< ")) || bytes.Contains(edited, []byte(">>>>>>> ")) {
+			fileops.ColorPrintln("The file still contains conflict markers", fileops.Yellow)
+			continue
+		}
+
+		return git.Resolution{Choice: git.ChooseEdited, Content: edited}, nil
+	}
+}
+
+func printConflictSide(label string, content []byte, color string) {
+	fileops.ColorPrintfn(color, "--- %s", label)
+	if content == nil {
+		fmt.Println("  (deleted)")
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for i, line := range lines {
+		if i == maxConflictPreviewLines {
+			fmt.Printf("  ... %d more lines\n", len(lines)-i)
+			break
+		}
+		fmt.Println("  " + line)
+	}
 }
@@ -36,17 +36,43 @@ var pushCommand = &cobra.Command{
 			fileops.ColorPrintln("Detected local committed changes. Pushing...", fileops.Cyan)
 		}
 
-		err := git.PushRepo()
+		results, err := git.PushRepoToRemotes()
 		if err != nil {
-			if git.IsSSHAgentError(err) {
-				git.DisplaySSHAgentError(true)
-			} else {
-				fileops.ColorPrintfn(fileops.Red, "Error pushing changes: %s", err)
-				os.Exit(1)
-			}
-			return
+			fileops.ColorPrintfn(fileops.Red, "Error pushing changes: %s", err)
+			os.Exit(1)
 		}
 
-		fileops.ColorPrintfn(fileops.Green, "Pushed changes to repository")
+		if !printPushResults(results) {
+			os.Exit(1)
+		}
 	},
 }
+
+// printPushResults prints the outcome for each remote and reports whether every push succeeded.
+func printPushResults(results []git.PushResult) bool {
+	ok := true
+	for _, result := range results {
+		switch {
+		case result.Err != nil && git.IsSSHAgentError(result.Err):
+			ok = false
+			fileops.ColorPrintfn(fileops.Red, "✗ %s: SSH agent is not available", result.Remote)
+		case result.Err != nil:
+			ok = false
+			fileops.ColorPrintfn(fileops.Red, "✗ %s: %s", result.Remote, result.Err)
+		case result.UpToDate:
+			fileops.ColorPrintfn(fileops.Green, "✓ %s: already up to date", result.Remote)
+		default:
+			fileops.ColorPrintfn(fileops.Green, "✓ %s: pushed", result.Remote)
+		}
+	}
+
+	if !ok {
+		for _, result := range results {
+			if result.Err != nil && git.IsSSHAgentError(result.Err) {
+				git.DisplaySSHAgentError(false)
+				break
+			}
+		}
+	}
+	return ok
+}
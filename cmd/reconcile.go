@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/symlink"
+)
+
+// pullReconciliation records what was applied to the system after a pull.
+type pullReconciliation struct {
+	Linked      int
+	Relinked    int
+	Failed      int
+	Regenerated []string
+	Uncommitted []string // Regenerated files that now differ from the repository
+}
+
+// reconcileAfterPull applies what a pull changed: new or retargeted linkings are linked,
+// stale copies of changed files are relinked, and init scripts are regenerated for
+// shells whose configuration changed. Regenerated files are never committed here, so a
+// pull does not leave the branch ahead of the remote; changed ones are reported instead.
+func reconcileAfterPull(repoPath string, result git.PullResult) (pullReconciliation, error) {
+	var summary pullReconciliation
+
+	changed, err := git.ChangedFiles(result.OldHead, result.NewHead)
+	if err != nil {
+		return summary, err
+	}
+
+	if err := reconcileLinkings(repoPath, result, changed, &summary); err != nil {
+		return summary, err
+	}
+
+	for _, shellName := range changedShells(changed) {
+		if !shell.ShellDirectoryExists(repoPath, shellName) {
+			continue
+		}
+		if err := shell.RegenerateInitScript(repoPath, shellName); err != nil {
+			fileops.ColorPrintfn(fileops.Red, "  Error regenerating init script for %s: %s", shellName, err)
+			continue
+		}
+		summary.Regenerated = append(summary.Regenerated, shellName)
+	}
+
+	if len(summary.Regenerated) > 0 {
+		pending, err := git.PendingChanges()
+		if err != nil {
+			return summary, err
+		}
+		for _, p := range pending {
+			for _, shellName := range summary.Regenerated {
+				if strings.HasPrefix(filepath.ToSlash(p), "omd-shells/"+shellName+"/") {
+					summary.Uncommitted = append(summary.Uncommitted, p)
+					break
+				}
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+func reconcileLinkings(repoPath string, result git.PullResult, changed []string, summary *pullReconciliation) error {
+	linkings, err := symlink.GetLinkings()
+	if err != nil {
+		return err
+	}
+
+	previous, err := linkingsAt(result)
+	if err != nil {
+		return err
+	}
+
+	pending := symlink.Linkings{}
+	for name, link := range linkings {
+		source := filepath.Join(repoPath, "files", name)
+		oldLink, existed := previous[name]
+
+		if !existed || oldLink != link {
+			if existed {
+				removeStaleLink(oldLink, source)
+			}
+			pending[name] = link
+			continue
+		}
+
+		if slices.Contains(changed, "files/"+name) && relinkChangedFile(result, name, source, link) {
+			summary.Relinked++
+		}
+	}
+
+	if len(pending) > 0 {
+		linked, failed := applyLinkings(repoPath, pending, false)
+		summary.Linked += linked
+		summary.Failed += failed
+	}
+
+	return nil
+}
+
+// linkingsAt returns the linkings from before the pull.
+func linkingsAt(result git.PullResult) (symlink.Linkings, error) {
+	if result.OldHead.IsZero() {
+		return symlink.Linkings{}, nil
+	}
+
+	content, ok, err := git.FileAtCommit(result.OldHead, "linkings.json")
+	if err != nil || !ok {
+		return symlink.Linkings{}, err
+	}
+	return symlink.ParseLinkings(content)
+}
+
+// removeStaleLink removes the previous target of a retargeted linking, but only when it is
+// a symlink to the repository file so user files are never deleted.
+func removeStaleLink(link, source string) {
+	expanded, err := fileops.ExpandPath(link)
+	if err != nil {
+		return
+	}
+
+	if target, err := os.Readlink(expanded); err == nil && target == source {
+		os.Remove(expanded)
+	}
+}
+
+// relinkChangedFile replaces a target that no longer shares the repository file, which happens
+// with hard links when a pull rewrites the file. The target is only replaced when it still
+// holds the previous committed content, so local edits are kept.
+func relinkChangedFile(result git.PullResult, name, source, link string) bool {
+	expanded, err := fileops.ExpandPath(link)
+	if err != nil {
+		return false
+	}
+
+	targetInfo, err := os.Stat(expanded)
+	if err != nil {
+		return false
+	}
+	sourceInfo, err := os.Stat(source)
+	if err != nil || os.SameFile(targetInfo, sourceInfo) {
+		return false
+	}
+
+	oldContent, ok, err := git.FileAtCommit(result.OldHead, "files/"+name)
+	if err != nil || !ok {
+		return false
+	}
+	current, err := os.ReadFile(expanded)
+	if err != nil || !bytes.Equal(current, oldContent) {
+		return false
+	}
+
+	if err := os.Remove(expanded); err != nil {
+		return false
+	}
+	if err := createDotfileLink(source, expanded); err != nil {
+		fileops.ColorPrintfn(fileops.Red, "  Error relinking %s -> %s: %s", expanded, source, err)
+		return false
+	}
+
+	return true
+}
+
+// changedShells returns the supported shells with changed files under omd-shells/<shell>/,
+// or every supported shell when the shared omd-shells/env.json changed.
+func changedShells(changed []string) []string {
+	var shells []string
+	for _, p := range changed {
+		rest, ok := strings.CutPrefix(p, "omd-shells/")
+		if !ok {
+			continue
+		}
+		if rest == "env.json" {
+			all := slices.Sorted(maps.Keys(shell.SupportedShells()))
+			for _, shellName := range shells {
+				all = slices.DeleteFunc(all, func(name string) bool { return name == shellName })
+			}
+			return append(shells, all...)
+		}
+		shellName, _, ok := strings.Cut(rest, "/")
+		if !ok || slices.Contains(shells, shellName) {
+			continue
+		}
+		if _, supported := shell.GetShellConfig(shellName); supported {
+			shells = append(shells, shellName)
+		}
+	}
+	return shells
+}
+
+func printPullReconciliation(summary pullReconciliation) {
+	fileops.ColorPrintln("Applied pulled changes:", fileops.Cyan)
+
+	if summary.Linked == 0 && summary.Relinked == 0 && summary.Failed == 0 && len(summary.Regenerated) == 0 {
+		fileops.ColorPrintln("  (nothing to apply)", fileops.Reset)
+		return
+	}
+
+	if summary.Linked > 0 {
+		fileops.ColorPrintfn(fileops.Green, "  ✓ %d new or moved files linked", summary.Linked)
+	}
+	if summary.Relinked > 0 {
+		fileops.ColorPrintfn(fileops.Green, "  ✓ %d changed files relinked", summary.Relinked)
+	}
+	if len(summary.Regenerated) > 0 {
+		fileops.ColorPrintfn(fileops.Green, "  ✓ init scripts regenerated: %s", strings.Join(summary.Regenerated, ", "))
+	}
+	if len(summary.Uncommitted) > 0 {
+		fileops.ColorPrintfn(fileops.Yellow, "  ! regenerated files differ from the repository and were left uncommitted: %s", strings.Join(summary.Uncommitted, ", "))
+		fileops.ColorPrintfn(fileops.Yellow, "    Commit them with '%s sync' when they should be shared", assumedAlias())
+	}
+	if summary.Failed > 0 {
+		fileops.ColorPrintfn(fileops.Yellow, "  ✗ %d files could not be applied", summary.Failed)
+	}
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+func TestChangedShells(t *testing.T) {
+	changed := []string{
+		"files/.bashrc",
+		"linkings.json",
+		"omd-shells/bash/enabled.json",
+		"omd-shells/bash/features/git-prompt.sh",
+		"omd-shells/zsh/features/nvm.sh",
+		"omd-shells/unknown/enabled.json",
+	}
+
+	got := changedShells(changed)
+	want := []string{"bash", "zsh"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("changedShells = %v, want %v", got, want)
+	}
+
+	// The shared environment applies to every shell
+	got = changedShells([]string{"omd-shells/zsh/enabled.json", "omd-shells/env.json"})
+	want = []string{"zsh", "bash", "fish", "posix", "powershell"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("changedShells = %v, want %v", got, want)
+	}
+}
+
+func TestReconcileAfterPull_LinksNewFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	// Another machine adds a dotfile and its linking.
+	clonePath := t.TempDir()
+	clone, err := gogit.PlainClone(clonePath, false, &gogit.CloneOptions{URL: viper.GetString("remote-url")})
+	if err != nil {
+		t.Fatalf("clone remote: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(clonePath, "files"), 0755); err != nil {
+		t.Fatalf("create files dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clonePath, "files", ".testrc"), []byte("export TEST=1\n"), 0644); err != nil {
+		t.Fatalf("write dotfile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clonePath, "linkings.json"), []byte(`{".testrc": "~/.testrc"}`), 0644); err != nil {
+		t.Fatalf("write linkings: %v", err)
+	}
+	wt, err := clone.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	_, err = wt.Commit("Add .testrc", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := clone.Push(&gogit.PushOptions{}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	result, err := git.PullRepoWithOptions(git.PullOptions{})
+	if err != nil {
+		t.Fatalf("PullRepoWithOptions error: %v", err)
+	}
+	if !result.Updated {
+		t.Fatal("pull reported no updates")
+	}
+
+	summary, err := reconcileAfterPull(repoPath, result)
+	if err != nil {
+		t.Fatalf("reconcileAfterPull error: %v", err)
+	}
+	if summary.Linked != 1 || summary.Failed != 0 {
+		t.Fatalf("summary = %+v, want one linked file", summary)
+	}
+
+	content, err := os.ReadFile(filepath.Join(home, ".testrc"))
+	if err != nil {
+		t.Fatalf("read linked file: %v", err)
+	}
+	if string(content) != "export TEST=1\n" {
+		t.Fatalf("linked content = %q", content)
+	}
+}
+
+func TestReconcileAfterPull_LeavesRegeneratedScriptsUncommitted(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	// Another machine enables bash features without committing an init script.
+	clonePath := t.TempDir()
+	clone, err := gogit.PlainClone(clonePath, false, &gogit.CloneOptions{URL: viper.GetString("remote-url")})
+	if err != nil {
+		t.Fatalf("clone remote: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(clonePath, "omd-shells", "bash", "features"), 0755); err != nil {
+		t.Fatalf("create shell dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(clonePath, "omd-shells", "bash", "enabled.json"), []byte(`{"features": []}`+"\n"), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	wt, err := clone.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	_, err = wt.Commit("Add bash manifest", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := clone.Push(&gogit.PushOptions{}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	result, err := git.PullRepoWithOptions(git.PullOptions{})
+	if err != nil {
+		t.Fatalf("PullRepoWithOptions error: %v", err)
+	}
+
+	summary, err := reconcileAfterPull(repoPath, result)
+	if err != nil {
+		t.Fatalf("reconcileAfterPull error: %v", err)
+	}
+	if !slices.Equal(summary.Regenerated, []string{"bash"}) {
+		t.Fatalf("regenerated = %v, want bash", summary.Regenerated)
+	}
+	if !slices.Contains(summary.Uncommitted, "omd-shells/bash/init.sh") {
+		t.Fatalf("uncommitted = %v, want the bash init script", summary.Uncommitted)
+	}
+
+	repo, err := gogit.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	if head.Hash() != result.NewHead {
+		t.Fatal("expected the pull to leave HEAD at the pulled commit")
+	}
+}
+
+// commitFiles commits files to a repository, and pushes them when push is set, as another machine would
+func commitFiles(t *testing.T, clone *gogit.Repository, push bool, files map[string]string) {
+	t.Helper()
+
+	wt, err := clone.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(wt.Filesystem.Root(), name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("stage: %v", err)
+		}
+	}
+	_, err = wt.Commit("Change files", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if !push {
+		return
+	}
+	if err := clone.Push(&gogit.PushOptions{}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+}
+
+func TestPull_RegeneratedInitScriptDoesNotBlockPull(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	initPath := filepath.Join(repoPath, "omd-shells", "bash", "init.sh")
+
+	clone, err := gogit.PlainClone(t.TempDir(), false, &gogit.CloneOptions{URL: viper.GetString("remote-url")})
+	if err != nil {
+		t.Fatalf("clone remote: %v", err)
+	}
+	commitFiles(t, clone, true, map[string]string{
+		"omd-shells/bash/enabled.json": `{"features": []}` + "\n",
+		"omd-shells/bash/init.sh":      "# committed elsewhere\n",
+	})
+
+	result, err := git.PullRepoWithOptions(git.PullOptions{})
+	if err != nil {
+		t.Fatalf("first pull: %v", err)
+	}
+	if _, err := reconcileAfterPull(repoPath, result); err != nil {
+		t.Fatalf("reconcileAfterPull error: %v", err)
+	}
+	regenerated, err := os.ReadFile(initPath)
+	if err != nil || string(regenerated) == "# committed elsewhere\n" {
+		t.Fatalf("expected a regenerated init script: %q, %v", regenerated, err)
+	}
+
+	// Both sides move on, so the next pull has to merge with the regenerated script still uncommitted
+	commitFiles(t, clone, true, map[string]string{"remote.txt": "remote\n"})
+	local, err := gogit.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, local, false, map[string]string{"local.txt": "local\n"})
+
+	result, err = git.PullRepoWithOptions(git.PullOptions{})
+	if err != nil {
+		t.Fatalf("pull with a regenerated init script: %v", err)
+	}
+	if !result.Updated {
+		t.Fatal("expected the pull to merge the remote change")
+	}
+	if content, err := os.ReadFile(initPath); err != nil || string(content) != string(regenerated) {
+		t.Fatalf("init script after the pull = %q, %v; want it regenerated", content, err)
+	}
+}
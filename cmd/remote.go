@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	remoteListCommand.Flags().BoolP("check", "c", false, "Check whether the current branch is in sync with each remote")
+	remoteAddCommand.Flags().Bool("no-push", false, "Don't push to this remote (e.g. a read-only upstream)")
+	remoteAddCommand.Flags().Bool("pull", false, "Pull from this remote instead of the current pull remote")
+
+	remoteCommand.AddCommand(remoteListCommand)
+	remoteCommand.AddCommand(remoteAddCommand)
+	remoteCommand.AddCommand(remoteRemoveCommand)
+	remoteCommand.AddCommand(remoteSetPullCommand)
+	rootCmd.AddCommand(remoteCommand)
+}
+
+var remoteCommand = &cobra.Command{
+	Use:   "remote",
+	Short: "Manage the remotes the dotfiles are pulled from and pushed to",
+	Long: `Manage the remotes of the dotfiles repository.
+Pulls come from one remote (origin unless changed with "remote set-pull"), and pushes go to
+every remote that was not added with --no-push, so mirrors stay up to date.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		remoteListCommand.Run(remoteListCommand, args)
+	},
+}
+
+var remoteListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List remotes and their roles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		check, _ := cmd.Flags().GetBool("check")
+
+		remotes, err := git.ListRemotes()
+		fileops.CheckIfErrorWithMessage(err, "Error listing remotes")
+
+		if len(remotes) == 0 {
+			fileops.ColorPrintln("No remotes configured", fileops.Yellow)
+			return
+		}
+
+		for _, remote := range remotes {
+			var roles []string
+			if remote.Pull {
+				roles = append(roles, "pull")
+			}
+			if remote.Push {
+				roles = append(roles, "push")
+			}
+			if len(roles) == 0 {
+				roles = append(roles, "fetch only")
+			}
+
+			line := fmt.Sprintf("%s  %s (%s)", fileops.SColorPrint(remote.Name, fileops.Cyan), remote.URL, strings.Join(roles, ", "))
+			if check {
+				state, err := git.GetRemoteSyncStateFor(remote.Name)
+				if err != nil {
+					line += "  " + fileops.SColorPrint("unreachable: "+err.Error(), fileops.Red)
+				} else {
+					line += "  " + fileops.SColorPrint(string(state), syncStateColor(state))
+				}
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+var remoteAddCommand = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: "Add a remote",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		noPush, _ := cmd.Flags().GetBool("no-push")
+		pull, _ := cmd.Flags().GetBool("pull")
+
+		err := git.AddRemote(args[0], args[1], !noPush)
+		fileops.CheckIfErrorWithMessage(err, "Error adding remote")
+
+		if pull {
+			err = git.SetPullRemote(args[0])
+			fileops.CheckIfErrorWithMessage(err, "Error setting pull remote")
+		}
+
+		err = viper.WriteConfig()
+		fileops.CheckIfErrorWithMessage(err, "Error saving config")
+
+		fileops.ColorPrintfn(fileops.Green, "Added remote %s", args[0])
+	},
+	Example: `oh-my-dot remote add mirror git@gitea.internal:me/dotfiles.git
+oh-my-dot remote add upstream https://github.com/team/dotfiles.git --no-push`,
+}
+
+var remoteRemoveCommand = &cobra.Command{
+	Aliases: []string{"rm"},
+	Use:     "remove <name>",
+	Short:   "Remove a remote",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := git.RemoveRemote(args[0])
+		fileops.CheckIfErrorWithMessage(err, "Error removing remote")
+
+		err = viper.WriteConfig()
+		fileops.CheckIfErrorWithMessage(err, "Error saving config")
+
+		fileops.ColorPrintfn(fileops.Green, "Removed remote %s", args[0])
+	},
+}
+
+var remoteSetPullCommand = &cobra.Command{
+	Use:   "set-pull <name>",
+	Short: "Pull from a different remote",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := git.SetPullRemote(args[0])
+		fileops.CheckIfErrorWithMessage(err, "Error setting pull remote")
+
+		err = viper.WriteConfig()
+		fileops.CheckIfErrorWithMessage(err, "Error saving config")
+
+		fileops.ColorPrintfn(fileops.Green, "Pulling from %s", args[0])
+	},
+}
+
+func syncStateColor(state git.RemoteSyncState) string {
+	switch state {
+	case git.RemoteSyncUpToDate:
+		return fileops.Green
+	case git.RemoteSyncLocalAhead:
+		return fileops.Cyan
+	default:
+		return fileops.Yellow
+	}
+}
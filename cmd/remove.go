@@ -184,7 +184,10 @@ func processRemoveFile(cmd *cobra.Command, file string) {
 	if !noCommit {
 		repoPath := viper.GetString("repo-path")
 		if repoPath != "" && git.IsGitRepo(repoPath) {
-			err = git.Commit("Removed " + filepath.Base(file))
+			err = git.Commit(git.CommitMessage(git.OpRemove, git.MessageData{Name: filepath.Base(file)}))
+			if git.DisplaySecretsError(err, interactive.ShouldPrompt(cmd, false)) {
+				return
+			}
 			if err != nil {
 				fileops.ColorPrintfn(fileops.Red, "Error%s committing changes: %s", fileops.Reset, err)
 				return
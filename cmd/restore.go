@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/symlink"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	restoreCommand.Flags().String("to", "", "Commit (hash, branch, HEAD~2) or date (YYYY-MM-DD [HH:MM]) to restore")
+	restoreCommand.Flags().String("shell", "", "Restore a shell's enabled.json and feature files (omd-shells/<shell>)")
+	restoreCommand.Flags().BoolP("yes", "y", false, "Apply without confirming the preview")
+	restoreCommand.Flags().BoolP("no-commit", "n", false, "Don't commit changes")
+	restoreCommand.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(restoreCommand)
+}
+
+var restoreCommand = &cobra.Command{
+	Use:   "restore [file] --to <commit|date>",
+	Short: "Restore a dotfile or shell configuration from history",
+	Long: `Restore a dotfile or a shell's features to how they were at an earlier commit or date.
+The changes are previewed as a diff before they are applied. Restored files are re-linked,
+restored shell configuration regenerates the init script, and the result is committed.
+A file can be given by its target path (~/.zshrc) or its name in the repository (.zshrc).`,
+	GroupID: "dotfiles",
+	Args:    cobra.MaximumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := git.CheckRepoWritePermission(); err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		to, _ := cmd.Flags().GetString("to")
+		shellName, _ := cmd.Flags().GetString("shell")
+		autoYes, _ := cmd.Flags().GetBool("yes")
+		noCommit, _ := cmd.Flags().GetBool("no-commit")
+
+		if (shellName == "") == (len(args) == 0) {
+			fileops.ColorPrintln("Specify either a file or --shell", fileops.Red)
+			os.Exit(exitcodes.MissingArgs)
+		}
+
+		repoPath := viper.GetString("repo-path")
+		hash, err := git.ResolveRevisionOrDate(to)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+		short := hash.String()[:7]
+
+		var name string
+		var filter git.HistoryFilter
+		var link string
+		var relink bool
+		if shellName != "" {
+			if _, ok := shell.GetShellConfig(shellName); !ok {
+				fileops.ColorPrintfn(fileops.Red, "Unsupported shell: %s", shellName)
+				os.Exit(exitcodes.Error)
+			}
+			name = shellName + " features"
+			prefix := "omd-shells/" + shellName + "/"
+			filter = git.HistoryFilter{Paths: []string{prefix + "enabled.json"}, Prefixes: []string{prefix + "features/"}}
+		} else {
+			name, err = resolveTrackedFile(args[0])
+			if err != nil {
+				fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+				os.Exit(exitcodes.Error)
+			}
+			filter = git.HistoryFilter{Paths: []string{"files/" + name}}
+
+			link, relink, err = restoredLinking(hash, name)
+			fileops.CheckIfErrorWithMessage(err, "Error reading linkings")
+			if relink {
+				fileops.ColorPrintfn(fileops.Cyan, "Linking restored: %s -> %s", name, link)
+			}
+		}
+
+		revisions, err := git.PlanRestore(hash, filter)
+		fileops.CheckIfErrorWithMessage(err, "Error reading "+name+" at "+short)
+
+		changed := 0
+		for _, revision := range revisions {
+			if revision.Changed() {
+				changed++
+				printRestoreDiff(revision.Diff())
+			}
+		}
+
+		if shellName == "" && (len(revisions) == 0 || revisions[0].Restored == nil) {
+			fileops.ColorPrintfn(fileops.Red, "%s does not exist at %s", name, short)
+			os.Exit(exitcodes.Error)
+		}
+		if changed == 0 && !relink {
+			fileops.ColorPrintfn(fileops.Green, "%s already matches %s", name, short)
+			return
+		}
+
+		if !autoYes {
+			if !interactive.ShouldPrompt(cmd, false) {
+				fileops.ColorPrintln("Use --yes to apply the restore", fileops.Yellow)
+				os.Exit(exitcodes.Error)
+			}
+			confirmed, err := interactive.PromptConfirm(fmt.Sprintf("Restore %s to %s (%d files)?", name, short, changed))
+			if err != nil || !confirmed {
+				fileops.ColorPrintln("Cancelled", fileops.Yellow)
+				return
+			}
+		}
+
+		err = git.ApplyRestore(revisions)
+		fileops.CheckIfErrorWithMessage(err, "Error restoring "+name)
+
+		message := git.CommitMessage(git.OpRestore, git.MessageData{Name: name, Shell: shellName, Revision: short})
+		if shellName != "" {
+			err = shell.RegenerateInitScript(repoPath, shellName)
+			fileops.CheckIfErrorWithMessage(err, "Error regenerating init script for "+shellName)
+
+			if !noCommit {
+				_, err = git.StageAndCommitShellFeatureChanges(message)
+				exitOnSecrets(cmd, err)
+				fileops.CheckIfErrorWithMessage(err, "Error committing changes")
+			}
+		} else {
+			if relink {
+				err = symlink.AddLinking(name, link)
+				fileops.CheckIfErrorWithMessage(err, "Error restoring linking")
+				err = git.StageChange("linkings.json")
+				fileops.CheckIfErrorWithMessage(err, "Error staging linkings.json")
+			}
+			if link != "" {
+				linked, failed := applyLinkings(repoPath, symlink.Linkings{name: link}, false)
+				if linked > 0 && failed == 0 {
+					fileops.ColorPrintfn(fileops.Green, "Linked %s -> %s", link, name)
+				}
+			}
+
+			if !noCommit {
+				err = git.Commit(message)
+				exitOnSecrets(cmd, err)
+				fileops.CheckIfErrorWithMessage(err, "Error committing changes")
+			}
+		}
+
+		fileops.ColorPrintfn(fileops.Green, "Restored %s to %s", name, short)
+	},
+	Example: `oh-my-dot restore ~/.zshrc --to HEAD~1
+oh-my-dot restore .gitconfig --to 2024-05-01
+oh-my-dot restore --shell zsh --to 3f2a1bc --yes`,
+}
+
+// restoredLinking returns the link target for a restored file. For a file that is no longer
+// linked, the linking is taken from the revision and relink is true.
+func restoredLinking(hash plumbing.Hash, name string) (link string, relink bool, err error) {
+	linkings, err := symlink.GetLinkings()
+	if err != nil {
+		return "", false, err
+	}
+	if link, ok := linkings[name]; ok {
+		return link, false, nil
+	}
+
+	content, ok, err := git.FileAtCommit(hash, "linkings.json")
+	if err != nil || !ok {
+		return "", false, err
+	}
+	previous, err := symlink.ParseLinkings(content)
+	if err != nil {
+		return "", false, err
+	}
+
+	link, ok = previous[name]
+	return link, ok, nil
+}
+
+// printRestoreDiff prints a unified diff with added and removed lines colored.
+func printRestoreDiff(diff string) {
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+			fmt.Println(fileops.SColorPrint(line, fileops.Cyan))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(fileops.SColorPrint(line, fileops.Green))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(fileops.SColorPrint(line, fileops.Red))
+		default:
+			fmt.Println(line)
+		}
+	}
+	fmt.Println()
+}
+
+// exitOnSecrets reports a commit blocked by the secret scanner and exits; other errors are left to the caller.
+func exitOnSecrets(cmd *cobra.Command, err error) {
+	if git.DisplaySecretsError(err, interactive.ShouldPrompt(cmd, false)) {
+		os.Exit(exitcodes.Error)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	// "log"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -48,6 +49,10 @@ oh-my-dot uses git to manage your dotfiles, so you can easily push and pull your
 			os.Exit(1)
 		}
 
+		if err := migrateLegacyUpdateCheck(); err != nil {
+			fileops.ColorPrintfn(fileops.Yellow, "Warning: failed to move the cached update check out of the config file: %v", err)
+		}
+
 		StartAsyncUpdateCheck(cmd)
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
@@ -155,6 +160,18 @@ func Execute(funcs ...func(*cobra.Command)) error {
 		Title: "Dotfile:",
 	})
 
+	// Add the global offline flag (only if not already added). It applies to this run only and
+	// is never written to the config; set "offline" in the config to stay offline.
+	if rootCmd.PersistentFlags().Lookup("offline") == nil {
+		rootCmd.PersistentFlags().Bool("offline", false,
+			"Skip every network call; remote checks use the last cached result")
+		cobra.OnInitialize(func() {
+			if offline, _ := rootCmd.PersistentFlags().GetBool("offline"); offline {
+				network.SetOffline(true)
+			}
+		})
+	}
+
 	// Add global interactive flags (only if not already added)
 	if rootCmd.PersistentFlags().Lookup("interactive") == nil {
 		rootCmd.PersistentFlags().BoolP("interactive", "i", false,
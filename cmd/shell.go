@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/profiler"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var shellCmd = &cobra.Command{
+	Use:     "shell",
+	Short:   "Tune how the shell framework loads features",
+	Long:    `Inspect and tune how the shell framework loads features.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+}
+
+var shellProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Time how long each feature adds to shell startup",
+	Long: `Run an instrumented copy of a shell's init script several times and report how long
+each feature takes to load, with its mean and 95th percentile.
+
+Every enabled feature is timed, whatever its strategy, so the report also shows what deferred
+and on-command features cost when they do load. The shell runs non-interactively without your
+own startup files. Eager features slower than --threshold get a suggestion for a lazier strategy.
+
+Results are saved next to the config file and each run is compared with the previous one.
+
+Examples:
+  oh-my-dot shell profile                   # Profile the current shell
+  oh-my-dot shell profile --shell zsh --runs 20
+  oh-my-dot shell profile --threshold 50ms  # Only suggest changes for slower features`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runShellProfile,
+}
+
+var shellBundleCmd = &cobra.Command{
+	Use:   "bundle [on|off]",
+	Short: "Load eager features from one generated bundle file",
+	Long: `Concatenate a shell's eager features into one generated file that the init script
+sources instead of every feature file on its own. This speeds up startup on slow disks and
+network home directories. zsh bundles are also compiled with zcompile.
+
+Each feature in the bundle runs in its own function (a block in fish, a script block in
+PowerShell), so a return or error in one feature does not stop the others. The bundle is
+rebuilt whenever oh-my-dot changes the manifest; when a feature file or manifest is newer
+than the bundle, the init script loads the feature files directly and rebuilds it in the
+background.
+
+Without an argument, the bundles of the selected shells are rebuilt.
+
+Examples:
+  oh-my-dot shell bundle on --shell zsh   # Bundle zsh features on every machine
+  oh-my-dot shell bundle on --local       # Bundle on this machine only
+  oh-my-dot shell bundle off --shell zsh
+  oh-my-dot shell bundle                  # Rebuild bundles`,
+	SilenceUsage: true,
+	Args:         cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:    []string{"on", "off"},
+	RunE:         runShellBundle,
+}
+
+var shellEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Apply and show the environment variables and PATH entries of the init scripts",
+	Long: `Regenerate the init scripts of the shells with features so they set the environment declared in
+omd-shells/env.json and in the env section of each shell's enabled.json and enabled.local.json,
+then show what each shell sets.
+
+Run it after editing these files by hand. The init scripts set the variables first, then the PATH
+entries, before any feature loads. Entries with os or hosts conditions only apply on matching
+machines.
+
+Examples:
+  oh-my-dot shell env
+  oh-my-dot shell env --shell zsh`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runShellEnv,
+}
+
+var shellCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the output cached by omd_cached_eval",
+	Long: `Manage the output cached by omd_cached_eval.
+
+Features call omd_cached_eval <key> <command> [args...] instead of eval "$(command)" for tools
+that print slow-to-generate init code, such as completions and prompts. The output is kept in
+~/.cache/oh-my-dot (or $XDG_CACHE_HOME, or $OMD_CACHE_DIR) until the tool's binary moves or
+changes, its arguments change or the cache is older than $OMD_CACHE_TTL seconds (one day by
+default).`,
+	Args: cobra.NoArgs,
+}
+
+var shellCacheListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List cached init output",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runShellCacheList,
+}
+
+var shellCacheClearCmd = &cobra.Command{
+	Use:   "clear [key...]",
+	Short: "Remove cached init output",
+	Long: `Remove cached init output so it is generated again by the next shell.
+
+Examples:
+  oh-my-dot shell cache clear                      # Clear everything
+  oh-my-dot shell cache clear oh-my-posh           # Clear one key in every shell
+  oh-my-dot shell cache clear --shell zsh`,
+	SilenceUsage: true,
+	RunE:         runShellCacheClear,
+}
+
+var (
+	flagBundleShell      []string
+	flagBundleLocal      bool
+	flagBundleBackground bool
+	flagCacheShell       []string
+	flagEnvShell         []string
+)
+
+var (
+	flagProfileShell     string
+	flagProfileRuns      int
+	flagProfileThreshold time.Duration
+	flagProfileNoSave    bool
+	flagProfileCompare   string
+)
+
+func init() {
+	shellProfileCmd.Flags().StringVar(&flagProfileShell, "shell", "", "Shell to profile (defaults to the current shell)")
+	shellProfileCmd.Flags().IntVarP(&flagProfileRuns, "runs", "n", 10, "Number of times to run the shell")
+	shellProfileCmd.Flags().DurationVar(&flagProfileThreshold, "threshold", 20*time.Millisecond, "Suggest a lazier strategy for eager features slower than this")
+	shellProfileCmd.Flags().BoolVar(&flagProfileNoSave, "no-save", false, "Don't save the results")
+	shellProfileCmd.Flags().StringVar(&flagProfileCompare, "compare", "", "Compare with this saved result instead of the previous run")
+
+	shellBundleCmd.Flags().StringSliceVar(&flagBundleShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+	shellBundleCmd.Flags().BoolVar(&flagBundleLocal, "local", false, "Turn bundling on or off for this machine only, in enabled.local.json")
+	shellBundleCmd.Flags().BoolVar(&flagBundleBackground, "background", false, "Rebuild quietly, skipping shells another rebuild is already building")
+	shellBundleCmd.Flags().MarkHidden("background")
+
+	shellEnvCmd.Flags().StringSliceVar(&flagEnvShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+
+	shellCacheListCmd.Flags().StringSliceVar(&flagCacheShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+	shellCacheClearCmd.Flags().StringSliceVar(&flagCacheShell, "shell", nil, "Target specific shell(s) (defaults to all shells)")
+
+	shellCacheCmd.AddCommand(shellCacheListCmd)
+	shellCacheCmd.AddCommand(shellCacheClearCmd)
+	shellCmd.AddCommand(shellProfileCmd)
+	shellCmd.AddCommand(shellBundleCmd)
+	shellCmd.AddCommand(shellCacheCmd)
+	shellCmd.AddCommand(shellEnvCmd)
+	rootCmd.AddCommand(shellCmd)
+}
+
+func runShellProfile(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+
+	shellName := flagProfileShell
+	if shellName == "" {
+		detected, err := shell.DetectCurrentShell()
+		if err != nil {
+			return fmt.Errorf("could not detect current shell, please specify with --shell flag")
+		}
+		shellName = detected
+	}
+	if !shell.IsShellSupported(shellName) {
+		return fmt.Errorf("unsupported shell: %s", shellName)
+	}
+	if !fileops.PathExists(shell.GetManifestPath(repoPath, shellName)) {
+		return fmt.Errorf("%s has no features; run '%s feature add' first", shellName, assumedAlias())
+	}
+
+	var previous *profiler.Result
+	var err error
+	if flagProfileCompare != "" {
+		previous, err = profiler.Load(flagProfileCompare)
+	} else {
+		previous, err = profiler.Latest(shellName)
+	}
+	if err != nil {
+		return err
+	}
+
+	fileops.ColorPrintfn(fileops.Cyan, "Profiling %s startup over %d runs...", shellName, flagProfileRuns)
+	result, err := profiler.Run(repoPath, shellName, flagProfileRuns)
+	if err != nil {
+		return err
+	}
+
+	profiler.Print(result, previous, flagProfileThreshold, assumedAlias())
+
+	if flagProfileNoSave {
+		return nil
+	}
+	path, err := profiler.Save(result)
+	if err != nil {
+		return err
+	}
+	fileops.ColorPrintfn(fileops.Cyan, "\nSaved to %s", path)
+	return nil
+}
+
+func runShellBundle(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+
+	shells := flagBundleShell
+	if len(shells) == 0 {
+		var err error
+		shells, err = shell.ListShellsWithFeatures(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to list shells: %w", err)
+		}
+	}
+	if len(shells) == 0 {
+		return fmt.Errorf("no shells have been initialized")
+	}
+
+	if len(args) == 0 {
+		// Init scripts start background rebuilds when a bundle is stale; nobody sees their output
+		if flagBundleBackground {
+			cmd.SilenceErrors = true
+		}
+		for _, shellName := range shells {
+			if flagBundleBackground {
+				unlock, locked, err := shell.LockBundle(repoPath, shellName)
+				if err != nil {
+					return err
+				}
+				if !locked {
+					continue
+				}
+				defer unlock()
+			}
+			if err := shell.RegenerateInitScript(repoPath, shellName); err != nil {
+				return fmt.Errorf("failed to rebuild %s: %w", shellName, err)
+			}
+			if bundled, _ := shell.IsBundleMode(repoPath, shellName); bundled && !flagBundleBackground {
+				fileops.ColorPrintfn(fileops.Green, "Rebuilt the %s bundle", shellName)
+			}
+		}
+		return nil
+	}
+
+	on := args[0] == "on"
+	for _, shellName := range shells {
+		if err := shell.SetBundleMode(repoPath, shellName, on, flagBundleLocal); err != nil {
+			return fmt.Errorf("failed to turn bundling %s in %s: %w", args[0], shellName, err)
+		}
+		fileops.ColorPrintfn(fileops.Green, "Turned bundling %s in %s", args[0], shellName)
+	}
+
+	if flagBundleLocal {
+		fileops.ColorPrintln("The setting is kept in enabled.local.json for this machine only.", fileops.Cyan)
+	} else {
+		_, err := git.StageAndCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureBundle, git.MessageData{Name: args[0], Shell: strings.Join(shells, ", ")}))
+		exitOnSecrets(cmd, err)
+		if err != nil {
+			return fmt.Errorf("failed to commit shell feature changes: %w", err)
+		}
+	}
+
+	fileops.ColorPrintfn(fileops.Cyan, "\nRun '%s apply' or open a new shell to use it", assumedAlias())
+	return nil
+}
+
+// cacheShells returns the shells selected with --shell, or every supported shell
+func cacheShells() ([]string, error) {
+	if len(flagCacheShell) > 0 {
+		for _, shellName := range flagCacheShell {
+			if !shell.IsShellSupported(shellName) {
+				return nil, fmt.Errorf("unsupported shell: %s", shellName)
+			}
+		}
+		return flagCacheShell, nil
+	}
+
+	var shells []string
+	for shellName := range shell.SupportedShells() {
+		shells = append(shells, shellName)
+	}
+	return shells, nil
+}
+
+func runShellCacheList(cmd *cobra.Command, args []string) error {
+	shells, err := cacheShells()
+	if err != nil {
+		return err
+	}
+	entries, err := shell.ListCache(shells)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fileops.ColorPrintln("Nothing is cached", fileops.Yellow)
+		return nil
+	}
+
+	keyWidth := len("Key")
+	for _, entry := range entries {
+		keyWidth = max(keyWidth, len(entry.Key))
+	}
+	fmt.Printf("  %-10s  %-*s  %-16s  %8s  %s\n", "Shell", keyWidth, "Key", "Cached", "Size", "Command")
+	for _, entry := range entries {
+		fmt.Printf("  %-10s  %-*s  %-16s  %7.1fK  %s\n", entry.Shell, keyWidth, entry.Key,
+			entry.Created.Local().Format("2006-01-02 15:04"), float64(entry.Size)/1024, entry.Command)
+	}
+	return nil
+}
+
+func runShellCacheClear(cmd *cobra.Command, args []string) error {
+	shells, err := cacheShells()
+	if err != nil {
+		return err
+	}
+	removed, err := shell.ClearCache(shells, args)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fileops.ColorPrintln("Nothing to clear", fileops.Yellow)
+		return nil
+	}
+	for _, entry := range removed {
+		fileops.ColorPrintfn(fileops.Green, "Cleared %s (%s)", entry.Key, entry.Shell)
+	}
+	return nil
+}
+
+func runShellEnv(cmd *cobra.Command, args []string) error {
+	repoPath := viper.GetString("repo-path")
+
+	shells := flagEnvShell
+	if len(shells) == 0 {
+		var err error
+		shells, err = shell.ListShellsWithFeatures(repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to list shells: %w", err)
+		}
+	}
+	if len(shells) == 0 {
+		return fmt.Errorf("no shells have been initialized")
+	}
+
+	for _, shellName := range shells {
+		env, err := shell.LoadEnv(repoPath, shellName)
+		if err != nil {
+			return fmt.Errorf("failed to read the %s environment: %w", shellName, err)
+		}
+		if err := shell.RegenerateInitScript(repoPath, shellName); err != nil {
+			return fmt.Errorf("failed to regenerate %s: %w", shellName, err)
+		}
+
+		fileops.ColorPrintfn(fileops.Cyan, "%s:", shellName)
+		lines := shell.EnvSummary(env)
+		if len(lines) == 0 {
+			fmt.Println("  (nothing declared)")
+		}
+		for _, line := range lines {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	_, err := git.StageAndCommitShellFeatureChanges(git.CommitMessage(git.OpShellEnv, git.MessageData{Shell: strings.Join(shells, ", ")}))
+	exitOnSecrets(cmd, err)
+	if err != nil {
+		return fmt.Errorf("failed to commit shell feature changes: %w", err)
+	}
+	return nil
+}
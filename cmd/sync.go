@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	syncCommand.Flags().BoolP("yes", "y", false, "Commit pending local changes without asking")
+	syncCommand.Flags().StringP("message", "m", "", "Commit message for pending local changes")
+	syncCommand.Flags().StringP("strategy", "s", "", "Resolve conflicting files automatically: ours (keep local) or theirs (take remote)")
+	syncCommand.Flags().Bool("no-push", false, "Stop after pulling and applying")
+
+	rootCmd.AddCommand(syncCommand)
+}
+
+// syncSteps is the number of steps sync reports.
+const syncSteps = 5
+
+var syncCommand = &cobra.Command{
+	Use:   "sync",
+	Short: "Commit local changes, pull, apply and push in one go",
+	Long: `Bring this machine and the remote in sync:
+  1. check how the local branch relates to the remote
+  2. commit pending local changes (after confirmation)
+  3. pull, merging diverged history
+  4. apply what the pull changed
+  5. push to every push remote
+Sync stops at the first step that fails. Earlier steps are kept, so the repository is never
+left half-merged, and the message says which command to run to continue.`,
+	GroupID: "dotfiles",
+	Args:    cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := git.CheckRepoWritePermission(); err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.PermissionDenied)
+		}
+
+		git.CheckRemoteAccessWithHelp(true)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		autoYes, _ := cmd.Flags().GetBool("yes")
+		message, _ := cmd.Flags().GetString("message")
+		noPush, _ := cmd.Flags().GetBool("no-push")
+		name := cmd.Root().Name()
+
+		resolve, err := conflictResolverForCommand(cmd)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Error: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+
+		// 1. Remote state
+		syncStep(1, "Checking remote")
+		state, err := git.GetRemoteSyncState()
+		if err != nil {
+			if git.IsSSHAgentError(err) {
+				git.DisplaySSHAgentError(true)
+			}
+			syncFailed("Error checking remote: %s", err)
+			fileops.ColorPrintln("Nothing was changed", fileops.Yellow)
+			os.Exit(exitcodes.Error)
+		}
+		fmt.Printf("  %s\n", fileops.SColorPrint(string(state), syncStateColor(state)))
+
+		// 2. Pending local changes
+		syncStep(2, "Committing local changes")
+		pending, err := git.PendingChanges()
+		if err != nil {
+			syncFailed("Error reading local changes: %s", err)
+			fileops.ColorPrintln("Nothing was changed", fileops.Yellow)
+			os.Exit(exitcodes.Error)
+		}
+
+		if len(pending) == 0 {
+			fmt.Println("  (nothing to commit)")
+		} else {
+			for _, file := range pending {
+				fmt.Printf("  %s\n", file)
+			}
+
+			if !autoYes {
+				if !interactive.ShouldPrompt(cmd, false) {
+					fileops.ColorPrintln("Use --yes to commit pending changes, or commit them first", fileops.Yellow)
+					os.Exit(exitcodes.Error)
+				}
+				confirmed, err := interactive.PromptConfirm(fmt.Sprintf("Commit %d changed files?", len(pending)))
+				if err != nil || !confirmed {
+					fileops.ColorPrintln("Cancelled; nothing was changed", fileops.Yellow)
+					return
+				}
+			}
+
+			if message == "" {
+				message = git.CommitMessage(git.OpSync, git.MessageData{Name: summarizeFiles(pending)})
+			}
+			if _, err := git.CommitPendingChanges(message); err != nil {
+				if git.DisplaySecretsError(err, interactive.ShouldPrompt(cmd, false)) {
+					os.Exit(exitcodes.Error)
+				}
+				syncFailed("Error committing changes: %s", err)
+				fileops.ColorPrintln("Nothing was committed", fileops.Yellow)
+				os.Exit(exitcodes.Error)
+			}
+			fileops.ColorPrintfn(fileops.Green, "  ✓ committed %d files", len(pending))
+		}
+
+		// 3. Pull
+		syncStep(3, "Pulling")
+		var result git.PullResult
+		if stateHasRemoteUpdates(state) {
+			result, err = git.PullRepoWithOptions(git.PullOptions{Resolve: resolve})
+			if err != nil {
+				var conflictErr *git.ConflictError
+				switch {
+				case git.IsSSHAgentError(err):
+					git.DisplaySSHAgentError(false)
+				case git.DisplaySecretsError(err, false):
+					os.Exit(exitcodes.Error)
+				case errors.As(err, &conflictErr):
+					syncFailed("Local and remote changes conflict in: %s", strings.Join(conflictErr.Paths, ", "))
+					fileops.ColorPrintfn(fileops.Yellow, "Your changes are committed locally; nothing was merged. Run '%s pull' to resolve the conflicts, then '%s sync' again", name, name)
+					os.Exit(exitcodes.Conflict)
+				}
+				syncFailed("Error pulling changes: %s", err)
+				fileops.ColorPrintln("Your changes are committed locally; nothing was merged", fileops.Yellow)
+				os.Exit(exitcodes.Error)
+			}
+		}
+		if result.Updated {
+			fileops.ColorPrintln("  ✓ pulled latest changes", fileops.Green)
+		} else {
+			fmt.Println("  (nothing to pull)")
+		}
+
+		// 4. Apply
+		syncStep(4, "Applying")
+		if result.Updated {
+			summary, err := reconcileAfterPull(viper.GetString("repo-path"), result)
+			if err != nil {
+				syncFailed("Error applying pulled changes: %s", err)
+				fileops.ColorPrintfn(fileops.Yellow, "The pull is complete but was not pushed. Run '%s apply', then '%s push'", name, name)
+				os.Exit(exitcodes.Error)
+			}
+			printPullReconciliation(summary)
+		} else {
+			fmt.Println("  (nothing to apply)")
+		}
+
+		// 5. Push
+		syncStep(5, "Pushing")
+		if noPush {
+			fileops.ColorPrintfn(fileops.Yellow, "  Skipped; run '%s push' to publish local commits", name)
+			return
+		}
+		results, err := git.PushRepoToRemotes()
+		if err != nil {
+			syncFailed("Error pushing changes: %s", err)
+			os.Exit(exitcodes.Error)
+		}
+		if !printPushResults(results) {
+			fileops.ColorPrintfn(fileops.Yellow, "Everything else is in sync. Run '%s push' to retry", name)
+			os.Exit(exitcodes.Error)
+		}
+
+		cacheUpdateCheckResult(updateCheckResult{state: git.RemoteSyncUpToDate, checkedAt: time.Now()})
+
+		fmt.Println()
+		fileops.ColorPrintln("In sync!", fileops.Green)
+	},
+	Example: `oh-my-dot sync
+oh-my-dot sync --yes -m "Tweak prompt"
+oh-my-dot sync --strategy theirs --no-push`,
+}
+
+func syncStep(n int, title string) {
+	fileops.ColorPrintfn(fileops.Cyan, "[%d/%d] %s", n, syncSteps, title)
+}
+
+func syncFailed(format string, a ...any) {
+	fileops.ColorPrintfn(fileops.Red, "  ✗ "+format, a...)
+}
+
+// summarizeFiles names a few changed files for a commit message, by base name only.
+func summarizeFiles(paths []string) string {
+	const shown = 3
+
+	names := make([]string, 0, shown)
+	for i, p := range paths {
+		if i == shown {
+			break
+		}
+		names = append(names, path.Base(p))
+	}
+
+	summary := strings.Join(names, ", ")
+	if len(paths) > shown {
+		summary += fmt.Sprintf(" and %d more", len(paths)-shown)
+	}
+	return summary
+}
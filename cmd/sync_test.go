@@ -0,0 +1,20 @@
+package cmd
+
+import "testing"
+
+func TestSummarizeFiles(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"files/.zshrc"}, ".zshrc"},
+		{[]string{"files/.zshrc", "linkings.json"}, ".zshrc, linkings.json"},
+		{[]string{"a/1", "b/2", "c/3", "d/4", "e/5"}, "1, 2, 3 and 2 more"},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeFiles(tt.paths); got != tt.want {
+			t.Errorf("summarizeFiles(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
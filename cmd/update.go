@@ -6,6 +6,7 @@ import (
 	"regexp"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/blang/semver"
 	"github.com/creativeprojects/go-selfupdate"
 	"github.com/spf13/cobra"
@@ -24,7 +25,12 @@ var updateCommand = &cobra.Command{
 	GroupID:          "basics",
 	Args:             cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx, cancel, err := network.Context(context.Background(), network.Transfer)
+		if err != nil {
+			fileops.ColorPrintfn(fileops.Red, "Cannot check for updates: %s", err)
+			return
+		}
+		defer cancel()
 
 		// Get the current executable path
 		executable, err := os.Executable()
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/config"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -14,15 +17,18 @@ const (
 	// updateCheckTTL is the time-to-live ("Time To Live") for cached update-check results.
 	updateCheckTTL               = 20 * time.Minute
 	updateCheckResultWaitTimeout = 150 * time.Millisecond
-	updateLastCheckedKey         = "update-check.last-checked-unix"
-	updateHasUpdatesKey          = "update-check.remote-has-updates"
-	updateStateKey               = "update-check.remote-sync-state"
+
+	// Older versions cached update checks in config.json under these keys
+	legacyUpdateCheckKey       = "update-check"
+	legacyUpdateLastCheckedKey = "update-check.last-checked-unix"
+	legacyUpdateHasUpdatesKey  = "update-check.remote-has-updates"
+	legacyUpdateRemoteStateKey = "update-check.remote-sync-state"
 )
 
 type updateCheckResult struct {
 	state     git.RemoteSyncState
 	err       error
-	checkedAt int64
+	checkedAt time.Time
 }
 
 type updateCheckRuntimeState struct {
@@ -85,11 +91,62 @@ func shouldShowSyncNotice(cmd *cobra.Command, state git.RemoteSyncState) bool {
 	return topLevelCommandName(cmd) != "push" && state == git.RemoteSyncLocalAhead
 }
 
+// cacheUpdateCheckResult records a successful check in the state file, keeping it out of the user's config.
 func cacheUpdateCheckResult(res updateCheckResult) {
-	viper.Set(updateLastCheckedKey, res.checkedAt)
-	viper.Set(updateStateKey, string(res.state))
-	viper.Set(updateHasUpdatesKey, stateHasRemoteUpdates(res.state))
-	_ = viper.WriteConfig()
+	state := config.LoadState()
+	state.UpdateCheck = config.UpdateCheckState{LastSuccess: res.checkedAt, RemoteState: string(res.state)}
+	_ = config.SaveState(state)
+}
+
+// cachedUpdateCheck returns the last recorded sync state and whether it is still fresh.
+// In offline mode the cache is all there is, so it never goes stale.
+func cachedUpdateCheck() (git.RemoteSyncState, bool) {
+	check := config.LoadState().UpdateCheck
+	if check.LastSuccess.IsZero() {
+		return "", false
+	}
+	fresh := network.Offline() || time.Since(check.LastSuccess) < updateCheckTTL
+	return git.RemoteSyncState(check.RemoteState), fresh
+}
+
+// migrateLegacyUpdateCheck moves an update check cached in config.json by older versions into the
+// state file and removes it from config.json, so later config writes do not carry it along.
+func migrateLegacyUpdateCheck() error {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" || !viper.InConfig(legacyUpdateCheckKey) {
+		return nil
+	}
+
+	state := config.LoadState()
+	if lastChecked := viper.GetInt64(legacyUpdateLastCheckedKey); lastChecked > 0 && state.UpdateCheck.LastSuccess.IsZero() {
+		remoteState := git.RemoteSyncState(viper.GetString(legacyUpdateRemoteStateKey))
+		if remoteState == "" && viper.GetBool(legacyUpdateHasUpdatesKey) {
+			remoteState = git.RemoteSyncRemoteAhead
+		}
+		state.UpdateCheck = config.UpdateCheckState{LastSuccess: time.Unix(lastChecked, 0), RemoteState: string(remoteState)}
+		if err := config.SaveState(state); err != nil {
+			return err
+		}
+	}
+
+	// Viper cannot unset a key, so the file is rewritten without it and read again
+	file := viper.New()
+	file.SetConfigFile(configFile)
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	settings := file.AllSettings()
+	delete(settings, legacyUpdateCheckKey)
+
+	rewritten := viper.New()
+	rewritten.SetConfigFile(configFile)
+	if err := rewritten.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	if err := rewritten.WriteConfig(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return viper.ReadInConfig()
 }
 
 func StartAsyncUpdateCheck(cmd *cobra.Command) {
@@ -102,23 +159,13 @@ func StartAsyncUpdateCheck(cmd *cobra.Command) {
 		return
 	}
 
-	now := time.Now().Unix()
-	lastChecked := viper.GetInt64(updateLastCheckedKey)
-	if lastChecked > 0 {
-		lastCheckTime := time.Unix(lastChecked, 0)
-		if time.Since(lastCheckTime) < updateCheckTTL {
-			state := git.RemoteSyncState(viper.GetString(updateStateKey))
-			if state == "" && viper.GetBool(updateHasUpdatesKey) {
-				state = git.RemoteSyncRemoteAhead
-			}
-
-			if shouldShowSyncNotice(cmd, state) {
-				updateCheckStateMu.Lock()
-				updateCheckState.immediateNotice = true
-				updateCheckStateMu.Unlock()
-			}
-			return
+	if state, fresh := cachedUpdateCheck(); fresh || network.Offline() {
+		if fresh && shouldShowSyncNotice(cmd, state) {
+			updateCheckStateMu.Lock()
+			updateCheckState.immediateNotice = true
+			updateCheckStateMu.Unlock()
 		}
+		return
 	}
 
 	ch := make(chan updateCheckResult, 1)
@@ -133,7 +180,7 @@ func StartAsyncUpdateCheck(cmd *cobra.Command) {
 		ch <- updateCheckResult{
 			state:     state,
 			err:       err,
-			checkedAt: now,
+			checkedAt: time.Now(),
 		}
 	}()
 }
@@ -149,10 +196,7 @@ func FinishAsyncUpdateCheck(cmd *cobra.Command) {
 	updateCheckStateMu.Unlock()
 
 	if state.immediateNotice {
-		cachedState := git.RemoteSyncState(viper.GetString(updateStateKey))
-		if cachedState == "" && viper.GetBool(updateHasUpdatesKey) {
-			cachedState = git.RemoteSyncRemoteAhead
-		}
+		cachedState, _ := cachedUpdateCheck()
 		if shouldShowSyncNotice(cmd, cachedState) {
 			printUpdateAvailableNotice(cmd, cachedState)
 		}
@@ -178,7 +222,7 @@ func FinishAsyncUpdateCheck(cmd *cobra.Command) {
 }
 
 func WarnIfRemoteUpdatesSync(cmd *cobra.Command) {
-	if !shouldCheckUpdatesForCommand(cmd) || topLevelCommandName(cmd) != "push" {
+	if !shouldCheckUpdatesForCommand(cmd) || topLevelCommandName(cmd) != "push" || network.Offline() {
 		return
 	}
 
@@ -195,7 +239,7 @@ func WarnIfRemoteUpdatesSync(cmd *cobra.Command) {
 	cacheUpdateCheckResult(updateCheckResult{
 		state:     state,
 		err:       nil,
-		checkedAt: time.Now().Unix(),
+		checkedAt: time.Now(),
 	})
 
 	if stateHasRemoteUpdates(state) {
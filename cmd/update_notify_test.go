@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/config"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/viper"
+)
+
+func TestMigrateLegacyUpdateCheck(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	legacy := `{"initialized": true, "repo-path": "/tmp/dotfiles", "update-check": {"last-checked-unix": 1700000000, "remote-has-updates": true}}`
+	if err := os.WriteFile(configFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(configFile)
+	viper.Set("dot-home", configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateLegacyUpdateCheck(); err != nil {
+		t.Fatalf("migrateLegacyUpdateCheck: %v", err)
+	}
+
+	check := config.LoadState().UpdateCheck
+	if !check.LastSuccess.Equal(time.Unix(1700000000, 0)) || check.RemoteState != string(git.RemoteSyncRemoteAhead) {
+		t.Fatalf("state = %+v", check)
+	}
+
+	// Later writes must not bring the old keys back
+	if err := viper.WriteConfig(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "update-check") || !strings.Contains(string(data), "repo-path") {
+		t.Fatalf("config after migration:\n%s", data)
+	}
+
+	if err := migrateLegacyUpdateCheck(); err != nil {
+		t.Fatalf("second migration: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/exitcodes"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	verifyCommand.Flags().String("since", "", "Only verify commits after this commit (e.g. origin/main before a pull)")
+	verifyCommand.Flags().IntP("limit", "n", 20, "Verify at most this many commits (0 for all)")
+
+	rootCmd.AddCommand(verifyCommand)
+}
+
+var verifyCommand = &cobra.Command{
+	Use:   "verify [commit]",
+	Short: "Verify commit signatures",
+	Long: `Verify the signatures of commits in the dotfiles repository, newest first.
+OpenPGP signatures are checked against commit.signing.keyring and SSH signatures against
+commit.signing.allowed-signers. Without them, only your own signing key is trusted.
+Exits with an error when any checked commit is unsigned or not signed by a trusted key.`,
+	GroupID: "dotfiles",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		since, _ := cmd.Flags().GetString("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		until := "HEAD"
+		if len(args) > 0 {
+			until = args[0]
+		}
+
+		statuses, err := git.VerifyCommits(since, until, limit)
+		fileops.CheckIfErrorWithMessage(err, "Error verifying signatures")
+
+		if len(statuses) == 0 {
+			fileops.ColorPrintln("No commits to verify", fileops.Yellow)
+			return
+		}
+
+		if !printSignatureStatuses(statuses) {
+			os.Exit(exitcodes.Error)
+		}
+	},
+	Example: `oh-my-dot verify
+oh-my-dot verify --since HEAD~5
+oh-my-dot verify origin/main -n 0`,
+}
+
+// printSignatureStatuses prints one line per commit and reports whether all are verified.
+func printSignatureStatuses(statuses []git.SignatureStatus) bool {
+	unverified := 0
+	for _, status := range statuses {
+		short := fileops.SColorPrint(status.Hash.String()[:7], fileops.Yellow)
+		if status.Verified() {
+			fmt.Printf("%s  %s  %s (%s, %s)\n", fileops.SColorPrint("✓", fileops.Green), short, status.Subject, status.Format, status.Signer)
+			continue
+		}
+
+		unverified++
+		fmt.Printf("%s  %s  %s (%s)\n", fileops.SColorPrint("✗", fileops.Red), short, status.Subject, status.Err)
+	}
+
+	fmt.Println()
+	if unverified > 0 {
+		fileops.ColorPrintfn(fileops.Red, "%d of %d commits are not verified", unverified, len(statuses))
+		return false
+	}
+	fileops.ColorPrintfn(fileops.Green, "All %d commits are signed by trusted keys", len(statuses))
+	return true
+}
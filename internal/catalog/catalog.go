@@ -37,10 +37,13 @@ type FeatureMetadata struct {
 	Name            string           // Feature identifier (e.g., "git-prompt")
 	Description     string           // Human-readable description
 	Category        string           // Category (e.g., "prompt", "completion", "alias")
-	DefaultStrategy string           // Default load strategy ("eager", "defer", "on-command")
-	DefaultCommands []string         // Default trigger commands for on-command features
+	DefaultStrategy string           // Default load strategy ("eager", "defer", "on-command", "on-completion", "on-directory")
+	DefaultCommands []string         // Default trigger commands for on-command and on-completion features
+	DefaultMarkers  []string         // Default marker files for on-directory features
 	SupportedShells []string         // Shells that support this feature
 	Options         []OptionMetadata // Configurable options for this feature
+	Requires        []string         // Features that must be enabled and load first
+	After           []string         // Features that load first when they are enabled
 }
 
 // Catalog is the global feature catalog
@@ -83,6 +86,7 @@ var Catalog = map[string]FeatureMetadata{
 		Category:        "tool",
 		DefaultStrategy: "on-command",
 		DefaultCommands: []string{"nvm", "node", "npm"},
+		DefaultMarkers:  []string{".nvmrc", ".node-version"},
 		SupportedShells: []string{"bash", "zsh", "fish"},
 	},
 	"terraform-completion": {
@@ -115,6 +119,7 @@ var Catalog = map[string]FeatureMetadata{
 		Category:        "tool",
 		DefaultStrategy: "eager",
 		DefaultCommands: nil,
+		DefaultMarkers:  []string{"pyproject.toml", "requirements.txt", ".python-version"},
 		SupportedShells: []string{"bash", "zsh", "fish"},
 	},
 	"directory-shortcuts": {
@@ -156,6 +161,7 @@ var Catalog = map[string]FeatureMetadata{
 		DefaultStrategy: "eager",
 		DefaultCommands: nil,
 		SupportedShells: []string{"powershell"},
+		After:           []string{"posh-git"},
 	},
 	"powershell-aliases": {
 		Name:            "powershell-aliases",
@@ -220,6 +226,7 @@ var Catalog = map[string]FeatureMetadata{
 		DefaultStrategy: "eager",
 		DefaultCommands: nil,
 		SupportedShells: []string{"bash", "zsh", "fish", "powershell"},
+		After:           []string{"homebrew-path"}, // oh-my-posh is often installed with Homebrew
 		Options: []OptionMetadata{
 			{
 				Name:        "theme",
@@ -3,8 +3,8 @@
 
 # Check if kubectl is installed
 if command -v kubectl >/dev/null 2>&1
-    # Generate Fish completions
-    kubectl completion fish | source
+    # Generate Fish completions, cached until kubectl changes
+    omd_cached_eval kubectl-completion kubectl completion fish
     
     # Common kubectl aliases
     alias k='kubectl'
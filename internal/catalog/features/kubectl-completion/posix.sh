@@ -3,15 +3,15 @@
 
 # Check if kubectl is installed
 if command -v kubectl >/dev/null 2>&1; then
-    # Generate completions for bash/zsh
+    # Generate completions for bash/zsh, cached until kubectl changes
     if [ -n "$BASH_VERSION" ]; then
         # Bash completion
-        source <(kubectl completion bash 2>/dev/null || true)
+        omd_cached_eval kubectl-completion kubectl completion bash 2>/dev/null
         # Enable alias completion
         complete -o default -F __start_kubectl k
     elif [ -n "$ZSH_VERSION" ]; then
         # Zsh completion
-        source <(kubectl completion zsh 2>/dev/null || true)
+        omd_cached_eval kubectl-completion kubectl completion zsh 2>/dev/null
         # Enable alias completion
         compdef __start_kubectl k
     fi
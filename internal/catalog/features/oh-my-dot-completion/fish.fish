@@ -3,9 +3,9 @@
 
 # Check if oh-my-dot/omdot is installed
 if command -v omdot >/dev/null 2>&1
-    # Generate and source Fish completions
-    omdot completion fish | source
+    # Generate and source Fish completions, cached until omdot changes
+    omd_cached_eval oh-my-dot-completion omdot completion fish
 else if command -v oh-my-dot >/dev/null 2>&1
     # Fallback to oh-my-dot command name
-    oh-my-dot completion fish | source
+    omd_cached_eval oh-my-dot-completion oh-my-dot completion fish
 end
@@ -9,15 +9,12 @@ if command -v omdot >/dev/null 2>&1 || command -v oh-my-dot >/dev/null 2>&1; the
         OMD_CMD="oh-my-dot"
     fi
     
+    # Completions are cached until the oh-my-dot binary changes
     if [ -n "$BASH_VERSION" ]; then
         # Bash completion
-        if omd_completion_output="$($OMD_CMD completion bash 2>/dev/null)"; then
-            source /dev/stdin <<<"$omd_completion_output"
-        fi
+        omd_cached_eval oh-my-dot-completion "$OMD_CMD" completion bash 2>/dev/null
     elif [ -n "$ZSH_VERSION" ]; then
         # Zsh completion
-        if omd_completion_output="$($OMD_CMD completion zsh 2>/dev/null)"; then
-            source /dev/stdin <<<"$omd_completion_output"
-        fi
+        omd_cached_eval oh-my-dot-completion "$OMD_CMD" completion zsh 2>/dev/null
     fi
 fi
@@ -2,17 +2,15 @@
 # Enables command-line completion for oh-my-dot (omdot) commands
 
 # Register completions for every available command name. Cobra binds the
-# completer to the command name used to generate the script.
+# completer to the command name used to generate the script, which is cached
+# until the binary changes.
 foreach ($commandName in @("omdot", "oh-my-dot")) {
     if (-not (Get-Command $commandName -ErrorAction SilentlyContinue)) {
         continue
     }
 
     try {
-        $completionScript = & $commandName completion powershell 2>$null | Out-String
-        if (-not [string]::IsNullOrWhiteSpace($completionScript)) {
-            Invoke-Expression $completionScript
-        }
+        . (omd_cached_eval "oh-my-dot-completion-$commandName" $commandName completion powershell 2>$null)
     }
     catch {
         continue
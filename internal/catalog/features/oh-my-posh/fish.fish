@@ -12,8 +12,9 @@ if not test -f "$omd_omp_config"
     end
 end
 
+# The init script is cached until oh-my-posh or the config path changes
 if test -f "$omd_omp_config"
-    oh-my-posh init fish --config "$omd_omp_config" | source
+    omd_cached_eval oh-my-posh oh-my-posh init fish --config "$omd_omp_config"
 else
-    oh-my-posh init fish | source
+    omd_cached_eval oh-my-posh oh-my-posh init fish
 end
\ No newline at end of file
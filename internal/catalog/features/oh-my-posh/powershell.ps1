@@ -13,9 +13,10 @@ if (-not (Test-Path $omdOmpConfig -PathType Leaf)) {
     }
 }
 
+# The init script is cached until oh-my-posh or the config path changes
 if (Test-Path $omdOmpConfig -PathType Leaf) {
-    oh-my-posh init pwsh --config $omdOmpConfig | Invoke-Expression
+    . (omd_cached_eval oh-my-posh oh-my-posh init pwsh --config $omdOmpConfig)
 }
 else {
-    oh-my-posh init pwsh | Invoke-Expression
+    . (omd_cached_eval oh-my-posh oh-my-posh init pwsh)
 }
\ No newline at end of file
@@ -12,8 +12,9 @@ if [ ! -f "$OMD_OMP_CONFIG" ]; then
   fi
 fi
 
+# The init script is cached until oh-my-posh or the config path changes
 if [ -f "$OMD_OMP_CONFIG" ]; then
-  eval "$(oh-my-posh init zsh --config \"$OMD_OMP_CONFIG\")"
+  omd_cached_eval oh-my-posh oh-my-posh init zsh --config "$OMD_OMP_CONFIG"
 else
-  eval "$(oh-my-posh init zsh)"
+  omd_cached_eval oh-my-posh oh-my-posh init zsh
 fi
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// StateFileName is the file next to config.json where oh-my-dot records what it has observed,
+// such as cached remote checks. Keeping it apart means the user's config only changes when they change it.
+const StateFileName = "state.json"
+
+// State is the content of the state file.
+type State struct {
+	UpdateCheck UpdateCheckState `json:"updateCheck"`
+}
+
+// UpdateCheckState caches the last successful remote sync check.
+type UpdateCheckState struct {
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`  // When the remote was last reached
+	RemoteState string    `json:"remoteState,omitempty"` // Sync state it reported
+}
+
+// Dir returns the directory holding the oh-my-dot config file.
+func Dir() string {
+	if configFile := viper.GetString("dot-home"); configFile != "" {
+		return filepath.Dir(configFile)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".oh-my-dot"
+	}
+	return filepath.Join(home, ".oh-my-dot")
+}
+
+// StatePath returns the location of the state file.
+func StatePath() string {
+	return filepath.Join(Dir(), StateFileName)
+}
+
+// LoadState reads the state file. A missing or unreadable file is an empty state,
+// since everything in it can be recomputed.
+func LoadState() State {
+	var state State
+
+	data, err := os.ReadFile(StatePath())
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}
+	}
+	return state
+}
+
+// SaveState writes the state file, replacing it atomically so concurrent commands never read a partial file.
+func SaveState(state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, StateFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	_, writeErr := tmp.Write(append(data, '\n'))
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), StatePath()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
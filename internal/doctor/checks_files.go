@@ -2,6 +2,7 @@ package doctor
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -160,6 +161,43 @@ func checkFeatureFiles(ctx context) []result {
 	return results
 }
 
+func checkFeatureDependencies(ctx context) []result {
+	var results []result
+
+	merged, err := manifest.ParseManifestWithLocal(
+		shell.GetManifestPath(ctx.repoPath, ctx.shellName),
+		shell.GetLocalManifestPath(ctx.repoPath, ctx.shellName),
+	)
+	if err != nil {
+		return results
+	}
+
+	features := make([]manifest.FeatureConfig, 0, len(merged.Features))
+	for _, f := range merged.Features {
+		features = append(features, f.FeatureConfig)
+	}
+	if len(features) == 0 {
+		return results
+	}
+
+	_, errs := shell.OrderFeatures(features)
+	for _, err := range errs {
+		// A dependency that loads too late may still work; missing ones and cycles will not
+		var dependencyErr *shell.DependencyError
+		if errors.As(err, &dependencyErr) && !dependencyErr.Missing {
+			results = addResult(results, ctx, warningResult("Feature dependencies", err.Error(), false), nil)
+		} else {
+			results = addResult(results, ctx, errorResult("Feature dependencies", err.Error(), false), nil)
+		}
+	}
+
+	if len(errs) == 0 {
+		results = addResult(results, ctx, okResult("Feature dependencies"), nil)
+	}
+
+	return results
+}
+
 func checkLineEndings(ctx context) []result {
 	var results []result
 
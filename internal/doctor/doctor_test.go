@@ -136,3 +136,44 @@ func TestCheckLineEndingsFixesCRLF(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckFeatureDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	shellDir := filepath.Join(tmpDir, "omd-shells", "bash")
+	if err := os.MkdirAll(shellDir, 0755); err != nil {
+		t.Fatalf("failed to create shell dir: %v", err)
+	}
+
+	manifestPath := filepath.Join(shellDir, "enabled.json")
+	writeManifest := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+	}
+	ctx := context{repoPath: tmpDir, shellName: "bash"}
+
+	writeManifest(`{"features": [{"name": "prompt", "requires": ["tool"]}, {"name": "tool"}]}`)
+	results := checkFeatureDependencies(ctx)
+	if len(results) != 1 || results[0].status != statusOK {
+		t.Fatalf("expected one ok result, got %+v", results)
+	}
+
+	writeManifest(`{"features": [
+		{"name": "prompt", "requires": ["absent"]},
+		{"name": "a", "after": ["b"]},
+		{"name": "b", "after": ["a"]}
+	]}`)
+	results = checkFeatureDependencies(ctx)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	for _, r := range results {
+		if r.status != statusError {
+			t.Fatalf("expected errors, got %+v", results)
+		}
+	}
+	if !strings.Contains(results[0].message, "absent") || !strings.Contains(results[1].message, "cycle") {
+		t.Fatalf("unexpected messages: %+v", results)
+	}
+}
@@ -0,0 +1,209 @@
+package externals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Action describes what applying an external did.
+type Action string
+
+const (
+	ActionUnchanged Action = "unchanged" // Already at the pinned version
+	ActionInstalled Action = "installed" // Linked or extracted for the first time
+	ActionUpdated   Action = "updated"   // Moved to a new pinned version
+)
+
+// Result is the outcome of applying one external.
+type Result struct {
+	Name   string
+	Dest   string
+	Action Action
+	Err    error
+}
+
+// State is the installed state of an external, as shown by "externals list".
+type State string
+
+const (
+	StateApplied  State = "applied"  // Destination matches the pin
+	StateOutdated State = "outdated" // Destination holds a different version
+	StateMissing  State = "missing"  // Nothing at the destination yet
+	StateConflict State = "conflict" // Destination exists but is not managed by oh-my-dot
+)
+
+// Apply fetches every external in the repository and places it at its destination.
+// A failing external does not stop the others.
+func Apply(ctx context.Context, repoPath string) ([]Result, error) {
+	file, err := Load(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir := CacheDir()
+	results := make([]Result, 0, len(file.Externals))
+	for _, external := range file.Externals {
+		results = append(results, ApplyExternal(ctx, cacheDir, external))
+	}
+	return results, nil
+}
+
+// ApplyExternal fetches one external into cacheDir and links or extracts it to its destination.
+func ApplyExternal(ctx context.Context, cacheDir string, e External) Result {
+	result := Result{Name: e.Name}
+
+	dest, err := e.Destination()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Dest = dest
+
+	switch e.Type {
+	case KindGit:
+		result.Action, result.Err = applyGit(ctx, cacheDir, e, dest)
+	case KindArchive:
+		result.Action, result.Err = applyArchive(ctx, cacheDir, e, dest)
+	default:
+		result.Err = fmt.Errorf("external %s: unknown type %q", e.Name, e.Type)
+	}
+	return result
+}
+
+func applyGit(ctx context.Context, cacheDir string, e External, dest string) (Action, error) {
+	state, err := Status(cacheDir, e)
+	if err != nil {
+		return "", err
+	}
+	if state == StateConflict {
+		return "", fmt.Errorf("%s already exists and is not managed by oh-my-dot", dest)
+	}
+	if state == StateApplied {
+		return ActionUnchanged, nil
+	}
+
+	dir, err := checkoutGit(ctx, cacheDir, e)
+	if err != nil {
+		return "", err
+	}
+
+	if state == StateMissing {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+		}
+	}
+	// The link may point at an older cache location; relinking is cheap either way.
+	if err := os.Remove(dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	if err := os.Symlink(dir, dest); err != nil {
+		return "", fmt.Errorf("failed to link %s: %w", dest, err)
+	}
+
+	if state == StateMissing {
+		return ActionInstalled, nil
+	}
+	return ActionUpdated, nil
+}
+
+func applyArchive(ctx context.Context, cacheDir string, e External, dest string) (Action, error) {
+	state, err := Status(cacheDir, e)
+	if err != nil {
+		return "", err
+	}
+	switch state {
+	case StateConflict:
+		return "", fmt.Errorf("%s already exists and is not managed by oh-my-dot", dest)
+	case StateApplied:
+		return ActionUnchanged, nil
+	}
+
+	data, err := fetchArchive(ctx, cacheDir, e)
+	if err != nil {
+		return "", err
+	}
+	if err := extract(data, e, dest); err != nil {
+		return "", err
+	}
+
+	if state == StateMissing {
+		return ActionInstalled, nil
+	}
+	return ActionUpdated, nil
+}
+
+// Status reports whether an external is in place at its pinned version, without fetching anything.
+func Status(cacheDir string, e External) (State, error) {
+	dest, err := e.Destination()
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Lstat(dest)
+	if errors.Is(err, os.ErrNotExist) {
+		return StateMissing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s: %w", dest, err)
+	}
+
+	switch e.Type {
+	case KindGit:
+		if info.Mode()&os.ModeSymlink == 0 {
+			return StateConflict, nil
+		}
+		target, err := os.Readlink(dest)
+		if err != nil {
+			return "", fmt.Errorf("failed to read link %s: %w", dest, err)
+		}
+		dir := filepath.Join(cacheDir, "git", e.Name)
+		if filepath.Clean(target) != dir {
+			if isInside(cacheDir, target) {
+				return StateOutdated, nil
+			}
+			return StateConflict, nil
+		}
+		if checkedOutCommit(dir) == e.Commit {
+			return StateApplied, nil
+		}
+		return StateOutdated, nil
+
+	case KindArchive:
+		if !info.IsDir() {
+			return StateConflict, nil
+		}
+		switch extractedChecksum(dest) {
+		case e.SHA256:
+			return StateApplied, nil
+		case "":
+			return StateConflict, nil
+		default:
+			return StateOutdated, nil
+		}
+	}
+	return "", fmt.Errorf("external %s: unknown type %q", e.Name, e.Type)
+}
+
+// checkedOutCommit returns the commit checked out in a cached clone, or "" if there is none.
+func checkedOutCommit(dir string) string {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+	head, err := r.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
+
+func isInside(root, path string) bool {
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,303 @@
+package externals
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+)
+
+// markerFile records the checksum of the archive a destination was extracted from.
+const markerFile = ".oh-my-dot-external"
+
+// maxArchiveSize is the largest archive download accepted. Archives are held in memory while they
+// are checked and extracted, so a larger one is refused rather than read.
+var maxArchiveSize int64 = 512 << 20
+
+type format int
+
+const (
+	formatTarGz format = iota
+	formatTar
+	formatZip
+)
+
+// archiveFormat infers the archive format from the URL's file name.
+func archiveFormat(rawURL string) (format, error) {
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		name = u.Path
+	}
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return formatTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return formatZip, nil
+	}
+	return 0, fmt.Errorf("unsupported archive %s: expected .tar.gz, .tgz, .tar or .zip", rawURL)
+}
+
+// download fetches url into memory and returns the content with its sha256 checksum.
+func download(ctx context.Context, rawURL string) ([]byte, string, error) {
+	ctx, cancel, err := network.Context(ctx, network.Transfer)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot download %s: %w", rawURL, err)
+	}
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", rawURL, network.Err(ctx, network.Transfer, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", rawURL, network.Err(ctx, network.Transfer, err))
+	}
+	if int64(len(data)) > maxArchiveSize {
+		return nil, "", fmt.Errorf("failed to download %s: larger than the %d MiB limit for archives", rawURL, maxArchiveSize>>20)
+	}
+
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+// fetchArchive returns the archive of an external, downloading it into the cache on first use.
+// Downloads that don't match the pinned checksum are rejected and never cached.
+func fetchArchive(ctx context.Context, cacheDir string, e External) ([]byte, error) {
+	cached := filepath.Join(cacheDir, "archives", e.SHA256)
+	if data, err := os.ReadFile(cached); err == nil {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) == e.SHA256 {
+			return data, nil
+		}
+	}
+
+	data, sum, err := download(ctx, e.URL)
+	if err != nil {
+		return nil, err
+	}
+	if sum != e.SHA256 {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", e.URL, e.SHA256, sum)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(cached, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to cache %s: %w", e.URL, err)
+	}
+	return data, nil
+}
+
+// extractedChecksum returns the checksum recorded in dest, or "" if dest was not extracted by oh-my-dot.
+func extractedChecksum(dest string) string {
+	data, err := os.ReadFile(filepath.Join(dest, markerFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// extract unpacks an archive into dest, replacing a previous extraction. The archive is unpacked
+// next to dest first, so a failure leaves the old contents in place. Entries are written through
+// an os.Root, so symlinks extracted earlier cannot lead later entries outside the destination.
+func extract(data []byte, e External, dest string) error {
+	f, err := archiveFormat(e.URL)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".oh-my-dot-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	root, err := os.OpenRoot(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to open temporary directory: %w", err)
+	}
+	switch f {
+	case formatZip:
+		err = extractZip(data, root, e.Strip)
+	case formatTarGz:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(data))
+		if err == nil {
+			err = extractTar(gz, root, e.Strip)
+		}
+	default:
+		err = extractTar(bytes.NewReader(data), root, e.Strip)
+	}
+	root.Close()
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", e.URL, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, markerFile), []byte(e.SHA256+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+// entryPath strips leading components from an archive entry and returns where it belongs under root.
+// ok is false for entries that are stripped away entirely. Entries escaping root are rejected.
+func entryPath(root, name string, strip int) (string, bool, error) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	parts := strings.Split(name, "/")
+	if name == "" || len(parts) <= strip {
+		return "", false, nil
+	}
+
+	rel := filepath.FromSlash(strings.Join(parts[strip:], "/"))
+	target := filepath.Join(root, rel)
+	if !strings.HasPrefix(target, root+string(filepath.Separator)) {
+		return "", false, fmt.Errorf("entry %s escapes the destination", name)
+	}
+	return target, true, nil
+}
+
+func extractTar(r io.Reader, root *os.Root, strip int) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, ok, err := entryPath(root.Name(), header.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(rootRelative(root, target), 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(root, target, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeSymlink(root, target, header.Linkname); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(data []byte, root *os.Root, strip int) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		target, ok, err := entryPath(root.Name(), file.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := root.MkdirAll(rootRelative(root, target), 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(root, target, rc, file.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootRelative returns target, a path under root, relative to root
+func rootRelative(root *os.Root, target string) string {
+	rel, _ := filepath.Rel(root.Name(), target)
+	return rel
+}
+
+func writeFile(root *os.Root, target string, r io.Reader, mode os.FileMode) error {
+	rel := rootRelative(root, target)
+	if err := root.MkdirAll(filepath.Dir(rel), 0755); err != nil {
+		return err
+	}
+	out, err := root.OpenFile(rel, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeSymlink creates a symlink from an archive, refusing targets that point outside root.
+func writeSymlink(root *os.Root, target, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("symlink %s points to an absolute path", target)
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	if resolved != root.Name() && !strings.HasPrefix(resolved, root.Name()+string(filepath.Separator)) {
+		return fmt.Errorf("symlink %s escapes the destination", target)
+	}
+
+	rel := rootRelative(root, target)
+	if err := root.MkdirAll(filepath.Dir(rel), 0755); err != nil {
+		return err
+	}
+	return root.Symlink(linkname, rel)
+}
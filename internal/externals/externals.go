@@ -0,0 +1,165 @@
+package externals
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/config"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"go.yaml.in/yaml/v3"
+)
+
+// FileName is the file at the repository root that declares externals.
+const FileName = "externals.yaml"
+
+// Kinds of externals.
+const (
+	KindGit     = "git"     // A git repository checked out at a pinned commit and symlinked
+	KindArchive = "archive" // A .tar.gz, .tgz, .tar or .zip file verified by checksum and extracted
+)
+
+// External is a third-party source that apply places under the home directory.
+type External struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	URL    string `yaml:"url"`
+	Ref    string `yaml:"ref,omitempty"`    // Git: branch or tag that update follows (default: remote HEAD)
+	Commit string `yaml:"commit,omitempty"` // Git: pinned commit
+	SHA256 string `yaml:"sha256,omitempty"` // Archive: checksum of the downloaded file
+	Strip  int    `yaml:"strip,omitempty"`  // Archive: leading path components to drop when extracting
+	Dest   string `yaml:"dest"`             // Destination under the home directory, e.g. ~/.oh-my-zsh/custom/plugins/x
+}
+
+// File is the structure of externals.yaml.
+type File struct {
+	Externals []External `yaml:"externals"`
+}
+
+var (
+	nameRegex   = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	sha256Regex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// Path returns the location of externals.yaml in the repository.
+func Path(repoPath string) string {
+	return filepath.Join(repoPath, FileName)
+}
+
+// Load reads externals.yaml. A missing file means no externals.
+func Load(repoPath string) (*File, error) {
+	data, err := os.ReadFile(Path(repoPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+
+	seen := make(map[string]bool, len(file.Externals))
+	for _, external := range file.Externals {
+		if err := external.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[external.Name] {
+			return nil, fmt.Errorf("external %q is declared more than once", external.Name)
+		}
+		seen[external.Name] = true
+	}
+
+	return &file, nil
+}
+
+// Save writes externals.yaml.
+func Save(repoPath string, file *File) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+	return fileops.WriteTextFileLF(Path(repoPath), string(data), 0644)
+}
+
+// Find returns the external with the given name.
+func (f *File) Find(name string) (*External, bool) {
+	for i := range f.Externals {
+		if f.Externals[i].Name == name {
+			return &f.Externals[i], true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks that an external is complete and its destination stays under the home directory.
+func (e External) Validate() error {
+	if !nameRegex.MatchString(e.Name) {
+		return fmt.Errorf("invalid external name %q: use letters, digits, '.', '-' and '_'", e.Name)
+	}
+	if e.URL == "" {
+		return fmt.Errorf("external %s: url is required", e.Name)
+	}
+
+	switch e.Type {
+	case KindGit:
+		if !commitRegex.MatchString(e.Commit) {
+			return fmt.Errorf("external %s: commit must be a full 40 character hash", e.Name)
+		}
+	case KindArchive:
+		if !sha256Regex.MatchString(e.SHA256) {
+			return fmt.Errorf("external %s: sha256 must be a 64 character hex checksum", e.Name)
+		}
+		if _, err := archiveFormat(e.URL); err != nil {
+			return fmt.Errorf("external %s: %w", e.Name, err)
+		}
+		if e.Strip < 0 {
+			return fmt.Errorf("external %s: strip cannot be negative", e.Name)
+		}
+	default:
+		return fmt.Errorf("external %s: type must be %q or %q", e.Name, KindGit, KindArchive)
+	}
+
+	if _, err := e.Destination(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Destination returns the expanded destination path, which must be inside the home directory.
+func (e External) Destination() (string, error) {
+	if e.Dest == "" {
+		return "", fmt.Errorf("external %s: dest is required", e.Name)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	dest, err := fileops.ExpandPath(e.Dest)
+	if err != nil {
+		return "", fmt.Errorf("external %s: %w", e.Name, err)
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(home, dest)
+	}
+	dest = filepath.Clean(dest)
+
+	rel, err := filepath.Rel(home, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("external %s: dest %s must be inside the home directory", e.Name, e.Dest)
+	}
+	return dest, nil
+}
+
+// CacheDir returns the directory fetched externals are kept in, next to the oh-my-dot config.
+func CacheDir() string {
+	return filepath.Join(config.Dir(), "cache", "externals")
+}
@@ -0,0 +1,507 @@
+package externals
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// setupHome points the home directory and the oh-my-dot config at temporary directories.
+func setupHome(t *testing.T) string {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	viper.Set("dot-home", filepath.Join(home, ".oh-my-dot", "config.json"))
+	return home
+}
+
+// createUpstream creates a bare repository on branch main with one commit and returns its path,
+// a working clone for adding commits, and the first commit.
+func createUpstream(t *testing.T) (string, *git.Repository, string) {
+	t.Helper()
+
+	bare := t.TempDir()
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := git.PlainInitWithOptions(t.TempDir(), &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := work.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := commitUpstream(t, work, "plugin.zsh", "echo v1\n")
+
+	repo, err := git.PlainOpen(bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))); err != nil {
+		t.Fatal(err)
+	}
+	return bare, work, hash
+}
+
+// commitUpstream commits a file in the working clone, pushes it and returns the commit hash.
+func commitUpstream(t *testing.T, work *git.Repository, name, content string) string {
+	t.Helper()
+
+	wt, err := work.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.Filesystem.Root(), name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := wt.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := work.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	return hash.String()
+}
+
+// tarGz builds a gzipped tarball with every file under a top-level "pkg-1.0/" directory.
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: "pkg-1.0/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveArchive serves the archive returned by current at /pkg.tar.gz.
+func serveArchive(t *testing.T, current func() []byte) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pkg.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(current())
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/pkg.tar.gz"
+}
+
+func TestLoadAndSave(t *testing.T) {
+	setupHome(t)
+	repo := t.TempDir()
+
+	file, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load without file: %v", err)
+	}
+	if len(file.Externals) != 0 {
+		t.Fatalf("expected no externals, got %d", len(file.Externals))
+	}
+
+	file.Externals = append(file.Externals, External{
+		Name:   "zsh-autosuggestions",
+		Type:   KindGit,
+		URL:    "https://github.com/zsh-users/zsh-autosuggestions",
+		Ref:    "master",
+		Commit: strings.Repeat("a", 40),
+		Dest:   "~/.zsh/zsh-autosuggestions",
+	})
+	if err := Save(repo, file); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded.Externals) != 1 || loaded.Externals[0] != file.Externals[0] {
+		t.Fatalf("round trip mismatch: %+v", loaded.Externals)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	setupHome(t)
+
+	commit := strings.Repeat("a", 40)
+	sum := strings.Repeat("b", 64)
+
+	tests := []struct {
+		name     string
+		external External
+		wantErr  string
+	}{
+		{"git", External{Name: "a", Type: KindGit, URL: "u", Commit: commit, Dest: "~/a"}, ""},
+		{"archive", External{Name: "a", Type: KindArchive, URL: "https://x/a.tar.gz", SHA256: sum, Dest: "~/a"}, ""},
+		{"relative dest is under home", External{Name: "a", Type: KindGit, URL: "u", Commit: commit, Dest: ".zsh/a"}, ""},
+		{"bad name", External{Name: "a/b", Type: KindGit, URL: "u", Commit: commit, Dest: "~/a"}, "invalid external name"},
+		{"short commit", External{Name: "a", Type: KindGit, URL: "u", Commit: "abc", Dest: "~/a"}, "commit must be"},
+		{"missing checksum", External{Name: "a", Type: KindArchive, URL: "https://x/a.tar.gz", Dest: "~/a"}, "sha256 must be"},
+		{"unknown archive", External{Name: "a", Type: KindArchive, URL: "https://x/a.rar", SHA256: sum, Dest: "~/a"}, "unsupported archive"},
+		{"unknown type", External{Name: "a", Type: "svn", URL: "u", Dest: "~/a"}, "type must be"},
+		{"dest outside home", External{Name: "a", Type: KindGit, URL: "u", Commit: commit, Dest: "/etc/a"}, "inside the home directory"},
+		{"dest escapes home", External{Name: "a", Type: KindGit, URL: "u", Commit: commit, Dest: "~/../a"}, "inside the home directory"},
+		{"dest is home", External{Name: "a", Type: KindGit, URL: "u", Commit: commit, Dest: "~"}, "inside the home directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.external.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyGitExternal(t *testing.T) {
+	home := setupHome(t)
+	bare, work, first := createUpstream(t)
+	cacheDir := CacheDir()
+
+	external := External{Name: "plugin", Type: KindGit, URL: bare, Ref: "main", Commit: first, Dest: "~/.zsh/plugin"}
+	dest := filepath.Join(home, ".zsh", "plugin")
+
+	result := ApplyExternal(context.Background(), cacheDir, external)
+	if result.Err != nil || result.Action != ActionInstalled {
+		t.Fatalf("first apply: %+v", result)
+	}
+	content, err := os.ReadFile(filepath.Join(dest, "plugin.zsh"))
+	if err != nil || string(content) != "echo v1\n" {
+		t.Fatalf("expected linked checkout, got %q (%v)", content, err)
+	}
+
+	result = ApplyExternal(context.Background(), cacheDir, external)
+	if result.Err != nil || result.Action != ActionUnchanged {
+		t.Fatalf("second apply: %+v", result)
+	}
+
+	// A new pin is fetched into the existing cache.
+	external.Commit = commitUpstream(t, work, "plugin.zsh", "echo v2\n")
+	if state, _ := Status(cacheDir, external); state != StateOutdated {
+		t.Fatalf("expected outdated state, got %s", state)
+	}
+	result = ApplyExternal(context.Background(), cacheDir, external)
+	if result.Err != nil || result.Action != ActionUpdated {
+		t.Fatalf("update apply: %+v", result)
+	}
+	content, _ = os.ReadFile(filepath.Join(dest, "plugin.zsh"))
+	if string(content) != "echo v2\n" {
+		t.Fatalf("expected v2 after update, got %q", content)
+	}
+
+	// Pinning back to an older commit works without fetching.
+	external.Commit = first
+	if result := ApplyExternal(context.Background(), cacheDir, external); result.Err != nil {
+		t.Fatalf("pin back: %v", result.Err)
+	}
+	content, _ = os.ReadFile(filepath.Join(dest, "plugin.zsh"))
+	if string(content) != "echo v1\n" {
+		t.Fatalf("expected v1 after pinning back, got %q", content)
+	}
+}
+
+func TestApplyRefusesUnmanagedDestination(t *testing.T) {
+	home := setupHome(t)
+	bare, _, first := createUpstream(t)
+	cacheDir := CacheDir()
+
+	dest := filepath.Join(home, ".zsh", "plugin")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ApplyExternal(context.Background(), cacheDir, External{Name: "plugin", Type: KindGit, URL: bare, Commit: first, Dest: "~/.zsh/plugin"})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "not managed") {
+		t.Fatalf("expected conflict error, got %+v", result)
+	}
+}
+
+func TestApplyGitExternalMissingCommit(t *testing.T) {
+	setupHome(t)
+	bare, _, _ := createUpstream(t)
+	cacheDir := CacheDir()
+
+	result := ApplyExternal(context.Background(), cacheDir, External{Name: "plugin", Type: KindGit, URL: bare, Commit: strings.Repeat("0", 40), Dest: "~/plugin"})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "not found") {
+		t.Fatalf("expected missing commit error, got %+v", result)
+	}
+}
+
+func TestApplyArchiveExternal(t *testing.T) {
+	home := setupHome(t)
+	cacheDir := CacheDir()
+
+	archive := tarGz(t, map[string]string{"theme.zsh": "theme v1\n", "lib/util.zsh": "util\n"})
+	requests := 0
+	url := serveArchive(t, func() []byte {
+		requests++
+		return archive
+	})
+
+	external := External{Name: "theme", Type: KindArchive, URL: url, SHA256: checksum(archive), Strip: 1, Dest: "~/.zsh/theme"}
+	dest := filepath.Join(home, ".zsh", "theme")
+
+	result := ApplyExternal(context.Background(), cacheDir, external)
+	if result.Err != nil || result.Action != ActionInstalled {
+		t.Fatalf("first apply: %+v", result)
+	}
+	for name, want := range map[string]string{"theme.zsh": "theme v1\n", "lib/util.zsh": "util\n"} {
+		content, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil || string(content) != want {
+			t.Fatalf("%s: got %q (%v)", name, content, err)
+		}
+	}
+
+	result = ApplyExternal(context.Background(), cacheDir, external)
+	if result.Err != nil || result.Action != ActionUnchanged {
+		t.Fatalf("second apply: %+v", result)
+	}
+
+	// Reinstalling uses the cached download.
+	if err := os.RemoveAll(dest); err != nil {
+		t.Fatal(err)
+	}
+	if result := ApplyExternal(context.Background(), cacheDir, external); result.Err != nil {
+		t.Fatalf("reinstall: %v", result.Err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected one download, got %d", requests)
+	}
+}
+
+func TestApplyArchiveChecksumMismatch(t *testing.T) {
+	home := setupHome(t)
+	cacheDir := CacheDir()
+
+	archive := tarGz(t, map[string]string{"theme.zsh": "theme\n"})
+	url := serveArchive(t, func() []byte { return archive })
+
+	result := ApplyExternal(context.Background(), cacheDir, External{Name: "theme", Type: KindArchive, URL: url, SHA256: strings.Repeat("0", 64), Dest: "~/theme"})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(home, "theme")); !os.IsNotExist(err) {
+		t.Fatalf("destination should not exist after a failed download")
+	}
+}
+
+func TestApplyArchiveTooLarge(t *testing.T) {
+	setupHome(t)
+	original := maxArchiveSize
+	t.Cleanup(func() { maxArchiveSize = original })
+
+	archive := tarGz(t, map[string]string{"theme.zsh": strings.Repeat("theme\n", 1000)})
+	maxArchiveSize = int64(len(archive)) - 1
+	url := serveArchive(t, func() []byte { return archive })
+
+	result := ApplyExternal(context.Background(), CacheDir(), External{Name: "theme", Type: KindArchive, URL: url, SHA256: checksum(archive), Dest: "~/theme"})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "limit") {
+		t.Fatalf("expected the download to exceed the limit, got %+v", result)
+	}
+
+	maxArchiveSize = int64(len(archive))
+	if result := ApplyExternal(context.Background(), CacheDir(), External{Name: "theme", Type: KindArchive, URL: url, SHA256: checksum(archive), Dest: "~/theme"}); result.Err != nil {
+		t.Fatalf("an archive at the limit should download: %v", result.Err)
+	}
+}
+
+func TestEntryPathRejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name    string
+		entry   string
+		strip   int
+		want    string
+		wantOK  bool
+		wantErr bool
+	}{
+		{"plain", "a/b.txt", 0, "a/b.txt", true, false},
+		{"stripped", "pkg/a/b.txt", 1, "a/b.txt", true, false},
+		{"stripped away", "pkg/", 1, "", false, false},
+		{"dot dot is cleaned", "../../etc/passwd", 0, "etc/passwd", true, false},
+		{"absolute is cleaned", "/etc/passwd", 0, "etc/passwd", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := entryPath(root, tt.entry, tt.strip)
+			if (err != nil) != tt.wantErr || ok != tt.wantOK {
+				t.Fatalf("entryPath(%q) = %q, %v, %v", tt.entry, got, ok, err)
+			}
+			if ok && got != filepath.Join(root, filepath.FromSlash(tt.want)) {
+				t.Fatalf("entryPath(%q) = %q, want %q", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	setupHome(t)
+	bare, work, first := createUpstream(t)
+
+	archive := tarGz(t, map[string]string{"theme.zsh": "v1\n"})
+	url := serveArchive(t, func() []byte { return archive })
+
+	file := &File{Externals: []External{
+		{Name: "plugin", Type: KindGit, URL: bare, Ref: "main", Commit: first, Dest: "~/plugin"},
+		{Name: "theme", Type: KindArchive, URL: url, SHA256: checksum(archive), Dest: "~/theme"},
+	}}
+
+	changes, err := Update(context.Background(), file, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	second := commitUpstream(t, work, "plugin.zsh", "echo v2\n")
+	archive = tarGz(t, map[string]string{"theme.zsh": "v2\n"})
+
+	changes, err = Update(context.Background(), file, []string{"plugin"})
+	if err != nil {
+		t.Fatalf("Update plugin: %v", err)
+	}
+	if len(changes) != 1 || changes[0].From != first || changes[0].To != second {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if file.Externals[0].Commit != second {
+		t.Fatalf("pin not moved: %s", file.Externals[0].Commit)
+	}
+	if file.Externals[1].SHA256 == checksum(archive) {
+		t.Fatalf("theme should not be updated when only plugin is named")
+	}
+
+	changes, err = Update(context.Background(), file, nil)
+	if err != nil {
+		t.Fatalf("Update all: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "theme" || file.Externals[1].SHA256 != checksum(archive) {
+		t.Fatalf("expected theme checksum update, got %+v", changes)
+	}
+
+	if _, err := Update(context.Background(), file, []string{"missing"}); err == nil {
+		t.Fatalf("expected error for an undeclared external")
+	}
+}
+
+func TestLatestCommitFollowsTags(t *testing.T) {
+	setupHome(t)
+	bare, work, first := createUpstream(t)
+
+	head, err := work.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = work.CreateTag("v1.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+		Message: "v1.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := work.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{"refs/tags/*:refs/tags/*"}}); err != nil {
+		t.Fatal(err)
+	}
+	commitUpstream(t, work, "plugin.zsh", "echo v2\n")
+
+	got, err := latestCommit(context.Background(), External{URL: bare, Ref: "v1.0"})
+	if err != nil {
+		t.Fatalf("latestCommit: %v", err)
+	}
+	if got != first {
+		t.Fatalf("expected annotated tag to resolve to %s, got %s", first, got)
+	}
+}
+
+func TestExtractRejectsChainedSymlinks(t *testing.T) {
+	parent := t.TempDir()
+	if err := os.Mkdir(filepath.Join(parent, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each link looks safe on its own, but a/l/m resolves to ../x once a/l points at the root
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range []*tar.Header{
+		{Name: "a/l", Linkname: "..", Typeflag: tar.TypeSymlink},
+		{Name: "a/l/m", Linkname: "../x", Typeflag: tar.TypeSymlink},
+		{Name: "a/l/m/evil", Mode: 0644, Size: 4, Typeflag: tar.TypeReg},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tw.Write([]byte("evil")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	external := External{Name: "evil", Type: KindArchive, URL: "https://example.com/evil.tar"}
+	if err := extract(buf.Bytes(), external, filepath.Join(parent, "dest")); err == nil {
+		t.Fatal("expected an archive that escapes through symlinks to fail")
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "x", "evil")); !os.IsNotExist(err) {
+		t.Fatalf("archive wrote outside the destination: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package externals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// checkoutGit makes the cached clone of a git external match its pinned commit and returns its path.
+// The clone is reused between runs and only fetched when the pinned commit is missing.
+func checkoutGit(ctx context.Context, cacheDir string, e External) (string, error) {
+	dir := filepath.Join(cacheDir, "git", e.Name)
+
+	r, err := openCachedClone(ctx, dir, e.URL)
+	if err != nil {
+		return "", err
+	}
+
+	hash := plumbing.NewHash(e.Commit)
+	if _, err := r.CommitObject(hash); err != nil {
+		fetchCtx, cancel, err := network.Context(ctx, network.Transfer)
+		if err != nil {
+			return "", fmt.Errorf("commit %s is not cached: %w", e.Commit, err)
+		}
+		defer cancel()
+
+		err = network.Err(fetchCtx, network.Transfer, r.FetchContext(fetchCtx, &git.FetchOptions{
+			RefSpecs: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"},
+			Force:    true,
+		}))
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return "", fmt.Errorf("failed to fetch %s: %w", e.URL, err)
+		}
+		if _, err := r.CommitObject(hash); err != nil {
+			return "", fmt.Errorf("commit %s not found in %s", e.Commit, e.URL)
+		}
+	}
+
+	worktree, err := r.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: hash, Force: true}); err != nil {
+		return "", fmt.Errorf("failed to check out %s: %w", e.Commit, err)
+	}
+
+	return dir, nil
+}
+
+// openCachedClone opens the cached clone in dir, cloning it again when it is missing or
+// was cloned from a different URL.
+func openCachedClone(ctx context.Context, dir, url string) (*git.Repository, error) {
+	if r, err := git.PlainOpen(dir); err == nil {
+		if remote, err := r.Remote("origin"); err == nil && len(remote.Config().URLs) > 0 && remote.Config().URLs[0] == url {
+			return r, nil
+		}
+	}
+
+	cloneCtx, cancel, err := network.Context(ctx, network.Transfer)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not cached: %w", url, err)
+	}
+	defer cancel()
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear cache %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	r, err := git.PlainCloneContext(cloneCtx, dir, false, &git.CloneOptions{URL: url, NoCheckout: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", url, network.Err(cloneCtx, network.Transfer, err))
+	}
+	return r, nil
+}
+
+// latestCommit asks the remote which commit ref points at. An empty ref follows the remote HEAD.
+func latestCommit(ctx context.Context, e External) (string, error) {
+	ctx, cancel, err := network.Context(ctx, network.Check)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	remote := git.NewRemote(nil, &config.RemoteConfig{Name: "origin", URLs: []string{e.URL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s: %w", e.URL, network.Err(ctx, network.Check, err))
+	}
+
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+
+	var candidates []plumbing.ReferenceName
+	if e.Ref == "" {
+		head, ok := byName[plumbing.HEAD]
+		if !ok {
+			return "", fmt.Errorf("%s has no HEAD; set ref to a branch or tag", e.URL)
+		}
+		if head.Type() == plumbing.SymbolicReference {
+			candidates = append(candidates, head.Target())
+		} else {
+			return head.Hash().String(), nil
+		}
+	} else {
+		tag := plumbing.NewTagReferenceName(e.Ref)
+		// Annotated tags are advertised twice; the peeled entry points at the commit.
+		candidates = append(candidates,
+			plumbing.NewBranchReferenceName(e.Ref),
+			plumbing.ReferenceName(tag.String()+"^{}"),
+			tag,
+			plumbing.ReferenceName(e.Ref),
+		)
+	}
+
+	for _, name := range candidates {
+		if ref, ok := byName[name]; ok && ref.Type() == plumbing.HashReference {
+			return ref.Hash().String(), nil
+		}
+	}
+	return "", fmt.Errorf("ref %s not found in %s", e.Ref, e.URL)
+}
@@ -0,0 +1,101 @@
+package externals
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Change is a pin moved by Update.
+type Change struct {
+	Name string
+	From string
+	To   string
+}
+
+// Update moves the pins of the named externals, or of all externals when names is empty,
+// to the latest commit of their ref or the current checksum of their archive.
+// It changes file in place and returns the pins that moved; the caller saves the file.
+func Update(ctx context.Context, file *File, names []string) ([]Change, error) {
+	targets := file.Externals
+	if len(names) > 0 {
+		targets = nil
+		for _, name := range names {
+			external, ok := file.Find(name)
+			if !ok {
+				return nil, fmt.Errorf("external %s is not declared in %s", name, FileName)
+			}
+			targets = append(targets, *external)
+		}
+	}
+
+	cacheDir := CacheDir()
+	var changes []Change
+	for _, target := range targets {
+		external, _ := file.Find(target.Name)
+
+		var latest string
+		var err error
+		switch external.Type {
+		case KindGit:
+			latest, err = latestCommit(ctx, *external)
+		case KindArchive:
+			latest, err = latestChecksum(ctx, cacheDir, *external)
+		default:
+			err = fmt.Errorf("external %s: unknown type %q", external.Name, external.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if external.pin() == latest {
+			continue
+		}
+		changes = append(changes, Change{Name: external.Name, From: external.pin(), To: latest})
+		external.setPin(latest)
+	}
+
+	return changes, nil
+}
+
+// latestChecksum downloads an archive again and caches it under its new checksum.
+func latestChecksum(ctx context.Context, cacheDir string, e External) (string, error) {
+	data, sum, err := download(ctx, e.URL)
+	if err != nil {
+		return "", err
+	}
+
+	cached := filepath.Join(cacheDir, "archives", sum)
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(cached, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to cache %s: %w", e.URL, err)
+	}
+	return sum, nil
+}
+
+// pin returns the commit or checksum an external is pinned to.
+func (e External) pin() string {
+	if e.Type == KindArchive {
+		return e.SHA256
+	}
+	return e.Commit
+}
+
+func (e *External) setPin(value string) {
+	if e.Type == KindArchive {
+		e.SHA256 = value
+		return
+	}
+	e.Commit = value
+}
+
+// Pin returns a short form of the pinned commit or checksum for display.
+func (e External) Pin() string {
+	if pin := e.pin(); len(pin) > 12 {
+		return pin[:12]
+	}
+	return e.pin()
+}
@@ -2,10 +2,13 @@ package featurecmd
 
 import (
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/options"
@@ -96,7 +99,7 @@ func (state *commandState) runFeatureAdd(cmd *cobra.Command, args []string) erro
 	for _, shellName := range targetShells {
 		fileops.ColorPrintfn(fileops.Cyan, "Adding %s to %s...", featureName, shellName)
 
-		err := shell.AddFeatureToShell(repoPath, shellName, featureName, state.flagStrategy, state.flagOnCommand, state.flagDisabled, optionValues)
+		err := shell.AddFeatureToShell(repoPath, shellName, featureName, state.flagStrategy, state.flagOnCommand, state.flagOnDirectory, state.flagDisabled, optionValues)
 		if err != nil {
 			return fmt.Errorf("failed to add feature to %s: %w", shellName, err)
 		}
@@ -104,7 +107,7 @@ func (state *commandState) runFeatureAdd(cmd *cobra.Command, args []string) erro
 		fileops.ColorPrintfn(fileops.Green, "  ✓ Feature added")
 	}
 
-	if err := autoCommitShellFeatureChanges("Add shell feature: " + featureName); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureAdd, git.MessageData{Name: featureName, Shell: strings.Join(targetShells, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -249,6 +252,7 @@ func (state *commandState) runInteractiveFeatureAdd(repoPath string) error {
 
 	addedCount := 0
 	skippedCount := 0
+	var addedFeatures []string
 
 	for _, feature := range selectedFeatures {
 		if !hasPendingFeatureInstall(feature, selectedShells, installedFeaturesByShell) {
@@ -288,7 +292,7 @@ func (state *commandState) runInteractiveFeatureAdd(repoPath string) error {
 
 			fileops.ColorPrintfn(fileops.Cyan, "Adding %s to %s...", feature.Name, shellName)
 
-			err = shell.AddFeatureToShell(repoPath, shellName, feature.Name, state.flagStrategy, state.flagOnCommand, state.flagDisabled, optionValues)
+			err = shell.AddFeatureToShell(repoPath, shellName, feature.Name, state.flagStrategy, state.flagOnCommand, state.flagOnDirectory, state.flagDisabled, optionValues)
 			if err != nil {
 				fileops.ColorPrintfn(fileops.Red, "  ✗ Failed: %s", err)
 				skippedCount++
@@ -298,6 +302,9 @@ func (state *commandState) runInteractiveFeatureAdd(repoPath string) error {
 			fileops.ColorPrintfn(fileops.Green, "  ✓ Feature added")
 			installedFeaturesByShell[shellName][feature.Name] = true
 			addedCount++
+			if !slices.Contains(addedFeatures, feature.Name) {
+				addedFeatures = append(addedFeatures, feature.Name)
+			}
 		}
 	}
 
@@ -310,7 +317,7 @@ func (state *commandState) runInteractiveFeatureAdd(repoPath string) error {
 	}
 
 	if addedCount > 0 {
-		if err := autoCommitShellFeatureChanges("Add shell features (interactive)"); err != nil {
+		if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureAdd, git.MessageData{Name: strings.Join(addedFeatures, ", "), Shell: strings.Join(selectedShells, ", ")})); err != nil {
 			return fmt.Errorf("failed to commit shell feature changes: %w", err)
 		}
 
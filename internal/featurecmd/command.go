@@ -10,10 +10,14 @@ type commandState struct {
 	flagAll         bool
 	flagStrategy    string
 	flagOnCommand   []string
+	flagOnDirectory []string
 	flagOption      []string
 	flagDisabled    bool
 	flagForce       bool
 	flagInteractive bool
+	flagBefore      string
+	flagAfter       string
+	flagLocal       bool
 }
 
 // NewCommand builds the feature command tree and keeps cmd/feature.go as a thin entrypoint.
@@ -144,13 +148,35 @@ Examples:
 		RunE: state.runFeatureInfo,
 	}
 
-	featureCmd.AddCommand(featureAddCmd, featureUpdateCmd, featureRemoveCmd, featureListCmd, featureEnableCmd, featureDisableCmd, featureInfoCmd)
+	featureMoveCmd := &cobra.Command{
+		Use:   "move [feature]",
+		Short: "Change the order features load in",
+		Long: `Move a feature before or after another feature in the load order.
+
+Interactive mode (-i): Rearrange all features of a shell in a list
+Non-interactive: Specify the feature and --before or --after
+
+The order is saved in enabled.json and committed. With --local it is saved in
+enabled.local.json instead and only applies to this machine. Features still load
+after the features they require or are declared to load after.
+
+Examples:
+  oh-my-dot feature move oh-my-posh --after homebrew-path
+  oh-my-dot feature move git-prompt --before core-aliases --shell bash
+  oh-my-dot feature move -i --shell zsh
+  oh-my-dot feature move work-prompt --after git-prompt --local`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: state.runFeatureMove,
+	}
+
+	featureCmd.AddCommand(featureAddCmd, featureUpdateCmd, featureRemoveCmd, featureListCmd, featureEnableCmd, featureDisableCmd, featureInfoCmd, featureMoveCmd)
 
 	featureAddCmd.Flags().BoolVarP(&state.flagInteractive, "interactive", "i", false, "Browse and select features from catalog")
 	featureAddCmd.Flags().StringSliceVar(&state.flagShell, "shell", nil, "Target specific shell(s)")
 	featureAddCmd.Flags().BoolVar(&state.flagAll, "all", false, "Add to all supported shells")
-	featureAddCmd.Flags().StringVar(&state.flagStrategy, "strategy", "", "Override load strategy (eager, defer, on-command)")
-	featureAddCmd.Flags().StringSliceVar(&state.flagOnCommand, "on-command", nil, "Set trigger commands for on-command strategy")
+	featureAddCmd.Flags().StringVar(&state.flagStrategy, "strategy", "", "Override load strategy (eager, defer, on-command, on-completion, on-directory)")
+	featureAddCmd.Flags().StringSliceVar(&state.flagOnCommand, "on-command", nil, "Set trigger commands for on-command and on-completion strategies")
+	featureAddCmd.Flags().StringSliceVar(&state.flagOnDirectory, "on-directory", nil, "Set marker files for on-directory strategy")
 	featureAddCmd.Flags().StringSliceVar(&state.flagOption, "option", nil, "Set feature option (key=value); repeatable")
 	featureAddCmd.Flags().BoolVar(&state.flagDisabled, "disabled", false, "Add feature but keep it disabled")
 
@@ -167,9 +193,16 @@ Examples:
 	featureEnableCmd.Flags().StringSliceVar(&state.flagShell, "shell", nil, "Target specific shell(s)")
 	featureEnableCmd.Flags().StringVar(&state.flagStrategy, "strategy", "", "Override load strategy")
 	featureEnableCmd.Flags().StringSliceVar(&state.flagOnCommand, "on-command", nil, "Set trigger commands")
+	featureEnableCmd.Flags().StringSliceVar(&state.flagOnDirectory, "on-directory", nil, "Set marker files")
 
 	featureDisableCmd.Flags().StringSliceVar(&state.flagShell, "shell", nil, "Target specific shell(s)")
 	featureDisableCmd.Flags().BoolVar(&state.flagAll, "all", false, "Disable in all shells")
 
+	featureMoveCmd.Flags().BoolVarP(&state.flagInteractive, "interactive", "i", false, "Rearrange features in a list")
+	featureMoveCmd.Flags().StringSliceVar(&state.flagShell, "shell", nil, "Target specific shell(s)")
+	featureMoveCmd.Flags().StringVar(&state.flagBefore, "before", "", "Move the feature directly before this feature")
+	featureMoveCmd.Flags().StringVar(&state.flagAfter, "after", "", "Move the feature directly after this feature")
+	featureMoveCmd.Flags().BoolVar(&state.flagLocal, "local", false, "Keep the order in enabled.local.json for this machine only")
+
 	return featureCmd
 }
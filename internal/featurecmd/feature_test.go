@@ -8,7 +8,10 @@ import (
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/spf13/viper"
 )
 
 func TestFilterFeaturesByShells(t *testing.T) {
@@ -184,7 +187,7 @@ func TestShellsWithFeature(t *testing.T) {
 		t.Fatalf("InitializeShellDirectory() error = %v", err)
 	}
 
-	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, false, nil); err != nil {
+	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, nil, false, nil); err != nil {
 		t.Fatalf("AddFeatureToShell() error = %v", err)
 	}
 
@@ -206,7 +209,7 @@ func TestRefreshFeatureTemplate(t *testing.T) {
 		t.Fatalf("InitializeShellDirectory() error = %v", err)
 	}
 
-	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, false, nil); err != nil {
+	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, nil, false, nil); err != nil {
 		t.Fatalf("AddFeatureToShell() error = %v", err)
 	}
 
@@ -240,11 +243,11 @@ func TestCollectUpdatableFeatures(t *testing.T) {
 		t.Fatalf("InitializeShellDirectory() error = %v", err)
 	}
 
-	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, false, nil); err != nil {
+	if err := shell.AddFeatureToShell(repoPath, "powershell", "powershell-aliases", "", nil, nil, false, nil); err != nil {
 		t.Fatalf("AddFeatureToShell() error = %v", err)
 	}
 
-	if err := shell.AddFeatureToShell(repoPath, "powershell", "custom-local-feature", "", nil, false, nil); err != nil {
+	if err := shell.AddFeatureToShell(repoPath, "powershell", "custom-local-feature", "", nil, nil, false, nil); err != nil {
 		t.Fatalf("AddFeatureToShell() custom feature error = %v", err)
 	}
 
@@ -270,3 +273,62 @@ func TestCollectUpdatableFeatures(t *testing.T) {
 		t.Fatalf("collectUpdatableFeatures() should not include custom-local-feature")
 	}
 }
+
+func TestFeatureMoveLocalDoesNotCommitInitScript(t *testing.T) {
+	repo, err := testutil.SetupTestRepo(t)
+	if err != nil {
+		t.Fatalf("SetupTestRepo() error = %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	if err := shell.InitializeShellDirectory(repoPath, "bash"); err != nil {
+		t.Fatalf("InitializeShellDirectory() error = %v", err)
+	}
+	for _, name := range []string{"first-feature", "second-feature"} {
+		if err := shell.AddFeatureToShell(repoPath, "bash", name, "", nil, nil, false, nil); err != nil {
+			t.Fatalf("AddFeatureToShell(%s) error = %v", name, err)
+		}
+	}
+	if _, err := git.StageAndCommitShellFeatureChanges("Added features"); err != nil {
+		t.Fatalf("StageAndCommitShellFeatureChanges() error = %v", err)
+	}
+
+	state := &commandState{
+		aliasProvider:    func() string { return "oh-my-dot" },
+		repoPathProvider: func() string { return repoPath },
+		flagAfter:        "second-feature",
+		flagLocal:        true,
+	}
+	if err := state.runFeatureMove(nil, []string{"first-feature"}); err != nil {
+		t.Fatalf("runFeatureMove() error = %v", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+	file, err := commit.File("omd-shells/bash/init.sh")
+	if err != nil {
+		t.Fatalf("committed init script: %v", err)
+	}
+	committed, err := file.Contents()
+	if err != nil {
+		t.Fatalf("Contents() error = %v", err)
+	}
+	if strings.Index(committed, "first-feature") > strings.Index(committed, "second-feature") {
+		t.Fatalf("committed init script should keep the shared order:\n%s", committed)
+	}
+
+	initPath, _ := shell.GetInitScriptPath(repoPath, "bash")
+	local, err := os.ReadFile(initPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Index(string(local), "first-feature") < strings.Index(string(local), "second-feature") {
+		t.Fatalf("regenerated init script should use the local order:\n%s", local)
+	}
+}
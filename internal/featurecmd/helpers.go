@@ -20,6 +20,9 @@ func isInteractive() bool {
 
 func autoCommitShellFeatureChanges(commitMessage string) error {
 	committed, err := git.StageAndCommitShellFeatureChanges(commitMessage)
+	if git.DisplaySecretsError(err, isInteractive()) {
+		return err
+	}
 	if err != nil {
 		return err
 	}
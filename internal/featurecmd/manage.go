@@ -7,6 +7,7 @@ import (
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
 	"github.com/spf13/cobra"
@@ -83,6 +84,9 @@ func (state *commandState) runFeatureList(cmd *cobra.Command, args []string) err
 			if len(feature.OnCommand) > 0 {
 				fmt.Printf(": %v", feature.OnCommand)
 			}
+			if len(feature.OnDirectory) > 0 {
+				fmt.Printf(": %v", feature.OnDirectory)
+			}
 			fmt.Println(")")
 
 			featurePath, err := shell.GetFeatureFilePath(repoPath, shellName, feature.Name)
@@ -114,8 +118,8 @@ func (state *commandState) runFeatureEnable(cmd *cobra.Command, args []string) e
 	}
 
 	for _, shellName := range targetShells {
-		if state.flagStrategy != "" || len(state.flagOnCommand) > 0 {
-			if err := shell.EnableFeatureWithOptions(repoPath, shellName, featureName, state.flagStrategy, state.flagOnCommand); err != nil {
+		if state.flagStrategy != "" || len(state.flagOnCommand) > 0 || len(state.flagOnDirectory) > 0 {
+			if err := shell.EnableFeatureWithOptions(repoPath, shellName, featureName, state.flagStrategy, state.flagOnCommand, state.flagOnDirectory); err != nil {
 				return fmt.Errorf("failed to enable feature in %s: %w", shellName, err)
 			}
 		} else {
@@ -126,7 +130,7 @@ func (state *commandState) runFeatureEnable(cmd *cobra.Command, args []string) e
 		fileops.ColorPrintfn(fileops.Green, "Enabled %s in %s", featureName, shellName)
 	}
 
-	if err := autoCommitShellFeatureChanges("Enable shell feature: " + featureName); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureEnable, git.MessageData{Name: featureName, Shell: strings.Join(targetShells, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -163,7 +167,7 @@ func (state *commandState) runFeatureDisable(cmd *cobra.Command, args []string)
 		fileops.ColorPrintfn(fileops.Yellow, "Disabled %s in %s", featureName, shellName)
 	}
 
-	if err := autoCommitShellFeatureChanges("Disable shell feature: " + featureName); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureDisable, git.MessageData{Name: featureName, Shell: strings.Join(targetShells, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -189,6 +193,9 @@ func (state *commandState) runFeatureInfo(cmd *cobra.Command, args []string) err
 	if len(metadata.DefaultCommands) > 0 {
 		fmt.Printf("  Default Commands: %v\n", metadata.DefaultCommands)
 	}
+	if len(metadata.DefaultMarkers) > 0 {
+		fmt.Printf("  Default Markers: %v\n", metadata.DefaultMarkers)
+	}
 
 	fmt.Printf("  Supported Shells: %v\n", metadata.SupportedShells)
 	fmt.Println("\nCurrent Configuration:")
@@ -222,6 +229,9 @@ func (state *commandState) runFeatureInfo(cmd *cobra.Command, args []string) err
 				if len(feature.OnCommand) > 0 {
 					fmt.Printf(": %v", feature.OnCommand)
 				}
+				if len(feature.OnDirectory) > 0 {
+					fmt.Printf(": %v", feature.OnDirectory)
+				}
 				fmt.Print(")")
 			}
 
@@ -0,0 +1,193 @@
+package featurecmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/spf13/cobra"
+)
+
+func (state *commandState) runFeatureMove(cmd *cobra.Command, args []string) error {
+	repoPath := state.repoPathProvider()
+	alias := state.aliasProvider()
+
+	if state.flagInteractive || len(args) == 0 {
+		if !isInteractive() {
+			return fmt.Errorf("feature name required (or use -i in a terminal)\n\nExamples:\n  %s feature move oh-my-posh --after homebrew-path\n  %s feature move -i --shell zsh", alias, alias)
+		}
+		return state.runInteractiveFeatureMove(repoPath)
+	}
+
+	featureName := args[0]
+	if (state.flagBefore == "") == (state.flagAfter == "") {
+		return fmt.Errorf("specify where to move '%s' with either --before or --after", featureName)
+	}
+	other, after := state.flagBefore, false
+	if state.flagAfter != "" {
+		other, after = state.flagAfter, true
+	}
+
+	targetShells, err := state.shellsWithFeature(repoPath, featureName)
+	if err != nil {
+		return err
+	}
+	if len(targetShells) == 0 {
+		return fmt.Errorf("feature '%s' is not installed in any shell", featureName)
+	}
+
+	var movedShells []string
+	for _, shellName := range targetShells {
+		names, err := featureOrder(repoPath, shellName, state.flagLocal)
+		if err != nil {
+			return err
+		}
+		if len(targetShells) > 1 && !slices.Contains(names, other) {
+			fileops.ColorPrintfn(fileops.Yellow, "Skipping %s: '%s' is not installed there", shellName, other)
+			continue
+		}
+
+		moved, err := manifest.MoveInOrder(names, featureName, other, after)
+		if err != nil {
+			return fmt.Errorf("failed to move feature in %s: %w", shellName, err)
+		}
+		if err := shell.SetFeatureOrder(repoPath, shellName, moved, state.flagLocal); err != nil {
+			return fmt.Errorf("failed to move feature in %s: %w", shellName, err)
+		}
+
+		position := "before"
+		if after {
+			position = "after"
+		}
+		fileops.ColorPrintfn(fileops.Green, "Moved %s %s %s in %s", featureName, position, other, shellName)
+		warnIfDependenciesOverride(repoPath, shellName, featureName, other, after)
+		movedShells = append(movedShells, shellName)
+	}
+
+	if len(movedShells) == 0 {
+		return fmt.Errorf("'%s' is not installed in any shell with '%s'", other, featureName)
+	}
+	return state.commitFeatureOrder(featureName, movedShells)
+}
+
+func (state *commandState) runInteractiveFeatureMove(repoPath string) error {
+	shellName, err := state.selectShellToReorder(repoPath)
+	if err != nil {
+		return err
+	}
+
+	names, err := featureOrder(repoPath, shellName, state.flagLocal)
+	if err != nil {
+		return err
+	}
+	if len(names) < 2 {
+		fileops.ColorPrintfn(fileops.Yellow, "%s has fewer than two features; nothing to reorder", shellName)
+		return nil
+	}
+
+	reordered, err := interactive.PromptReorder(fmt.Sprintf("Order of %s features (top loads first):", shellName), names)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for i, name := range reordered {
+		if names[i] != name {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		fileops.ColorPrintln("Order unchanged", fileops.Yellow)
+		return nil
+	}
+
+	if err := shell.SetFeatureOrder(repoPath, shellName, reordered, state.flagLocal); err != nil {
+		return fmt.Errorf("failed to reorder features in %s: %w", shellName, err)
+	}
+	fileops.ColorPrintfn(fileops.Green, "Reordered %s features", shellName)
+
+	return state.commitFeatureOrder(strings.Join(changed, ", "), []string{shellName})
+}
+
+// selectShellToReorder returns the shell given with --shell, or asks which shell to reorder.
+func (state *commandState) selectShellToReorder(repoPath string) (string, error) {
+	if len(state.flagShell) > 0 {
+		return state.flagShell[0], nil
+	}
+
+	shells, err := shell.ListShellsWithFeatures(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to list shells: %w", err)
+	}
+	switch len(shells) {
+	case 0:
+		return "", fmt.Errorf("no shells have been initialized")
+	case 1:
+		return shells[0], nil
+	}
+
+	index, err := interactive.PromptSelect("Reorder the features of which shell?", shells)
+	if err != nil {
+		return "", err
+	}
+	return shells[index], nil
+}
+
+// featureOrder returns the feature order a move starts from: this machine's order, including
+// local overrides, or the shared order in enabled.json.
+func featureOrder(repoPath, shellName string, local bool) ([]string, error) {
+	if local {
+		names, err := shell.FeatureOrder(repoPath, shellName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s features: %w", shellName, err)
+		}
+		return names, nil
+	}
+
+	m, err := manifest.ParseManifest(shell.GetManifestPath(repoPath, shellName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s features: %w", shellName, err)
+	}
+	names := make([]string, len(m.Features))
+	for i, f := range m.Features {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// warnIfDependenciesOverride tells the user when requires or after keep the init script from
+// loading featureName where it was moved to.
+func warnIfDependenciesOverride(repoPath, shellName, featureName, other string, after bool) {
+	loadOrder, err := shell.LoadOrder(repoPath, shellName)
+	if err != nil {
+		return
+	}
+
+	featureAt, otherAt := slices.Index(loadOrder, featureName), slices.Index(loadOrder, other)
+	if featureAt == -1 || otherAt == -1 || (featureAt > otherAt) == after {
+		return
+	}
+
+	position := "after"
+	if featureAt < otherAt {
+		position = "before"
+	}
+	fileops.ColorPrintfn(fileops.Yellow, "%s still loads %s %s in %s because of its strategy or dependencies", featureName, position, other, shellName)
+}
+
+func (state *commandState) commitFeatureOrder(name string, shells []string) error {
+	// A local order also ends up in the regenerated init script, which must not be committed
+	if state.flagLocal {
+		fileops.ColorPrintln("The order is kept in enabled.local.json for this machine only, so nothing is committed.", fileops.Cyan)
+	} else if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureMove, git.MessageData{Name: name, Shell: strings.Join(shells, ", ")})); err != nil {
+		return fmt.Errorf("failed to commit shell feature changes: %w", err)
+	}
+
+	fileops.ColorPrintfn(fileops.Cyan, "\nRun '%s apply' to activate changes", state.aliasProvider())
+	return nil
+}
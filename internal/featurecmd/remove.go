@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
@@ -109,7 +110,7 @@ func (state *commandState) runFeatureRemove(cmd *cobra.Command, args []string) e
 		}
 	}
 
-	if err := autoCommitShellFeatureChanges("Remove shell feature: " + featureName); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureRemove, git.MessageData{Name: featureName, Shell: strings.Join(targetShells, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -255,7 +256,7 @@ func (state *commandState) runInteractiveFeatureRemove(repoPath string) error {
 
 	fmt.Println()
 	if removedCount > 0 {
-		if err := autoCommitShellFeatureChanges("Remove shell features (interactive)"); err != nil {
+		if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureRemove, git.MessageData{Name: strings.Join(selectedFeatures, ", "), Shell: strings.Join(selectedShells, ", ")})); err != nil {
 			return fmt.Errorf("failed to commit shell feature changes: %w", err)
 		}
 
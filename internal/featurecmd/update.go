@@ -7,6 +7,7 @@ import (
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
@@ -50,7 +51,7 @@ func (state *commandState) runFeatureUpdate(cmd *cobra.Command, args []string) e
 		fileops.ColorPrintfn(fileops.Green, "  ✓ Feature file updated")
 	}
 
-	if err := autoCommitShellFeatureChanges("Refresh shell feature: " + featureName); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureRefresh, git.MessageData{Name: featureName, Shell: strings.Join(targetShells, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -131,7 +132,7 @@ func (state *commandState) runInteractiveFeatureUpdate(repoPath string) error {
 		}
 	}
 
-	if err := autoCommitShellFeatureChanges("Refresh shell features (interactive)"); err != nil {
+	if err := autoCommitShellFeatureChanges(git.CommitMessage(git.OpFeatureRefresh, git.MessageData{Name: strings.Join(selectedFeatureNames, ", ")})); err != nil {
 		return fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
@@ -0,0 +1,504 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// defaultRemoteName is the remote used when a branch has no upstream configured.
+const defaultRemoteName = "origin"
+
+// ErrNothingToPromote is returned when the target branch already contains the promoted changes.
+var ErrNothingToPromote = errors.New("target branch already contains these changes")
+
+// BranchInfo describes a local branch and the upstream it syncs with.
+type BranchInfo struct {
+	Name     string
+	Current  bool
+	Remote   string
+	Upstream string
+	Rebase   bool
+}
+
+// openRepo opens the repository at the configured repo-path.
+func openRepo() (*git.Repository, error) {
+	repoPath := viper.GetString("repo-path")
+	if repoPath == "" {
+		return nil, fmt.Errorf("repository path is not set")
+	}
+
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	return r, nil
+}
+
+// headBranchName returns the branch HEAD points to, even when the branch has no commits yet.
+func headBranchName(r *git.Repository) (plumbing.ReferenceName, error) {
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target(), nil
+	}
+
+	return "", fmt.Errorf("HEAD is detached")
+}
+
+// trackedBranch returns the remote and remote branch that a local branch syncs with.
+// Branches without upstream configuration track the same-named branch on the pull remote.
+// A configured pull remote takes precedence over the remote in the branch's upstream.
+func trackedBranch(r *git.Repository, branch plumbing.ReferenceName) (string, plumbing.ReferenceName, bool) {
+	cfg, err := r.Config()
+	if err == nil {
+		if b, ok := cfg.Branches[branch.Short()]; ok && b.Merge != "" {
+			remote := b.Remote
+			if remote == "" || viper.GetString(PullRemoteKey) != "" {
+				remote = pullRemote()
+			}
+			return remote, b.Merge, b.Rebase == "true"
+		}
+	}
+
+	return pullRemote(), branch, false
+}
+
+// remoteTrackingRef returns the local remote-tracking reference for a remote branch.
+func remoteTrackingRef(remote string, branch plumbing.ReferenceName) plumbing.ReferenceName {
+	return plumbing.NewRemoteReferenceName(remote, branch.Short())
+}
+
+// CurrentBranch returns the name of the checked out branch.
+func CurrentBranch() (string, error) {
+	r, err := openRepo()
+	if err != nil {
+		return "", err
+	}
+
+	name, err := headBranchName(r)
+	if err != nil {
+		return "", err
+	}
+
+	return name.Short(), nil
+}
+
+// ListBranches returns the local branches sorted by name, with their upstream configuration.
+func ListBranches() ([]BranchInfo, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := headBranchName(r)
+
+	refs, err := r.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	names := map[plumbing.ReferenceName]struct{}{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		names[ref.Name()] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	// An unborn branch has no ref yet but is still the branch the user is on.
+	if current != "" {
+		names[current] = struct{}{}
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git config: %w", err)
+	}
+
+	branches := make([]BranchInfo, 0, len(names))
+	for name := range names {
+		info := BranchInfo{Name: name.Short(), Current: name == current}
+		if b, ok := cfg.Branches[name.Short()]; ok && b.Merge != "" {
+			info.Remote = b.Remote
+			info.Upstream = b.Merge.Short()
+			info.Rebase = b.Rebase == "true"
+		}
+		branches = append(branches, info)
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Name < branches[j].Name
+	})
+
+	return branches, nil
+}
+
+// CreateBranch creates a local branch that syncs with upstream.
+// The branch starts from its own remote counterpart when one exists (for example
+// when setting up a machine whose branch was already pushed), otherwise from the
+// upstream branch, otherwise from HEAD. When rebase is true, pulls replay the
+// branch's commits on top of the upstream instead of merging.
+func CreateBranch(name, upstream string, rebase bool) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewBranchReferenceName(name)
+	if err := refName.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+
+	if _, err := r.Reference(refName, false); err == nil {
+		return fmt.Errorf("branch %s already exists", name)
+	}
+
+	start, err := branchStartPoint(r, name, upstream)
+	if err != nil {
+		return err
+	}
+
+	if !start.IsZero() {
+		if err := r.Storer.SetReference(plumbing.NewHashReference(refName, start)); err != nil {
+			return fmt.Errorf("failed to create branch %s: %w", name, err)
+		}
+	}
+
+	if upstream == "" {
+		return nil
+	}
+
+	return setBranchUpstream(r, name, pullRemote(), upstream, rebase)
+}
+
+func branchStartPoint(r *git.Repository, name, upstream string) (plumbing.Hash, error) {
+	candidates := []plumbing.ReferenceName{
+		remoteTrackingRef(pullRemote(), plumbing.NewBranchReferenceName(name)),
+	}
+	if upstream != "" {
+		candidates = append(candidates,
+			remoteTrackingRef(pullRemote(), plumbing.NewBranchReferenceName(upstream)),
+			plumbing.NewBranchReferenceName(upstream),
+		)
+	}
+
+	for _, candidate := range candidates {
+		if ref, err := r.Reference(candidate, true); err == nil {
+			return ref.Hash(), nil
+		}
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			// Nothing has been committed yet, so the branch is created on first commit.
+			return plumbing.ZeroHash, nil
+		}
+		return plumbing.ZeroHash, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	return head.Hash(), nil
+}
+
+// SetBranchUpstream configures which branch on the pull remote a local branch pulls from.
+func SetBranchUpstream(name, upstream string, rebase bool) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	return setBranchUpstream(r, name, pullRemote(), upstream, rebase)
+}
+
+func setBranchUpstream(r *git.Repository, name, remote, upstream string, rebase bool) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read git config: %w", err)
+	}
+
+	branch := &config.Branch{
+		Name:   name,
+		Remote: remote,
+		Merge:  plumbing.NewBranchReferenceName(upstream),
+	}
+	if rebase {
+		branch.Rebase = "true"
+	}
+
+	if existing, ok := cfg.Branches[name]; ok {
+		branch.Description = existing.Description
+	}
+	cfg.Branches[name] = branch
+
+	if err := r.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save upstream for branch %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// SwitchBranch checks out an existing local branch.
+// Tracked changes must be committed first; untracked files are left alone.
+func SwitchBranch(name string) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewBranchReferenceName(name)
+	if current, err := headBranchName(r); err == nil && current == refName {
+		return nil
+	}
+
+	if _, err := r.Reference(refName, false); err != nil {
+		if _, headErr := r.Head(); errors.Is(headErr, plumbing.ErrReferenceNotFound) {
+			// Nothing committed yet: just point HEAD at the new branch.
+			if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName)); err != nil {
+				return fmt.Errorf("failed to switch to branch %s: %w", name, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("branch %s does not exist", name)
+	}
+
+	worktree, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	dirty, err := hasTrackedChanges(worktree)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("cannot switch branches with uncommitted changes; commit or discard them first")
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: refName}); err != nil {
+		return fmt.Errorf("failed to switch to branch %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// CheckoutTrackingBranch switches to the named branch, creating it first when needed.
+// A newly created branch tracks the branch that was checked out before, rebasing on pull.
+func CheckoutTrackingBranch(name string) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	current, err := headBranchName(r)
+	if err != nil {
+		return err
+	}
+
+	if current.Short() == name {
+		return nil
+	}
+
+	if _, err := r.Reference(plumbing.NewBranchReferenceName(name), false); err != nil {
+		if err := CreateBranch(name, current.Short(), true); err != nil {
+			return err
+		}
+	}
+
+	return SwitchBranch(name)
+}
+
+func hasTrackedChanges(worktree *git.Worktree) (bool, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+
+	for _, fileStatus := range status {
+		if fileStatus.Worktree == git.Untracked {
+			continue
+		}
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PromoteCommit cherry-picks a commit onto the target branch without touching the worktree.
+// It is used to share a change made on a machine branch with every machine.
+// The target branch is created from origin when it only exists there.
+// Returns the hash of the new commit on target.
+func PromoteCommit(revision, target string) (plumbing.Hash, error) {
+	r, err := openRepo()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	targetRef := plumbing.NewBranchReferenceName(target)
+	if current, err := headBranchName(r); err == nil && current == targetRef {
+		return plumbing.ZeroHash, fmt.Errorf("already on %s; switch to the machine branch to promote from it", target)
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve %s: %w", revision, err)
+	}
+
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	if commit.NumParents() > 1 {
+		return plumbing.ZeroHash, fmt.Errorf("cannot promote merge commit %s", commit.Hash.String()[:7])
+	}
+
+	tip, err := r.Reference(targetRef, true)
+	if err != nil {
+		tip, err = r.Reference(remoteTrackingRef(pullRemote(), targetRef), true)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("branch %s does not exist locally or on %s", target, pullRemote())
+		}
+	}
+
+	onto, err := r.CommitObject(tip.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read commit %s: %w", tip.Hash(), err)
+	}
+
+	tree, err := applyCommitOnto(r, commit, onto, nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if tree == onto.TreeHash {
+		return plumbing.ZeroHash, ErrNothingToPromote
+	}
+
+	// Like git cherry-pick -x, the note joins the trailers, so the commit keeps its Machine trailer
+	// instead of getting a second one
+	message := strings.TrimRight(commit.Message, "\n") + "\n\n"
+	if CommitTrailer(commit.Message, MachineTrailer) != "" {
+		message = strings.TrimRight(message, "\n") + "\n"
+	}
+	message += fmt.Sprintf("(cherry picked from commit %s)\n", commit.Hash)
+	newHash, err := createCommit(r, tree, []plumbing.Hash{onto.Hash}, commit.Author, message)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(targetRef, newHash)); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to update branch %s: %w", target, err)
+	}
+
+	return newHash, nil
+}
+
+// rebaseOnto replays the first-parent commits of branch that are not in upstream on top of upstream.
+// Commits whose changes upstream already contains (for example after a promote) are dropped.
+// Conflicts are passed to resolve. Returns the new tip of the branch.
+func rebaseOnto(r *git.Repository, branchTip, upstream *object.Commit, resolve ConflictResolver) (plumbing.Hash, error) {
+	bases, err := branchTip.MergeBase(upstream)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to find merge base: %w", err)
+	}
+
+	var base plumbing.Hash
+	if len(bases) > 0 {
+		base = bases[0].Hash
+	}
+
+	// Collect local commits back to the merge base, newest first.
+	var local []*object.Commit
+	for current := branchTip; current.Hash != base; {
+		local = append(local, current)
+		if current.NumParents() == 0 {
+			break
+		}
+		parent, err := current.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read parent of %s: %w", current.Hash, err)
+		}
+		current = parent
+	}
+
+	onto := upstream
+	for i := len(local) - 1; i >= 0; i-- {
+		commit := local[i]
+		if commit.NumParents() > 1 {
+			continue
+		}
+
+		tree, err := applyCommitOnto(r, commit, onto, resolve)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if tree == onto.TreeHash {
+			continue
+		}
+
+		hash, err := createCommit(r, tree, []plumbing.Hash{onto.Hash}, commit.Author, commit.Message)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		onto, err = r.CommitObject(hash)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+	}
+
+	return onto.Hash, nil
+}
+
+// PushBranch pushes a local branch to the same-named branch on every push remote.
+func PushBranch(name string) ([]PushResult, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	return pushToRemotes(r, plumbing.NewBranchReferenceName(name), nil)
+}
+
+func pushBranch(r *git.Repository, remoteName string, branch plumbing.ReferenceName, withLease bool) error {
+	remote, err := r.Remote(remoteName)
+	if err != nil {
+		return err
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", branch, branch))
+	options := &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{refSpec},
+	}
+	if withLease {
+		// Rebased machine branches need a forced update, guarded by the last fetched remote state.
+		options.RefSpecs = []config.RefSpec{"+" + refSpec}
+		options.ForceWithLease = &git.ForceWithLease{}
+	}
+
+	ctx, cancel, err := network.Context(context.Background(), network.Transfer)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	return network.Err(ctx, network.Transfer, remote.PushContext(ctx, options))
+}
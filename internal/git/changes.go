@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangedFiles returns the repository paths that differ between two commits, sorted.
+// Added, modified and deleted files are all included. A zero from hash compares against an empty tree.
+func ChangedFiles(from, to plumbing.Hash) ([]string, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	fromFiles := treeFiles{}
+	if !from.IsZero() {
+		commit, err := r.CommitObject(from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %s: %w", from, err)
+		}
+		if fromFiles, err = commitFiles(commit); err != nil {
+			return nil, err
+		}
+	}
+
+	commit, err := r.CommitObject(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", to, err)
+	}
+	toFiles, err := commitFiles(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for p, entry := range toFiles {
+		previous, ok := fromFiles[p]
+		if !sameEntry(previous, entry, ok, true) {
+			changed = append(changed, p)
+		}
+	}
+	for p := range fromFiles {
+		if _, ok := toFiles[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// FileAtCommit returns the content of a repository path at a commit.
+// The second return value is false when the file does not exist in that commit.
+func FileAtCommit(hash plumbing.Hash, path string) ([]byte, bool, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, false, err
+	}
+
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	file, err := commit.File(path)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read %s at %s: %w", path, hash, err)
+	}
+
+	content, err := file.Contents()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s at %s: %w", path, hash, err)
+	}
+
+	return []byte(content), true, nil
+}
@@ -0,0 +1,178 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// Config keys controlling the commits oh-my-dot creates.
+const (
+	CommitAuthorNameKey     = "commit.author-name"
+	CommitAuthorEmailKey    = "commit.author-email"
+	CommitMachineTrailerKey = "commit.machine-trailer"
+	CommitTemplatesKey      = "commit.templates"
+)
+
+// Operation names a kind of change oh-my-dot commits. Each has a configurable message template.
+type Operation string
+
+const (
+	OpInit            Operation = "init"
+	OpAdd             Operation = "add"
+	OpRemove          Operation = "remove"
+	OpRestore         Operation = "restore"
+	OpFeatureAdd      Operation = "feature-add"
+	OpFeatureRemove   Operation = "feature-remove"
+	OpFeatureEnable   Operation = "feature-enable"
+	OpFeatureDisable  Operation = "feature-disable"
+	OpFeatureRefresh  Operation = "feature-refresh"
+	OpFeatureMove     Operation = "feature-move"
+	OpFeatureBundle   Operation = "feature-bundle"
+	OpShellEnv        Operation = "shell-env"
+	OpAliasAdd        Operation = "alias-add"
+	OpAliasRemove     Operation = "alias-remove"
+	OpExternalsUpdate Operation = "externals-update"
+	OpSync            Operation = "sync"
+)
+
+// defaultTemplates are used for operations without a configured template.
+var defaultTemplates = map[Operation]string{
+	OpInit:            "Initialize dotfiles repository",
+	OpAdd:             "Added {{.Name}}",
+	OpRemove:          "Removed {{.Name}}",
+	OpRestore:         "Restore {{.Name}} to {{.Revision}}",
+	OpFeatureAdd:      "Add shell feature: {{.Name}}",
+	OpFeatureRemove:   "Remove shell feature: {{.Name}}",
+	OpFeatureEnable:   "Enable shell feature: {{.Name}}",
+	OpFeatureDisable:  "Disable shell feature: {{.Name}}",
+	OpFeatureRefresh:  "Refresh shell feature: {{.Name}}",
+	OpFeatureMove:     "Reorder shell features: {{.Name}}",
+	OpFeatureBundle:   "Turn shell feature bundling {{.Name}}: {{.Shell}}",
+	OpShellEnv:        "Update shell environment: {{.Shell}}",
+	OpAliasAdd:        "Add shell alias: {{.Name}}",
+	OpAliasRemove:     "Remove shell alias: {{.Name}}",
+	OpExternalsUpdate: "Update externals: {{.Name}}",
+	OpSync:            "Sync changes from {{.Machine}}: {{.Name}}",
+}
+
+// MessageData is available to commit message templates.
+type MessageData struct {
+	Name     string // File name (never the full path) feature name(s) or alias name
+	Shell    string // Shell(s) the change applies to, if any
+	Revision string // Short commit hash, for restores
+	Machine  string // Hostname of this machine
+}
+
+// CommitMessage renders the message template for an operation.
+// Templates use text/template syntax and are configured under commit.templates.<operation>;
+// an invalid template falls back to the default.
+func CommitMessage(op Operation, data MessageData) string {
+	if data.Machine == "" {
+		data.Machine = machineName()
+	}
+
+	if configured := viper.GetString(CommitTemplatesKey + "." + string(op)); configured != "" {
+		if message, err := renderMessage(configured, data); err == nil {
+			return message
+		}
+	}
+
+	message, err := renderMessage(defaultTemplates[op], data)
+	if err != nil || message == "" {
+		return string(op) + " " + data.Name
+	}
+	return message
+}
+
+func renderMessage(text string, data MessageData) (string, error) {
+	tmpl, err := template.New("commit").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// machineName returns the hostname recorded in Machine trailers.
+func machineName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return host
+}
+
+// withMachineTrailer appends a "Machine: <hostname>" trailer unless it is disabled
+// or the message already has one.
+func withMachineTrailer(message string) string {
+	if viper.IsSet(CommitMachineTrailerKey) && !viper.GetBool(CommitMachineTrailerKey) {
+		return message
+	}
+	if CommitTrailer(message, MachineTrailer) != "" {
+		return message
+	}
+
+	return strings.TrimRight(message, "\n") + "\n\n" + MachineTrailer + ": " + machineName() + "\n"
+}
+
+// configuredSignature returns the identity oh-my-dot commits as. The author configured in
+// oh-my-dot takes precedence over the repository and global git configuration. Without
+// either, commits are attributed to oh-my-dot on this machine instead of failing.
+func configuredSignature(r *git.Repository) (object.Signature, error) {
+	cfg, err := r.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return object.Signature{}, fmt.Errorf("failed to read git config: %w", err)
+	}
+
+	name := viper.GetString(CommitAuthorNameKey)
+	if name == "" {
+		name = cfg.User.Name
+	}
+	email := viper.GetString(CommitAuthorEmailKey)
+	if email == "" {
+		email = cfg.User.Email
+	}
+
+	if name == "" {
+		name = "oh-my-dot"
+	}
+	if email == "" {
+		email = "oh-my-dot@" + machineName()
+	}
+
+	return object.Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+// commitWorktree commits the staged changes as the configured identity, signed when signing is configured.
+// Every commit of the worktree goes through here, so staged content is scanned for secrets here too.
+func commitWorktree(r *git.Repository, worktree *git.Worktree, message string) error {
+	if err := scanStaged(r, worktree); err != nil {
+		return err
+	}
+
+	author, err := configuredSignature(r)
+	if err != nil {
+		return err
+	}
+
+	signer, err := commitSigner()
+	if err != nil {
+		return err
+	}
+
+	_, err = worktree.Commit(withMachineTrailer(message), &git.CommitOptions{Author: &author, Signer: signer})
+	return err
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCommitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		op       Operation
+		data     MessageData
+		want     string
+	}{
+		{
+			name: "default template",
+			op:   OpAdd,
+			data: MessageData{Name: ".zshrc"},
+			want: "Added .zshrc",
+		},
+		{
+			name:     "configured template",
+			template: "dotfiles: add {{.Name}} on {{.Machine}}",
+			op:       OpAdd,
+			data:     MessageData{Name: ".zshrc", Machine: "laptop"},
+			want:     "dotfiles: add .zshrc on laptop",
+		},
+		{
+			name:     "invalid template falls back to default",
+			template: "add {{.Missing}}",
+			op:       OpAdd,
+			data:     MessageData{Name: ".zshrc"},
+			want:     "Added .zshrc",
+		},
+		{
+			name: "feature template",
+			op:   OpFeatureEnable,
+			data: MessageData{Name: "git-prompt", Shell: "bash"},
+			want: "Enable shell feature: git-prompt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			if tt.template != "" {
+				viper.Set(CommitTemplatesKey+"."+string(tt.op), tt.template)
+			}
+
+			if got := CommitMessage(tt.op, tt.data); got != tt.want {
+				t.Errorf("CommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMachineTrailer(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	message := withMachineTrailer("Added .zshrc")
+	if got := CommitTrailer(message, MachineTrailer); got != machineName() {
+		t.Fatalf("Machine trailer = %q, want %q in:\n%s", got, machineName(), message)
+	}
+	if again := withMachineTrailer(message); again != message {
+		t.Fatalf("trailer added twice:\n%s", again)
+	}
+
+	viper.Set(CommitMachineTrailerKey, false)
+	if got := withMachineTrailer("Added .zshrc"); strings.Contains(got, MachineTrailer) {
+		t.Fatalf("trailer added while disabled:\n%s", got)
+	}
+}
@@ -0,0 +1,214 @@
+package git
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table used by diffLines. Larger files are not
+// merged line by line and are reported as a whole-file conflict instead.
+const maxDiffCells = 4_000_000
+
+// lineHunk is a changed region: base[baseStart:baseEnd] became other[otherStart:otherEnd].
+type lineHunk struct {
+	baseStart, baseEnd   int
+	otherStart, otherEnd int
+}
+
+// splitLines splits content into lines, keeping the line terminators.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the changed regions between base and other using a longest common subsequence.
+// The second return value is false when the inputs are too large to diff.
+func diffLines(base, other []string) ([]lineHunk, bool) {
+	// Trim the common prefix and suffix; dotfile edits are usually small and local.
+	prefix := 0
+	for prefix < len(base) && prefix < len(other) && base[prefix] == other[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(other)-prefix &&
+		base[len(base)-1-suffix] == other[len(other)-1-suffix] {
+		suffix++
+	}
+
+	a := base[prefix : len(base)-suffix]
+	b := other[prefix : len(other)-suffix]
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+	if (n+1)*(m+1) > maxDiffCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var hunks []lineHunk
+	var current *lineHunk
+	closeHunk := func(i, j int) {
+		if current != nil {
+			current.baseEnd, current.otherEnd = prefix+i, prefix+j
+			hunks = append(hunks, *current)
+			current = nil
+		}
+	}
+	openHunk := func(i, j int) {
+		if current == nil {
+			current = &lineHunk{baseStart: prefix + i, otherStart: prefix + j}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			closeHunk(i, j)
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			openHunk(i, j)
+			i++
+		default:
+			openHunk(i, j)
+			j++
+		}
+	}
+	if i < n || j < m {
+		openHunk(i, j)
+	}
+	closeHunk(n, m)
+
+	return hunks, true
+}
+
+// mergeLines performs a line based three-way merge of ours and theirs against base.
+// Regions changed on only one side are taken from that side. Regions changed on both
+// sides in different ways are written with git style conflict markers, and clean is false.
+func mergeLines(base, ours, theirs []byte, oursLabel, theirsLabel string) (merged []byte, clean bool) {
+	baseLines := splitLines(base)
+	oursLines := splitLines(ours)
+	theirsLines := splitLines(theirs)
+
+	oursHunks, okOurs := diffLines(baseLines, oursLines)
+	theirsHunks, okTheirs := diffLines(baseLines, theirsLines)
+	if !okOurs || !okTheirs {
+		var out bytes.Buffer
+		writeConflict(&out, oursLines, theirsLines, oursLabel, theirsLabel)
+		return out.Bytes(), false
+	}
+
+	type sidedHunk struct {
+		lineHunk
+		theirs bool
+	}
+	all := make([]sidedHunk, 0, len(oursHunks)+len(theirsHunks))
+	for _, h := range oursHunks {
+		all = append(all, sidedHunk{h, false})
+	}
+	for _, h := range theirsHunks {
+		all = append(all, sidedHunk{h, true})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].baseStart < all[j].baseStart
+	})
+
+	var out bytes.Buffer
+	clean = true
+	pos := 0
+	for k := 0; k < len(all); {
+		start, end := all[k].baseStart, all[k].baseEnd
+		var oursGroup, theirsGroup []lineHunk
+
+		// Group hunks that overlap or touch in base; they have to be resolved together.
+		for k < len(all) && all[k].baseStart <= end {
+			end = max(end, all[k].baseEnd)
+			if all[k].theirs {
+				theirsGroup = append(theirsGroup, all[k].lineHunk)
+			} else {
+				oursGroup = append(oursGroup, all[k].lineHunk)
+			}
+			k++
+		}
+
+		writeLines(&out, baseLines[pos:start])
+		pos = end
+
+		oursRegion := sideRegion(oursGroup, start, end, baseLines, oursLines)
+		theirsRegion := sideRegion(theirsGroup, start, end, baseLines, theirsLines)
+
+		switch {
+		case len(theirsGroup) == 0:
+			writeLines(&out, oursRegion)
+		case len(oursGroup) == 0:
+			writeLines(&out, theirsRegion)
+		case strings.Join(oursRegion, "") == strings.Join(theirsRegion, ""):
+			writeLines(&out, oursRegion)
+		default:
+			clean = false
+			writeConflict(&out, oursRegion, theirsRegion, oursLabel, theirsLabel)
+		}
+	}
+	writeLines(&out, baseLines[pos:])
+
+	return out.Bytes(), clean
+}
+
+// sideRegion returns the lines one side has in place of base[start:end].
+func sideRegion(hunks []lineHunk, start, end int, baseLines, sideLines []string) []string {
+	if len(hunks) == 0 {
+		return baseLines[start:end]
+	}
+
+	first, last := hunks[0], hunks[len(hunks)-1]
+	from := first.otherStart - (first.baseStart - start)
+	to := last.otherEnd + (end - last.baseEnd)
+	return sideLines[from:to]
+}
+
+func writeLines(out *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+func writeConflict(out *bytes.Buffer, ours, theirs []string, oursLabel, theirsLabel string) {
+	out.WriteString("<<<<<<< " + oursLabel + "\n")
+	writeTerminatedLines(out, ours)
+	out.WriteString("=======\n")
+	writeTerminatedLines(out, theirs)
+	out.WriteString(">>>>>>> " + theirsLabel + "\n")
+}
+
+// writeTerminatedLines writes lines and makes sure the block ends with a newline,
+// so a missing newline at end of file does not swallow the next conflict marker.
+func writeTerminatedLines(out *bytes.Buffer, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
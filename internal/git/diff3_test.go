@@ -0,0 +1,83 @@
+package git
+
+import "testing"
+
+func TestMergeLines(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      string
+		wantClean bool
+	}{
+		{
+			name:      "changes in separate regions",
+			ours:      "A\nb\nc\nd\ne\n",
+			theirs:    "a\nb\nc\nd\nE\n",
+			want:      "A\nb\nc\nd\nE\n",
+			wantClean: true,
+		},
+		{
+			name:      "same change on both sides",
+			ours:      "a\nB\nc\nd\ne\n",
+			theirs:    "a\nB\nc\nd\ne\n",
+			want:      "a\nB\nc\nd\ne\n",
+			wantClean: true,
+		},
+		{
+			name:      "insertion and deletion",
+			ours:      "a\nb\nnew\nc\nd\ne\n",
+			theirs:    "a\nb\nc\ne\n",
+			want:      "a\nb\nnew\nc\ne\n",
+			wantClean: true,
+		},
+		{
+			name:      "conflicting change",
+			ours:      "a\nb\nours\nd\ne\n",
+			theirs:    "a\nb\ntheirs\nd\ne\n",
+			want:      "a\nb\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nd\ne\n",
+			wantClean: false,
+		},
+		{
+			name:      "conflict without trailing newline",
+			ours:      "a\nb\nc\nd\nours",
+			theirs:    "a\nb\nc\nd\ntheirs",
+			want:      "a\nb\nc\nd\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n",
+			wantClean: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clean := mergeLines([]byte(base), []byte(tt.ours), []byte(tt.theirs), "ours", "theirs")
+			if clean != tt.wantClean {
+				t.Fatalf("clean = %v, want %v", clean, tt.wantClean)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("merged =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLinkingsVersions(t *testing.T) {
+	base := []byte(`{"a": "~/.a"}`)
+	ours := []byte(`{"a": "~/.a", "b": "~/.b"}`)
+	theirs := []byte(`{"c": "~/.c"}`)
+
+	got, err := mergeLinkingsVersions(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("mergeLinkingsVersions error: %v", err)
+	}
+
+	want := "{\n  \"b\": \"~/.b\",\n  \"c\": \"~/.c\"\n}"
+	if string(got) != want {
+		t.Fatalf("merged = %s, want %s", got, want)
+	}
+
+	if _, err := mergeLinkingsVersions(base, []byte(`{"a": "~/.x"}`), []byte(`{"a": "~/.y"}`)); err == nil {
+		t.Fatal("expected conflict when both sides relink the same file")
+	}
+}
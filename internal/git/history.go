@@ -0,0 +1,214 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// MachineTrailer is the commit message trailer recording which machine made a commit.
+const MachineTrailer = "Machine"
+
+// HistoryFilter selects which changes a commit must touch to be part of a history.
+type HistoryFilter struct {
+	Paths    []string // Exact repository paths, e.g. "files/.zshrc"
+	Prefixes []string // Directory prefixes, e.g. "omd-shells/zsh/"
+	Linking  string   // Also match commits that change this entry in linkings.json
+}
+
+// HistoryEntry is a commit in a filtered history.
+type HistoryEntry struct {
+	Hash    plumbing.Hash
+	When    time.Time
+	Author  string
+	Machine string // Empty when the commit has no Machine trailer
+	Message string
+	Paths   []string // Matching paths changed by the commit
+	Patch   string   // Unified diff of the matching paths, when requested
+}
+
+// Subject returns the first line of the commit message.
+func (e HistoryEntry) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(e.Message), "\n")
+	return subject
+}
+
+func (f HistoryFilter) matchesPath(p string) bool {
+	if slices.Contains(f.Paths, p) {
+		return true
+	}
+	for _, prefix := range f.Prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// History walks the history of the current branch, newest first, and returns the
+// commits whose changes match filter. Each commit is compared with its first parent.
+// A limit of zero or less returns every matching commit.
+func History(filter HistoryFilter, limit int, withPatch bool) ([]HistoryEntry, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	commits, err := r.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer commits.Close()
+
+	var entries []HistoryEntry
+	for {
+		commit, err := commits.Next()
+		if errors.Is(err, io.EOF) || isShallowBoundary(r, err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+
+		entry, matched, err := historyEntry(commit, filter, withPatch)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+func historyEntry(commit *object.Commit, filter HistoryFilter, withPatch bool) (HistoryEntry, bool, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		switch {
+		case errors.Is(err, plumbing.ErrObjectNotFound):
+			// Beyond a shallow clone's boundary; like git, show the commit as adding its whole tree.
+		case err != nil:
+			return HistoryEntry{}, false, fmt.Errorf("failed to read parent of %s: %w", commit.Hash, err)
+		default:
+			if parentTree, err = parent.Tree(); err != nil {
+				return HistoryEntry{}, false, fmt.Errorf("failed to read tree of %s: %w", parent.Hash, err)
+			}
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("failed to diff %s: %w", commit.Hash, err)
+	}
+
+	var matched object.Changes
+	var paths []string
+	for _, change := range changes {
+		p := change.To.Name
+		if p == "" {
+			p = change.From.Name
+		}
+
+		include := filter.matchesPath(p)
+		if !include && p == "linkings.json" && filter.Linking != "" {
+			include = linkingChanged(parentTree, tree, filter.Linking)
+		}
+		if include {
+			matched = append(matched, change)
+			paths = append(paths, p)
+		}
+	}
+
+	if len(matched) == 0 {
+		return HistoryEntry{}, false, nil
+	}
+
+	entry := HistoryEntry{
+		Hash:    commit.Hash,
+		When:    commit.Author.When,
+		Author:  commit.Author.Name,
+		Machine: CommitTrailer(commit.Message, MachineTrailer),
+		Message: commit.Message,
+		Paths:   paths,
+	}
+
+	if withPatch {
+		patch, err := matched.Patch()
+		if err != nil {
+			return HistoryEntry{}, false, fmt.Errorf("failed to build patch for %s: %w", commit.Hash, err)
+		}
+		entry.Patch = patch.String()
+	}
+
+	return entry, true, nil
+}
+
+// linkingChanged reports whether a single linkings.json entry differs between two trees.
+func linkingChanged(from, to *object.Tree, name string) bool {
+	return linkingTarget(from, name) != linkingTarget(to, name)
+}
+
+func linkingTarget(tree *object.Tree, name string) string {
+	if tree == nil {
+		return ""
+	}
+
+	file, err := tree.File("linkings.json")
+	if err != nil {
+		return ""
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return ""
+	}
+
+	links := map[string]string{}
+	if err := json.Unmarshal([]byte(content), &links); err != nil {
+		return ""
+	}
+	return links[name]
+}
+
+// CommitTrailer returns the value of a "Key: value" trailer in the last paragraph of a commit message.
+func CommitTrailer(message, key string) string {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return ""
+	}
+
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package git
+
+import "testing"
+
+func TestCommitTrailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{name: "trailer present", message: "Added .zshrc\n\nMachine: work-laptop\n", want: "work-laptop"},
+		{name: "among other trailers", message: "Added .zshrc\n\nSome details.\n\nSigned-off-by: A <a@b>\nMachine: desktop", want: "desktop"},
+		{name: "subject only", message: "Machine: not a trailer", want: ""},
+		{name: "not in last paragraph", message: "Added\n\nMachine: old\n\nMore text", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommitTrailer(tt.message, MachineTrailer); got != tt.want {
+				t.Fatalf("CommitTrailer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
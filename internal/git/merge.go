@@ -0,0 +1,297 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ConflictChoice is how a single conflicting file was resolved.
+type ConflictChoice string
+
+const (
+	// ChooseOurs keeps the local version of the file.
+	ChooseOurs ConflictChoice = "ours"
+	// ChooseTheirs takes the incoming remote version of the file.
+	ChooseTheirs ConflictChoice = "theirs"
+	// ChooseEdited uses the content supplied in the resolution.
+	ChooseEdited ConflictChoice = "edited"
+)
+
+// Conflict describes a file that both sides changed in ways that could not be merged automatically.
+// Ours is the local version and Theirs the remote version; a nil side means the file was deleted there.
+type Conflict struct {
+	Path   string
+	Base   []byte
+	Ours   []byte
+	Theirs []byte
+	// Merged holds the file with conflict markers around the regions that could not be merged.
+	Merged []byte
+}
+
+// Resolution is the outcome chosen for a Conflict.
+type Resolution struct {
+	Choice  ConflictChoice
+	Content []byte // Used when Choice is ChooseEdited
+}
+
+// ConflictResolver decides how to resolve a conflicting file.
+// Returning an error aborts the pull without changing the branch.
+type ConflictResolver func(Conflict) (Resolution, error)
+
+// ResolveWith returns a resolver that always picks the same side.
+func ResolveWith(choice ConflictChoice) ConflictResolver {
+	return func(Conflict) (Resolution, error) {
+		return Resolution{Choice: choice}, nil
+	}
+}
+
+// ParseConflictStrategy validates a conflict strategy name given on the command line.
+func ParseConflictStrategy(strategy string) (ConflictChoice, error) {
+	switch ConflictChoice(strategy) {
+	case ChooseOurs, ChooseTheirs:
+		return ConflictChoice(strategy), nil
+	default:
+		return "", fmt.Errorf("invalid strategy '%s': must be 'ours' or 'theirs'", strategy)
+	}
+}
+
+// structuredMergers merge JSON files by their structure instead of by lines,
+// keyed by the base name of the file.
+var structuredMergers = map[string]func(base, ours, theirs []byte) ([]byte, error){
+	"enabled.json":  manifest.MergeManifestVersions,
+	"linkings.json": mergeLinkingsVersions,
+}
+
+// mergeTrees merges the ours and theirs file sets against base.
+// Files changed on both sides are merged structurally (JSON manifests) or by lines,
+// and anything left is passed to resolve. Without a resolver, or when the resolver
+// leaves files unresolved, a *ConflictError naming the files is returned.
+func mergeTrees(r *git.Repository, base, ours, theirs treeFiles, resolve ConflictResolver, commit plumbing.Hash) (treeFiles, error) {
+	merged, conflicts := mergeTreeFiles(base, ours, theirs)
+
+	var unresolved []string
+	for _, p := range conflicts {
+		entry, deleted, ok, err := mergeConflictingFile(r, p, base, ours, theirs, resolve)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unresolved = append(unresolved, p)
+			continue
+		}
+
+		if deleted {
+			delete(merged, p)
+		} else {
+			merged[p] = entry
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return nil, &ConflictError{Commit: commit, Paths: unresolved}
+	}
+
+	return merged, nil
+}
+
+func mergeConflictingFile(r *git.Repository, p string, base, ours, theirs treeFiles, resolve ConflictResolver) (object.TreeEntry, bool, bool, error) {
+	baseEntry, inBase := base[p]
+	oursEntry, inOurs := ours[p]
+	theirsEntry, inTheirs := theirs[p]
+
+	baseContent, err := readEntry(r, baseEntry, inBase)
+	if err != nil {
+		return object.TreeEntry{}, false, false, err
+	}
+	oursContent, err := readEntry(r, oursEntry, inOurs)
+	if err != nil {
+		return object.TreeEntry{}, false, false, err
+	}
+	theirsContent, err := readEntry(r, theirsEntry, inTheirs)
+	if err != nil {
+		return object.TreeEntry{}, false, false, err
+	}
+
+	mode := filemode.Regular
+	if inOurs {
+		mode = oursEntry.Mode
+	} else if inTheirs {
+		mode = theirsEntry.Mode
+	}
+
+	if inOurs && inTheirs {
+		if merger, ok := structuredMergers[path.Base(p)]; ok {
+			if content, err := merger(baseContent, oursContent, theirsContent); err == nil {
+				entry, err := storeBlob(r, p, mode, content)
+				return entry, false, err == nil, err
+			}
+		}
+	}
+
+	mergedContent, clean := mergeLines(baseContent, oursContent, theirsContent, "ours", "theirs")
+	if clean && inOurs && inTheirs {
+		entry, err := storeBlob(r, p, mode, mergedContent)
+		return entry, false, err == nil, err
+	}
+
+	if resolve == nil {
+		return object.TreeEntry{}, false, false, nil
+	}
+
+	resolution, err := resolve(Conflict{
+		Path:   p,
+		Base:   baseContent,
+		Ours:   oursContent,
+		Theirs: theirsContent,
+		Merged: mergedContent,
+	})
+	if err != nil {
+		return object.TreeEntry{}, false, false, err
+	}
+
+	switch resolution.Choice {
+	case ChooseOurs:
+		return oursEntry, !inOurs, true, nil
+	case ChooseTheirs:
+		return theirsEntry, !inTheirs, true, nil
+	case ChooseEdited:
+		entry, err := storeBlob(r, p, mode, resolution.Content)
+		return entry, false, err == nil, err
+	default:
+		return object.TreeEntry{}, false, false, nil
+	}
+}
+
+// readEntry returns the content of a tree entry, or nil when the file does not exist.
+func readEntry(r *git.Repository, entry object.TreeEntry, exists bool) ([]byte, error) {
+	if !exists {
+		return nil, nil
+	}
+
+	blob, err := r.BlobObject(entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+	}
+
+	// Keep empty files distinguishable from deleted ones.
+	if content == nil {
+		content = []byte{}
+	}
+	return content, nil
+}
+
+func storeBlob(r *git.Repository, p string, mode filemode.FileMode, content []byte) (object.TreeEntry, error) {
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+
+	writer, err := obj.Writer()
+	if err != nil {
+		return object.TreeEntry{}, fmt.Errorf("failed to write %s: %w", p, err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return object.TreeEntry{}, fmt.Errorf("failed to write %s: %w", p, err)
+	}
+	if err := writer.Close(); err != nil {
+		return object.TreeEntry{}, fmt.Errorf("failed to write %s: %w", p, err)
+	}
+
+	hash, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return object.TreeEntry{}, fmt.Errorf("failed to store %s: %w", p, err)
+	}
+
+	return object.TreeEntry{Name: path.Base(p), Mode: mode, Hash: hash}, nil
+}
+
+// errLinkingsConflict is returned when the same file is linked to different targets on both sides.
+var errLinkingsConflict = errors.New("linkings changes conflict")
+
+// mergeLinkingsVersions merges linkings.json, which maps repository file names to link targets, key by key.
+func mergeLinkingsVersions(base, ours, theirs []byte) ([]byte, error) {
+	decode := func(data []byte) (map[string]string, error) {
+		links := map[string]string{}
+		if data == nil {
+			return links, nil
+		}
+		if err := json.Unmarshal(data, &links); err != nil {
+			return nil, err
+		}
+		return links, nil
+	}
+
+	baseLinks, err := decode(base)
+	if err != nil {
+		return nil, err
+	}
+	oursLinks, err := decode(ours)
+	if err != nil {
+		return nil, err
+	}
+	theirsLinks, err := decode(theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]struct{}{}
+	for _, links := range []map[string]string{baseLinks, oursLinks, theirsLinks} {
+		for name := range links {
+			names[name] = struct{}{}
+		}
+	}
+
+	merged := map[string]string{}
+	var conflicts []string
+	for name := range names {
+		b, inBase := baseLinks[name]
+		o, inOurs := oursLinks[name]
+		t, inTheirs := theirsLinks[name]
+
+		switch {
+		case inOurs == inTheirs && o == t:
+			if inOurs {
+				merged[name] = o
+			}
+		case inOurs == inBase && o == b:
+			if inTheirs {
+				merged[name] = t
+			}
+		case inTheirs == inBase && t == b:
+			if inOurs {
+				merged[name] = o
+			}
+		default:
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("%w: %v", errLinkingsConflict, conflicts)
+	}
+
+	// Same encoding as symlink.SaveLinkings.
+	return json.MarshalIndent(merged, "", "  ")
+}
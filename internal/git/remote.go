@@ -0,0 +1,216 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+// Config keys for remote roles. Remotes themselves live in the repository's git config.
+const (
+	PullRemoteKey    = "remotes.pull"    // Remote pulled from; defaults to origin
+	NoPushRemotesKey = "remotes.no-push" // Remotes left out when pushing
+)
+
+// RemoteInfo describes a configured remote and its roles.
+type RemoteInfo struct {
+	Name string
+	URL  string
+	Pull bool // Pulls come from this remote
+	Push bool // Pushes go to this remote
+}
+
+// PushResult is the outcome of pushing to one remote.
+type PushResult struct {
+	Remote   string
+	UpToDate bool
+	Err      error
+}
+
+// pullRemote returns the remote that pulls and sync checks use.
+func pullRemote() string {
+	if name := viper.GetString(PullRemoteKey); name != "" {
+		return name
+	}
+	return defaultRemoteName
+}
+
+// ListRemotes returns the configured remotes sorted by name.
+func ListRemotes() ([]RemoteInfo, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := r.Remotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remotes: %w", err)
+	}
+
+	noPush := viper.GetStringSlice(NoPushRemotesKey)
+	infos := make([]RemoteInfo, 0, len(remotes))
+	for _, remote := range remotes {
+		cfg := remote.Config()
+		info := RemoteInfo{
+			Name: cfg.Name,
+			Pull: cfg.Name == pullRemote(),
+			Push: !slices.Contains(noPush, cfg.Name),
+		}
+		if len(cfg.URLs) > 0 {
+			info.URL = cfg.URLs[0]
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// AddRemote adds a remote to the repository. Remotes are pushed to unless push is false.
+// The caller persists the config when push is false.
+func AddRemote(name, url string, push bool) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{url}})
+	if err != nil {
+		if errors.Is(err, git.ErrRemoteExists) {
+			return fmt.Errorf("remote %s already exists", name)
+		}
+		return fmt.Errorf("failed to add remote %s: %w", name, err)
+	}
+
+	noPush := slices.DeleteFunc(viper.GetStringSlice(NoPushRemotesKey), func(n string) bool { return n == name })
+	if !push {
+		noPush = append(noPush, name)
+	}
+	viper.Set(NoPushRemotesKey, noPush)
+	return nil
+}
+
+// RemoveRemote removes a remote and its remote-tracking branches. Removing the pull remote
+// makes pulls fall back to origin.
+func RemoveRemote(name string) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	if err := r.DeleteRemote(name); err != nil {
+		if errors.Is(err, git.ErrRemoteNotFound) {
+			return fmt.Errorf("remote %s does not exist", name)
+		}
+		return fmt.Errorf("failed to remove remote %s: %w", name, err)
+	}
+
+	refs, err := r.References()
+	if err != nil {
+		return fmt.Errorf("failed to read references: %w", err)
+	}
+	prefix := "refs/remotes/" + name + "/"
+	var stale []plumbing.ReferenceName
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), prefix) {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	for _, ref := range stale {
+		if err := r.Storer.RemoveReference(ref); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", ref, err)
+		}
+	}
+
+	viper.Set(NoPushRemotesKey, slices.DeleteFunc(viper.GetStringSlice(NoPushRemotesKey), func(n string) bool { return n == name }))
+	if viper.GetString(PullRemoteKey) == name {
+		viper.Set(PullRemoteKey, "")
+	}
+	return nil
+}
+
+// SetPullRemote makes pulls and sync checks use the named remote.
+func SetPullRemote(name string) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+	if _, err := r.Remote(name); err != nil {
+		return fmt.Errorf("remote %s does not exist", name)
+	}
+
+	viper.Set(PullRemoteKey, name)
+	return nil
+}
+
+// pushRemotes returns the remotes pushes fan out to.
+func pushRemotes(r *git.Repository) ([]string, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remotes: %w", err)
+	}
+
+	noPush := viper.GetStringSlice(NoPushRemotesKey)
+	var names []string
+	for _, remote := range remotes {
+		if name := remote.Config().Name; !slices.Contains(noPush, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no push remotes configured")
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// pushToRemotes pushes a branch to every push remote. A failing remote does not stop the others.
+// withLease reports whether a remote needs a forced update guarded by its last fetched state.
+func pushToRemotes(r *git.Repository, branch plumbing.ReferenceName, withLease func(remote string) bool) ([]PushResult, error) {
+	if network.Offline() {
+		return nil, network.ErrOffline
+	}
+
+	names, err := pushRemotes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PushResult, 0, len(names))
+	for _, name := range names {
+		err := pushBranch(r, name, branch, withLease != nil && withLease(name))
+		result := PushResult{Remote: name}
+		switch {
+		case errors.Is(err, git.NoErrAlreadyUpToDate):
+			result.UpToDate = true
+		case err != nil:
+			result.Err = err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// pushError combines the failures in results into one error, or nil when every push succeeded.
+func pushError(results []PushResult) error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Remote, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,14 +9,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/spf13/viper"
 )
 
@@ -74,21 +78,47 @@ func InitGitRepo(rootGitRepoPath string, remoteUrl string, opts ...bool) (*git.R
 		bare = opts[0]
 	}
 
+	return InitGitRepoWithOptions(rootGitRepoPath, remoteUrl, InitOptions{Bare: bare})
+}
+
+// InitOptions controls how InitGitRepoWithOptions creates the repository.
+type InitOptions struct {
+	Bare bool
+	// Depth limits the clone to the latest Depth commits; 0 clones the full history.
+	// Pulls fetch older history on demand when a merge needs it.
+	Depth int
+}
+
+// InitGitRepoWithOptions clones remoteUrl into rootGitRepoPath, or initializes an empty
+// repository with origin set to remoteUrl when the remote cannot be cloned.
+func InitGitRepoWithOptions(rootGitRepoPath string, remoteUrl string, opts InitOptions) (*git.Repository, error) {
+	bare := opts.Bare
+
 	// If a remote URL is provided, try to clone the repository first
 	if remoteUrl != "" && !bare {
+		ctx, cancel, err := network.Context(context.Background(), network.Transfer)
+		if err != nil {
+			return nil, fmt.Errorf("cannot clone %s: %w", remoteUrl, err)
+		}
+		defer cancel()
+
 		// Attempt to clone the remote repository
-		r, err := git.PlainClone(rootGitRepoPath, false, &git.CloneOptions{
-			URL: remoteUrl,
+		r, err := git.PlainCloneContext(ctx, rootGitRepoPath, false, &git.CloneOptions{
+			URL:   remoteUrl,
+			Depth: opts.Depth,
 		})
 		if err == nil {
 			// Clone succeeded, return the cloned repository
 			return r, nil
 		}
-		// If clone fails, fall back to initializing a new repository
-		// This handles cases where the remote doesn't exist yet or is inaccessible
+		// An empty remote has nothing to clone, so a new repository is created with it as origin.
+		// Any other failure is reported rather than hidden behind a fresh repository.
+		if !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return nil, fmt.Errorf("failed to clone %s: %w", remoteUrl, err)
+		}
 	}
 
-	// Fall back to creating a new empty repository
+	// Create a new repository, for no remote or an empty one
 	r, err := git.PlainInit(rootGitRepoPath, bare)
 	if err != nil {
 		return nil, err
@@ -108,6 +138,47 @@ func InitGitRepo(rootGitRepoPath string, remoteUrl string, opts ...bool) (*git.R
 	return r, nil
 }
 
+// initialReadme is committed to repositories that have no commits yet, so there is a branch to push.
+const initialReadme = `# Dotfiles
+
+Managed with [oh-my-dot](https://github.com/PatrickMatthiesen/oh-my-dot).
+`
+
+// CreateInitialCommit commits a README.md to a repository without commits.
+// Returns false when the repository already has commits.
+func CreateInitialCommit(message string) (bool, error) {
+	r, err := openRepo()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := r.Head(); err == nil {
+		return false, nil
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	worktree, err := r.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	readme := filepath.Join(worktree.Filesystem.Root(), "README.md")
+	if !fileops.PathExists(readme) {
+		if err := fileops.WriteTextFileLF(readme, initialReadme, 0644); err != nil {
+			return false, fmt.Errorf("failed to write README.md: %w", err)
+		}
+	}
+	if _, err := worktree.Add("README.md"); err != nil {
+		return false, fmt.Errorf("failed to stage README.md: %w", err)
+	}
+
+	if err := commitWorktree(r, worktree, message); err != nil {
+		return false, fmt.Errorf("failed to create initial commit: %w", err)
+	}
+	return true, nil
+}
+
 // InitFromExistingRepo initializes a git repository from an existing repository located at the specified path.
 func InitFromExistingRepo(rootGitRepoPath string) error {
 	r, err := git.PlainOpen(rootGitRepoPath)
@@ -228,77 +299,335 @@ func StageChange(file string) error {
 
 // Commits the changes in the git repository located at the specified path.
 func Commit(message string) error {
-	worktree, err := GetWorktree(viper.GetString("repo-path"))
+	r, err := openRepo()
 	if err != nil {
 		return err
 	}
 
-	_, err = worktree.Commit(message, &git.CommitOptions{})
+	worktree, err := r.Worktree()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return commitWorktree(r, worktree, message)
 }
 
-// PushRepo pushes the changes in the git repository located at the specified path to the remote repository.
+// PushRepo pushes the current branch to every push remote and returns the combined failures.
 func PushRepo() error {
-	r, err := git.PlainOpen(viper.GetString("repo-path"))
-	fileops.CheckIfError(err)
-
-	remote, err := r.Remote("origin")
+	results, err := PushRepoToRemotes()
 	if err != nil {
 		return err
 	}
+	return pushError(results)
+}
 
-	err = remote.Push(&git.PushOptions{})
+// PushRepoToRemotes pushes the current branch to every push remote and reports each result.
+// Branches that rebase onto an upstream (machine branches) are force-pushed with a lease,
+// so a rebased history only replaces what was last fetched from that remote.
+func PushRepoToRemotes() ([]PushResult, error) {
+	r, err := openRepo()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	headRef, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	if !headRef.Name().IsBranch() {
+		return nil, fmt.Errorf("cannot push from detached HEAD")
+	}
+
+	_, upstream, rebase := trackedBranch(r, headRef.Name())
+	withLease := func(remote string) bool {
+		if !rebase || upstream == headRef.Name() {
+			return false
+		}
+		// Without a remote-tracking ref the branch has never been pushed, so no force is needed.
+		_, err := r.Reference(remoteTrackingRef(remote, headRef.Name()), true)
+		return err == nil
+	}
+
+	return pushToRemotes(r, headRef.Name(), withLease)
+}
+
+// PullOptions controls how PullRepoWithOptions handles diverged histories.
+type PullOptions struct {
+	// Resolve is called for files that could not be merged automatically.
+	// When nil, such files make the pull fail with a *ConflictError.
+	Resolve ConflictResolver
+	// Verify is called with the old and new head before the branch and worktree are updated.
+	// An error stops the pull and leaves the branch where it was.
+	Verify func(PullResult) error
+}
+
+// PullResult describes what a pull changed.
+type PullResult struct {
+	Updated bool
+	OldHead plumbing.Hash
+	NewHead plumbing.Hash
 }
 
-// PullRepo pulls changes from the origin remote for the current branch.
+// PullRepo pulls changes for the current branch from the branch it tracks.
 // Returns true if updates were applied, false if already up to date.
 func PullRepo() (bool, error) {
-	repoPath := viper.GetString("repo-path")
-	if repoPath == "" {
-		return false, fmt.Errorf("repository path is not set")
-	}
+	result, err := PullRepoWithOptions(PullOptions{})
+	return result.Updated, err
+}
 
-	r, err := git.PlainOpen(repoPath)
+// PullRepoWithOptions pulls changes for the current branch from the branch it tracks.
+// Fast-forwards are applied directly. Diverged histories are merged with a merge commit,
+// or, for branches configured to rebase, by replaying the local commits on top of the upstream.
+func PullRepoWithOptions(opts PullOptions) (PullResult, error) {
+	r, err := openRepo()
 	if err != nil {
-		return false, fmt.Errorf("failed to open repository: %w", err)
+		return PullResult{}, err
 	}
 
 	headRef, err := r.Head()
 	if err != nil {
-		return false, fmt.Errorf("failed to get current branch: %w", err)
+		return PullResult{}, fmt.Errorf("failed to get current branch: %w", err)
 	}
 
 	if !headRef.Name().IsBranch() {
-		return false, fmt.Errorf("cannot pull from detached HEAD")
+		return PullResult{}, fmt.Errorf("cannot pull from detached HEAD")
 	}
 
 	worktree, err := r.Worktree()
 	if err != nil {
-		return false, fmt.Errorf("failed to get worktree: %w", err)
+		return PullResult{}, fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	err = worktree.Pull(&git.PullOptions{
-		RemoteName:    "origin",
-		ReferenceName: headRef.Name(),
+	// Init scripts regenerated on this machine, for example from enabled.local.json, differ from
+	// the repository without being a change to keep. They are set aside for the pull and
+	// regenerated afterwards, so they never block it.
+	regenerate, err := setAsideInitScripts(worktree)
+	if err != nil {
+		return PullResult{}, err
+	}
+
+	result, err := pullBranch(r, worktree, headRef, opts)
+	for _, shellName := range regenerate {
+		if regenErr := shell.RegenerateInitScript(worktree.Filesystem.Root(), shellName); regenErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to regenerate the %s init script: %w", shellName, regenErr))
+		}
+	}
+	return result, err
+}
+
+// setAsideInitScripts restores the init scripts that differ only in the worktree to their committed
+// version and returns the shells they belong to.
+func setAsideInitScripts(worktree *git.Worktree) ([]string, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+
+	var files, shells []string
+	for path, fileStatus := range status {
+		shellName, ok := shell.InitScriptShell(path)
+		if !ok || fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Modified {
+			continue
+		}
+		files = append(files, path)
+		shells = append(shells, shellName)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	if err := worktree.Restore(&git.RestoreOptions{Staged: true, Worktree: true, Files: files}); err != nil {
+		return nil, fmt.Errorf("failed to set aside regenerated init scripts: %w", err)
+	}
+	sort.Strings(shells)
+	return shells, nil
+}
+
+// pullBranch pulls the upstream of the current branch into it
+func pullBranch(r *git.Repository, worktree *git.Worktree, headRef *plumbing.Reference, opts PullOptions) (PullResult, error) {
+	result := PullResult{OldHead: headRef.Hash(), NewHead: headRef.Hash()}
+
+	remoteName, upstream, rebase := trackedBranch(r, headRef.Name())
+	// go-git's pull moves the branch before anything can be checked, so verified pulls are
+	// integrated like diverged ones, which also handle fast-forwards.
+	if rebase || opts.Verify != nil {
+		var err error
+		result.NewHead, err = pullDiverged(r, worktree, headRef, remoteName, upstream, rebase, opts)
+		result.Updated = result.NewHead != result.OldHead
+		return result, err
+	}
+
+	ctx, cancel, err := network.Context(context.Background(), network.Transfer)
+	if err != nil {
+		return result, err
+	}
+	defer cancel()
+
+	err = worktree.PullContext(ctx, &git.PullOptions{
+		RemoteName:    remoteName,
+		ReferenceName: upstream,
 		SingleBranch:  true,
 	})
+	err = network.Err(ctx, network.Transfer, err)
 	if err != nil {
 		if errors.Is(err, git.NoErrAlreadyUpToDate) {
-			return false, nil
+			return result, nil
 		}
-		return false, fmt.Errorf("failed to pull repository: %w", err)
+		// In a shallow clone, go-git cannot tell a diverged history from a missing one.
+		if errors.Is(err, git.ErrNonFastForwardUpdate) || isShallowBoundary(r, err) {
+			result.NewHead, err = pullDiverged(r, worktree, headRef, remoteName, upstream, false, opts)
+			result.Updated = result.NewHead != result.OldHead
+			return result, err
+		}
+		return result, fmt.Errorf("failed to pull repository: %w", err)
 	}
 
-	return true, nil
+	newHead, err := r.Head()
+	if err != nil {
+		return result, fmt.Errorf("failed to get current branch: %w", err)
+	}
+	result.NewHead = newHead.Hash()
+	result.Updated = true
+
+	return result, nil
+}
+
+// pullDiverged integrates the upstream into the current branch by rebasing or merging
+// and returns the new head, which is unchanged when there was nothing to integrate.
+func pullDiverged(r *git.Repository, worktree *git.Worktree, headRef *plumbing.Reference, remoteName string, upstream plumbing.ReferenceName, rebase bool, opts PullOptions) (plumbing.Hash, error) {
+	resolve := opts.Resolve
+	dirty, err := hasTrackedChanges(worktree)
+	if err != nil {
+		return headRef.Hash(), err
+	}
+	if dirty {
+		return headRef.Hash(), fmt.Errorf("cannot pull with uncommitted changes; commit or discard them first")
+	}
+
+	ctx, cancel, err := network.Context(context.Background(), network.Transfer)
+	if err != nil {
+		return headRef.Hash(), err
+	}
+	defer cancel()
+
+	err = network.Err(ctx, network.Transfer, r.FetchContext(ctx, &git.FetchOptions{RemoteName: remoteName}))
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return headRef.Hash(), fmt.Errorf("failed to fetch %s: %w", remoteName, err)
+	}
+
+	upstreamRef, err := r.Reference(remoteTrackingRef(remoteName, upstream), true)
+	if err != nil {
+		return headRef.Hash(), fmt.Errorf("remote branch %s not found on %s", upstream.Short(), remoteName)
+	}
+
+	upstreamCommit, err := r.CommitObject(upstreamRef.Hash())
+	if err != nil {
+		return headRef.Hash(), fmt.Errorf("failed to inspect remote commit %s: %w", upstreamRef.Hash(), err)
+	}
+
+	localCommit, err := r.CommitObject(headRef.Hash())
+	if err != nil {
+		return headRef.Hash(), fmt.Errorf("failed to inspect local commit %s: %w", headRef.Hash(), err)
+	}
+
+	newHead := headRef.Hash()
+	// Finding the merge base may need history a shallow clone does not have yet.
+	err = withFullHistory(ctx, r, remoteName, func() error {
+		upToDate, err := upstreamCommit.IsAncestor(localCommit)
+		if err != nil {
+			return fmt.Errorf("failed to compare local and remote commits: %w", err)
+		}
+		if upToDate {
+			return nil
+		}
+
+		if rebase {
+			newHead, err = rebaseOnto(r, localCommit, upstreamCommit, resolve)
+			if err != nil {
+				return fmt.Errorf("failed to rebase %s onto %s/%s: %w", headRef.Name().Short(), remoteName, upstream.Short(), err)
+			}
+		} else {
+			newHead, err = mergeCommits(r, localCommit, upstreamCommit, resolve, fmt.Sprintf("Merge %s/%s into %s", remoteName, upstream.Short(), headRef.Name().Short()))
+			if err != nil {
+				return fmt.Errorf("failed to merge %s/%s: %w", remoteName, upstream.Short(), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return headRef.Hash(), err
+	}
+	if newHead == headRef.Hash() {
+		return newHead, nil
+	}
+
+	if opts.Verify != nil {
+		if err := opts.Verify(PullResult{Updated: true, OldHead: headRef.Hash(), NewHead: newHead}); err != nil {
+			return headRef.Hash(), err
+		}
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(headRef.Name(), newHead)); err != nil {
+		return headRef.Hash(), fmt.Errorf("failed to update branch %s: %w", headRef.Name().Short(), err)
+	}
+
+	if err := worktree.Reset(&git.ResetOptions{Commit: newHead, Mode: git.HardReset}); err != nil {
+		return headRef.Hash(), fmt.Errorf("failed to update worktree: %w", err)
+	}
+
+	return newHead, nil
+}
+
+// mergeCommits creates a merge commit of local and remote. A fast-forward is returned as the remote commit.
+func mergeCommits(r *git.Repository, local, remote *object.Commit, resolve ConflictResolver, message string) (plumbing.Hash, error) {
+	fastForward, err := local.IsAncestor(remote)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to compare local and remote commits: %w", err)
+	}
+	if fastForward {
+		return remote.Hash, nil
+	}
+
+	bases, err := local.MergeBase(remote)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to find merge base: %w", err)
+	}
+
+	var base *object.Commit
+	if len(bases) > 0 {
+		base = bases[0]
+	}
+
+	baseFiles, err := commitFiles(base)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	localFiles, err := commitFiles(local)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	remoteFiles, err := commitFiles(remote)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	merged, err := mergeTrees(r, baseFiles, localFiles, remoteFiles, resolve, plumbing.ZeroHash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	tree, err := writeTree(r.Storer, merged)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	author, err := configuredSignature(r)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return createCommit(r, tree, []plumbing.Hash{local.Hash, remote.Hash}, author, message)
 }
 
 // HasRemoteUpdates checks if the current branch needs pull from origin.
@@ -317,16 +646,18 @@ func stateHasRemoteUpdates(state RemoteSyncState) bool {
 }
 
 // GetRemoteSyncState returns local/remote relationship for the current branch.
-// It uses a lightweight remote reference list and local commit graph traversal.
+// The current branch is compared with its configured upstream, or the same-named
+// branch on the pull remote when no upstream is set. It uses a lightweight remote reference list and local commit graph traversal.
 func GetRemoteSyncState() (RemoteSyncState, error) {
-	repoPath := viper.GetString("repo-path")
-	if repoPath == "" {
-		return "", fmt.Errorf("repository path is not set")
-	}
+	return GetRemoteSyncStateFor("")
+}
 
-	r, err := git.PlainOpen(repoPath)
+// GetRemoteSyncStateFor compares the current branch with its upstream branch on a specific remote.
+// An empty remote name uses the remote the branch tracks.
+func GetRemoteSyncStateFor(remoteName string) (RemoteSyncState, error) {
+	r, err := openRepo()
 	if err != nil {
-		return "", fmt.Errorf("failed to open repository: %w", err)
+		return "", err
 	}
 
 	headRef, err := r.Head()
@@ -338,17 +669,27 @@ func GetRemoteSyncState() (RemoteSyncState, error) {
 		return "", fmt.Errorf("cannot check updates from detached HEAD")
 	}
 
-	remote, err := r.Remote("origin")
+	trackedRemote, remoteBranchRefName, _ := trackedBranch(r, headRef.Name())
+	if remoteName == "" {
+		remoteName = trackedRemote
+	}
+
+	remote, err := r.Remote(remoteName)
 	if err != nil {
-		return "", fmt.Errorf("no remote 'origin' configured: %w", err)
+		return "", fmt.Errorf("no remote '%s' configured: %w", remoteName, err)
 	}
 
-	remoteRefs, err := remote.List(&git.ListOptions{})
+	ctx, cancel, err := network.Context(context.Background(), network.Check)
 	if err != nil {
-		return "", fmt.Errorf("unable to access remote repository: %w", err)
+		return "", err
+	}
+	defer cancel()
+
+	remoteRefs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to access remote repository: %w", network.Err(ctx, network.Check, err))
 	}
 
-	remoteBranchRefName := plumbing.NewBranchReferenceName(headRef.Name().Short())
 	var remoteBranchHash plumbing.Hash
 	found := false
 	for _, ref := range remoteRefs {
@@ -432,17 +773,15 @@ func commitContainsAncestor(start *object.Commit, targetHash plumbing.Hash, maxD
 		}
 		visited[current.Hash] = struct{}{}
 
-		parents := current.Parents()
-		err := func() error {
-			defer parents.Close()
-
-			return parents.ForEach(func(parent *object.Commit) error {
-				stack = append(stack, parent)
-				return nil
-			})
-		}()
-		if err != nil {
-			return false, false, fmt.Errorf("failed to iterate commit parents: %w", err)
+		for i := 0; i < current.NumParents(); i++ {
+			parent, err := current.Parent(i)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				continue // The boundary of a shallow clone ends the history like a root commit
+			}
+			if err != nil {
+				return false, false, fmt.Errorf("failed to iterate commit parents: %w", err)
+			}
+			stack = append(stack, parent)
 		}
 	}
 
@@ -580,10 +919,15 @@ func CheckRepoWritePermission() error {
 }
 
 // StageAndCommitShellFeatureChanges stages and commits shell framework changes under omd-shells.
-// Device-local override manifests (enabled.local.json) are always excluded.
+// Device-local files (enabled.local.json and generated bundles) are always excluded.
 // Returns true when a commit was created, false when there were no committable changes.
 func StageAndCommitShellFeatureChanges(message string) (bool, error) {
-	worktree, err := GetWorktree(viper.GetString("repo-path"))
+	r, err := openRepo()
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := r.Worktree()
 	if err != nil {
 		return false, fmt.Errorf("failed to get worktree: %w", err)
 	}
@@ -620,23 +964,111 @@ func StageAndCommitShellFeatureChanges(message string) (bool, error) {
 		return false, nil
 	}
 
-	if _, err := worktree.Commit(message, &git.CommitOptions{}); err != nil {
+	if err := commitWorktree(r, worktree, message); err != nil {
 		return false, fmt.Errorf("failed to commit shell feature changes: %w", err)
 	}
 
 	return true, nil
 }
 
+// PendingChanges lists the files with uncommitted changes, including new files, sorted by path.
+// Device-local shell files are left out since they are never committed.
+func PendingChanges() ([]string, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := pendingStatus(worktree)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(status))
+	for path := range status {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// CommitPendingChanges stages every pending change and commits it.
+// Returns true when a commit was created, false when there was nothing to commit.
+func CommitPendingChanges(message string) (bool, error) {
+	r, err := openRepo()
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := r.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := pendingStatus(worktree)
+	if err != nil {
+		return false, err
+	}
+	if len(status) == 0 {
+		return false, nil
+	}
+
+	for path, fileStatus := range status {
+		if fileStatus.Worktree == git.Deleted {
+			if _, err := worktree.Remove(path); err != nil {
+				return false, fmt.Errorf("failed to stage deleted file %s: %w", path, err)
+			}
+			continue
+		}
+		if fileStatus.Staging == git.Deleted {
+			continue // Already staged
+		}
+		if _, err := worktree.Add(path); err != nil {
+			return false, fmt.Errorf("failed to stage file %s: %w", path, err)
+		}
+	}
+
+	if err := commitWorktree(r, worktree, message); err != nil {
+		return false, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return true, nil
+}
+
+func pendingStatus(worktree *git.Worktree) (git.Status, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+
+	pending := git.Status{}
+	for path, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if strings.HasPrefix(filepath.ToSlash(path), "omd-shells/") && shell.IsLocalOnlyFile(path) {
+			continue
+		}
+		pending[path] = fileStatus
+	}
+	return pending, nil
+}
+
 func isCommittableShellChangePath(path string) bool {
 	normalizedPath := filepath.ToSlash(filepath.Clean(path))
 	if !strings.HasPrefix(normalizedPath, "omd-shells/") {
 		return false
 	}
 
-	return !strings.HasSuffix(normalizedPath, "/"+shell.LocalManifestFileName())
+	return !shell.IsLocalOnlyFile(normalizedPath)
 }
 
 // CheckRemotePushPermission checks if the user has valid git credentials for pushing to the remote repository.
+// Only the pull remote is checked; failures on mirrors are reported per remote when pushing.
 // It uses the same authentication mechanism as git push (SSH keys, credential helpers, etc.) to verify access.
 func CheckRemotePushPermission() error {
 	repoPath := viper.GetString("repo-path")
@@ -649,17 +1081,24 @@ func CheckRemotePushPermission() error {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
 
-	remote, err := r.Remote("origin")
+	name := pullRemote()
+	remote, err := r.Remote(name)
 	if err != nil {
-		return fmt.Errorf("no remote 'origin' configured: %w", err)
+		return fmt.Errorf("no remote '%s' configured: %w", name, err)
 	}
 
 	// List references from the remote to check connectivity and credentials.
 	// This is a lightweight operation that verifies we can authenticate without actually pushing.
 	// Uses default git authentication (SSH keys, credential helpers, etc.).
-	_, err = remote.List(&git.ListOptions{})
+	ctx, cancel, err := network.Context(context.Background(), network.Check)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	_, err = remote.ListContext(ctx, &git.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("unable to access remote repository (check credentials and network): %w", err)
+		return fmt.Errorf("unable to access remote repository (check credentials and network): %w", network.Err(ctx, network.Check, err))
 	}
 
 	return nil
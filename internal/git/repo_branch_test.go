@@ -0,0 +1,219 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+func setupMachineBranch(t *testing.T, name string) (*git.Repository, string) {
+	t.Helper()
+
+	r, err := testutil.SetupTestRepo(t)
+	if err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+
+	if err := internalgit.CheckoutTrackingBranch(name); err != nil {
+		t.Fatalf("CheckoutTrackingBranch error: %v", err)
+	}
+
+	return r, viper.GetString("repo-path")
+}
+
+func TestCheckoutTrackingBranch_TracksClonedBranch(t *testing.T) {
+	setupMachineBranch(t, "laptop")
+
+	current, err := internalgit.CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch error: %v", err)
+	}
+	if current != "laptop" {
+		t.Fatalf("current branch = %q, want %q", current, "laptop")
+	}
+
+	branches, err := internalgit.ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches error: %v", err)
+	}
+
+	var laptop *internalgit.BranchInfo
+	for i := range branches {
+		if branches[i].Name == "laptop" {
+			laptop = &branches[i]
+		}
+	}
+	if laptop == nil {
+		t.Fatalf("branch laptop not listed in %+v", branches)
+	}
+	if !laptop.Current || laptop.Upstream != "main" || laptop.Remote != "origin" || !laptop.Rebase {
+		t.Fatalf("laptop = %+v, want current branch tracking origin/main with rebase", *laptop)
+	}
+}
+
+func TestGetRemoteSyncState_ComparesWithUpstream(t *testing.T) {
+	setMaxAncestorSearchDepth(t, 10)
+	setupMachineBranch(t, "laptop")
+
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "shared.txt", "shared"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	state, err := internalgit.GetRemoteSyncState()
+	if err != nil {
+		t.Fatalf("GetRemoteSyncState error: %v", err)
+	}
+	if state != internalgit.RemoteSyncRemoteAhead {
+		t.Fatalf("state = %q, want %q", state, internalgit.RemoteSyncRemoteAhead)
+	}
+}
+
+func TestPullRepo_RebasesMachineBranch(t *testing.T) {
+	r, repoPath := setupMachineBranch(t, "laptop")
+
+	if err := commitToRepo(t, repoPath, "local.txt", "local"); err != nil {
+		t.Fatalf("commit local: %v", err)
+	}
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "shared.txt", "shared"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	updated, err := internalgit.PullRepo()
+	if err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+	if !updated {
+		t.Fatal("PullRepo reported no updates")
+	}
+
+	for _, name := range []string{"local.txt", "shared.txt"} {
+		if _, err := os.Stat(filepath.Join(repoPath, name)); err != nil {
+			t.Fatalf("expected %s in worktree: %v", name, err)
+		}
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("read HEAD: %v", err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName("laptop") {
+		t.Fatalf("HEAD = %s, want refs/heads/laptop", head.Name())
+	}
+
+	headCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("read HEAD commit: %v", err)
+	}
+	upstream, err := r.Reference(plumbing.NewRemoteReferenceName("origin", "main"), true)
+	if err != nil {
+		t.Fatalf("read origin/main: %v", err)
+	}
+	if headCommit.NumParents() != 1 || headCommit.ParentHashes[0] != upstream.Hash() {
+		t.Fatalf("local commit was not replayed on top of origin/main")
+	}
+
+	// The machine branch must stay pushable after it is rebased again.
+	if err := internalgit.PushRepo(); err != nil {
+		t.Fatalf("PushRepo error: %v", err)
+	}
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "shared-2.txt", "shared again"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+	if _, err := internalgit.PullRepo(); err != nil {
+		t.Fatalf("second PullRepo error: %v", err)
+	}
+	if err := internalgit.PushRepo(); err != nil {
+		t.Fatalf("PushRepo after rebase error: %v", err)
+	}
+}
+
+func TestPromoteCommit_CopiesCommitToSharedBranch(t *testing.T) {
+	r, repoPath := setupMachineBranch(t, "laptop")
+
+	if err := os.WriteFile(filepath.Join(repoPath, "promoted.txt"), []byte("for everyone"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := internalgit.StageChange("promoted.txt"); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := internalgit.Commit("Added promoted.txt"); err != nil {
+		t.Fatalf("commit local: %v", err)
+	}
+
+	hash, err := internalgit.PromoteCommit("HEAD", "main")
+	if err != nil {
+		t.Fatalf("PromoteCommit error: %v", err)
+	}
+
+	mainRef, err := r.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("read main: %v", err)
+	}
+	if mainRef.Hash() != hash {
+		t.Fatalf("main = %s, want promoted commit %s", mainRef.Hash(), hash)
+	}
+
+	mainCommit, err := r.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("read promoted commit: %v", err)
+	}
+	if _, err := mainCommit.File("promoted.txt"); err != nil {
+		t.Fatalf("promoted commit is missing promoted.txt: %v", err)
+	}
+	if count := strings.Count(mainCommit.Message, internalgit.MachineTrailer+":"); count != 1 {
+		t.Fatalf("promoted commit has %d Machine trailers, want 1:\n%s", count, mainCommit.Message)
+	}
+	if !strings.Contains(mainCommit.Message, "(cherry picked from commit ") || internalgit.CommitTrailer(mainCommit.Message, internalgit.MachineTrailer) == "" {
+		t.Fatalf("promoted commit message:\n%s", mainCommit.Message)
+	}
+
+	current, err := internalgit.CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch error: %v", err)
+	}
+	if current != "laptop" {
+		t.Fatalf("current branch = %q, want laptop to stay checked out", current)
+	}
+
+	if _, err := internalgit.PromoteCommit("HEAD", "main"); !errors.Is(err, internalgit.ErrNothingToPromote) {
+		t.Fatalf("second PromoteCommit error = %v, want ErrNothingToPromote", err)
+	}
+
+	// Once main is shared, rebasing the machine branch drops the now-duplicate commit.
+	results, err := internalgit.PushBranch("main")
+	if err == nil {
+		err = results[0].Err
+	}
+	if err != nil {
+		t.Fatalf("PushBranch error: %v", err)
+	}
+	if _, err := internalgit.PullRepo(); err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("read HEAD: %v", err)
+	}
+	if head.Hash() != hash {
+		t.Fatalf("HEAD = %s, want machine branch to match promoted main %s", head.Hash(), hash)
+	}
+}
+
+func TestPromoteCommit_RefusesCurrentBranch(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+
+	if _, err := internalgit.PromoteCommit("HEAD", "main"); err == nil {
+		t.Fatal("expected error when promoting onto the checked out branch")
+	}
+}
@@ -0,0 +1,54 @@
+package git_test
+
+import (
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/spf13/viper"
+)
+
+func TestChangedFilesAndFileAtCommit(t *testing.T) {
+	r, err := testutil.SetupTestRepo(t)
+	if err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	before, err := r.Head()
+	if err != nil {
+		t.Fatalf("read HEAD: %v", err)
+	}
+
+	if err := commitToRepo(t, repoPath, "linkings.json", `{"a": "~/.a"}`); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := commitToRepo(t, repoPath, "test.txt", "changed"); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	after, err := r.Head()
+	if err != nil {
+		t.Fatalf("read HEAD: %v", err)
+	}
+
+	changed, err := internalgit.ChangedFiles(before.Hash(), after.Hash())
+	if err != nil {
+		t.Fatalf("ChangedFiles error: %v", err)
+	}
+	if len(changed) != 2 || changed[0] != "linkings.json" || changed[1] != "test.txt" {
+		t.Fatalf("changed = %v, want [linkings.json test.txt]", changed)
+	}
+
+	content, ok, err := internalgit.FileAtCommit(before.Hash(), "test.txt")
+	if err != nil || !ok {
+		t.Fatalf("FileAtCommit error = %v, found = %v", err, ok)
+	}
+	if string(content) != "test content" {
+		t.Fatalf("test.txt at old head = %q, want %q", content, "test content")
+	}
+
+	if _, ok, err := internalgit.FileAtCommit(before.Hash(), "linkings.json"); err != nil || ok {
+		t.Fatalf("FileAtCommit for missing file = (%v, %v), want not found", ok, err)
+	}
+}
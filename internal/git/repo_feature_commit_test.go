@@ -33,6 +33,21 @@ func TestIsCommittableShellChangePath(t *testing.T) {
 			path: filepath.Join("omd-shells", "powershell", shell.LocalManifestFileName()),
 			want: false,
 		},
+		{
+			name: "skips generated bundle",
+			path: "omd-shells/zsh/bundle.zsh",
+			want: false,
+		},
+		{
+			name: "skips compiled bundle",
+			path: "omd-shells/zsh/bundle.zsh.zwc",
+			want: false,
+		},
+		{
+			name: "commits feature named bundle",
+			path: "omd-shells/bash/features/bundle.sh",
+			want: true,
+		},
 		{
 			name: "skips non shell file",
 			path: "files/.gitconfig",
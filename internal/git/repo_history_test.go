@@ -0,0 +1,103 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/spf13/viper"
+)
+
+func TestHistory_FiltersByFileAndLinking(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := os.MkdirAll(filepath.Join(repoPath, "files"), 0755); err != nil {
+		t.Fatalf("create files dir: %v", err)
+	}
+
+	steps := []struct{ file, content string }{
+		{"files/.zshrc", "export A=1\n"},
+		{"linkings.json", `{".zshrc": "~/.zshrc"}`},
+		{"files/.bashrc", "export B=1\n"},
+		{"linkings.json", `{".zshrc": "~/.zshrc", ".bashrc": "~/.bashrc"}`},
+		{"files/.zshrc", "export A=2\n"},
+	}
+	for _, step := range steps {
+		if err := commitToRepo(t, repoPath, step.file, step.content); err != nil {
+			t.Fatalf("commit %s: %v", step.file, err)
+		}
+	}
+
+	entries, err := internalgit.History(internalgit.HistoryFilter{Paths: []string{"files/.zshrc"}, Linking: ".zshrc"}, 0, true)
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+
+	var subjects []string
+	for _, entry := range entries {
+		subjects = append(subjects, entry.Subject())
+	}
+	want := []string{"test commit files/.zshrc", "test commit linkings.json", "test commit files/.zshrc"}
+	if strings.Join(subjects, "|") != strings.Join(want, "|") {
+		t.Fatalf("history = %v, want %v", subjects, want)
+	}
+
+	if !strings.Contains(entries[0].Patch, "+export A=2") {
+		t.Fatalf("patch of latest commit does not show the change:\n%s", entries[0].Patch)
+	}
+	if strings.Contains(entries[0].Patch, ".bashrc") {
+		t.Fatalf("patch includes unrelated files:\n%s", entries[0].Patch)
+	}
+
+	limited, err := internalgit.History(internalgit.HistoryFilter{Prefixes: []string{"files/"}}, 2, false)
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Fatalf("limited history has %d entries, want 2", len(limited))
+	}
+}
+
+func TestCommit_UsesConfiguredAuthorAndMachineTrailer(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	viper.Set(internalgit.CommitAuthorNameKey, "Dot Bot")
+	viper.Set(internalgit.CommitAuthorEmailKey, "dot@example.com")
+	t.Cleanup(func() {
+		viper.Set(internalgit.CommitAuthorNameKey, "")
+		viper.Set(internalgit.CommitAuthorEmailKey, "")
+	})
+
+	if err := os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := internalgit.StageChange("a.txt"); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := internalgit.Commit("Added a.txt"); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+
+	entries, err := internalgit.History(internalgit.HistoryFilter{Paths: []string{"a.txt"}}, 1, false)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("History = %v, %v", entries, err)
+	}
+
+	host, _ := os.Hostname()
+	if entries[0].Author != "Dot Bot" {
+		t.Fatalf("author = %q, want Dot Bot", entries[0].Author)
+	}
+	if entries[0].Machine != host {
+		t.Fatalf("machine = %q, want %q", entries[0].Machine, host)
+	}
+	if entries[0].Subject() != "Added a.txt" {
+		t.Fatalf("subject = %q", entries[0].Subject())
+	}
+}
@@ -0,0 +1,160 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/spf13/viper"
+)
+
+// setupDivergedRepo creates a repository whose file has been changed both locally and on the remote.
+func setupDivergedRepo(t *testing.T, filename, base, ours, theirs string) string {
+	t.Helper()
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	if err := commitToRepo(t, repoPath, filename, base); err != nil {
+		t.Fatalf("commit base: %v", err)
+	}
+	if err := internalgit.PushRepo(); err != nil {
+		t.Fatalf("push base: %v", err)
+	}
+	if err := commitToRepo(t, repoPath, filename, ours); err != nil {
+		t.Fatalf("commit local: %v", err)
+	}
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), filename, theirs); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	return repoPath
+}
+
+func readRepoFile(t *testing.T, repoPath, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(repoPath, filename))
+	if err != nil {
+		t.Fatalf("read %s: %v", filename, err)
+	}
+	return string(data)
+}
+
+func TestPullRepo_MergesDivergedHistory(t *testing.T) {
+	repoPath := setupDivergedRepo(t, "aliases.sh",
+		"alias a=1\nalias b=2\nalias c=3\n",
+		"alias a=local\nalias b=2\nalias c=3\n",
+		"alias a=1\nalias b=2\nalias c=remote\n",
+	)
+
+	updated, err := internalgit.PullRepo()
+	if err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+	if !updated {
+		t.Fatal("PullRepo reported no updates")
+	}
+
+	want := "alias a=local\nalias b=2\nalias c=remote\n"
+	if got := readRepoFile(t, repoPath, "aliases.sh"); got != want {
+		t.Fatalf("aliases.sh = %q, want %q", got, want)
+	}
+
+	state, err := internalgit.GetRemoteSyncState()
+	if err != nil {
+		t.Fatalf("GetRemoteSyncState error: %v", err)
+	}
+	if state != internalgit.RemoteSyncLocalAhead {
+		t.Fatalf("state after merge = %q, want %q", state, internalgit.RemoteSyncLocalAhead)
+	}
+}
+
+func TestPullRepo_ConflictWithoutResolver(t *testing.T) {
+	repoPath := setupDivergedRepo(t, "aliases.sh", "alias a=1\n", "alias a=local\n", "alias a=remote\n")
+
+	_, err := internalgit.PullRepo()
+	var conflictErr *internalgit.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("PullRepo error = %v, want *ConflictError", err)
+	}
+	if len(conflictErr.Paths) != 1 || conflictErr.Paths[0] != "aliases.sh" {
+		t.Fatalf("conflict paths = %v, want [aliases.sh]", conflictErr.Paths)
+	}
+
+	if got := readRepoFile(t, repoPath, "aliases.sh"); got != "alias a=local\n" {
+		t.Fatalf("aliases.sh = %q, want the local version to be left untouched", got)
+	}
+}
+
+func TestPullRepo_ResolvesConflictsWithStrategy(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice internalgit.ConflictChoice
+		want   string
+	}{
+		{name: "ours", choice: internalgit.ChooseOurs, want: "alias a=local\n"},
+		{name: "theirs", choice: internalgit.ChooseTheirs, want: "alias a=remote\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath := setupDivergedRepo(t, "aliases.sh", "alias a=1\n", "alias a=local\n", "alias a=remote\n")
+
+			if _, err := internalgit.PullRepoWithOptions(internalgit.PullOptions{Resolve: internalgit.ResolveWith(tt.choice)}); err != nil {
+				t.Fatalf("PullRepoWithOptions error: %v", err)
+			}
+
+			if got := readRepoFile(t, repoPath, "aliases.sh"); got != tt.want {
+				t.Fatalf("aliases.sh = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullRepo_EditedResolutionSeesConflictMarkers(t *testing.T) {
+	repoPath := setupDivergedRepo(t, "aliases.sh", "alias a=1\n", "alias a=local\n", "alias a=remote\n")
+
+	resolve := func(conflict internalgit.Conflict) (internalgit.Resolution, error) {
+		if !strings.Contains(string(conflict.Merged), "<<<<<<< ours") {
+			t.Errorf("merged content has no conflict markers: %q", conflict.Merged)
+		}
+		return internalgit.Resolution{Choice: internalgit.ChooseEdited, Content: []byte("alias a=both\n")}, nil
+	}
+
+	if _, err := internalgit.PullRepoWithOptions(internalgit.PullOptions{Resolve: resolve}); err != nil {
+		t.Fatalf("PullRepoWithOptions error: %v", err)
+	}
+
+	if got := readRepoFile(t, repoPath, "aliases.sh"); got != "alias a=both\n" {
+		t.Fatalf("aliases.sh = %q, want the edited content", got)
+	}
+}
+
+func TestPullRepo_MergesManifestsStructurally(t *testing.T) {
+	repoPath := setupDivergedRepo(t, "enabled.json",
+		`{"features": [{"name": "git-prompt"}]}`,
+		`{"features": [{"name": "git-prompt"}, {"name": "nvm"}]}`,
+		`{"features": [{"name": "git-prompt"}, {"name": "ssh-agent"}]}`,
+	)
+
+	if _, err := internalgit.PullRepo(); err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+
+	got := readRepoFile(t, repoPath, "enabled.json")
+	for _, name := range []string{"git-prompt", "nvm", "ssh-agent"} {
+		if !strings.Contains(got, `"name": "`+name+`"`) {
+			t.Fatalf("enabled.json is missing %s:\n%s", name, got)
+		}
+	}
+	if strings.Contains(got, "<<<<<<<") {
+		t.Fatalf("enabled.json contains conflict markers:\n%s", got)
+	}
+}
@@ -0,0 +1,85 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/spf13/viper"
+)
+
+func TestCommitPendingChanges(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := os.MkdirAll(filepath.Join(repoPath, "files"), 0755); err != nil {
+		t.Fatalf("create files dir: %v", err)
+	}
+
+	if err := commitToRepo(t, repoPath, "files/.zshrc", "export A=1\n"); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := commitToRepo(t, repoPath, "files/.vimrc", "set nu\n"); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	pending, err := internalgit.PendingChanges()
+	if err != nil {
+		t.Fatalf("PendingChanges error: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected a clean worktree, got %v", pending)
+	}
+
+	writes := map[string]string{
+		"files/.zshrc":                      "export A=2\n",
+		"files/.bashrc":                     "export B=1\n",
+		"omd-shells/zsh/enabled.local.json": `{"features": []}`,
+	}
+	for name, content := range writes {
+		path := filepath.Join(repoPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(repoPath, "files", ".vimrc")); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = internalgit.PendingChanges()
+	if err != nil {
+		t.Fatalf("PendingChanges error: %v", err)
+	}
+	want := []string{"files/.bashrc", "files/.vimrc", "files/.zshrc"}
+	if !slices.Equal(pending, want) {
+		t.Fatalf("pending = %v, want %v", pending, want)
+	}
+
+	committed, err := internalgit.CommitPendingChanges("sync")
+	if err != nil {
+		t.Fatalf("CommitPendingChanges error: %v", err)
+	}
+	if !committed {
+		t.Fatalf("expected a commit")
+	}
+
+	pending, err = internalgit.PendingChanges()
+	if err != nil {
+		t.Fatalf("PendingChanges error: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected everything committed, got %v", pending)
+	}
+
+	committed, err = internalgit.CommitPendingChanges("sync")
+	if err != nil || committed {
+		t.Fatalf("expected no commit on a clean worktree, got %v, %v", committed, err)
+	}
+}
@@ -0,0 +1,123 @@
+package git_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+func TestPushRepoToRemotes_FansOutToPushRemotes(t *testing.T) {
+	r, err := testutil.SetupTestRepo(t)
+	if err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+
+	mirror := testutil.CreateBareRemoteRepo(t)
+	backup := testutil.CreateBareRemoteRepo(t)
+	if err := internalgit.AddRemote("mirror", mirror, true); err != nil {
+		t.Fatalf("AddRemote mirror: %v", err)
+	}
+	if err := internalgit.AddRemote("backup", backup, false); err != nil {
+		t.Fatalf("AddRemote backup: %v", err)
+	}
+
+	if err := commitToRepo(t, repoPath, "a.txt", "a\n"); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	results, err := internalgit.PushRepoToRemotes()
+	if err != nil {
+		t.Fatalf("PushRepoToRemotes error: %v", err)
+	}
+
+	pushed := map[string]bool{}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("push to %s failed: %v", result.Remote, result.Err)
+		}
+		pushed[result.Remote] = true
+	}
+	if !pushed["origin"] || !pushed["mirror"] || pushed["backup"] {
+		t.Fatalf("pushed to %v, want origin and mirror only", pushed)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	mirrorRepo, err := git.PlainOpen(mirror)
+	if err != nil {
+		t.Fatalf("open mirror: %v", err)
+	}
+	ref, err := mirrorRepo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil || ref.Hash() != head.Hash() {
+		t.Fatalf("mirror main = %v (%v), want %s", ref, err, head.Hash())
+	}
+
+	state, err := internalgit.GetRemoteSyncStateFor("mirror")
+	if err != nil || state != internalgit.RemoteSyncUpToDate {
+		t.Fatalf("mirror sync state = %q, %v; want up-to-date", state, err)
+	}
+}
+
+func TestRemotes_PullRemoteAndRemoval(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+
+	mirror := testutil.CreateBareRemoteRepo(t)
+	if err := internalgit.AddRemote("mirror", mirror, true); err != nil {
+		t.Fatalf("AddRemote: %v", err)
+	}
+	if err := internalgit.AddRemote("mirror", mirror, true); err == nil {
+		t.Fatal("expected an error adding a duplicate remote")
+	}
+	if err := internalgit.SetPullRemote("missing"); err == nil {
+		t.Fatal("expected an error for an unknown pull remote")
+	}
+	if err := internalgit.SetPullRemote("mirror"); err != nil {
+		t.Fatalf("SetPullRemote: %v", err)
+	}
+
+	remotes, err := internalgit.ListRemotes()
+	if err != nil {
+		t.Fatalf("ListRemotes: %v", err)
+	}
+	if len(remotes) != 2 || remotes[0].Name != "mirror" || !remotes[0].Pull || remotes[1].Pull {
+		t.Fatalf("remotes = %+v, want mirror as the pull remote", remotes)
+	}
+
+	if err := internalgit.RemoveRemote("mirror"); err != nil {
+		t.Fatalf("RemoveRemote: %v", err)
+	}
+	if viper.GetString(internalgit.PullRemoteKey) != "" {
+		t.Fatal("removing the pull remote should reset it")
+	}
+	if err := internalgit.RemoveRemote("mirror"); err == nil {
+		t.Fatal("expected an error removing a missing remote")
+	}
+}
+
+func TestInitGitRepoWithOptions_CloneFailure(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.git")
+	if _, err := internalgit.InitGitRepoWithOptions(t.TempDir(), missing, internalgit.InitOptions{}); err == nil {
+		t.Fatal("expected a remote that cannot be cloned to be an error")
+	}
+
+	// An empty remote has nothing to clone, so a new repository is created with it as origin
+	empty := testutil.CreateBareRemoteRepo(t)
+	r, err := internalgit.InitGitRepoWithOptions(t.TempDir(), empty, internalgit.InitOptions{})
+	if err != nil {
+		t.Fatalf("InitGitRepoWithOptions with an empty remote: %v", err)
+	}
+	origin, err := r.Remote("origin")
+	if err != nil || origin.Config().URLs[0] != empty {
+		t.Fatalf("origin = %v, %v", origin, err)
+	}
+}
@@ -0,0 +1,140 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+func TestRestore_FileToEarlierCommit(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := os.MkdirAll(filepath.Join(repoPath, "files"), 0755); err != nil {
+		t.Fatalf("create files dir: %v", err)
+	}
+
+	for _, content := range []string{"export A=1\n", "export A=2\n"} {
+		if err := commitToRepo(t, repoPath, "files/.zshrc", content); err != nil {
+			t.Fatalf("commit .zshrc: %v", err)
+		}
+	}
+
+	hash, err := internalgit.ResolveRevisionOrDate("HEAD~1")
+	if err != nil {
+		t.Fatalf("ResolveRevisionOrDate error: %v", err)
+	}
+
+	revisions, err := internalgit.PlanRestore(hash, internalgit.HistoryFilter{Paths: []string{"files/.zshrc"}})
+	if err != nil {
+		t.Fatalf("PlanRestore error: %v", err)
+	}
+	if len(revisions) != 1 || !revisions[0].Changed() {
+		t.Fatalf("expected one changed file, got %+v", revisions)
+	}
+
+	if err := internalgit.ApplyRestore(revisions); err != nil {
+		t.Fatalf("ApplyRestore error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(repoPath, "files", ".zshrc"))
+	if err != nil {
+		t.Fatalf("read restored file: %v", err)
+	}
+	if string(content) != "export A=1\n" {
+		t.Fatalf("restored content = %q", content)
+	}
+
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("open repo: %v", err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.File("files/.zshrc").Staging != git.Modified {
+		t.Fatalf("restored file is not staged: %v", status)
+	}
+}
+
+func TestRestore_ShellFeaturesRemovesNewerFiles(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := os.MkdirAll(filepath.Join(repoPath, "omd-shells", "bash", "features"), 0755); err != nil {
+		t.Fatalf("create features dir: %v", err)
+	}
+
+	if err := commitToRepo(t, repoPath, "omd-shells/bash/features/git.sh", "alias g=git\n"); err != nil {
+		t.Fatalf("commit feature: %v", err)
+	}
+	if err := commitToRepo(t, repoPath, "omd-shells/bash/features/extra.sh", "alias x=ls\n"); err != nil {
+		t.Fatalf("commit feature: %v", err)
+	}
+
+	hash, err := internalgit.ResolveRevisionOrDate("HEAD~1")
+	if err != nil {
+		t.Fatalf("ResolveRevisionOrDate error: %v", err)
+	}
+
+	filter := internalgit.HistoryFilter{
+		Paths:    []string{"omd-shells/bash/enabled.json"},
+		Prefixes: []string{"omd-shells/bash/features/"},
+	}
+	revisions, err := internalgit.PlanRestore(hash, filter)
+	if err != nil {
+		t.Fatalf("PlanRestore error: %v", err)
+	}
+	if err := internalgit.ApplyRestore(revisions); err != nil {
+		t.Fatalf("ApplyRestore error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoPath, "omd-shells", "bash", "features", "extra.sh")); !os.IsNotExist(err) {
+		t.Fatalf("feature added after the revision should be removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "omd-shells", "bash", "features", "git.sh")); err != nil {
+		t.Fatalf("feature from the revision should remain: %v", err)
+	}
+}
+
+func TestResolveRevisionOrDate(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := commitToRepo(t, repoPath, "a.txt", "a\n"); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	head, err := internalgit.ResolveRevisionOrDate("HEAD")
+	if err != nil {
+		t.Fatalf("resolve HEAD: %v", err)
+	}
+
+	byDate, err := internalgit.ResolveRevisionOrDate("2999-01-01")
+	if err != nil {
+		t.Fatalf("resolve future date: %v", err)
+	}
+	if byDate != head {
+		t.Fatalf("future date resolved to %s, want HEAD %s", byDate, head)
+	}
+
+	if _, err := internalgit.ResolveRevisionOrDate("2000-01-01"); err == nil {
+		t.Fatal("expected an error for a date before the first commit")
+	}
+	if _, err := internalgit.ResolveRevisionOrDate("not-a-rev"); err == nil {
+		t.Fatal("expected an error for an unknown revision")
+	}
+}
@@ -0,0 +1,123 @@
+package git_test
+
+import (
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// setupShallowClone clones a remote with several commits at depth 1 and makes it the dotfiles repository.
+func setupShallowClone(t *testing.T) (*git.Repository, string) {
+	t.Helper()
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	remotePath := viper.GetString("remote-url")
+	if err := commitAndPushManyToRemote(t, remotePath, 5); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	clonePath := t.TempDir()
+	r, err := internalgit.InitGitRepoWithOptions(clonePath, remotePath, internalgit.InitOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("InitGitRepoWithOptions error: %v", err)
+	}
+	viper.Set("repo-path", clonePath)
+
+	if !internalgit.IsShallow(r) {
+		t.Fatal("a clone with depth 1 should be shallow")
+	}
+	return r, clonePath
+}
+
+func TestShallowClone_FastForwardPull(t *testing.T) {
+	r, _ := setupShallowClone(t)
+
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "remote.txt", "remote"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	updated, err := internalgit.PullRepo()
+	if err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+	if !updated {
+		t.Fatal("PullRepo reported no updates")
+	}
+	if !internalgit.IsShallow(r) {
+		t.Fatal("a fast-forward should not need the full history")
+	}
+}
+
+func TestShallowClone_DivergedPullAndPush(t *testing.T) {
+	setMaxAncestorSearchDepth(t, 10)
+	r, clonePath := setupShallowClone(t)
+
+	if err := commitToRepo(t, clonePath, "local.txt", "local"); err != nil {
+		t.Fatalf("commit local: %v", err)
+	}
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "remote.txt", "remote"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	updated, err := internalgit.PullRepo()
+	if err != nil {
+		t.Fatalf("PullRepo error: %v", err)
+	}
+	if !updated {
+		t.Fatal("PullRepo reported no updates")
+	}
+	if got := readRepoFile(t, clonePath, "remote.txt"); got != "remote" {
+		t.Fatalf("remote.txt = %q, want the remote change merged", got)
+	}
+	if got := readRepoFile(t, clonePath, "local.txt"); got != "local" {
+		t.Fatalf("local.txt = %q, want the local change kept", got)
+	}
+	if internalgit.IsShallow(r) {
+		t.Fatal("merging beyond the shallow boundary should fetch the full history")
+	}
+
+	if err := internalgit.PushRepo(); err != nil {
+		t.Fatalf("PushRepo error: %v", err)
+	}
+	state, err := internalgit.GetRemoteSyncState()
+	if err != nil {
+		t.Fatalf("GetRemoteSyncState error: %v", err)
+	}
+	if state != internalgit.RemoteSyncUpToDate {
+		t.Fatalf("state after push = %q, want %q", state, internalgit.RemoteSyncUpToDate)
+	}
+}
+
+func TestShallowClone_SyncStateStopsAtBoundary(t *testing.T) {
+	setMaxAncestorSearchDepth(t, 100)
+	_, clonePath := setupShallowClone(t)
+
+	if err := commitToRepo(t, clonePath, "local.txt", "local"); err != nil {
+		t.Fatalf("commit local: %v", err)
+	}
+
+	state, err := internalgit.GetRemoteSyncState()
+	if err != nil {
+		t.Fatalf("GetRemoteSyncState error: %v", err)
+	}
+	if state != internalgit.RemoteSyncLocalAhead {
+		t.Fatalf("state = %q, want %q", state, internalgit.RemoteSyncLocalAhead)
+	}
+}
+
+func TestShallowClone_HistoryStopsAtBoundary(t *testing.T) {
+	setupShallowClone(t)
+
+	entries, err := internalgit.History(internalgit.HistoryFilter{Prefixes: []string{""}}, 0, false)
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("History returned %d entries, want only the cloned commit", len(entries))
+	}
+}
@@ -0,0 +1,153 @@
+package git_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+func setSigningConfig(t *testing.T, values map[string]string) {
+	t.Helper()
+	for key, value := range values {
+		viper.Set(key, value)
+	}
+	t.Cleanup(func() {
+		for key := range values {
+			viper.Set(key, "")
+		}
+	})
+}
+
+func commitSignedFile(t *testing.T, name string) {
+	t.Helper()
+	repoPath := viper.GetString("repo-path")
+	if err := os.WriteFile(filepath.Join(repoPath, name), []byte(name+"\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := internalgit.StageChange(name); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := internalgit.Commit("Added " + name); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+}
+
+func writeSSHKey(t *testing.T, dir string) ssh.PublicKey {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "id_ed25519"), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	return signer.PublicKey()
+}
+
+func TestSigning_SSHSignedCommitsVerify(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	keyDir := t.TempDir()
+	publicKey := writeSSHKey(t, keyDir)
+
+	setSigningConfig(t, map[string]string{
+		internalgit.SigningFormatKey: internalgit.SigningFormatSSH,
+		internalgit.SigningKeyKey:    filepath.Join(keyDir, "id_ed25519"),
+	})
+
+	commitSignedFile(t, "a.txt")
+
+	statuses, err := internalgit.VerifyCommits("HEAD~1", "HEAD", 0)
+	if err != nil {
+		t.Fatalf("VerifyCommits error: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[0].Verified() || statuses[0].Format != internalgit.SigningFormatSSH {
+		t.Fatalf("expected one verified SSH commit, got %+v", statuses)
+	}
+
+	// An allowed signers entry for another principal does not trust the key.
+	signersFile := filepath.Join(keyDir, "allowed_signers")
+	line := "someone@example.com " + string(ssh.MarshalAuthorizedKey(publicKey))
+	if err := os.WriteFile(signersFile, []byte(line), 0644); err != nil {
+		t.Fatalf("write allowed signers: %v", err)
+	}
+	setSigningConfig(t, map[string]string{internalgit.SigningAllowedSignersKey: signersFile})
+
+	statuses, err = internalgit.VerifyCommits("HEAD~1", "HEAD", 0)
+	if err != nil {
+		t.Fatalf("VerifyCommits error: %v", err)
+	}
+	if statuses[0].Verified() {
+		t.Fatal("commit verified with an allowed signer for a different principal")
+	}
+
+	// The initial commit from the test setup is unsigned.
+	statuses, err = internalgit.VerifyCommits("", "HEAD~1", 0)
+	if err != nil {
+		t.Fatalf("VerifyCommits error: %v", err)
+	}
+	if len(statuses) == 0 || statuses[0].Verified() || statuses[0].Format != "" {
+		t.Fatalf("expected unsigned initial commit, got %+v", statuses)
+	}
+}
+
+func TestSigning_OpenPGPSignedCommitsVerify(t *testing.T) {
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+
+	entity, err := openpgp.NewEntity("Dot Signer", "", "signer@example.com", nil)
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("serialize key: %v", err)
+	}
+	w.Close()
+
+	keyPath := filepath.Join(t.TempDir(), "signing.asc")
+	if err := os.WriteFile(keyPath, armored.Bytes(), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	setSigningConfig(t, map[string]string{
+		internalgit.SigningFormatKey: internalgit.SigningFormatOpenPGP,
+		internalgit.SigningKeyKey:    keyPath,
+	})
+
+	commitSignedFile(t, "b.txt")
+
+	statuses, err := internalgit.VerifyCommits("HEAD~1", "HEAD", 0)
+	if err != nil {
+		t.Fatalf("VerifyCommits error: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[0].Verified() || statuses[0].Format != internalgit.SigningFormatOpenPGP {
+		t.Fatalf("expected one verified OpenPGP commit, got %+v", statuses)
+	}
+}
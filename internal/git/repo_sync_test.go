@@ -9,6 +9,7 @@ import (
 	"time"
 
 	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
@@ -241,3 +242,89 @@ func fetchLocalOriginMain(repoPath string) error {
 
 	return nil
 }
+
+func TestRemoteOperations_OfflineSkipsNetwork(t *testing.T) {
+	setMaxAncestorSearchDepth(t, 10)
+
+	if _, err := testutil.SetupTestRepo(t); err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+
+	remotePath := viper.GetString("remote-url")
+	if err := commitAndPushToRemote(t, remotePath, "remote-ahead.txt", "remote ahead"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+
+	viper.Set(network.OfflineKey, true)
+	t.Cleanup(func() { viper.Set(network.OfflineKey, false) })
+
+	if _, err := internalgit.GetRemoteSyncState(); !errors.Is(err, network.ErrOffline) {
+		t.Fatalf("GetRemoteSyncState error = %v, want offline", err)
+	}
+	if err := internalgit.CheckRemotePushPermission(); !errors.Is(err, network.ErrOffline) {
+		t.Fatalf("CheckRemotePushPermission error = %v, want offline", err)
+	}
+	if _, err := internalgit.PullRepo(); !errors.Is(err, network.ErrOffline) {
+		t.Fatalf("PullRepo error = %v, want offline", err)
+	}
+	if err := internalgit.PushRepo(); !errors.Is(err, network.ErrOffline) {
+		t.Fatalf("PushRepo error = %v, want offline", err)
+	}
+
+	viper.Set(network.OfflineKey, false)
+	state, err := internalgit.GetRemoteSyncState()
+	if err != nil {
+		t.Fatalf("GetRemoteSyncState error: %v", err)
+	}
+	if state != internalgit.RemoteSyncRemoteAhead {
+		t.Fatalf("offline calls should not have pulled anything, state = %q", state)
+	}
+}
+
+func TestPullRepoWithOptions_RejectedVerifyLeavesHead(t *testing.T) {
+	r, err := testutil.SetupTestRepo(t)
+	if err != nil {
+		t.Fatalf("setup repo: %v", err)
+	}
+	repoPath := viper.GetString("repo-path")
+	if err := commitAndPushToRemote(t, viper.GetString("remote-url"), "unsigned.txt", "unsigned"); err != nil {
+		t.Fatalf("commit remote: %v", err)
+	}
+	before, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := errors.New("not verified")
+	var checked internalgit.PullResult
+	_, err = internalgit.PullRepoWithOptions(internalgit.PullOptions{Verify: func(result internalgit.PullResult) error {
+		checked = result
+		return rejected
+	}})
+	if !errors.Is(err, rejected) {
+		t.Fatalf("PullRepoWithOptions error = %v, want the verify error", err)
+	}
+	if checked.OldHead != before.Hash() || checked.NewHead == before.Hash() {
+		t.Fatalf("verify was called with %+v", checked)
+	}
+
+	after, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Hash() != before.Hash() {
+		t.Fatalf("HEAD moved from %s to %s after a rejected pull", before.Hash(), after.Hash())
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "unsigned.txt")); !os.IsNotExist(err) {
+		t.Fatalf("the rejected commit was checked out: %v", err)
+	}
+
+	// Accepted, the same pull is applied
+	result, err := internalgit.PullRepoWithOptions(internalgit.PullOptions{Verify: func(internalgit.PullResult) error { return nil }})
+	if err != nil || !result.Updated || result.NewHead != checked.NewHead {
+		t.Fatalf("verified pull: %+v, %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "unsigned.txt")); err != nil {
+		t.Fatalf("the verified commit was not checked out: %v", err)
+	}
+}
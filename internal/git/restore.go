@@ -0,0 +1,240 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+// FileRevision pairs the current content of a repository path with the content it is restored to.
+type FileRevision struct {
+	Path     string
+	Current  []byte // nil when the file does not exist in the worktree
+	Restored []byte // nil when the file did not exist at the revision
+}
+
+// Changed reports whether restoring the file would change it.
+func (f FileRevision) Changed() bool {
+	if (f.Current == nil) != (f.Restored == nil) {
+		return true
+	}
+	return !bytes.Equal(f.Current, f.Restored)
+}
+
+// Diff returns a unified diff from the current content to the restored content.
+func (f FileRevision) Diff() string {
+	return UnifiedDiff(f.Path, f.Current, f.Restored)
+}
+
+// dateLayouts are the date formats accepted by ResolveRevisionOrDate, most specific first.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ResolveRevisionOrDate resolves a commit reference (hash, branch, HEAD~2, ...) or a date.
+// A date resolves to the newest commit on the current branch made at or before it;
+// a date without a time means the end of that day.
+func ResolveRevisionOrDate(spec string) (plumbing.Hash, error) {
+	r, err := openRepo()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if hash, err := r.ResolveRevision(plumbing.Revision(spec)); err == nil {
+		return *hash, nil
+	}
+
+	var at time.Time
+	parsed := false
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, spec, time.Local)
+		if err != nil {
+			continue
+		}
+		at = t
+		if layout == "2006-01-02" {
+			at = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		parsed = true
+		break
+	}
+	if !parsed {
+		return plumbing.ZeroHash, fmt.Errorf("%s is neither a commit nor a date (use YYYY-MM-DD or YYYY-MM-DD HH:MM)", spec)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	commits, err := r.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer commits.Close()
+
+	for {
+		commit, err := commits.Next()
+		if errors.Is(err, io.EOF) || isShallowBoundary(r, err) {
+			break
+		}
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read history: %w", err)
+		}
+		if !commit.Committer.When.After(at) {
+			return commit.Hash, nil
+		}
+	}
+
+	return plumbing.ZeroHash, fmt.Errorf("no commit found at or before %s", spec)
+}
+
+// PlanRestore compares the files matching filter at a revision with the worktree.
+// Files matching filter that exist now but not at the revision are included so they get deleted.
+func PlanRestore(hash plumbing.Hash, filter HistoryFilter) ([]FileRevision, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	files, err := commitFiles(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := map[string]*FileRevision{}
+
+	for p, entry := range files {
+		if !filter.matchesPath(p) {
+			continue
+		}
+		content, err := readEntry(r, entry, true)
+		if err != nil {
+			return nil, err
+		}
+		revisions[p] = &FileRevision{Path: p, Restored: content}
+	}
+
+	repoPath := viper.GetString("repo-path")
+	current, err := worktreeFiles(repoPath, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range current {
+		if !filter.matchesPath(p) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if revisions[p] == nil {
+			revisions[p] = &FileRevision{Path: p}
+		}
+		revisions[p].Current = content
+	}
+
+	result := make([]FileRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		result = append(result, *revision)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	return result, nil
+}
+
+// worktreeFiles lists the files in the worktree that match filter's paths and prefixes.
+func worktreeFiles(repoPath string, filter HistoryFilter) ([]string, error) {
+	var paths []string
+	for _, p := range filter.Paths {
+		if info, err := os.Stat(filepath.Join(repoPath, filepath.FromSlash(p))); err == nil && !info.IsDir() {
+			paths = append(paths, p)
+		}
+	}
+
+	for _, prefix := range filter.Prefixes {
+		root := filepath.Join(repoPath, filepath.FromSlash(strings.TrimSuffix(prefix, "/")))
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(repoPath, p)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+	}
+
+	return paths, nil
+}
+
+// ApplyRestore writes the restored content of each changed file into the worktree and stages it.
+// Files that did not exist at the revision are removed.
+func ApplyRestore(revisions []FileRevision) error {
+	repoPath := viper.GetString("repo-path")
+	worktree, err := GetWorktree(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	for _, revision := range revisions {
+		if !revision.Changed() {
+			continue
+		}
+
+		fullPath := filepath.Join(repoPath, filepath.FromSlash(revision.Path))
+		if revision.Restored == nil {
+			if _, err := worktree.Remove(revision.Path); err != nil {
+				if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return fmt.Errorf("failed to remove %s: %w", revision.Path, err)
+				}
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", revision.Path, err)
+		}
+		// Write in place so hard links to the file keep pointing at the restored content.
+		if err := os.WriteFile(fullPath, revision.Restored, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", revision.Path, err)
+		}
+		if _, err := worktree.Add(revision.Path); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", revision.Path, err)
+		}
+	}
+
+	return nil
+}
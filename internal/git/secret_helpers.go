@@ -0,0 +1,51 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/interactive"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/secrets"
+)
+
+// DisplaySecretsError prints the findings of a commit blocked by the secret scanner and,
+// for staged files when prompt is true, offers to unstage them. Returns false if err is not a SecretsError.
+func DisplaySecretsError(err error, prompt bool) bool {
+	var secretsErr *SecretsError
+	if !errors.As(err, &secretsErr) {
+		return false
+	}
+
+	fileops.ColorPrintln("✗ Commit blocked: these changes look like they contain secrets", fileops.Red)
+	for _, f := range secretsErr.Findings {
+		fmt.Printf("  %s:%d  %s  %s  %s\n",
+			fileops.SColorPrint(f.Path, fileops.Cyan), f.Line, fileops.SColorPrint(f.Rule, fileops.Yellow), f.Match,
+			fileops.SColorPrint("fingerprint:"+f.Fingerprint, fileops.Reset))
+	}
+	fmt.Println()
+	fileops.ColorPrintfn(fileops.Reset, "If a finding is a false positive, add its fingerprint line or the file path to %s", secrets.AllowlistFileName)
+
+	if !secretsErr.Staged {
+		fileops.ColorPrintln("Nothing was merged; remove the secrets from the conflicting files or allowlist them and try again", fileops.Yellow)
+		return true
+	}
+
+	paths := secretsErr.Paths()
+	if !prompt {
+		fileops.ColorPrintln("The files are still staged; remove the secrets or allowlist them and commit again", fileops.Yellow)
+		return true
+	}
+
+	unstage, promptErr := interactive.PromptConfirm(fmt.Sprintf("Unstage %d files?", len(paths)))
+	if promptErr != nil || !unstage {
+		fileops.ColorPrintln("The files are still staged", fileops.Yellow)
+		return true
+	}
+	if err := UnstageFiles(paths); err != nil {
+		fileops.ColorPrintfn(fileops.Red, "Error unstaging files: %s", err)
+		return true
+	}
+	fileops.ColorPrintln("Unstaged; the files are unchanged on disk", fileops.Green)
+	return true
+}
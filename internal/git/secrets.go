@@ -0,0 +1,207 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/secrets"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/spf13/viper"
+)
+
+// SecretsError blocks a commit whose changes contain possible secrets.
+type SecretsError struct {
+	Findings []secrets.Finding
+	Staged   bool // The findings are in the index, so unstaging the files unblocks the commit
+}
+
+func (e *SecretsError) Error() string {
+	return fmt.Sprintf("possible secrets found in %s; nothing was committed", strings.Join(e.Paths(), ", "))
+}
+
+// Paths returns the files with findings, sorted and without duplicates.
+func (e *SecretsError) Paths() []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, f := range e.Findings {
+		if !seen[f.Path] {
+			seen[f.Path] = true
+			paths = append(paths, f.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// scanStaged scans the staged version of every added or modified file.
+func scanStaged(r *git.Repository, worktree *git.Worktree) error {
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read worktree status: %w", err)
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	staged := map[string]plumbing.Hash{}
+	for _, entry := range idx.Entries {
+		switch status.File(entry.Name).Staging {
+		case git.Added, git.Modified, git.Renamed, git.Copied:
+			if entry.Mode.IsFile() {
+				staged[entry.Name] = entry.Hash
+			}
+		}
+	}
+
+	err = scanBlobs(r, worktree.Filesystem.Root(), staged)
+	var secretsErr *SecretsError
+	if errors.As(err, &secretsErr) {
+		secretsErr.Staged = true
+	}
+	return err
+}
+
+// scanNewContent scans the files in tree whose content differs from every parent, which is
+// what a merge or rebase commit adds beyond the commits it combines.
+func scanNewContent(r *git.Repository, tree plumbing.Hash, parents []plumbing.Hash) error {
+	t, err := r.TreeObject(tree)
+	if err != nil {
+		return fmt.Errorf("failed to read tree: %w", err)
+	}
+	files, err := flattenTree(t)
+	if err != nil {
+		return err
+	}
+
+	parentFiles := make([]treeFiles, 0, len(parents))
+	for _, hash := range parents {
+		parent, err := r.CommitObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+		pf, err := commitFiles(parent)
+		if err != nil {
+			return err
+		}
+		parentFiles = append(parentFiles, pf)
+	}
+
+	changed := map[string]plumbing.Hash{}
+	for name, entry := range files {
+		if entry.Mode == filemode.Symlink || entry.Mode == filemode.Submodule {
+			continue
+		}
+		inParent := false
+		for _, pf := range parentFiles {
+			if existing, ok := pf[name]; ok && existing.Hash == entry.Hash {
+				inParent = true
+				break
+			}
+		}
+		if !inParent {
+			changed[name] = entry.Hash
+		}
+	}
+
+	root := viper.GetString("repo-path")
+	if worktree, err := r.Worktree(); err == nil {
+		root = worktree.Filesystem.Root()
+	}
+	return scanBlobs(r, root, changed)
+}
+
+// scanBlobs scans the given blobs and returns a SecretsError for findings not in the repository's allowlist.
+func scanBlobs(r *git.Repository, repoPath string, blobs map[string]plumbing.Hash) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	allowlist, err := secrets.LoadAllowlist(repoPath)
+	if err != nil {
+		return err
+	}
+
+	var findings []secrets.Finding
+	for name, hash := range blobs {
+		blob, err := r.BlobObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		findings = append(findings, allowlist.Filter(secrets.Scan(name, content))...)
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return &SecretsError{Findings: findings}
+}
+
+// UnstageFiles removes files from the index, restoring the version in HEAD if there is one.
+// The files themselves are left untouched in the worktree.
+func UnstageFiles(paths []string) error {
+	r, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var headFiles treeFiles
+	if head, err := r.Head(); err == nil {
+		commit, err := r.CommitObject(head.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to read HEAD commit: %w", err)
+		}
+		if headFiles, err = commitFiles(commit); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range paths {
+		headEntry, inHead := headFiles[name]
+		if !inHead {
+			if _, err := idx.Remove(name); err != nil {
+				return fmt.Errorf("failed to unstage %s: %w", name, err)
+			}
+			continue
+		}
+
+		entry, err := idx.Entry(name)
+		if err != nil {
+			entry = idx.Add(name)
+		}
+		entry.Hash = headEntry.Hash
+		entry.Mode = headEntry.Mode
+	}
+
+	if err := r.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CloneDepthKey is the config key for the default clone depth of init; 0 clones the full history.
+const CloneDepthKey = "clone.depth"
+
+// IsShallow reports whether the repository was cloned without its full history.
+func IsShallow(r *git.Repository) bool {
+	shallow, err := r.Storer.Shallow()
+	return err == nil && len(shallow) > 0
+}
+
+// isShallowBoundary reports whether err comes from walking past the oldest commit of a shallow clone.
+func isShallowBoundary(r *git.Repository, err error) bool {
+	return errors.Is(err, plumbing.ErrObjectNotFound) && IsShallow(r)
+}
+
+// withFullHistory runs fn and, if it needed a commit beyond the boundary of a shallow
+// clone, fetches the rest of the history from remoteName and runs fn again.
+func withFullHistory(ctx context.Context, r *git.Repository, remoteName string, fn func() error) error {
+	err := fn()
+	if !isShallowBoundary(r, err) {
+		return err
+	}
+
+	if err := unshallow(ctx, r, remoteName); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// unshallow fetches the history a shallow clone is missing.
+func unshallow(ctx context.Context, r *git.Repository, remoteName string) error {
+	// Like git fetch --unshallow, ask for "infinite" depth.
+	err := network.Err(ctx, network.Transfer, r.FetchContext(ctx, &git.FetchOptions{RemoteName: remoteName, Depth: math.MaxInt32}))
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch the full history from %s: %w", remoteName, err)
+	}
+
+	// go-git keeps the old boundary commits listed after deepening; all their parents are present now.
+	if err := r.Storer.SetShallow(nil); err != nil {
+		return fmt.Errorf("failed to update shallow commit list: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,491 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
+)
+
+// Config keys for commit signing.
+const (
+	SigningFormatKey         = "commit.signing.format"          // "openpgp" or "ssh"; empty disables signing
+	SigningKeyKey            = "commit.signing.key"             // Private key file, or an SSH public key held by ssh-agent
+	SigningKeyringKey        = "commit.signing.keyring"         // Armored OpenPGP public keys trusted when verifying
+	SigningAllowedSignersKey = "commit.signing.allowed-signers" // SSH allowed signers file trusted when verifying
+)
+
+// SigningPassphraseEnv holds the passphrase of an encrypted signing key for non-interactive use.
+const SigningPassphraseEnv = "OH_MY_DOT_SIGNING_PASSPHRASE"
+
+const (
+	SigningFormatOpenPGP = "openpgp"
+	SigningFormatSSH     = "ssh"
+)
+
+// loadedSigner caches the signer so an encrypted key is only unlocked once per run.
+var loadedSigner struct {
+	format, key string
+	signer      git.Signer
+}
+
+// commitSigner returns the configured signer, or nil when signing is disabled.
+func commitSigner() (git.Signer, error) {
+	format := viper.GetString(SigningFormatKey)
+	if format == "" {
+		return nil, nil
+	}
+
+	keyPath := viper.GetString(SigningKeyKey)
+	if keyPath == "" {
+		return nil, fmt.Errorf("commit signing is enabled but %s is not set", SigningKeyKey)
+	}
+	if loadedSigner.signer != nil && loadedSigner.format == format && loadedSigner.key == keyPath {
+		return loadedSigner.signer, nil
+	}
+
+	expanded, err := fileops.ExpandPath(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand signing key path: %w", err)
+	}
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	var signer git.Signer
+	switch format {
+	case SigningFormatOpenPGP:
+		signer, err = openPGPSigner(data, expanded)
+	case SigningFormatSSH:
+		signer, err = sshKeySigner(data, expanded)
+	default:
+		return nil, fmt.Errorf("unsupported signing format %q (use %s or %s)", format, SigningFormatOpenPGP, SigningFormatSSH)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loadedSigner.format, loadedSigner.key, loadedSigner.signer = format, keyPath, signer
+	return signer, nil
+}
+
+// pgpSigner signs git objects with an OpenPGP key.
+type pgpSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s *pgpSigner) Sign(message io.Reader) ([]byte, error) {
+	var out bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&out, s.entity, message, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign with OpenPGP key: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func readOpenPGPKeys(data []byte) (openpgp.EntityList, error) {
+	if entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data)); err == nil {
+		return entities, nil
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+func openPGPSigner(data []byte, keyPath string) (git.Signer, error) {
+	entities, err := readOpenPGPKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenPGP key %s: %w", keyPath, err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			passphrase, err := signingPassphrase(keyPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := entity.DecryptPrivateKeys(passphrase); err != nil {
+				return nil, fmt.Errorf("failed to unlock OpenPGP key: %w", err)
+			}
+		}
+		return &pgpSigner{entity: entity}, nil
+	}
+
+	return nil, fmt.Errorf("%s does not contain an OpenPGP private key", keyPath)
+}
+
+// sshKeySigner loads a private key, or uses ssh-agent when the key file is a public key.
+func sshKeySigner(data []byte, keyPath string) (git.Signer, error) {
+	if publicKey, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+		return sshAgentSigner(publicKey)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase, perr := signingPassphrase(keyPath)
+		if perr != nil {
+			return nil, perr
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key %s: %w", keyPath, err)
+	}
+
+	return &sshSigner{signer: signer}, nil
+}
+
+func sshAgentSigner(publicKey ssh.PublicKey) (git.Signer, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, errors.New("signing key is a public key but SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	for _, signer := range signers {
+		if bytes.Equal(signer.PublicKey().Marshal(), publicKey.Marshal()) {
+			return &sshSigner{signer: signer}, nil
+		}
+	}
+
+	return nil, errors.New("the signing key is not loaded in ssh-agent")
+}
+
+func signingPassphrase(keyPath string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(SigningPassphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("signing key %s is encrypted; set %s", keyPath, SigningPassphraseEnv)
+	}
+
+	fmt.Fprintf(os.Stderr, "Passphrase for %s: ", keyPath)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+// signCommitObject signs a commit built without the worktree, such as merge and rebased commits.
+func signCommitObject(commit *object.Commit) error {
+	signer, err := commitSigner()
+	if err != nil || signer == nil {
+		return err
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return fmt.Errorf("failed to encode commit for signing: %w", err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(reader)
+	if err != nil {
+		return err
+	}
+	commit.PGPSignature = string(signature)
+	return nil
+}
+
+// SignatureStatus is the result of verifying one commit's signature.
+type SignatureStatus struct {
+	Hash    plumbing.Hash
+	Subject string
+	Format  string // SigningFormatOpenPGP or SigningFormatSSH; empty when the commit is unsigned
+	Signer  string // Identity of the trusted key that made the signature
+	Err     error  // Why the commit is not verified
+}
+
+// Verified reports whether the commit has a valid signature from a trusted key.
+func (s SignatureStatus) Verified() bool {
+	return s.Format != "" && s.Err == nil
+}
+
+// VerifyCommits checks the signatures of the commits reachable from until but not from since.
+// An empty since verifies from the start of history; a limit above zero stops after that many commits.
+func VerifyCommits(since, until string, limit int) ([]SignatureStatus, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	if until == "" {
+		until = "HEAD"
+	}
+	to, err := r.ResolveRevision(plumbing.Revision(until))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", until, err)
+	}
+
+	from := plumbing.ZeroHash
+	if since != "" {
+		hash, err := r.ResolveRevision(plumbing.Revision(since))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", since, err)
+		}
+		from = *hash
+	}
+
+	return verifyRange(r, from, *to, limit)
+}
+
+// VerifyPulledCommits checks the signatures of the commits a pull brought in.
+func VerifyPulledCommits(result PullResult) ([]SignatureStatus, error) {
+	r, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+	return verifyRange(r, result.OldHead, result.NewHead, 0)
+}
+
+func verifyRange(r *git.Repository, from, to plumbing.Hash, limit int) ([]SignatureStatus, error) {
+	excluded := map[plumbing.Hash]bool{}
+	if !from.IsZero() {
+		commits, err := r.Log(&git.LogOptions{From: from})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		err = commits.ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+
+	trust, err := loadTrust()
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := r.Log(&git.LogOptions{From: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer commits.Close()
+
+	var statuses []SignatureStatus
+	for {
+		commit, err := commits.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		if excluded[commit.Hash] {
+			continue
+		}
+
+		statuses = append(statuses, trust.verify(commit))
+		if limit > 0 && len(statuses) >= limit {
+			break
+		}
+	}
+
+	return statuses, nil
+}
+
+// signatureTrust holds the keys whose signatures are accepted.
+type signatureTrust struct {
+	keyring        openpgp.EntityList
+	allowedSigners []allowedSigner
+}
+
+type allowedSigner struct {
+	principals []string // Email patterns the key may sign for; empty matches any committer
+	key        ssh.PublicKey
+}
+
+// loadTrust reads the configured keyring and allowed signers. Without them, the configured
+// signing key is trusted so a machine can verify its own commits.
+func loadTrust() (*signatureTrust, error) {
+	trust := &signatureTrust{}
+
+	if keyringPath := viper.GetString(SigningKeyringKey); keyringPath != "" {
+		data, err := readConfiguredFile(keyringPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenPGP keyring: %w", err)
+		}
+		if trust.keyring, err = readOpenPGPKeys(data); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenPGP keyring: %w", err)
+		}
+	}
+
+	if signersPath := viper.GetString(SigningAllowedSignersKey); signersPath != "" {
+		data, err := readConfiguredFile(signersPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read allowed signers: %w", err)
+		}
+		trust.allowedSigners = parseAllowedSigners(data)
+	}
+
+	keyPath := viper.GetString(SigningKeyKey)
+	if keyPath == "" {
+		return trust, nil
+	}
+	data, err := readConfiguredFile(keyPath)
+	if err != nil {
+		return trust, nil
+	}
+
+	switch viper.GetString(SigningFormatKey) {
+	case SigningFormatOpenPGP:
+		if trust.keyring == nil {
+			trust.keyring, _ = readOpenPGPKeys(data)
+		}
+	case SigningFormatSSH:
+		if trust.allowedSigners == nil {
+			if key := ownSSHPublicKey(data, keyPath); key != nil {
+				trust.allowedSigners = []allowedSigner{{key: key}}
+			}
+		}
+	}
+
+	return trust, nil
+}
+
+func readConfiguredFile(p string) ([]byte, error) {
+	expanded, err := fileops.ExpandPath(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(expanded)
+}
+
+// ownSSHPublicKey returns the public half of the configured SSH signing key without unlocking it.
+func ownSSHPublicKey(data []byte, keyPath string) ssh.PublicKey {
+	if key, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+		return key
+	}
+	if pub, err := readConfiguredFile(keyPath + ".pub"); err == nil {
+		if key, _, _, _, err := ssh.ParseAuthorizedKey(pub); err == nil {
+			return key
+		}
+	}
+	if signer, err := ssh.ParsePrivateKey(data); err == nil {
+		return signer.PublicKey()
+	}
+	return nil
+}
+
+// parseAllowedSigners reads git's gpg.ssh.allowedSignersFile format:
+// "principals [options] keytype base64-key [comment]" per line.
+func parseAllowedSigners(data []byte) []allowedSigner {
+	var signers []allowedSigner
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 1; i < len(fields); i++ {
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[i:], " ")))
+			if err != nil {
+				continue
+			}
+			signers = append(signers, allowedSigner{principals: strings.Split(fields[0], ","), key: key})
+			break
+		}
+	}
+	return signers
+}
+
+func (s allowedSigner) allows(key ssh.PublicKey, email string) bool {
+	if !bytes.Equal(s.key.Marshal(), key.Marshal()) {
+		return false
+	}
+	if len(s.principals) == 0 {
+		return true
+	}
+	for _, principal := range s.principals {
+		if matched, _ := path.Match(principal, email); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *signatureTrust) verify(commit *object.Commit) SignatureStatus {
+	status := SignatureStatus{Hash: commit.Hash, Subject: strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0]}
+	if commit.PGPSignature == "" {
+		status.Err = errors.New("not signed")
+		return status
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		status.Err = err
+		return status
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	if isSSHSignature(commit.PGPSignature) {
+		status.Format = SigningFormatSSH
+		key, err := verifySSHSignature(commit.PGPSignature, reader)
+		if err != nil {
+			status.Err = err
+			return status
+		}
+		for _, signer := range t.allowedSigners {
+			if signer.allows(key, commit.Committer.Email) {
+				status.Signer = ssh.FingerprintSHA256(key)
+				return status
+			}
+		}
+		status.Err = fmt.Errorf("signed by untrusted SSH key %s", ssh.FingerprintSHA256(key))
+		return status
+	}
+
+	status.Format = SigningFormatOpenPGP
+	if len(t.keyring) == 0 {
+		status.Err = fmt.Errorf("no OpenPGP keyring configured (%s)", SigningKeyringKey)
+		return status
+	}
+	entity, err := openpgp.CheckArmoredDetachedSignature(t.keyring, reader, strings.NewReader(commit.PGPSignature), nil)
+	if err != nil {
+		status.Err = fmt.Errorf("bad or untrusted OpenPGP signature: %w", err)
+		return status
+	}
+	status.Signer = fmt.Sprintf("%X", entity.PrimaryKey.KeyId)
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		status.Signer = identity.Name
+	}
+	return status
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
 )
 
 // DisplaySSHAgentError displays a helpful error message when SSH agent is not configured
@@ -26,6 +27,16 @@ func DisplaySSHAgentError(exitOnError bool) {
 // CheckRemoteAccessWithHelp checks remote push permissions and provides helpful error messages
 // exitOnError: if true, exits on error; if false, displays warning and continues
 func CheckRemoteAccessWithHelp(exitOnError bool) {
+	if network.Offline() {
+		// Commands that need the remote fail here instead of at their first network call.
+		if exitOnError {
+			fileops.ColorPrintln("Error: this command needs the remote, but offline mode is enabled", fileops.Red)
+			fileops.ColorPrintln("Run it without --offline, or set offline to false in the config", fileops.Yellow)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := CheckRemotePushPermission(); err != nil {
 		if IsSSHAgentError(err) {
 			DisplaySSHAgentError(exitOnError)
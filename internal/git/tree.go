@@ -0,0 +1,268 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// treeFiles is a flattened tree keyed by slash separated path.
+type treeFiles map[string]object.TreeEntry
+
+// flattenTree returns every non-directory entry of a tree keyed by its full path.
+// A nil tree is treated as empty, which is what a root commit's parent looks like.
+func flattenTree(tree *object.Tree) (treeFiles, error) {
+	files := treeFiles{}
+	if tree == nil {
+		return files, nil
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to walk tree: %w", err)
+		}
+
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+
+		entry.Name = path.Base(name)
+		files[name] = entry
+	}
+
+	return files, nil
+}
+
+// commitFiles flattens the tree of a commit. A nil commit yields an empty set.
+func commitFiles(commit *object.Commit) (treeFiles, error) {
+	if commit == nil {
+		return treeFiles{}, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+	}
+
+	return flattenTree(tree)
+}
+
+func sameEntry(a, b object.TreeEntry, aOK, bOK bool) bool {
+	if aOK != bOK {
+		return false
+	}
+	if !aOK {
+		return true
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// mergeTreeFiles performs a file level three-way merge.
+// A path is taken from whichever side changed it relative to base; paths
+// changed differently on both sides are returned as conflicts and keep the
+// ours version in the result.
+func mergeTreeFiles(base, ours, theirs treeFiles) (treeFiles, []string) {
+	result := treeFiles{}
+	var conflicts []string
+
+	paths := map[string]struct{}{}
+	for _, files := range []treeFiles{base, ours, theirs} {
+		for p := range files {
+			paths[p] = struct{}{}
+		}
+	}
+
+	for p := range paths {
+		b, bOK := base[p]
+		o, oOK := ours[p]
+		t, tOK := theirs[p]
+
+		switch {
+		case sameEntry(o, t, oOK, tOK):
+			if oOK {
+				result[p] = o
+			}
+		case sameEntry(o, b, oOK, bOK):
+			if tOK {
+				result[p] = t
+			}
+		case sameEntry(t, b, tOK, bOK):
+			if oOK {
+				result[p] = o
+			}
+		default:
+			if oOK {
+				result[p] = o
+			}
+			conflicts = append(conflicts, p)
+		}
+	}
+
+	sort.Strings(conflicts)
+	return result, conflicts
+}
+
+// writeTree stores the nested tree objects for a flattened file set and returns the root hash.
+func writeTree(s storer.EncodedObjectStorer, files treeFiles) (plumbing.Hash, error) {
+	children := map[string][]string{"": nil}
+	for p := range files {
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		children[dir] = append(children[dir], p)
+
+		// Register every ancestor directory so intermediate trees get written.
+		for dir != "" {
+			parent := path.Dir(dir)
+			if parent == "." {
+				parent = ""
+			}
+			if _, seen := children[dir]; !seen {
+				children[dir] = nil
+			}
+			if !slices.Contains(children[parent], dir+"/") {
+				children[parent] = append(children[parent], dir+"/")
+			}
+			dir = parent
+		}
+	}
+
+	var build func(dir string) (plumbing.Hash, error)
+	build = func(dir string) (plumbing.Hash, error) {
+		tree := &object.Tree{}
+		for _, child := range children[dir] {
+			if sub, isDir := strings.CutSuffix(child, "/"); isDir {
+				hash, err := build(sub)
+				if err != nil {
+					return plumbing.ZeroHash, err
+				}
+				tree.Entries = append(tree.Entries, object.TreeEntry{Name: path.Base(sub), Mode: filemode.Dir, Hash: hash})
+				continue
+			}
+
+			entry := files[child]
+			entry.Name = path.Base(child)
+			tree.Entries = append(tree.Entries, entry)
+		}
+
+		sort.Slice(tree.Entries, func(i, j int) bool {
+			return treeSortName(tree.Entries[i]) < treeSortName(tree.Entries[j])
+		})
+
+		obj := s.NewEncodedObject()
+		if err := tree.Encode(obj); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to encode tree: %w", err)
+		}
+
+		return s.SetEncodedObject(obj)
+	}
+
+	return build("")
+}
+
+// treeSortName mirrors git's tree ordering, where directories sort as if they had a trailing slash.
+func treeSortName(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
+
+// createCommit writes a commit object with the given tree and parents.
+// The committer is the configured identity, falling back to the author, and the
+// message gets this machine's trailer unless it already has one. It is signed when signing is configured.
+// Content that is in none of the parents, such as hand-resolved conflicts, is scanned for secrets first.
+func createCommit(r *git.Repository, tree plumbing.Hash, parents []plumbing.Hash, author object.Signature, message string) (plumbing.Hash, error) {
+	if err := scanNewContent(r, tree, parents); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	committer := author
+	if signature, err := configuredSignature(r); err == nil {
+		committer = signature
+	}
+
+	commit := &object.Commit{
+		Author:       author,
+		Committer:    committer,
+		Message:      withMachineTrailer(message),
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+
+	if err := signCommitObject(commit); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode commit: %w", err)
+	}
+
+	return r.Storer.SetEncodedObject(obj)
+}
+
+// applyCommitOnto replays the changes introduced by commit on top of the tree of onto
+// and returns the hash of the resulting tree. In conflicts the replayed commit is
+// "ours" and onto is "theirs".
+func applyCommitOnto(r *git.Repository, commit, onto *object.Commit, resolve ConflictResolver) (plumbing.Hash, error) {
+	var parent *object.Commit
+	if commit.NumParents() > 0 {
+		p, err := commit.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read parent of %s: %w", commit.Hash, err)
+		}
+		parent = p
+	}
+
+	base, err := commitFiles(parent)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	picked, err := commitFiles(commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	target, err := commitFiles(onto)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	merged, err := mergeTrees(r, base, picked, target, resolve, commit.Hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return writeTree(r.Storer, merged)
+}
+
+// ConflictError reports paths that could not be merged automatically.
+type ConflictError struct {
+	Commit plumbing.Hash
+	Paths  []string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Commit.IsZero() {
+		return fmt.Sprintf("conflicting changes in %s", strings.Join(e.Paths, ", "))
+	}
+	return fmt.Sprintf("commit %s conflicts in %s", e.Commit.String()[:7], strings.Join(e.Paths, ", "))
+}
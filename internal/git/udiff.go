@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff returns a git-style unified diff from old to new content for path.
+// A nil old or new means the file is created or deleted. Equal contents return an empty string.
+func UnifiedDiff(path string, old, new []byte) string {
+	a, b := splitLines(old), splitLines(new)
+	hunks, ok := diffLines(a, b)
+	if !ok {
+		hunks = []lineHunk{{baseEnd: len(a), otherEnd: len(b)}}
+	}
+	if len(hunks) == 0 && (old == nil) == (new == nil) {
+		return ""
+	}
+
+	var out strings.Builder
+	from, to := "a/"+path, "b/"+path
+	if old == nil {
+		from = "/dev/null"
+	}
+	if new == nil {
+		to = "/dev/null"
+	}
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+
+	for start := 0; start < len(hunks); {
+		end := start + 1
+		for end < len(hunks) && hunks[end].baseStart-hunks[end-1].baseEnd <= 2*diffContext {
+			end++
+		}
+		writeHunkGroup(&out, hunks[start:end], a, b)
+		start = end
+	}
+
+	return out.String()
+}
+
+func writeHunkGroup(out *strings.Builder, group []lineHunk, a, b []string) {
+	first, last := group[0], group[len(group)-1]
+	baseFrom := max(0, first.baseStart-diffContext)
+	baseTo := min(len(a), last.baseEnd+diffContext)
+	otherFrom := first.otherStart - (first.baseStart - baseFrom)
+	otherTo := last.otherEnd + (baseTo - last.baseEnd)
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(baseFrom, baseTo-baseFrom), hunkRange(otherFrom, otherTo-otherFrom))
+
+	i := baseFrom
+	for _, h := range group {
+		writeDiffLines(out, " ", a[i:h.baseStart])
+		writeDiffLines(out, "-", a[h.baseStart:h.baseEnd])
+		writeDiffLines(out, "+", b[h.otherStart:h.otherEnd])
+		i = h.baseEnd
+	}
+	writeDiffLines(out, " ", a[i:baseTo])
+}
+
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
+
+func writeDiffLines(out *strings.Builder, prefix string, lines []string) {
+	for _, line := range lines {
+		out.WriteString(prefix)
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
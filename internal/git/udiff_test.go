@@ -0,0 +1,45 @@
+package git
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  []byte
+		new  []byte
+		want string
+	}{
+		{
+			name: "equal content",
+			old:  []byte("a\n"),
+			new:  []byte("a\n"),
+			want: "",
+		},
+		{
+			name: "changed line with context",
+			old:  []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n"),
+			new:  []byte("1\n2\n3\n4\nfive\n6\n7\n8\n9\n"),
+			want: "--- a/f\n+++ b/f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "new file",
+			old:  nil,
+			new:  []byte("x\n"),
+			want: "--- /dev/null\n+++ b/f\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "deleted file without trailing newline",
+			old:  []byte("x"),
+			new:  nil,
+			want: "--- a/f\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("f", tt.old, tt.new); got != tt.want {
+				t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package hosting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/network"
+)
+
+// Created describes a repository created through a provider API.
+type Created struct {
+	HTTPSURL string
+	SSHURL   string
+	WebURL   string
+}
+
+// Client talks to the API of one provider.
+type Client struct {
+	Provider Provider
+	APIURL   string // API root, e.g. https://api.github.com
+	Token    string
+	HTTP     *http.Client // Defaults to http.DefaultClient
+}
+
+// apiError is a non-2xx response.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("API returned %d %s", e.status, http.StatusText(e.status))
+	}
+	return fmt.Sprintf("API returned %d %s: %s", e.status, http.StatusText(e.status), e.message)
+}
+
+// CreateRepository creates repo, owned by the token's user or by the organization or
+// group named as its owner. Returns ErrExists if the repository is already there.
+func (c *Client) CreateRepository(ctx context.Context, repo Repository, private bool) (Created, error) {
+	ctx, cancel, err := network.Context(ctx, network.Transfer)
+	if err != nil {
+		return Created{}, err
+	}
+	defer cancel()
+
+	var created Created
+	switch c.Provider {
+	case GitHub, Gitea:
+		created, err = c.createGitHubStyle(ctx, repo, private)
+	case GitLab:
+		created, err = c.createGitLab(ctx, repo, private)
+	default:
+		err = fmt.Errorf("unknown provider %q", c.Provider)
+	}
+	if err != nil {
+		return Created{}, fmt.Errorf("failed to create %s on %s: %w", repo, c.Provider.Title(), network.Err(ctx, network.Transfer, err))
+	}
+	return created, nil
+}
+
+// createGitHubStyle creates a repository on GitHub or Gitea, whose APIs share their shape.
+func (c *Client) createGitHubStyle(ctx context.Context, repo Repository, private bool) (Created, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return Created{}, err
+	}
+
+	endpoint := "/user/repos"
+	if !strings.EqualFold(user.Login, repo.Owner) {
+		endpoint = "/orgs/" + url.PathEscape(repo.Owner) + "/repos"
+	}
+
+	var response struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+	}
+	body := map[string]any{"name": repo.Name, "private": private}
+	if err := c.do(ctx, http.MethodPost, endpoint, body, &response); err != nil {
+		return Created{}, c.existsError(err)
+	}
+	return Created{HTTPSURL: response.CloneURL, SSHURL: response.SSHURL, WebURL: response.HTMLURL}, nil
+}
+
+func (c *Client) createGitLab(ctx context.Context, repo Repository, private bool) (Created, error) {
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return Created{}, err
+	}
+
+	visibility := "public"
+	if private {
+		visibility = "private"
+	}
+	body := map[string]any{"name": repo.Name, "path": repo.Name, "visibility": visibility}
+
+	if !strings.EqualFold(user.Username, repo.Owner) {
+		var namespace struct {
+			ID int `json:"id"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(repo.Owner), nil, &namespace); err != nil {
+			return Created{}, fmt.Errorf("failed to find group %s: %w", repo.Owner, err)
+		}
+		body["namespace_id"] = namespace.ID
+	}
+
+	var response struct {
+		HTTPURL string `json:"http_url_to_repo"`
+		SSHURL  string `json:"ssh_url_to_repo"`
+		WebURL  string `json:"web_url"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/projects", body, &response); err != nil {
+		return Created{}, c.existsError(err)
+	}
+	return Created{HTTPSURL: response.HTTPURL, SSHURL: response.SSHURL, WebURL: response.WebURL}, nil
+}
+
+// existsError turns the provider's "name already taken" response into ErrExists.
+func (c *Client) existsError(err error) error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	message := strings.ToLower(apiErr.message)
+	switch {
+	case c.Provider == Gitea && apiErr.status == http.StatusConflict,
+		c.Provider == GitHub && apiErr.status == http.StatusUnprocessableEntity && strings.Contains(message, "already exists"),
+		c.Provider == GitLab && apiErr.status == http.StatusBadRequest && strings.Contains(message, "already been taken"):
+		return ErrExists
+	}
+	return err
+}
+
+// do sends a JSON request to the API and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, endpoint string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.APIURL, "/")+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch c.Provider {
+	case GitHub:
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	case GitLab:
+		req.Header.Set("PRIVATE-TOKEN", c.Token)
+	case Gitea:
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return &apiError{status: resp.StatusCode, message: "the token was rejected"}
+		}
+		return &apiError{status: resp.StatusCode, message: errorMessage(data)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts a readable message from an API error body.
+// GitHub and Gitea send {"message": "..."}, GitHub adds "errors", and GitLab may send a map of field errors.
+func errorMessage(data []byte) string {
+	var body struct {
+		Message any `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	parts := []string{}
+	switch message := body.Message.(type) {
+	case string:
+		parts = append(parts, message)
+	case map[string]any:
+		fields := make([]string, 0, len(message))
+		for field, value := range message {
+			fields = append(fields, fmt.Sprintf("%s %v", field, value))
+		}
+		sort.Strings(fields)
+		parts = append(parts, fields...)
+	}
+	for _, e := range body.Errors {
+		parts = append(parts, e.Message)
+	}
+	return strings.Join(parts, "; ")
+}
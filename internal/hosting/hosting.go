@@ -0,0 +1,156 @@
+package hosting
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Provider is a git hosting service with an API for creating repositories.
+type Provider string
+
+const (
+	GitHub Provider = "github"
+	GitLab Provider = "gitlab"
+	Gitea  Provider = "gitea"
+)
+
+// Providers lists the supported providers in the order they are shown to users.
+var Providers = []Provider{GitHub, GitLab, Gitea}
+
+// ErrExists is returned when the repository to create is already there.
+var ErrExists = errors.New("repository already exists")
+
+// knownHosts maps public hosts to their provider; self-hosted instances need an explicit provider.
+var knownHosts = map[string]Provider{
+	"github.com":   GitHub,
+	"gitlab.com":   GitLab,
+	"gitea.com":    Gitea,
+	"codeberg.org": Gitea,
+}
+
+// Repository names a repository on a hosting provider.
+type Repository struct {
+	Host  string // Host name, with a port if the remote URL had one
+	Owner string // User, organization or group, e.g. "me" or "group/subgroup"
+	Name  string // Repository name without .git
+}
+
+// ParseRepository reads the host, owner and name from a remote URL.
+// It accepts https://host/owner/name, ssh://git@host/owner/name, git@host:owner/name
+// host/owner/name and provider:owner/name, each with or without a .git suffix.
+func ParseRepository(remote string) (Repository, error) {
+	remote = strings.TrimSpace(remote)
+
+	var host, repoPath string
+	switch expanded, ok := expandPrefix(remote); {
+	case ok:
+		host, repoPath, _ = strings.Cut(expanded, "/")
+	case strings.Contains(remote, "://"):
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Repository{}, fmt.Errorf("invalid remote URL %q: %w", remote, err)
+		}
+		host, repoPath = u.Host, u.Path
+		if u.Scheme == "ssh" {
+			host = u.Hostname() // SSH ports are not the API's port
+		}
+	case strings.Contains(remote, "@") && strings.Contains(remote, ":"):
+		// scp-like syntax: git@host:owner/name.git
+		userHost, p, _ := strings.Cut(remote, ":")
+		_, host, _ = strings.Cut(userHost, "@")
+		repoPath = p
+	default:
+		host, repoPath, _ = strings.Cut(remote, "/")
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	slash := strings.LastIndex(repoPath, "/")
+	if host == "" || slash <= 0 || slash == len(repoPath)-1 {
+		return Repository{}, fmt.Errorf("remote %q does not name a repository as host/owner/name", remote)
+	}
+
+	return Repository{Host: host, Owner: repoPath[:slash], Name: repoPath[slash+1:]}, nil
+}
+
+// HTTPSURL returns the HTTPS clone URL of the repository.
+func (r Repository) HTTPSURL() string {
+	return fmt.Sprintf("https://%s/%s/%s.git", r.Host, r.Owner, r.Name)
+}
+
+// SSHURL returns the SSH clone URL of the repository in scp-like syntax.
+func (r Repository) SSHURL() string {
+	host, _, _ := strings.Cut(r.Host, ":")
+	return fmt.Sprintf("git@%s:%s/%s.git", host, r.Owner, r.Name)
+}
+
+func (r Repository) String() string {
+	return r.Host + "/" + r.Owner + "/" + r.Name
+}
+
+// DetectProvider returns the provider of a well-known host.
+func DetectProvider(host string) (Provider, bool) {
+	provider, ok := knownHosts[strings.ToLower(host)]
+	return provider, ok
+}
+
+// ParseProvider validates a provider name.
+func ParseProvider(name string) (Provider, error) {
+	for _, provider := range Providers {
+		if strings.EqualFold(name, string(provider)) {
+			return provider, nil
+		}
+	}
+	return "", fmt.Errorf("unknown provider %q: use github, gitlab or gitea", name)
+}
+
+// DefaultAPIURL returns the API root of the provider on host.
+func (p Provider) DefaultAPIURL(host string) string {
+	switch p {
+	case GitHub:
+		if strings.EqualFold(host, "github.com") {
+			return "https://api.github.com"
+		}
+		return "https://" + host + "/api/v3" // GitHub Enterprise Server
+	case GitLab:
+		return "https://" + host + "/api/v4"
+	default:
+		return "https://" + host + "/api/v1"
+	}
+}
+
+// TokenEnvVars lists the environment variables a token for the provider is read from, in order.
+func (p Provider) TokenEnvVars() []string {
+	switch p {
+	case GitHub:
+		return []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	case GitLab:
+		return []string{"GITLAB_TOKEN"}
+	default:
+		return []string{"GITEA_TOKEN"}
+	}
+}
+
+// Title returns the display name of the provider.
+func (p Provider) Title() string {
+	switch p {
+	case GitHub:
+		return "GitHub"
+	case GitLab:
+		return "GitLab"
+	default:
+		return "Gitea"
+	}
+}
+
+// TokenFromEnv returns the first token set in the provider's environment variables.
+func TokenFromEnv(p Provider) string {
+	for _, name := range p.TokenEnvVars() {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token
+		}
+	}
+	return ""
+}
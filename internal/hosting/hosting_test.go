@@ -0,0 +1,292 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		remote  string
+		want    Repository
+		wantErr bool
+	}{
+		{remote: "https://github.com/me/dotfiles.git", want: Repository{"github.com", "me", "dotfiles"}},
+		{remote: "https://gitlab.com/group/sub/dotfiles", want: Repository{"gitlab.com", "group/sub", "dotfiles"}},
+		{remote: "git@github.com:me/dotfiles.git", want: Repository{"github.com", "me", "dotfiles"}},
+		{remote: "ssh://git@git.example.com:2222/me/dotfiles.git", want: Repository{"git.example.com", "me", "dotfiles"}},
+		{remote: "github.com/me/dotfiles", want: Repository{"github.com", "me", "dotfiles"}},
+		{remote: "http://localhost:3000/me/dotfiles", want: Repository{"localhost:3000", "me", "dotfiles"}},
+		{remote: "gh:me/dotfiles", want: Repository{"github.com", "me", "dotfiles"}},
+		{remote: "gitlab:group/sub/dotfiles.git", want: Repository{"gitlab.com", "group/sub", "dotfiles"}},
+		{remote: "github.com/me", wantErr: true},
+		{remote: "dotfiles", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			got, err := ParseRepository(tt.remote)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRepository error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseRepository = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryURLs(t *testing.T) {
+	repo := Repository{Host: "github.com", Owner: "me", Name: "dotfiles"}
+	if got := repo.HTTPSURL(); got != "https://github.com/me/dotfiles.git" {
+		t.Fatalf("HTTPSURL = %q", got)
+	}
+	if got := repo.SSHURL(); got != "git@github.com:me/dotfiles.git" {
+		t.Fatalf("SSHURL = %q", got)
+	}
+}
+
+func TestResolveRemote(t *testing.T) {
+	local := t.TempDir()
+	tests := []struct {
+		remote   string
+		protocol string
+		want     string
+	}{
+		{"github.com/me/dotfiles", ProtocolHTTPS, "https://github.com/me/dotfiles.git"},
+		{"github.com/me/dotfiles", ProtocolSSH, "git@github.com:me/dotfiles.git"},
+		{"gh:me/dotfiles", ProtocolSSH, "git@github.com:me/dotfiles.git"},
+		{"github:me/dotfiles", ProtocolHTTPS, "https://github.com/me/dotfiles.git"},
+		{"gitlab:group/sub/dotfiles", ProtocolSSH, "git@gitlab.com:group/sub/dotfiles.git"},
+		{"codeberg:me/dotfiles", ProtocolHTTPS, "https://codeberg.org/me/dotfiles.git"},
+		// Full URLs and local paths are left alone.
+		{"https://github.com/me/dotfiles.git", ProtocolSSH, "https://github.com/me/dotfiles.git"},
+		{"git@github.com:me/dotfiles.git", ProtocolHTTPS, "git@github.com:me/dotfiles.git"},
+		{local, ProtocolSSH, local},
+		{"../remote.git", ProtocolSSH, "../remote.git"},
+		{"dotfiles/me/repo", ProtocolSSH, "dotfiles/me/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remote+" "+tt.protocol, func(t *testing.T) {
+			got, err := ResolveRemote(tt.remote, tt.protocol)
+			if err != nil {
+				t.Fatalf("ResolveRemote error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ResolveRemote = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSSHKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if key := DefaultSSHKey(); key != "" {
+		t.Fatalf("DefaultSSHKey = %q without keys", key)
+	}
+
+	sshDir := filepath.Join(home, ".ssh")
+	if err := os.MkdirAll(sshDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id_rsa", "id_ed25519"} {
+		if err := os.WriteFile(filepath.Join(sshDir, name), []byte("key"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if key := DefaultSSHKey(); key != filepath.Join(sshDir, "id_ed25519") {
+		t.Fatalf("DefaultSSHKey = %q, want id_ed25519 first", key)
+	}
+}
+
+// request is what the stand-in API server saw.
+type request struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]any
+}
+
+// fakeAPI serves the user endpoint and records the create request.
+func fakeAPI(t *testing.T, user map[string]any, createStatus int, createResponse map[string]any) (*httptest.Server, *[]request) {
+	t.Helper()
+
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path}
+		req.auth = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&req.body)
+		}
+		requests = append(requests, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/user":
+			_ = json.NewEncoder(w).Encode(user)
+		case r.Method == http.MethodGet && r.URL.Path == "/namespaces/team":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 42})
+		case r.Method == http.MethodPost:
+			w.WriteHeader(createStatus)
+			_ = json.NewEncoder(w).Encode(createResponse)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestCreateRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		owner    string
+		user     map[string]any
+		response map[string]any
+		wantPath string
+		wantAuth string
+		wantBody map[string]any
+		wantURL  string
+	}{
+		{
+			name:     "github user",
+			provider: GitHub,
+			owner:    "me",
+			user:     map[string]any{"login": "me"},
+			response: map[string]any{"clone_url": "https://github.com/me/dotfiles.git", "ssh_url": "git@github.com:me/dotfiles.git"},
+			wantPath: "/user/repos",
+			wantAuth: "Bearer secret",
+			wantBody: map[string]any{"name": "dotfiles", "private": true},
+			wantURL:  "https://github.com/me/dotfiles.git",
+		},
+		{
+			name:     "github organization",
+			provider: GitHub,
+			owner:    "team",
+			user:     map[string]any{"login": "me"},
+			response: map[string]any{"clone_url": "https://github.com/team/dotfiles.git"},
+			wantPath: "/orgs/team/repos",
+			wantAuth: "Bearer secret",
+			wantBody: map[string]any{"name": "dotfiles", "private": true},
+			wantURL:  "https://github.com/team/dotfiles.git",
+		},
+		{
+			name:     "gitlab user",
+			provider: GitLab,
+			owner:    "me",
+			user:     map[string]any{"username": "me"},
+			response: map[string]any{"http_url_to_repo": "https://gitlab.com/me/dotfiles.git"},
+			wantPath: "/projects",
+			wantAuth: "secret",
+			wantBody: map[string]any{"name": "dotfiles", "path": "dotfiles", "visibility": "private"},
+			wantURL:  "https://gitlab.com/me/dotfiles.git",
+		},
+		{
+			name:     "gitlab group",
+			provider: GitLab,
+			owner:    "team",
+			user:     map[string]any{"username": "me"},
+			response: map[string]any{"http_url_to_repo": "https://gitlab.com/team/dotfiles.git"},
+			wantPath: "/projects",
+			wantAuth: "secret",
+			wantBody: map[string]any{"name": "dotfiles", "path": "dotfiles", "visibility": "private", "namespace_id": float64(42)},
+			wantURL:  "https://gitlab.com/team/dotfiles.git",
+		},
+		{
+			name:     "gitea user",
+			provider: Gitea,
+			owner:    "me",
+			user:     map[string]any{"login": "me"},
+			response: map[string]any{"clone_url": "https://codeberg.org/me/dotfiles.git"},
+			wantPath: "/user/repos",
+			wantAuth: "token secret",
+			wantBody: map[string]any{"name": "dotfiles", "private": true},
+			wantURL:  "https://codeberg.org/me/dotfiles.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := fakeAPI(t, tt.user, http.StatusCreated, tt.response)
+			client := &Client{Provider: tt.provider, APIURL: server.URL, Token: "secret"}
+
+			created, err := client.CreateRepository(context.Background(), Repository{Host: "example.com", Owner: tt.owner, Name: "dotfiles"}, true)
+			if err != nil {
+				t.Fatalf("CreateRepository error: %v", err)
+			}
+			if created.HTTPSURL != tt.wantURL {
+				t.Fatalf("HTTPSURL = %q, want %q", created.HTTPSURL, tt.wantURL)
+			}
+
+			create := (*requests)[len(*requests)-1]
+			if create.method != http.MethodPost || create.path != tt.wantPath {
+				t.Fatalf("create request = %s %s, want POST %s", create.method, create.path, tt.wantPath)
+			}
+			if create.auth != tt.wantAuth {
+				t.Fatalf("auth header = %q, want %q", create.auth, tt.wantAuth)
+			}
+			if len(create.body) != len(tt.wantBody) {
+				t.Fatalf("body = %v, want %v", create.body, tt.wantBody)
+			}
+			for key, value := range tt.wantBody {
+				if create.body[key] != value {
+					t.Fatalf("body[%s] = %v, want %v", key, create.body[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateRepository_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   Provider
+		user       map[string]any
+		status     int
+		response   map[string]any
+		wantExists bool
+	}{
+		{"github exists", GitHub, map[string]any{"login": "me"}, http.StatusUnprocessableEntity,
+			map[string]any{"message": "Repository creation failed.", "errors": []any{map[string]any{"message": "name already exists on this account"}}}, true},
+		{"gitlab exists", GitLab, map[string]any{"username": "me"}, http.StatusBadRequest,
+			map[string]any{"message": map[string]any{"name": []any{"has already been taken"}}}, true},
+		{"gitea exists", Gitea, map[string]any{"login": "me"}, http.StatusConflict,
+			map[string]any{"message": "The repository with the same name already exists."}, true},
+		{"rejected token", GitHub, map[string]any{"login": "me"}, http.StatusUnauthorized,
+			map[string]any{"message": "Bad credentials"}, false},
+		{"forbidden", Gitea, map[string]any{"login": "me"}, http.StatusForbidden,
+			map[string]any{"message": "token does not have the required scope"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := fakeAPI(t, tt.user, tt.status, tt.response)
+			client := &Client{Provider: tt.provider, APIURL: server.URL, Token: "secret"}
+
+			_, err := client.CreateRepository(context.Background(), Repository{Host: "example.com", Owner: "me", Name: "dotfiles"}, true)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := errors.Is(err, ErrExists); got != tt.wantExists {
+				t.Fatalf("errors.Is(err, ErrExists) = %t, want %t (err: %v)", got, tt.wantExists, err)
+			}
+		})
+	}
+}
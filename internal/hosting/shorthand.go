@@ -0,0 +1,105 @@
+package hosting
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProtocolKey is the config key for the protocol shorthand remotes resolve to: "ssh" or "https".
+const ProtocolKey = "remote.protocol"
+
+const (
+	ProtocolSSH   = "ssh"
+	ProtocolHTTPS = "https"
+)
+
+// shorthandPrefixes maps the prefixes of provider:owner/name shorthands to their host.
+var shorthandPrefixes = map[string]string{
+	"gh":       "github.com",
+	"github":   "github.com",
+	"gitlab":   "gitlab.com",
+	"codeberg": "codeberg.org",
+}
+
+// sshKeyNames are the identity files ssh tries by default, in its order.
+var sshKeyNames = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// ParseProtocol validates a protocol name.
+func ParseProtocol(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ProtocolSSH:
+		return ProtocolSSH, nil
+	case ProtocolHTTPS:
+		return ProtocolHTTPS, nil
+	}
+	return "", fmt.Errorf("unknown protocol %q: use ssh or https", name)
+}
+
+// IsShorthand reports whether remote is a shorthand such as gh:owner/name or github.com/owner/name
+// rather than a URL or a local path go-git can use as is.
+func IsShorthand(remote string) bool {
+	remote = strings.TrimSpace(remote)
+	if _, ok := expandPrefix(remote); ok {
+		return true
+	}
+	if remote == "" || strings.Contains(remote, "://") || strings.Contains(remote, "@") ||
+		strings.ContainsAny(remote, `:\`) || filepath.IsAbs(remote) {
+		return false
+	}
+
+	// host/owner/name: the first element must look like a host name, and not be a path on disk.
+	host, rest, found := strings.Cut(remote, "/")
+	if !found || !strings.Contains(host, ".") || strings.HasPrefix(host, ".") || !strings.Contains(strings.Trim(rest, "/"), "/") {
+		return false
+	}
+	if _, err := os.Stat(remote); err == nil {
+		return false
+	}
+	return true
+}
+
+// ResolveRemote turns a shorthand remote into a full URL using protocol; other remotes are returned unchanged.
+func ResolveRemote(remote, protocol string) (string, error) {
+	if !IsShorthand(remote) {
+		return remote, nil
+	}
+
+	repo, err := ParseRepository(remote)
+	if err != nil {
+		return "", err
+	}
+	if protocol == ProtocolSSH {
+		return repo.SSHURL(), nil
+	}
+	return repo.HTTPSURL(), nil
+}
+
+// expandPrefix rewrites provider:owner/name as host/owner/name.
+func expandPrefix(remote string) (string, bool) {
+	prefix, rest, found := strings.Cut(remote, ":")
+	if !found {
+		return "", false
+	}
+	host, ok := shorthandPrefixes[strings.ToLower(prefix)]
+	if !ok || strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	return host + "/" + rest, true
+}
+
+// DefaultSSHKey returns the first of ssh's default identity files that exists in ~/.ssh, or "".
+func DefaultSSHKey() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range sshKeyNames {
+		path := filepath.Join(home, ".ssh", name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package interactive
+
+import (
+	"fmt"
+	"slices"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// PromptReorder lets the user rearrange a list and returns the items in their new order
+func PromptReorder(question string, options []string) ([]string, error) {
+	m := reorderModel{
+		question: question,
+		items:    slices.Clone(options),
+	}
+
+	p := tea.NewProgram(m)
+	result, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	if finalModel, ok := result.(reorderModel); ok {
+		if finalModel.cancelled {
+			return nil, fmt.Errorf("cancelled")
+		}
+		return finalModel.items, nil
+	}
+
+	return nil, fmt.Errorf("unexpected model type")
+}
+
+type reorderModel struct {
+	question  string
+	items     []string
+	cursor    int
+	grabbed   bool // Moving the cursor carries the item along
+	cancelled bool
+}
+
+func (m reorderModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m reorderModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			m = m.moveCursor(-1, m.grabbed)
+		case "down", "j":
+			m = m.moveCursor(1, m.grabbed)
+		case "shift+up", "K":
+			m = m.moveCursor(-1, true)
+		case "shift+down", "J":
+			m = m.moveCursor(1, true)
+		case "space":
+			m.grabbed = !m.grabbed
+		case "enter":
+			return m, tea.Quit
+		case "ctrl+c", "esc":
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// moveCursor moves the cursor by delta, swapping the item under it along when carry is set
+func (m reorderModel) moveCursor(delta int, carry bool) reorderModel {
+	target := m.cursor + delta
+	if target < 0 || target >= len(m.items) {
+		return m
+	}
+
+	if carry {
+		m.items = slices.Clone(m.items)
+		m.items[m.cursor], m.items[target] = m.items[target], m.items[m.cursor]
+	}
+	m.cursor = target
+	return m
+}
+
+func (m reorderModel) View() tea.View {
+	s := lipgloss.NewStyle().Bold(true).Render(m.question) + "\n\n"
+
+	for i, opt := range m.items {
+		cursor := " "
+		line := opt
+		if m.cursor == i {
+			cursor = lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Render("→")
+			if m.grabbed {
+				line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("42")).Render("≡ " + opt)
+			}
+		}
+
+		s += fmt.Sprintf("%s %d. %s\n", cursor, i+1, line)
+	}
+
+	s += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("(Use arrows to move, Space to pick up or drop, Shift+arrows to move the item, Enter to save, Esc to cancel)")
+
+	return tea.NewView(s)
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AliasConfig is an alias that the init script defines in the shell's own syntax
+type AliasConfig struct {
+	Name    string `json:"name"`
+	Command string `json:"command"` // Command line the alias expands to, in POSIX sh syntax
+}
+
+// aliasNameRegex validates alias names, which are written into init scripts
+var aliasNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.][a-zA-Z0-9_.+-]*$`)
+
+// ValidateAliasName checks if an alias name is valid
+func ValidateAliasName(name string) error {
+	if name == "" {
+		return fmt.Errorf("alias name cannot be empty")
+	}
+	if !aliasNameRegex.MatchString(name) || name == "." {
+		return fmt.Errorf("alias name must contain only alphanumeric characters, dots, underscores, plus signs, and hyphens, and cannot start with a hyphen")
+	}
+	return nil
+}
+
+// Validate validates an alias
+func (a *AliasConfig) Validate() error {
+	if err := ValidateAliasName(a.Name); err != nil {
+		return err
+	}
+	if strings.TrimSpace(a.Command) == "" {
+		return fmt.Errorf("alias '%s' has no command", a.Name)
+	}
+	if strings.ContainsAny(a.Command, "\n\r") {
+		return fmt.Errorf("alias '%s' must be a single line", a.Name)
+	}
+	return nil
+}
+
+// MergeAliases returns the base aliases with those of the local manifest applied: a local alias
+// replaces the base alias with the same name and other local aliases are appended
+func MergeAliases(base, local []AliasConfig) []AliasConfig {
+	merged := append([]AliasConfig{}, base...)
+	for _, alias := range local {
+		if i := aliasIndex(merged, alias.Name); i >= 0 {
+			merged[i] = alias
+		} else {
+			merged = append(merged, alias)
+		}
+	}
+	return merged
+}
+
+// SetAlias adds an alias to the manifest, replacing an alias with the same name
+func (m *FeatureManifest) SetAlias(alias AliasConfig) error {
+	if err := alias.Validate(); err != nil {
+		return err
+	}
+	if i := aliasIndex(m.Aliases, alias.Name); i >= 0 {
+		m.Aliases[i] = alias
+		return nil
+	}
+	m.Aliases = append(m.Aliases, alias)
+	return nil
+}
+
+// RemoveAlias removes an alias from the manifest
+func (m *FeatureManifest) RemoveAlias(name string) error {
+	i := aliasIndex(m.Aliases, name)
+	if i < 0 {
+		return fmt.Errorf("alias '%s' not found", name)
+	}
+	m.Aliases = append(m.Aliases[:i], m.Aliases[i+1:]...)
+	return nil
+}
+
+func aliasIndex(aliases []AliasConfig, name string) int {
+	for i, alias := range aliases {
+		if alias.Name == name {
+			return i
+		}
+	}
+	return -1
+}
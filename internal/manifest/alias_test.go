@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAliasConfigValidate(t *testing.T) {
+	valid := []AliasConfig{
+		{Name: "ll", Command: "ls -la"},
+		{Name: "..", Command: "cd .."},
+		{Name: "g++-11", Command: "g++ -std=c++11"},
+	}
+	for _, alias := range valid {
+		if err := alias.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", alias, err)
+		}
+	}
+
+	invalid := []AliasConfig{
+		{Name: "", Command: "ls"},
+		{Name: "-l", Command: "ls"},
+		{Name: ".", Command: "source"},
+		{Name: "a b", Command: "ls"},
+		{Name: "x;rm", Command: "ls"},
+		{Name: "ll", Command: "  "},
+		{Name: "ll", Command: "ls\nrm -rf ~"},
+	}
+	for _, alias := range invalid {
+		if err := alias.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", alias)
+		}
+	}
+}
+
+func TestParseManifest_Aliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enabled.json")
+	if err := os.WriteFile(path, []byte(`{"features": [], "aliases": [{"name": "ll", "command": ""}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseManifest(path); err == nil || !strings.Contains(err.Error(), "alias at index 0") {
+		t.Fatalf("expected an alias error, got %v", err)
+	}
+}
+
+func TestSetAndRemoveAlias(t *testing.T) {
+	m := &FeatureManifest{}
+	if err := m.SetAlias(AliasConfig{Name: "ll", Command: "ls -l"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAlias(AliasConfig{Name: "la", Command: "ls -A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAlias(AliasConfig{Name: "ll", Command: "ls -la"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Aliases) != 2 || m.Aliases[0].Command != "ls -la" {
+		t.Fatalf("expected ll to be replaced in place, got %+v", m.Aliases)
+	}
+	if err := m.SetAlias(AliasConfig{Name: "bad name", Command: "ls"}); err == nil {
+		t.Fatal("expected an invalid alias to be rejected")
+	}
+
+	if err := m.RemoveAlias("ll"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveAlias("ll"); err == nil {
+		t.Fatal("expected removing a missing alias to fail")
+	}
+	if len(m.Aliases) != 1 || m.Aliases[0].Name != "la" {
+		t.Fatalf("aliases = %+v, want only la", m.Aliases)
+	}
+}
+
+func TestMergeManifests_Aliases(t *testing.T) {
+	base := &FeatureManifest{Aliases: []AliasConfig{{Name: "ll", Command: "ls -l"}, {Name: "g", Command: "git"}}}
+	local := &FeatureManifest{Aliases: []AliasConfig{{Name: "k", Command: "kubectl"}, {Name: "ll", Command: "ls -la"}}}
+
+	merged := MergeManifests(base, local)
+	var got []string
+	for _, alias := range merged.Aliases {
+		got = append(got, alias.Name+"="+alias.Command)
+	}
+	if want := "ll=ls -la,g=git,k=kubectl"; strings.Join(got, ",") != want {
+		t.Fatalf("aliases = %s, want %s", strings.Join(got, ","), want)
+	}
+	if base.Aliases[0].Command != "ls -l" {
+		t.Fatal("merging should not change the base manifest")
+	}
+}
@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// EnvConfig declares environment variables and PATH entries that init scripts set before any feature loads.
+// It is read from omd-shells/env.json for every shell and from the env section of a shell's manifests.
+type EnvConfig struct {
+	Vars []EnvVar    `json:"vars,omitempty"`
+	Path []PathEntry `json:"path,omitempty"`
+}
+
+// EnvVar is an environment variable. Values may use ~ at the start and $NAME or ${NAME} references.
+type EnvVar struct {
+	Name  string   `json:"name"`
+	Value string   `json:"value"`
+	OS    []string `json:"os,omitempty"`    // Only set on these operating systems: linux, darwin or windows
+	Hosts []string `json:"hosts,omitempty"` // Only set on these hosts, by short host name
+}
+
+// PathEntry is a directory added to PATH. Entries are added in order and each directory appears once.
+type PathEntry struct {
+	Dir    string   `json:"dir"`
+	Append bool     `json:"append,omitempty"` // Add after the existing entries instead of before them
+	OS     []string `json:"os,omitempty"`     // Only added on these operating systems: linux, darwin or windows
+	Hosts  []string `json:"hosts,omitempty"`  // Only added on these hosts, by short host name
+}
+
+var (
+	// envNameRegex validates environment variable names
+	envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// hostNameRegex validates host names in env conditions
+	hostNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+	// validOS are the operating systems env conditions can name
+	validOS = map[string]bool{
+		"linux":   true,
+		"darwin":  true,
+		"windows": true,
+	}
+)
+
+// IsEmpty reports whether the config declares nothing
+func (e *EnvConfig) IsEmpty() bool {
+	return e == nil || (len(e.Vars) == 0 && len(e.Path) == 0)
+}
+
+// Validate validates the variables and PATH entries
+func (e *EnvConfig) Validate() error {
+	if e == nil {
+		return nil
+	}
+	for i, v := range e.Vars {
+		if !envNameRegex.MatchString(v.Name) {
+			return fmt.Errorf("env variable at index %d: invalid name '%s'", i, v.Name)
+		}
+		if v.Name == "PATH" {
+			return fmt.Errorf("env variable at index %d: set PATH with path entries", i)
+		}
+		if err := validateConditions(v.OS, v.Hosts); err != nil {
+			return fmt.Errorf("env variable '%s': %w", v.Name, err)
+		}
+	}
+	for i, p := range e.Path {
+		if p.Dir == "" {
+			return fmt.Errorf("path entry at index %d: dir cannot be empty", i)
+		}
+		if err := validateConditions(p.OS, p.Hosts); err != nil {
+			return fmt.Errorf("path entry '%s': %w", p.Dir, err)
+		}
+	}
+	return nil
+}
+
+func validateConditions(osNames, hosts []string) error {
+	for _, name := range osNames {
+		if !validOS[name] {
+			return fmt.Errorf("invalid os '%s': must be 'linux', 'darwin', or 'windows'", name)
+		}
+	}
+	for _, host := range hosts {
+		if !hostNameRegex.MatchString(host) {
+			return fmt.Errorf("invalid host '%s': must be a short host name", host)
+		}
+	}
+	return nil
+}
+
+// ParseEnvFile reads and validates a shared env file; a missing file is an empty config
+func ParseEnvFile(path string) (*EnvConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &EnvConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	var env EnvConfig
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse env JSON: %w", err)
+	}
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// JoinEnv returns the variables and PATH entries of configs in order. Later variables win when they
+// are set; a PATH entry listed again with the same conditions is dropped.
+func JoinEnv(configs ...*EnvConfig) EnvConfig {
+	var joined EnvConfig
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		joined.Vars = append(joined.Vars, config.Vars...)
+		for _, entry := range config.Path {
+			if !containsPathEntry(joined.Path, entry) {
+				joined.Path = append(joined.Path, entry)
+			}
+		}
+	}
+	return joined
+}
+
+func containsPathEntry(entries []PathEntry, entry PathEntry) bool {
+	for _, e := range entries {
+		if e.Dir == entry.Dir && e.Append == entry.Append && slices.Equal(e.OS, entry.OS) && slices.Equal(e.Hosts, entry.Hosts) {
+			return true
+		}
+	}
+	return false
+}
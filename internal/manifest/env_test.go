@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     EnvConfig
+		wantErr bool
+	}{
+		{"valid", EnvConfig{
+			Vars: []EnvVar{{Name: "EDITOR", Value: "nvim", OS: []string{"linux", "darwin"}, Hosts: []string{"work-laptop"}}},
+			Path: []PathEntry{{Dir: "~/bin"}, {Dir: "/opt/bin", Append: true, OS: []string{"windows"}}},
+		}, false},
+		{"invalid name", EnvConfig{Vars: []EnvVar{{Name: "MY-VAR", Value: "x"}}}, true},
+		{"PATH as a variable", EnvConfig{Vars: []EnvVar{{Name: "PATH", Value: "/bin"}}}, true},
+		{"unknown os", EnvConfig{Vars: []EnvVar{{Name: "A", OS: []string{"macos"}}}}, true},
+		{"invalid host", EnvConfig{Path: []PathEntry{{Dir: "/bin", Hosts: []string{"$(id)"}}}}, true},
+		{"empty dir", EnvConfig{Path: []PathEntry{{}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.env.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	dir := t.TempDir()
+
+	env, err := ParseEnvFile(filepath.Join(dir, "missing.json"))
+	if err != nil || !env.IsEmpty() {
+		t.Fatalf("a missing env file should be empty, got %+v, %v", env, err)
+	}
+
+	path := filepath.Join(dir, "env.json")
+	if err := os.WriteFile(path, []byte(`{"vars": [{"name": "EDITOR", "value": "nvim"}], "path": [{"dir": "~/bin"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env, err = ParseEnvFile(path)
+	if err != nil || len(env.Vars) != 1 || len(env.Path) != 1 || env.Path[0].Dir != "~/bin" {
+		t.Fatalf("ParseEnvFile = %+v, %v", env, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"vars": [{"name": "PATH", "value": "/bin"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEnvFile(path); err == nil {
+		t.Fatal("expected an invalid env file to fail")
+	}
+}
+
+func TestJoinEnv(t *testing.T) {
+	shared := &EnvConfig{
+		Vars: []EnvVar{{Name: "EDITOR", Value: "vim"}},
+		Path: []PathEntry{{Dir: "~/bin"}, {Dir: "/opt/bin", OS: []string{"linux"}}},
+	}
+	shell := &EnvConfig{
+		Vars: []EnvVar{{Name: "EDITOR", Value: "nvim"}},
+		Path: []PathEntry{{Dir: "~/bin"}, {Dir: "/opt/bin"}},
+	}
+
+	joined := JoinEnv(shared, nil, shell)
+	if len(joined.Vars) != 2 || joined.Vars[1].Value != "nvim" {
+		t.Errorf("expected both variables in order so the later one wins, got %+v", joined.Vars)
+	}
+	// The same directory under other conditions is kept
+	if len(joined.Path) != 3 || joined.Path[2].Dir != "/opt/bin" || len(joined.Path[2].OS) != 0 {
+		t.Errorf("expected the repeated ~/bin to be dropped, got %+v", joined.Path)
+	}
+}
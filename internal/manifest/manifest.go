@@ -9,27 +9,39 @@ import (
 
 // FeatureConfig represents a single feature configuration in enabled.json
 type FeatureConfig struct {
-	Name      string         `json:"name"`
-	Strategy  string         `json:"strategy,omitempty"`  // "eager", "defer", or "on-command"
-	OnCommand []string       `json:"onCommand,omitempty"` // Commands that trigger on-command loading
-	Disabled  bool           `json:"disabled,omitempty"`  // If true, feature is disabled
-	Options   map[string]any `json:"options,omitempty"`   // User-provided option values
+	Name        string         `json:"name"`
+	Strategy    string         `json:"strategy,omitempty"`    // "eager", "defer", "on-command", "on-completion" or "on-directory"
+	OnCommand   []string       `json:"onCommand,omitempty"`   // Commands that trigger on-command or on-completion loading
+	OnDirectory []string       `json:"onDirectory,omitempty"` // Marker files that trigger on-directory loading
+	Disabled    bool           `json:"disabled,omitempty"`    // If true, feature is disabled
+	Options     map[string]any `json:"options,omitempty"`     // User-provided option values
+	Requires    []string       `json:"requires,omitzero"`     // Features that must be enabled and load first; an empty list drops the catalog's
+	After       []string       `json:"after,omitzero"`        // Features that load first when they are enabled; an empty list drops the catalog's
 }
 
 // FeatureManifest represents the enabled.json file structure
 type FeatureManifest struct {
 	Features []FeatureConfig `json:"features"`
+	Order    []string        `json:"order,omitempty"`   // Per-machine load order; only read from local manifests
+	Bundle   *bool           `json:"bundle,omitempty"`  // Load eager features from one generated bundle file
+	Env      *EnvConfig      `json:"env,omitempty"`     // Environment variables and PATH entries for this shell
+	Aliases  []AliasConfig   `json:"aliases,omitempty"` // Aliases defined after the eager features load
 }
 
 var (
 	// featureNameRegex validates feature names (alphanumeric, hyphens, underscores)
 	featureNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
+	// markerNameRegex validates on-directory marker file names, which are written into init scripts
+	markerNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._+-]+$`)
+
 	// validStrategies are the allowed load strategies
 	validStrategies = map[string]bool{
-		"eager":      true,
-		"defer":      true,
-		"on-command": true,
+		"eager":         true,
+		"defer":         true,
+		"on-command":    true,
+		"on-completion": true,
+		"on-directory":  true,
 	}
 )
 
@@ -50,7 +62,7 @@ func ValidateStrategy(strategy string) error {
 		return nil // Empty strategy is allowed (defaults to catalog)
 	}
 	if !validStrategies[strategy] {
-		return fmt.Errorf("invalid strategy '%s': must be 'eager', 'defer', or 'on-command'", strategy)
+		return fmt.Errorf("invalid strategy '%s': must be 'eager', 'defer', 'on-command', 'on-completion', or 'on-directory'", strategy)
 	}
 	return nil
 }
@@ -67,6 +79,27 @@ func (f *FeatureConfig) Validate() error {
 	if f.Strategy == "on-command" && len(f.OnCommand) == 0 {
 		return fmt.Errorf("feature '%s' uses on-command strategy but has no trigger commands", f.Name)
 	}
+	// Require onCommand if strategy is on-completion
+	if f.Strategy == "on-completion" && len(f.OnCommand) == 0 {
+		return fmt.Errorf("feature '%s' uses on-completion strategy but has no trigger commands", f.Name)
+	}
+	// Require marker files if strategy is on-directory
+	if f.Strategy == "on-directory" && len(f.OnDirectory) == 0 {
+		return fmt.Errorf("feature '%s' uses on-directory strategy but has no marker files", f.Name)
+	}
+	for _, marker := range f.OnDirectory {
+		if !markerNameRegex.MatchString(marker) || marker == "." || marker == ".." {
+			return fmt.Errorf("feature '%s' has an invalid marker file '%s': must be a file name", f.Name, marker)
+		}
+	}
+	for _, dependency := range append(append([]string{}, f.Requires...), f.After...) {
+		if err := ValidateFeatureName(dependency); err != nil {
+			return fmt.Errorf("feature '%s' has an invalid dependency '%s': %w", f.Name, dependency, err)
+		}
+		if dependency == f.Name {
+			return fmt.Errorf("feature '%s' cannot depend on itself", f.Name)
+		}
+	}
 	return nil
 }
 
@@ -88,6 +121,19 @@ func ParseManifest(path string) (*FeatureManifest, error) {
 			return nil, fmt.Errorf("feature at index %d: %w", i, err)
 		}
 	}
+	for _, name := range manifest.Order {
+		if err := ValidateFeatureName(name); err != nil {
+			return nil, fmt.Errorf("order entry '%s': %w", name, err)
+		}
+	}
+	if err := manifest.Env.Validate(); err != nil {
+		return nil, err
+	}
+	for i, alias := range manifest.Aliases {
+		if err := alias.Validate(); err != nil {
+			return nil, fmt.Errorf("alias at index %d: %w", i, err)
+		}
+	}
 
 	return &manifest, nil
 }
@@ -138,6 +184,72 @@ func (m *FeatureManifest) RemoveFeature(name string) error {
 	return fmt.Errorf("feature '%s' not found", name)
 }
 
+// SetOrder reorders the features to follow names; features not named keep their relative order after them
+func (m *FeatureManifest) SetOrder(names []string) {
+	m.Features = applyOrder(m.Features, names, func(f FeatureConfig) string { return f.Name })
+}
+
+// MoveInOrder returns names with name moved directly before or after other
+func MoveInOrder(names []string, name, other string, after bool) ([]string, error) {
+	if name == other {
+		return nil, fmt.Errorf("cannot move feature '%s' relative to itself", name)
+	}
+
+	moved := make([]string, 0, len(names))
+	found := false
+	for _, n := range names {
+		if n == name {
+			found = true
+			continue
+		}
+		moved = append(moved, n)
+	}
+	if !found {
+		return nil, fmt.Errorf("feature '%s' not found", name)
+	}
+
+	for i, n := range moved {
+		if n != other {
+			continue
+		}
+		if after {
+			i++
+		}
+		moved = append(moved[:i], append([]string{name}, moved[i:]...)...)
+		return moved, nil
+	}
+	return nil, fmt.Errorf("feature '%s' not found", other)
+}
+
+// applyOrder places the items named in order first, in that order, followed by the rest in their original order
+func applyOrder[T any](items []T, order []string, name func(T) string) []T {
+	position := make(map[string]int, len(order))
+	for i, n := range order {
+		if _, seen := position[n]; !seen {
+			position[n] = i
+		}
+	}
+
+	ordered := make([]T, 0, len(items))
+	named := make([]T, len(order))
+	present := make([]bool, len(order))
+	var rest []T
+	for _, item := range items {
+		if i, ok := position[name(item)]; ok {
+			named[i] = item
+			present[i] = true
+		} else {
+			rest = append(rest, item)
+		}
+	}
+	for i := range named {
+		if present[i] {
+			ordered = append(ordered, named[i])
+		}
+	}
+	return append(ordered, rest...)
+}
+
 // GetFeature retrieves a feature by name
 func (m *FeatureManifest) GetFeature(name string) (*FeatureConfig, error) {
 	for i := range m.Features {
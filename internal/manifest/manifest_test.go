@@ -3,6 +3,7 @@ package manifest
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -39,6 +40,8 @@ func TestValidateStrategy(t *testing.T) {
 		{"valid eager", "eager", false},
 		{"valid defer", "defer", false},
 		{"valid on-command", "on-command", false},
+		{"valid on-completion", "on-completion", false},
+		{"valid on-directory", "on-directory", false},
 		{"empty (defaults to catalog)", "", false},
 		{"invalid strategy", "lazy", true},
 	}
@@ -74,11 +77,56 @@ func TestFeatureConfigValidate(t *testing.T) {
 			FeatureConfig{Name: "kubectl", Strategy: "on-command"},
 			true,
 		},
+		{
+			"valid on-completion with commands",
+			FeatureConfig{Name: "kubectl-completion", Strategy: "on-completion", OnCommand: []string{"kubectl", "k"}},
+			false,
+		},
+		{
+			"invalid on-completion without commands",
+			FeatureConfig{Name: "kubectl-completion", Strategy: "on-completion"},
+			true,
+		},
+		{
+			"valid on-directory with marker files",
+			FeatureConfig{Name: "nvm", Strategy: "on-directory", OnDirectory: []string{".nvmrc", "pyproject.toml"}},
+			false,
+		},
+		{
+			"invalid on-directory without marker files",
+			FeatureConfig{Name: "nvm", Strategy: "on-directory"},
+			true,
+		},
+		{
+			"invalid marker path",
+			FeatureConfig{Name: "nvm", Strategy: "on-directory", OnDirectory: []string{"../.nvmrc"}},
+			true,
+		},
+		{
+			"invalid marker with shell syntax",
+			FeatureConfig{Name: "nvm", Strategy: "on-directory", OnDirectory: []string{"$(id)"}},
+			true,
+		},
 		{
 			"invalid name",
 			FeatureConfig{Name: "git prompt", Strategy: "eager"},
 			true,
 		},
+		{
+			"valid dependencies",
+			FeatureConfig{Name: "oh-my-posh", Requires: []string{"homebrew-path"}, After: []string{"posh-git"}},
+			false,
+		},
+		{
+			"invalid dependency name",
+			FeatureConfig{Name: "oh-my-posh", Requires: []string{"homebrew path"}},
+			true,
+		},
+		{
+			"invalid dependency on itself",
+			FeatureConfig{Name: "oh-my-posh", After: []string{"oh-my-posh"}},
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -138,7 +186,7 @@ func TestParseAndWriteManifest(t *testing.T) {
 	manifest := &FeatureManifest{
 		Features: []FeatureConfig{
 			{Name: "git-prompt", Strategy: "defer"},
-			{Name: "kubectl", Strategy: "on-command", OnCommand: []string{"kubectl"}},
+			{Name: "kubectl", Strategy: "on-command", OnCommand: []string{"kubectl"}, After: []string{}},
 		},
 	}
 
@@ -161,6 +209,12 @@ func TestParseAndWriteManifest(t *testing.T) {
 	if parsed.Features[0].Name != "git-prompt" {
 		t.Errorf("Expected first feature to be 'git-prompt', got '%s'", parsed.Features[0].Name)
 	}
+	if parsed.Features[0].After != nil {
+		t.Errorf("Expected unset after to stay unset, got %v", parsed.Features[0].After)
+	}
+	if parsed.Features[1].After == nil {
+		t.Error("Expected an empty after list to be kept")
+	}
 }
 
 func TestGetEnabledFeatures(t *testing.T) {
@@ -183,3 +237,59 @@ func TestGetEnabledFeatures(t *testing.T) {
 		}
 	}
 }
+
+func TestMoveInOrder(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name    string
+		feature string
+		other   string
+		after   bool
+		want    string
+		wantErr bool
+	}{
+		{name: "before earlier", feature: "d", other: "b", want: "a,d,b,c"},
+		{name: "after later", feature: "a", other: "c", after: true, want: "b,c,a,d"},
+		{name: "after last", feature: "b", other: "d", after: true, want: "a,c,d,b"},
+		{name: "before first", feature: "c", other: "a", want: "c,a,b,d"},
+		{name: "unknown feature", feature: "x", other: "a", wantErr: true},
+		{name: "unknown other", feature: "a", other: "x", wantErr: true},
+		{name: "itself", feature: "a", other: "a", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MoveInOrder(names, tt.feature, tt.other, tt.after)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MoveInOrder error: %v", err)
+			}
+			if joined := strings.Join(got, ","); joined != tt.want {
+				t.Fatalf("MoveInOrder = %s, want %s", joined, tt.want)
+			}
+		})
+	}
+
+	if strings.Join(names, ",") != "a,b,c,d" {
+		t.Fatalf("MoveInOrder modified its input: %v", names)
+	}
+}
+
+func TestSetOrder(t *testing.T) {
+	m := &FeatureManifest{Features: []FeatureConfig{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}}
+	m.SetOrder([]string{"c", "unknown", "a"})
+
+	var got []string
+	for _, f := range m.Features {
+		got = append(got, f.Name)
+	}
+	if joined := strings.Join(got, ","); joined != "c,a,b,d" {
+		t.Fatalf("SetOrder = %s, want c,a,b,d", joined)
+	}
+}
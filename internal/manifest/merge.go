@@ -21,17 +21,29 @@ type FeatureWithOverride struct {
 // MergedManifest represents a manifest with local overrides applied
 type MergedManifest struct {
 	Features []FeatureWithOverride
+	Bundle   bool          // Eager features load from a bundle; the local manifest overrides the base
+	Env      EnvConfig     // Environment of the base manifest followed by that of the local manifest
+	Aliases  []AliasConfig // Aliases of the base manifest with those of the local manifest applied
 }
 
 // MergeManifests merges a base manifest with a local override manifest
 // Local manifest can:
 // - Override strategy for existing features
 // - Set disabled flag for existing features
+// - Replace requires and after for existing features
 // - Add new local-only features
-// The merge preserves the order of the base manifest and appends local-only features
+// - Reorder features with its order list
+// - Turn bundle mode on or off
+// - Add environment variables and PATH entries after those of the base
+// - Replace aliases of the base and add new ones
+// The merge preserves the order of the base manifest and appends local-only features,
+// unless the local manifest sets an order
 func MergeManifests(base, local *FeatureManifest) *MergedManifest {
 	merged := &MergedManifest{
 		Features: make([]FeatureWithOverride, 0),
+		Bundle:   base.Bundle != nil && *base.Bundle,
+		Env:      JoinEnv(base.Env),
+		Aliases:  MergeAliases(base.Aliases, nil),
 	}
 
 	if local == nil {
@@ -49,6 +61,12 @@ func MergeManifests(base, local *FeatureManifest) *MergedManifest {
 		return merged
 	}
 
+	if local.Bundle != nil {
+		merged.Bundle = *local.Bundle
+	}
+	merged.Env = JoinEnv(base.Env, local.Env)
+	merged.Aliases = MergeAliases(base.Aliases, local.Aliases)
+
 	// Create a map of local features for quick lookup
 	localFeatures := make(map[string]FeatureConfig)
 	for _, f := range local.Features {
@@ -71,6 +89,19 @@ func MergeManifests(base, local *FeatureManifest) *MergedManifest {
 				mergedFeature.OnCommand = localFeature.OnCommand
 			}
 
+			// Override onDirectory if set in local
+			if len(localFeature.OnDirectory) > 0 {
+				mergedFeature.OnDirectory = localFeature.OnDirectory
+			}
+
+			// Override dependencies if set in local
+			if localFeature.Requires != nil {
+				mergedFeature.Requires = localFeature.Requires
+			}
+			if localFeature.After != nil {
+				mergedFeature.After = localFeature.After
+			}
+
 			// Always use local disabled flag (explicit override)
 			mergedFeature.Disabled = localFeature.Disabled
 
@@ -98,8 +129,11 @@ func MergeManifests(base, local *FeatureManifest) *MergedManifest {
 		}
 	}
 
-	// Add remaining local-only features (not in base)
-	for _, localFeature := range localFeatures {
+	// Add remaining local-only features (not in base), in the order of the local manifest
+	for _, localFeature := range local.Features {
+		if _, remaining := localFeatures[localFeature.Name]; !remaining {
+			continue
+		}
 		merged.Features = append(merged.Features, FeatureWithOverride{
 			FeatureConfig: localFeature,
 			Override: LocalOverride{
@@ -110,6 +144,10 @@ func MergeManifests(base, local *FeatureManifest) *MergedManifest {
 		})
 	}
 
+	if len(local.Order) > 0 {
+		merged.Features = applyOrder(merged.Features, local.Order, func(f FeatureWithOverride) string { return f.Name })
+	}
+
 	return merged
 }
 
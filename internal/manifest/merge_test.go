@@ -123,6 +123,24 @@ func TestMergeManifests_OnCommandOverride(t *testing.T) {
 	}
 }
 
+func TestMergeManifests_OnDirectoryOverride(t *testing.T) {
+	base := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "nvm", Strategy: "on-command", OnCommand: []string{"nvm"}},
+		},
+	}
+	local := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "nvm", Strategy: "on-directory", OnDirectory: []string{".nvmrc"}},
+		},
+	}
+
+	nvm := MergeManifests(base, local).Features[0]
+	if nvm.Strategy != "on-directory" || len(nvm.OnDirectory) != 1 || nvm.OnDirectory[0] != ".nvmrc" {
+		t.Errorf("Expected the local on-directory strategy and markers, got %+v", nvm.FeatureConfig)
+	}
+}
+
 func TestMergeManifests_DisabledFlag(t *testing.T) {
 	base := &FeatureManifest{
 		Features: []FeatureConfig{
@@ -234,6 +252,85 @@ func TestMergeManifests_OrderPreservation(t *testing.T) {
 	}
 }
 
+func TestMergeManifests_LocalOnlyFeaturesKeepLocalOrder(t *testing.T) {
+	base := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "feature-a", Strategy: "eager"},
+		},
+	}
+
+	local := &FeatureManifest{}
+	for _, name := range []string{"local-e", "local-d", "local-c", "local-b", "local-a"} {
+		local.Features = append(local.Features, FeatureConfig{Name: name})
+	}
+
+	// Run repeatedly, since an unordered merge would only shuffle some of the time
+	for run := 0; run < 20; run++ {
+		merged := MergeManifests(base, local)
+		for i, f := range local.Features {
+			if merged.Features[i+1].Name != f.Name {
+				t.Fatalf("run %d: expected feature at position %d to be %s, got %s", run, i+1, f.Name, merged.Features[i+1].Name)
+			}
+		}
+	}
+}
+
+func TestMergeManifests_DependencyOverride(t *testing.T) {
+	base := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "prompt", Requires: []string{"tool"}, After: []string{"path"}},
+		},
+	}
+
+	local := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "prompt", After: []string{"other-path"}},
+		},
+	}
+
+	merged := MergeManifests(base, local)
+	f := merged.Features[0]
+	if len(f.Requires) != 1 || f.Requires[0] != "tool" {
+		t.Errorf("Expected requires to stay [tool], got %v", f.Requires)
+	}
+	if len(f.After) != 1 || f.After[0] != "other-path" {
+		t.Errorf("Expected after to be overridden to [other-path], got %v", f.After)
+	}
+
+	local.Features[0].Requires = []string{}
+	merged = MergeManifests(base, local)
+	if f := merged.Features[0]; f.Requires == nil || len(f.Requires) != 0 {
+		t.Errorf("Expected an empty local requires to drop [tool], got %v", f.Requires)
+	}
+}
+
+func TestMergeManifests_LocalOrder(t *testing.T) {
+	base := &FeatureManifest{
+		Features: []FeatureConfig{
+			{Name: "feature-a"},
+			{Name: "feature-b"},
+			{Name: "feature-c"},
+		},
+	}
+
+	local := &FeatureManifest{
+		Features: []FeatureConfig{{Name: "local-feature"}},
+		Order:    []string{"local-feature", "feature-c", "feature-a"},
+	}
+
+	merged := MergeManifests(base, local)
+
+	expectedOrder := []string{"local-feature", "feature-c", "feature-a", "feature-b"}
+	for i, expected := range expectedOrder {
+		if merged.Features[i].Name != expected {
+			t.Errorf("Expected feature at position %d to be %s, got %s", i, expected, merged.Features[i].Name)
+		}
+	}
+	if !merged.Features[0].Override.IsFromLocal {
+		t.Error("Expected local-feature to stay marked as from local")
+	}
+}
+
 func TestMergedManifest_GetEnabledFeatures(t *testing.T) {
 	base := &FeatureManifest{
 		Features: []FeatureConfig{
@@ -530,3 +627,20 @@ func TestParseManifestWithLocal_UnsafeLocal(t *testing.T) {
 		t.Error("Expected HasLocal to be false when local is unsafe")
 	}
 }
+
+func TestMergeManifests_Bundle(t *testing.T) {
+	on, off := true, false
+
+	if merged := MergeManifests(&FeatureManifest{Bundle: &on}, nil); !merged.Bundle {
+		t.Error("Expected bundle mode from the base manifest")
+	}
+	if merged := MergeManifests(&FeatureManifest{Bundle: &on}, &FeatureManifest{Bundle: &off}); merged.Bundle {
+		t.Error("Expected the local manifest to turn bundle mode off")
+	}
+	if merged := MergeManifests(&FeatureManifest{}, &FeatureManifest{Bundle: &on}); !merged.Bundle {
+		t.Error("Expected the local manifest to turn bundle mode on")
+	}
+	if merged := MergeManifests(&FeatureManifest{Bundle: &on}, &FeatureManifest{}); !merged.Bundle {
+		t.Error("Expected a local manifest without bundle to keep the base setting")
+	}
+}
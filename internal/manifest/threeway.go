@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrMergeConflict is returned when both sides changed the same feature, alias or setting in different ways.
+var ErrMergeConflict = errors.New("manifest changes conflict")
+
+// MergeManifestVersions performs a structural three-way merge of enabled.json contents.
+// Features are matched by name: a feature changed, added or removed on one side keeps that
+// change, and a feature changed differently on both sides is a conflict. The result keeps
+// the order of ours, with features only added in theirs appended in their order. Aliases are
+// merged by name the same way, and the bundle and env settings as a whole.
+// A nil input means the file does not exist on that side.
+func MergeManifestVersions(base, ours, theirs []byte) ([]byte, error) {
+	baseManifest, err := decodeManifestVersion(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base manifest: %w", err)
+	}
+	oursManifest, err := decodeManifestVersion(ours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse local manifest: %w", err)
+	}
+	theirsManifest, err := decodeManifestVersion(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse remote manifest: %w", err)
+	}
+
+	var conflicts []string
+	merged := &FeatureManifest{
+		Features: mergeNamed(baseManifest.Features, oursManifest.Features, theirsManifest.Features, func(f FeatureConfig) string { return f.Name }, "", &conflicts),
+		Aliases:  mergeNamed(baseManifest.Aliases, oursManifest.Aliases, theirsManifest.Aliases, func(a AliasConfig) string { return a.Name }, "alias ", &conflicts),
+	}
+	if merged.Features == nil {
+		merged.Features = []FeatureConfig{}
+	}
+
+	merged.Bundle = mergeSetting(baseManifest.Bundle, oursManifest.Bundle, theirsManifest.Bundle, "bundle", &conflicts)
+	merged.Env = mergeSetting(baseManifest.Env, oursManifest.Env, theirsManifest.Env, "env", &conflicts)
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrMergeConflict, conflicts)
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+func decodeManifestVersion(data []byte) (*FeatureManifest, error) {
+	m := &FeatureManifest{}
+	if data == nil {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeNamed merges lists of items matched by name, such as features. Conflicting names are
+// recorded with prefix.
+func mergeNamed[T any](base, ours, theirs []T, name func(T) string, prefix string, conflicts *[]string) []T {
+	baseItems := itemsByName(base, name)
+	oursItems := itemsByName(ours, name)
+	theirsItems := itemsByName(theirs, name)
+
+	var merged []T
+	pick := func(n string) {
+		b, inBase := baseItems[n]
+		o, inOurs := oursItems[n]
+		t, inTheirs := theirsItems[n]
+
+		switch {
+		case sameItem(o, t, inOurs, inTheirs):
+			if inOurs {
+				merged = append(merged, o)
+			}
+		case sameItem(o, b, inOurs, inBase):
+			if inTheirs {
+				merged = append(merged, t)
+			}
+		case sameItem(t, b, inTheirs, inBase):
+			if inOurs {
+				merged = append(merged, o)
+			}
+		default:
+			*conflicts = append(*conflicts, prefix+n)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, item := range ours {
+		seen[name(item)] = true
+		pick(name(item))
+	}
+	for _, item := range theirs {
+		if !seen[name(item)] {
+			seen[name(item)] = true
+			pick(name(item))
+		}
+	}
+	// Items deleted on both sides, or on one side and unchanged on the other.
+	for _, item := range base {
+		if !seen[name(item)] {
+			pick(name(item))
+		}
+	}
+	return merged
+}
+
+func itemsByName[T any](items []T, name func(T) string) map[string]T {
+	byName := make(map[string]T, len(items))
+	for _, item := range items {
+		byName[name(item)] = item
+	}
+	return byName
+}
+
+func sameItem[T any](a, b T, aOK, bOK bool) bool {
+	if aOK != bOK {
+		return false
+	}
+	return !aOK || reflect.DeepEqual(a, b)
+}
+
+// mergeSetting returns the side of a setting that changed, or records a conflict when both changed it differently
+func mergeSetting[T any](base, ours, theirs T, name string, conflicts *[]string) T {
+	switch {
+	case reflect.DeepEqual(ours, theirs), reflect.DeepEqual(theirs, base):
+		return ours
+	case reflect.DeepEqual(ours, base):
+		return theirs
+	default:
+		*conflicts = append(*conflicts, name)
+		return ours
+	}
+}
@@ -0,0 +1,139 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMergeManifestVersions(t *testing.T) {
+	base := `{"features": [{"name": "git-prompt", "strategy": "eager"}, {"name": "nvm", "strategy": "on-command"}]}`
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      []string
+		wantError bool
+	}{
+		{
+			name:   "features added on both sides",
+			ours:   `{"features": [{"name": "git-prompt", "strategy": "eager"}, {"name": "nvm", "strategy": "on-command"}, {"name": "ssh-agent"}]}`,
+			theirs: `{"features": [{"name": "git-prompt", "strategy": "eager"}, {"name": "nvm", "strategy": "on-command"}, {"name": "python-venv"}]}`,
+			want:   []string{"git-prompt", "nvm", "ssh-agent", "python-venv"},
+		},
+		{
+			name:   "removed on one side and changed on the other side",
+			ours:   `{"features": [{"name": "nvm", "strategy": "on-command"}]}`,
+			theirs: `{"features": [{"name": "git-prompt", "strategy": "eager"}, {"name": "nvm", "strategy": "defer"}]}`,
+			want:   []string{"nvm"},
+		},
+		{
+			name:      "same feature changed differently",
+			ours:      `{"features": [{"name": "git-prompt", "strategy": "defer"}, {"name": "nvm", "strategy": "on-command"}]}`,
+			theirs:    `{"features": [{"name": "git-prompt", "disabled": true}, {"name": "nvm", "strategy": "on-command"}]}`,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := MergeManifestVersions([]byte(base), []byte(tt.ours), []byte(tt.theirs))
+			if tt.wantError {
+				if !errors.Is(err, ErrMergeConflict) {
+					t.Fatalf("error = %v, want ErrMergeConflict", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeManifestVersions error: %v", err)
+			}
+
+			var merged FeatureManifest
+			if err := json.Unmarshal(data, &merged); err != nil {
+				t.Fatalf("merged manifest is not valid JSON: %v", err)
+			}
+
+			var names []string
+			for _, f := range merged.Features {
+				names = append(names, f.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("features = %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("features = %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("changed strategy is kept", func(t *testing.T) {
+		theirs := `{"features": [{"name": "git-prompt", "strategy": "eager"}, {"name": "nvm", "strategy": "defer"}]}`
+		data, err := MergeManifestVersions([]byte(base), []byte(base), []byte(theirs))
+		if err != nil {
+			t.Fatalf("MergeManifestVersions error: %v", err)
+		}
+
+		var merged FeatureManifest
+		if err := json.Unmarshal(data, &merged); err != nil {
+			t.Fatalf("merged manifest is not valid JSON: %v", err)
+		}
+		if merged.Features[1].Strategy != "defer" {
+			t.Fatalf("nvm strategy = %q, want defer", merged.Features[1].Strategy)
+		}
+	})
+
+	t.Run("settings changed on one side are kept", func(t *testing.T) {
+		ours := `{"features": [], "bundle": true}`
+		theirs := `{"features": [], "env": {"vars": [{"name": "EDITOR", "value": "nvim"}]}}`
+		data, err := MergeManifestVersions([]byte(`{"features": []}`), []byte(ours), []byte(theirs))
+		if err != nil {
+			t.Fatalf("MergeManifestVersions error: %v", err)
+		}
+
+		var merged FeatureManifest
+		if err := json.Unmarshal(data, &merged); err != nil {
+			t.Fatalf("merged manifest is not valid JSON: %v", err)
+		}
+		if merged.Bundle == nil || !*merged.Bundle {
+			t.Fatal("expected bundle mode from ours")
+		}
+		if merged.Env == nil || len(merged.Env.Vars) != 1 || merged.Env.Vars[0].Name != "EDITOR" {
+			t.Fatalf("env = %+v, want EDITOR from theirs", merged.Env)
+		}
+	})
+
+	t.Run("aliases merge by name", func(t *testing.T) {
+		base := `{"features": [], "aliases": [{"name": "ll", "command": "ls -l"}]}`
+		ours := `{"features": [], "aliases": [{"name": "ll", "command": "ls -la"}, {"name": "g", "command": "git"}]}`
+		theirs := `{"features": [], "aliases": [{"name": "ll", "command": "ls -l"}, {"name": "k", "command": "kubectl"}]}`
+		data, err := MergeManifestVersions([]byte(base), []byte(ours), []byte(theirs))
+		if err != nil {
+			t.Fatalf("MergeManifestVersions error: %v", err)
+		}
+
+		var merged FeatureManifest
+		if err := json.Unmarshal(data, &merged); err != nil {
+			t.Fatalf("merged manifest is not valid JSON: %v", err)
+		}
+		if len(merged.Aliases) != 3 || merged.Aliases[0].Command != "ls -la" || merged.Aliases[2].Name != "k" {
+			t.Fatalf("aliases = %+v, want ll from ours, g and k", merged.Aliases)
+		}
+
+		theirs = `{"features": [], "aliases": [{"name": "ll", "command": "ls -lh"}]}`
+		if _, err := MergeManifestVersions([]byte(base), []byte(ours), []byte(theirs)); !errors.Is(err, ErrMergeConflict) {
+			t.Fatalf("error = %v, want ErrMergeConflict", err)
+		}
+	})
+
+	t.Run("settings changed differently", func(t *testing.T) {
+		ours := `{"features": [], "env": {"vars": [{"name": "EDITOR", "value": "vim"}]}}`
+		theirs := `{"features": [], "env": {"vars": [{"name": "EDITOR", "value": "nvim"}]}}`
+		_, err := MergeManifestVersions([]byte(`{"features": []}`), []byte(ours), []byte(theirs))
+		if !errors.Is(err, ErrMergeConflict) {
+			t.Fatalf("error = %v, want ErrMergeConflict", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config keys for network access.
+const (
+	OfflineKey      = "offline"               // Skip every network call
+	TimeoutKey      = "network.timeout"       // Limit for clones, fetches, pulls, pushes and downloads
+	CheckTimeoutKey = "network.check-timeout" // Limit for quick checks such as listing remote branches
+)
+
+// Default limits, used when the config has no valid duration.
+const (
+	DefaultTimeout      = 2 * time.Minute
+	DefaultCheckTimeout = 10 * time.Second
+)
+
+// Kind selects which timeout bounds an operation.
+type Kind int
+
+const (
+	Check    Kind = iota // Quick round trips: listing references, permission checks
+	Transfer             // Operations that move objects or files
+)
+
+var (
+	// ErrOffline is returned instead of contacting a remote in offline mode.
+	ErrOffline = errors.New("offline mode is enabled")
+	// ErrTimeout wraps errors of remote operations that ran out of time.
+	ErrTimeout = errors.New("remote did not respond in time")
+)
+
+// offlineFlag is set by --offline for the current process only, so the flag is never saved to the config.
+var offlineFlag atomic.Bool
+
+// SetOffline turns offline mode on or off for this process, on top of the offline config setting.
+func SetOffline(offline bool) {
+	offlineFlag.Store(offline)
+}
+
+// Offline reports whether network calls are disabled by --offline or the offline config setting.
+func Offline() bool {
+	return offlineFlag.Load() || viper.GetBool(OfflineKey)
+}
+
+// Timeout returns the configured limit for an operation kind.
+func Timeout(kind Kind) time.Duration {
+	key, fallback := TimeoutKey, DefaultTimeout
+	if kind == Check {
+		key, fallback = CheckTimeoutKey, DefaultCheckTimeout
+	}
+
+	if timeout := viper.GetDuration(key); timeout > 0 {
+		return timeout
+	}
+	return fallback
+}
+
+// Context returns a context bounded by the timeout for kind, or ErrOffline in offline mode.
+// Callers must call the returned cancel function when it is non-nil.
+func Context(parent context.Context, kind Kind) (context.Context, context.CancelFunc, error) {
+	if Offline() {
+		return nil, nil, ErrOffline
+	}
+	ctx, cancel := context.WithTimeout(parent, Timeout(kind))
+	return ctx, cancel, nil
+}
+
+// Err marks err as a timeout when ctx ran out of time, so callers can tell a slow network
+// from a rejected request. Other errors are returned unchanged.
+func Err(ctx context.Context, kind Kind, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, ErrTimeout) {
+		return err
+	}
+
+	key := TimeoutKey
+	if kind == Check {
+		key = CheckTimeoutKey
+	}
+	return fmt.Errorf("%w (%s is %s): %w", ErrTimeout, key, Timeout(kind), err)
+}
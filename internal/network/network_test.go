@@ -0,0 +1,103 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestOffline(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		SetOffline(false)
+	})
+
+	tests := []struct {
+		name   string
+		flag   bool
+		config bool
+		want   bool
+	}{
+		{"online", false, false, false},
+		{"flag", true, false, true},
+		{"config", false, true, true},
+		{"both", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetOffline(tt.flag)
+			viper.Set(OfflineKey, tt.config)
+
+			if got := Offline(); got != tt.want {
+				t.Fatalf("Offline() = %t, want %t", got, tt.want)
+			}
+
+			_, cancel, err := Context(context.Background(), Check)
+			if cancel != nil {
+				cancel()
+			}
+			if tt.want != errors.Is(err, ErrOffline) {
+				t.Fatalf("Context() error = %v, want offline error %t", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	if got := Timeout(Transfer); got != DefaultTimeout {
+		t.Fatalf("default transfer timeout = %s, want %s", got, DefaultTimeout)
+	}
+	if got := Timeout(Check); got != DefaultCheckTimeout {
+		t.Fatalf("default check timeout = %s, want %s", got, DefaultCheckTimeout)
+	}
+
+	viper.Set(TimeoutKey, "45s")
+	viper.Set(CheckTimeoutKey, "3s")
+	if got := Timeout(Transfer); got != 45*time.Second {
+		t.Fatalf("configured transfer timeout = %s", got)
+	}
+	if got := Timeout(Check); got != 3*time.Second {
+		t.Fatalf("configured check timeout = %s", got)
+	}
+
+	viper.Set(CheckTimeoutKey, "soon")
+	if got := Timeout(Check); got != DefaultCheckTimeout {
+		t.Fatalf("invalid check timeout should fall back to the default, got %s", got)
+	}
+}
+
+func TestErrMarksTimeouts(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set(CheckTimeoutKey, "1ms")
+
+	ctx, cancel, err := Context(context.Background(), Check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	<-ctx.Done()
+
+	cause := errors.New("dial tcp: i/o timeout")
+	err = Err(ctx, Check, cause)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, cause) {
+		t.Fatalf("expected a timeout wrapping the cause, got %v", err)
+	}
+
+	live, cancelLive := context.WithCancel(context.Background())
+	defer cancelLive()
+	if err := Err(live, Check, cause); err != cause {
+		t.Fatalf("errors before the deadline should be unchanged, got %v", err)
+	}
+	if err := Err(ctx, Check, nil); err != nil {
+		t.Fatalf("nil should stay nil, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package profiler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+)
+
+// Result holds the startup timings of a shell's features over several runs.
+type Result struct {
+	Shell    string          `json:"shell"`
+	Time     time.Time       `json:"time"`
+	Runs     int             `json:"runs"`
+	Total    Timing          `json:"total"` // The whole shell, from start to exit
+	Features []FeatureTiming `json:"features"`
+}
+
+// Timing summarizes the samples of one measurement.
+type Timing struct {
+	Mean time.Duration `json:"mean"`
+	P95  time.Duration `json:"p95"`
+}
+
+// FeatureTiming is the time it takes to source one feature.
+type FeatureTiming struct {
+	Name     string `json:"name"`
+	Strategy string `json:"strategy"`
+	Timing
+}
+
+// timeUnits maps the units a profile script can log timestamps in to their length.
+var timeUnits = map[string]time.Duration{
+	"ns":    time.Nanosecond,
+	"us":    time.Microsecond,
+	"ticks": 100 * time.Nanosecond,
+}
+
+// Run runs an instrumented init script for a shell runs times, non-interactively and without
+// the user's own startup files, and returns how long each feature took to source.
+func Run(repoPath, shellName string, runs int) (*Result, error) {
+	if runs < 1 {
+		return nil, fmt.Errorf("runs must be at least 1")
+	}
+
+	executable, ok := shell.FindShellExecutable(shellName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not installed or not in PATH", shellName)
+	}
+
+	tempDir, err := os.MkdirTemp("", "omd-profile-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	logPath := filepath.Join(tempDir, "timings.log")
+	script, features, err := shell.GenerateProfileScript(repoPath, shellName, logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _ := shell.GetShellConfig(shellName)
+	scriptPath := filepath.Join(tempDir, "profile"+config.Extension)
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write profile script: %w", err)
+	}
+
+	var totals []time.Duration
+	samples := make(map[string][]time.Duration, len(features))
+	for range runs {
+		var stderr bytes.Buffer
+		cmd := exec.Command(executable, profileArgs(shellName, scriptPath)...)
+		cmd.Stderr = &stderr
+
+		start := time.Now()
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("%s failed to run the profile script: %w\n%s", shellName, err, strings.TrimSpace(stderr.String()))
+		}
+		totals = append(totals, time.Since(start))
+
+		timings, err := readTimings(logPath)
+		if err != nil {
+			return nil, err
+		}
+		for name, d := range timings {
+			samples[name] = append(samples[name], d)
+		}
+	}
+
+	result := &Result{
+		Shell: shellName,
+		Time:  time.Now(),
+		Runs:  runs,
+		Total: summarize(totals),
+	}
+	for _, f := range features {
+		result.Features = append(result.Features, FeatureTiming{Name: f.Name, Strategy: f.Strategy, Timing: summarize(samples[f.Name])})
+	}
+	return result, nil
+}
+
+// profileArgs returns the arguments that run a script in a shell without loading any startup file.
+func profileArgs(shellName, scriptPath string) []string {
+	switch shellName {
+	case "bash":
+		return []string{"--noprofile", "--norc", scriptPath}
+	case "zsh":
+		return []string{"-f", scriptPath}
+	case "fish":
+		return []string{"--no-config", scriptPath}
+	case "powershell":
+		return []string{"-NoLogo", "-NoProfile", "-NonInteractive", "-File", scriptPath}
+	default:
+		return []string{scriptPath}
+	}
+}
+
+// readTimings parses the log a profile script writes into the time each feature took.
+func readTimings(logPath string) (map[string]time.Duration, error) {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile timings: %w", err)
+	}
+	defer file.Close()
+
+	timings := map[string]time.Duration{}
+	unit := time.Duration(0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		if len(fields) == 2 && fields[0] == "#unit" {
+			unit = timeUnits[fields[1]]
+			continue
+		}
+		if len(fields) != 3 || unit == 0 {
+			return nil, fmt.Errorf("malformed profile timing %q", scanner.Text())
+		}
+
+		start, startErr := strconv.ParseInt(fields[1], 10, 64)
+		end, endErr := strconv.ParseInt(fields[2], 10, 64)
+		if startErr != nil || endErr != nil {
+			return nil, fmt.Errorf("the shell has no usable clock: got timestamps %q and %q", fields[1], fields[2])
+		}
+		timings[fields[0]] = time.Duration(end-start) * unit
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile timings: %w", err)
+	}
+	return timings, nil
+}
+
+// summarize returns the mean and the nearest-rank 95th percentile of samples.
+func summarize(samples []time.Duration) Timing {
+	if len(samples) == 0 {
+		return Timing{}
+	}
+
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return Timing{Mean: sum / time.Duration(len(sorted)), P95: sorted[rank]}
+}
@@ -0,0 +1,179 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/shell"
+	"github.com/spf13/viper"
+)
+
+func writeProfileRepo(t *testing.T, shellName string) string {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	featuresDir := shell.GetFeaturesDirectory(repoPath, shellName)
+	if err := os.MkdirAll(featuresDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{
+		{Name: "slow"},
+		{Name: "fast"},
+		{Name: "later", Strategy: "on-command", OnCommand: []string{"later"}},
+	}}
+	if err := manifest.WriteManifest(shell.GetManifestPath(repoPath, shellName), m); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"slow.sh":  "sleep 0.2\necho noise\n",
+		"fast.sh":  "OMD_FAST=1\n",
+		"later.sh": "later() { :; }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(featuresDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repoPath
+}
+
+func TestRun(t *testing.T) {
+	for _, shellName := range []string{"bash", "posix"} {
+		t.Run(shellName, func(t *testing.T) {
+			if !shell.IsShellExecutableAvailable(shellName) {
+				t.Skipf("%s is not available", shellName)
+			}
+			repoPath := writeProfileRepo(t, shellName)
+
+			result, err := Run(repoPath, shellName, 2)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			if result.Shell != shellName || result.Runs != 2 || len(result.Features) != 3 {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			slow, fast, later := result.Features[0], result.Features[1], result.Features[2]
+			if slow.Name != "slow" || fast.Name != "fast" || later.Name != "later" || later.Strategy != "on-command" {
+				t.Fatalf("features out of load order: %+v", result.Features)
+			}
+			if slow.Mean < 150*time.Millisecond || slow.P95 < slow.Mean {
+				t.Fatalf("slow feature timing = %+v, want about 200ms", slow.Timing)
+			}
+			if fast.Mean >= slow.Mean {
+				t.Fatalf("fast feature (%v) should be faster than slow (%v)", fast.Mean, slow.Mean)
+			}
+			if result.Total.Mean < slow.Mean {
+				t.Fatalf("whole shell (%v) should take longer than its slowest feature (%v)", result.Total.Mean, slow.Mean)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var samples []time.Duration
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+
+	got := summarize(samples)
+	if got.Mean != 10500*time.Microsecond {
+		t.Errorf("mean = %v, want 10.5ms", got.Mean)
+	}
+	if got.P95 != 19*time.Millisecond {
+		t.Errorf("p95 = %v, want 19ms", got.P95)
+	}
+	if samples[0] != 20*time.Millisecond {
+		t.Error("summarize must not reorder the samples")
+	}
+	if (summarize(nil) != Timing{}) {
+		t.Error("expected a zero timing without samples")
+	}
+}
+
+func TestReadTimings(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "timings.log")
+	if err := os.WriteFile(logPath, []byte("#unit\tus\nslow\t1000\t251000\r\nfast\t251000\t251500\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	timings, err := readTimings(logPath)
+	if err != nil {
+		t.Fatalf("readTimings: %v", err)
+	}
+	if timings["slow"] != 250*time.Millisecond || timings["fast"] != 500*time.Microsecond {
+		t.Fatalf("unexpected timings: %v", timings)
+	}
+
+	if err := os.WriteFile(logPath, []byte("#unit\tns\nslow\t1700000000N\t1700000001N\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTimings(logPath); err == nil {
+		t.Fatal("expected an error for a date without nanosecond support")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	result := &Result{Features: []FeatureTiming{
+		{Name: "kubectl-completion", Strategy: "eager", Timing: Timing{Mean: 80 * time.Millisecond}},
+		{Name: "git-prompt", Strategy: "eager", Timing: Timing{Mean: 30 * time.Millisecond}},
+		{Name: "core-aliases", Strategy: "eager", Timing: Timing{Mean: 2 * time.Millisecond}},
+		{Name: "nvm", Strategy: "on-command", Timing: Timing{Mean: 300 * time.Millisecond}},
+	}}
+
+	suggestions := Suggest(result, 20*time.Millisecond)
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %+v", suggestions)
+	}
+	if s := suggestions[0]; s.Feature != "kubectl-completion" || s.Strategy != "on-command" || len(s.Commands) != 1 || s.Commands[0] != "kubectl" {
+		t.Errorf("unexpected suggestion for kubectl-completion: %+v", s)
+	}
+	if s := suggestions[1]; s.Feature != "git-prompt" || s.Strategy != "defer" {
+		t.Errorf("unexpected suggestion for git-prompt: %+v", s)
+	}
+
+	totals := StrategyTotals(result)
+	if totals["eager"] != 112*time.Millisecond || totals["on-command"] != 300*time.Millisecond {
+		t.Errorf("unexpected strategy totals: %v", totals)
+	}
+}
+
+func TestSaveAndLatest(t *testing.T) {
+	viper.Set("dot-home", filepath.Join(t.TempDir(), "config.json"))
+	t.Cleanup(func() { viper.Set("dot-home", "") })
+
+	if latest, err := Latest("bash"); err != nil || latest != nil {
+		t.Fatalf("Latest without saved results = %v, %v", latest, err)
+	}
+
+	first := &Result{Shell: "bash", Time: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), Runs: 5,
+		Features: []FeatureTiming{{Name: "a", Strategy: "eager", Timing: Timing{Mean: time.Millisecond}}}}
+	second := &Result{Shell: "bash", Time: first.Time.Add(time.Hour), Runs: 10}
+	other := &Result{Shell: "zsh", Time: first.Time.Add(2 * time.Hour), Runs: 1}
+	for _, result := range []*Result{second, first, other} {
+		if _, err := Save(result); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	latest, err := Latest("bash")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if latest == nil || latest.Runs != 10 {
+		t.Fatalf("expected the later bash run, got %+v", latest)
+	}
+
+	path, err := Save(&Result{Shell: "bash", Time: first.Time.Add(-time.Hour), Runs: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil || loaded.Runs != 3 {
+		t.Fatalf("Load = %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,143 @@
+package profiler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+)
+
+// Suggestion recommends a lazier strategy for a feature that slows down every startup.
+type Suggestion struct {
+	Feature  string
+	Strategy string   // "on-command" or "defer"
+	Commands []string // Trigger commands when Strategy is on-command
+	Mean     time.Duration
+}
+
+// strategyNotes explains when the time of each strategy is paid.
+var strategyNotes = map[string]string{
+	"eager":         "added to every startup",
+	"defer":         "loaded after startup",
+	"on-command":    "paid when a trigger command first runs",
+	"on-completion": "paid when a trigger command is first completed",
+	"on-directory":  "paid when entering a matching directory",
+}
+
+// Suggest returns a suggestion for every eager feature that takes at least threshold on average.
+// Features the catalog knows commands for can load on-command; the rest can be deferred.
+func Suggest(result *Result, threshold time.Duration) []Suggestion {
+	var suggestions []Suggestion
+	for _, f := range result.Features {
+		if f.Strategy != "eager" || f.Mean < threshold {
+			continue
+		}
+
+		suggestion := Suggestion{Feature: f.Name, Strategy: "defer", Mean: f.Mean}
+		if metadata, ok := catalog.GetFeature(f.Name); ok && len(metadata.DefaultCommands) > 0 {
+			suggestion.Strategy = "on-command"
+			suggestion.Commands = metadata.DefaultCommands
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// StrategyTotals returns the summed mean time of the features of each strategy.
+func StrategyTotals(result *Result) map[string]time.Duration {
+	totals := map[string]time.Duration{}
+	for _, f := range result.Features {
+		totals[f.Strategy] += f.Mean
+	}
+	return totals
+}
+
+// Print prints a result, the change since previous when it is not nil, and suggestions for
+// eager features slower than threshold.
+func Print(result, previous *Result, threshold time.Duration, alias string) {
+	fileops.ColorPrintfn(fileops.Cyan, "\nStartup profile for %s (%d runs)", result.Shell, result.Runs)
+	if previous != nil {
+		fileops.ColorPrintfn(fileops.Cyan, "Compared with the run of %s", previous.Time.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	if len(result.Features) == 0 {
+		fileops.ColorPrintln("No enabled features to profile", fileops.Yellow)
+	} else {
+		previousMeans := map[string]time.Duration{}
+		if previous != nil {
+			for _, f := range previous.Features {
+				previousMeans[f.Name] = f.Mean
+			}
+		}
+
+		width := len("Feature")
+		for _, f := range result.Features {
+			width = max(width, len(f.Name))
+		}
+
+		header := fmt.Sprintf("  %-*s  %-13s  %9s  %9s", width, "Feature", "Strategy", "Mean", "p95")
+		if previous != nil {
+			header += "  Change"
+		}
+		fmt.Println(header)
+		for _, f := range result.Features {
+			line := fmt.Sprintf("  %-*s  %-13s  %9s  %9s", width, f.Name, f.Strategy, formatDuration(f.Mean), formatDuration(f.P95))
+			if previous != nil {
+				previousMean, known := previousMeans[f.Name]
+				line += "  " + formatChange(f.Mean, previousMean, known)
+			}
+			fmt.Println(line)
+		}
+
+		fmt.Println("\n  By strategy:")
+		totals := StrategyTotals(result)
+		for _, strategy := range []string{"eager", "defer", "on-command", "on-completion", "on-directory"} {
+			if total, ok := totals[strategy]; ok {
+				fmt.Printf("    %-13s  %9s  %s\n", strategy, formatDuration(total), strategyNotes[strategy])
+			}
+		}
+	}
+
+	total := fmt.Sprintf("\n  Whole shell: %s mean, %s p95", formatDuration(result.Total.Mean), formatDuration(result.Total.P95))
+	if previous != nil {
+		total += "  " + formatChange(result.Total.Mean, previous.Total.Mean, true)
+	}
+	fmt.Println(total)
+
+	suggestions := Suggest(result, threshold)
+	if len(suggestions) == 0 {
+		fileops.ColorPrintfn(fileops.Green, "\nNo eager feature takes %s or longer", formatDuration(threshold))
+		return
+	}
+
+	fileops.ColorPrintln("\nSuggestions:", fileops.Yellow)
+	for _, s := range suggestions {
+		if s.Strategy == "on-command" {
+			fmt.Printf("  • Switch %s to on-command (%s): it adds %s to every startup\n", s.Feature, strings.Join(s.Commands, ", "), formatDuration(s.Mean))
+			fileops.ColorPrintfn(fileops.Cyan, "    %s feature enable %s --shell %s --strategy on-command --on-command %s", alias, s.Feature, result.Shell, strings.Join(s.Commands, ","))
+			continue
+		}
+		fmt.Printf("  • Switch %s to defer: it adds %s to every startup\n", s.Feature, formatDuration(s.Mean))
+		fileops.ColorPrintfn(fileops.Cyan, "    %s feature enable %s --shell %s --strategy defer", alias, s.Feature, result.Shell)
+	}
+}
+
+// formatDuration formats a duration in milliseconds with one decimal.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// formatChange formats the difference between a measurement and the previous one.
+func formatChange(current, previous time.Duration, known bool) string {
+	if !known {
+		return "new"
+	}
+	change := current - previous
+	if change >= 0 {
+		return "+" + formatDuration(change)
+	}
+	return formatDuration(change)
+}
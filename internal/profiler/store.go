@@ -0,0 +1,67 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/config"
+)
+
+// ProfilesDirName is the directory next to config.json that keeps saved profile results.
+const ProfilesDirName = "profiles"
+
+// ProfilesDir returns the directory that keeps saved profile results.
+func ProfilesDir() string {
+	return filepath.Join(config.Dir(), ProfilesDirName)
+}
+
+// Save writes a result to the profiles directory and returns its path.
+func Save(result *Result) (string, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	dir := ProfilesDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", result.Shell, result.Time.UTC().Format("20060102T150405Z")))
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to save profile: %w", err)
+	}
+	return path, nil
+}
+
+// Latest returns the most recently saved result for a shell, or nil when there is none.
+func Latest(shellName string) (*Result, error) {
+	paths, err := filepath.Glob(filepath.Join(ProfilesDir(), shellName+"-*.json"))
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	// Names end in a UTC timestamp, so they sort by time
+	slices.Sort(paths)
+	return Load(paths[len(paths)-1])
+}
+
+// Load reads a saved result.
+func Load(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", filepath.Base(path), err)
+	}
+	if result.Shell == "" {
+		return nil, fmt.Errorf("%s is not a profile result", filepath.Base(path))
+	}
+	return &result, nil
+}
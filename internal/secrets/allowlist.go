@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AllowlistFileName is the file at the repository root listing known false positives.
+const AllowlistFileName = ".secrets-allowlist"
+
+// Allowlist suppresses findings that are known to be harmless.
+//
+// Each line of the allowlist file is one of:
+//
+//	fingerprint:<hex>   a single secret, as printed in the scan report
+//	<glob>              every finding in matching files, e.g. files/.config/gh/*.yml
+//	<glob> <rule>       one rule in matching files, e.g. files/.zshrc high-entropy
+//
+// Globs match repository-relative paths with forward slashes. Blank lines and lines
+// starting with # are ignored.
+type Allowlist struct {
+	fingerprints map[string]bool
+	paths        []pathEntry
+}
+
+type pathEntry struct {
+	glob string
+	rule string // Empty allows every rule
+}
+
+// LoadAllowlist reads the allowlist file in the repository. A missing file allows nothing.
+func LoadAllowlist(repoPath string) (*Allowlist, error) {
+	file, err := os.Open(filepath.Join(repoPath, AllowlistFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Allowlist{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", AllowlistFileName, err)
+	}
+	defer file.Close()
+
+	allowlist := &Allowlist{fingerprints: map[string]bool{}}
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if fingerprint, ok := strings.CutPrefix(line, "fingerprint:"); ok {
+			allowlist.fingerprints[strings.TrimSpace(fingerprint)] = true
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s line %d: expected a path and an optional rule", AllowlistFileName, n)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid pattern %q", AllowlistFileName, n, fields[0])
+		}
+
+		entry := pathEntry{glob: fields[0]}
+		if len(fields) == 2 {
+			entry.rule = fields[1]
+		}
+		allowlist.paths = append(allowlist.paths, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", AllowlistFileName, err)
+	}
+
+	return allowlist, nil
+}
+
+// Allows reports whether a finding is allowlisted.
+func (a *Allowlist) Allows(f Finding) bool {
+	if a == nil {
+		return false
+	}
+	if a.fingerprints[f.Fingerprint] {
+		return true
+	}
+	for _, entry := range a.paths {
+		if entry.rule != "" && entry.rule != f.Rule {
+			continue
+		}
+		if matched, _ := path.Match(entry.glob, f.Path); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the findings that are not allowlisted.
+func (a *Allowlist) Filter(findings []Finding) []Finding {
+	var kept []Finding
+	for _, f := range findings {
+		if !a.Allows(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,170 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// aliasUnsupported lists, per shell, POSIX sh syntax that the shell reads differently
+var aliasUnsupported = map[string][]string{
+	"fish":       {"`", "${", "$((", "$?", "[[", "<(", "<<"},
+	"powershell": {"`", "$", "&&", "||", "<", "[["},
+}
+
+// powerShellCmdletAliases maps the built-in PowerShell aliases on Windows that share a name with a
+// POSIX command to the cmdlet they run
+var powerShellCmdletAliases = map[string]string{
+	"cat": "Get-Content", "cd": "Set-Location", "clear": "Clear-Host", "cp": "Copy-Item",
+	"curl": "Invoke-WebRequest", "diff": "Compare-Object", "dir": "Get-ChildItem", "echo": "Write-Output",
+	"history": "Get-History", "kill": "Stop-Process", "ls": "Get-ChildItem", "man": "Get-Help",
+	"mount": "New-PSDrive", "mv": "Move-Item", "ps": "Get-Process", "pwd": "Get-Location",
+	"rm": "Remove-Item", "rmdir": "Remove-Item", "sleep": "Start-Sleep", "sort": "Sort-Object",
+	"tee": "Tee-Object", "type": "Get-Content", "wget": "Invoke-WebRequest",
+}
+
+var (
+	// powerShellNamePattern matches alias names PowerShell can call as a function
+	powerShellNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	// posixOptionPattern matches long options and short option clusters such as -la, which cmdlets
+	// do not take
+	posixOptionPattern = regexp.MustCompile(`^(--[A-Za-z0-9]|-[a-z]{1,3}$)`)
+	// cmdletPattern matches cmdlet names written as Verb-Noun, unlike native commands such as docker-compose
+	cmdletPattern = regexp.MustCompile(`^[A-Z][a-z]+-[A-Z][A-Za-z]+$`)
+)
+
+// AliasProblem returns why an alias cannot be defined in a shell, or "" when it can
+func AliasProblem(shellName string, alias manifest.AliasConfig) string {
+	for _, syntax := range aliasUnsupported[shellName] {
+		if strings.Contains(alias.Command, syntax) {
+			return fmt.Sprintf("'%s' does not work as it does in POSIX sh", syntax)
+		}
+	}
+	if shellName == "powershell" {
+		return powerShellAliasProblem(alias)
+	}
+	return ""
+}
+
+// powerShellAliasProblem returns why an alias cannot be a PowerShell alias or function, or "" when it can
+func powerShellAliasProblem(alias manifest.AliasConfig) string {
+	if !powerShellNamePattern.MatchString(alias.Name) {
+		return fmt.Sprintf("'%s' is not a valid PowerShell function name", alias.Name)
+	}
+
+	fields := strings.Fields(alias.Command)
+	if len(fields) == 0 {
+		return ""
+	}
+	// A function that calls a command with its own name calls itself
+	if strings.EqualFold(fields[0], alias.Name) {
+		return "a PowerShell function cannot call a command with its own name"
+	}
+
+	cmdlet, isAlias := powerShellCmdletAliases[fields[0]]
+	if !isAlias && !cmdletPattern.MatchString(fields[0]) {
+		return ""
+	}
+	for _, argument := range fields[1:] {
+		if posixOptionPattern.MatchString(argument) {
+			if isAlias {
+				return fmt.Sprintf("'%s' runs %s in PowerShell, which does not take '%s'", fields[0], cmdlet, argument)
+			}
+			return fmt.Sprintf("%s does not take '%s'", fields[0], argument)
+		}
+	}
+	return ""
+}
+
+// LoadAliases returns the aliases a shell's init script defines, with local aliases applied
+func LoadAliases(repoPath, shellName string) ([]manifest.AliasConfig, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifests: %w", err)
+	}
+	return merged.Aliases, nil
+}
+
+// SetAlias adds or replaces an alias in a shell's manifest, or in its local manifest, and regenerates the init script
+func SetAlias(repoPath, shellName string, alias manifest.AliasConfig, local bool) error {
+	return updateAliases(repoPath, shellName, local, func(m *manifest.FeatureManifest) error {
+		return m.SetAlias(alias)
+	})
+}
+
+// RemoveAlias removes an alias from a shell's manifest, or from its local manifest, and regenerates the init script
+func RemoveAlias(repoPath, shellName, name string, local bool) error {
+	return updateAliases(repoPath, shellName, local, func(m *manifest.FeatureManifest) error {
+		return m.RemoveAlias(name)
+	})
+}
+
+func updateAliases(repoPath, shellName string, local bool, update func(*manifest.FeatureManifest) error) error {
+	manifestPath := GetManifestPath(repoPath, shellName)
+	if local {
+		manifestPath = GetLocalManifestPath(repoPath, shellName)
+		if err := manifest.ValidateLocalManifest(manifestPath); err != nil {
+			return fmt.Errorf("refusing to write %s: %w", localManifestFileName, err)
+		}
+	}
+
+	m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{}}
+	if !local || fileops.PathExists(manifestPath) {
+		parsed, err := manifest.ParseManifest(manifestPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		m = parsed
+	}
+
+	if err := update(m); err != nil {
+		return err
+	}
+	if err := manifest.WriteManifest(manifestPath, m); err != nil {
+		return err
+	}
+
+	return RegenerateInitScript(repoPath, shellName)
+}
+
+// aliasBlock returns the code that defines the aliases in a shell. Aliases the shell cannot
+// express are left out with a comment saying why.
+func aliasBlock(shellName string, aliases []manifest.AliasConfig) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Aliases\n")
+	for _, alias := range aliases {
+		if problem := AliasProblem(shellName, alias); problem != "" {
+			sb.WriteString(fmt.Sprintf("# Alias '%s' is not defined: %s\n", alias.Name, problem))
+			continue
+		}
+		switch shellName {
+		case "fish":
+			// Abbreviations expand as they are typed, so history shows the full command. Before fish
+			// 3.6 they are universal unless -g is given, and would outlive the init script.
+			sb.WriteString(fmt.Sprintf("abbr --add -g %s %s\n", alias.Name, fishQuote(alias.Command)))
+		case "powershell":
+			sb.WriteString(powerShellAlias(alias))
+		default:
+			sb.WriteString(fmt.Sprintf("alias %s=%s\n", alias.Name, shQuote(alias.Command)))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// powerShellAlias returns Set-Alias for an alias to a single command, and a function that passes
+// its arguments on otherwise. Functions rank below aliases, so an alias with the same name is removed.
+func powerShellAlias(alias manifest.AliasConfig) string {
+	fields := strings.Fields(alias.Command)
+	if len(fields) == 1 && !strings.ContainsAny(alias.Command, "'\"|;&(){}@>") {
+		return fmt.Sprintf("Set-Alias -Name %s -Value %s -Option AllScope -Force\n", alias.Name, psQuote(fields[0]))
+	}
+	return fmt.Sprintf("Remove-Item Alias:%s -Force -ErrorAction SilentlyContinue\nfunction %s { %s @args }\n", alias.Name, alias.Name, strings.TrimSpace(alias.Command))
+}
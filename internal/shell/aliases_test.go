@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func TestAliasProblem(t *testing.T) {
+	tests := []struct {
+		shell   string
+		command string
+		ok      bool
+	}{
+		{"bash", "echo `date` ${HOME} $((1+1))", true},
+		{"fish", "git log --oneline | head", true},
+		{"fish", "cd && ls", true},
+		{"fish", "echo ${HOME}", false},
+		{"fish", "echo `date`", false},
+		{"powershell", "git status", true},
+		{"powershell", "Get-ChildItem -Force | Select-Object Name", true},
+		{"powershell", "cd $HOME", false},
+		{"powershell", "make && make install", false},
+		{"powershell", "ls -la", false},
+		{"powershell", "rm --recursive build", false},
+		{"powershell", "ls ~/src", true},
+		{"powershell", "ls -Force", true},
+		{"powershell", "Get-ChildItem -al", false},
+		{"powershell", "docker ps -aq", true},
+		{"powershell", "docker-compose -f dev.yml up", true},
+	}
+	for _, tt := range tests {
+		problem := AliasProblem(tt.shell, manifest.AliasConfig{Name: "x", Command: tt.command})
+		if (problem == "") != tt.ok {
+			t.Errorf("AliasProblem(%s, %q) = %q", tt.shell, tt.command, problem)
+		}
+	}
+
+	if AliasProblem("powershell", manifest.AliasConfig{Name: "ls", Command: "ls --color"}) == "" {
+		t.Error("expected a PowerShell alias that calls itself to be rejected")
+	}
+	if AliasProblem("bash", manifest.AliasConfig{Name: "ls", Command: "ls --color"}) != "" {
+		t.Error("expected a bash alias with its own name to be allowed")
+	}
+	if AliasProblem("powershell", manifest.AliasConfig{Name: "..", Command: "cd .."}) == "" {
+		t.Error("expected a name PowerShell cannot call to be rejected")
+	}
+	if AliasProblem("bash", manifest.AliasConfig{Name: "..", Command: "cd .."}) != "" {
+		t.Error("expected '..' to be allowed in bash")
+	}
+}
+
+func TestAliasBlocks(t *testing.T) {
+	aliases := []manifest.AliasConfig{
+		{Name: "ll", Command: "ls -la"},
+		{Name: "g", Command: "git"},
+		{Name: "home", Command: "cd ${HOME}"},
+		{Name: "hi", Command: "echo 'hi'"},
+		{Name: "gs", Command: "git status"},
+	}
+
+	fish := aliasBlock("fish", aliases)
+	for _, want := range []string{
+		"abbr --add -g ll 'ls -la'\n",
+		"abbr --add -g g 'git'\n",
+		`abbr --add -g hi 'echo \'hi\''`,
+		"# Alias 'home' is not defined: '${' does not work as it does in POSIX sh",
+	} {
+		if !strings.Contains(fish, want) {
+			t.Errorf("fish aliases should contain %q:\n%s", want, fish)
+		}
+	}
+
+	powershell := aliasBlock("powershell", aliases)
+	for _, want := range []string{
+		"Remove-Item Alias:gs -Force -ErrorAction SilentlyContinue\nfunction gs { git status @args }\n",
+		"Set-Alias -Name g -Value 'git' -Option AllScope -Force\n",
+		"function hi { echo 'hi' @args }\n",
+		"# Alias 'home' is not defined",
+		"# Alias 'll' is not defined: 'ls' runs Get-ChildItem in PowerShell, which does not take '-la'\n",
+	} {
+		if !strings.Contains(powershell, want) {
+			t.Errorf("PowerShell aliases should contain %q:\n%s", want, powershell)
+		}
+	}
+
+	if got := aliasBlock("zsh", aliases[:1]); got != "# Aliases\nalias ll='ls -la'\n\n" {
+		t.Errorf("zsh aliases = %q", got)
+	}
+	if aliasBlock("bash", nil) != "" {
+		t.Error("no aliases should not add anything")
+	}
+}
+
+func TestAliasesInInitScript(t *testing.T) {
+	for _, shellName := range []string{"bash", "posix"} {
+		t.Run(shellName, func(t *testing.T) {
+			executable, ok := FindShellExecutable(shellName)
+			if !ok {
+				t.Skipf("%s is not available", shellName)
+			}
+
+			// The feature defines ll too; the declared alias wins
+			m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{{Name: "core-aliases"}}}
+			repoPath := writeShellRepo(t, shellName, m, map[string]string{"core-aliases": "alias ll='ls -l'\n"})
+			if err := SetAlias(repoPath, shellName, manifest.AliasConfig{Name: "ll", Command: "ls -la"}, false); err != nil {
+				t.Fatalf("SetAlias: %v", err)
+			}
+			if err := SetAlias(repoPath, shellName, manifest.AliasConfig{Name: "say", Command: `printf '%s\n' "it's"`}, true); err != nil {
+				t.Fatalf("SetAlias local: %v", err)
+			}
+			initPath, _ := GetInitScriptPath(repoPath, shellName)
+
+			script := ". ./init.sh\nalias ll\nsay"
+			if shellName == "bash" {
+				// Non-interactive bash only expands aliases when asked to
+				script = "shopt -s expand_aliases\n" + script
+			}
+			cmd := exec.Command(executable, "-c", script)
+			// The POSIX init script finds its directory from $0, so run it from the shell directory
+			cmd.Args[0] = filepath.Base(executable)
+			cmd.Dir = filepath.Dir(initPath)
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", shellName, err, output)
+			}
+			if got := strings.TrimPrefix(string(output), "alias "); got != "ll='ls -la'\nit's\n" {
+				t.Fatalf("got:\n%s", output)
+			}
+
+			if err := RemoveAlias(repoPath, shellName, "say", true); err != nil {
+				t.Fatalf("RemoveAlias: %v", err)
+			}
+			if err := RemoveAlias(repoPath, shellName, "say", false); err == nil {
+				t.Fatal("expected removing an alias that is only local from enabled.json to fail")
+			}
+			aliases, err := LoadAliases(repoPath, shellName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(aliases) != 1 || aliases[0].Name != "ll" {
+				t.Fatalf("aliases = %+v, want only ll", aliases)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/fileops"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// bundleBaseName is the name, without extension, of the file bundling a shell's eager features.
+// Bundles are built per machine and never committed.
+const bundleBaseName = "bundle"
+
+// bundleLockTimeout is how long a background rebuild may hold the bundle lock. An older lock was
+// left by a rebuild that did not finish, and is taken over.
+const bundleLockTimeout = time.Minute
+
+// GetBundlePath returns the path of the bundle of eager features for a shell
+func GetBundlePath(repoPath, shellName string) (string, error) {
+	config, ok := GetShellConfig(shellName)
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s", shellName)
+	}
+	return filepath.Join(GetShellDirectory(repoPath, shellName), bundleBaseName+config.Extension), nil
+}
+
+// IsLocalOnlyFile reports whether a path under omd-shells belongs to this machine only and is
+// never committed: the local override manifest, generated bundles and their locks.
+func IsLocalOnlyFile(path string) bool {
+	path = filepath.ToSlash(path)
+	name := filepath.Base(path)
+	if name == localManifestFileName {
+		return true
+	}
+
+	// Bundles sit directly in the shell directory, next to the init script
+	config, ok := GetShellConfig(filepath.Base(filepath.Dir(path)))
+	if !ok {
+		return false
+	}
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".zwc"), ".lock")
+	return name == bundleBaseName+config.Extension
+}
+
+// LockBundle takes the lock that lets only one background rebuild of a shell's bundle run at a
+// time, so shells started together with a stale bundle do not all rebuild it. It returns false
+// when another rebuild holds the lock.
+func LockBundle(repoPath, shellName string) (unlock func(), ok bool, err error) {
+	bundlePath, err := GetBundlePath(repoPath, shellName)
+	if err != nil {
+		return nil, false, err
+	}
+	lockPath := bundlePath + ".lock"
+
+	if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > bundleLockTimeout {
+		os.Remove(lockPath)
+	}
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock bundle: %w", err)
+	}
+	file.Close()
+	return func() { os.Remove(lockPath) }, true, nil
+}
+
+// SetBundleMode turns bundle mode on or off for a shell, in enabled.json or, with local,
+// in enabled.local.json for this machine only, and rebuilds the init script and bundle.
+func SetBundleMode(repoPath, shellName string, on, local bool) error {
+	manifestPath := GetManifestPath(repoPath, shellName)
+	if local {
+		manifestPath = GetLocalManifestPath(repoPath, shellName)
+		if err := manifest.ValidateLocalManifest(manifestPath); err != nil {
+			return fmt.Errorf("refusing to write %s: %w", localManifestFileName, err)
+		}
+	}
+
+	m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{}}
+	if !local || fileops.PathExists(manifestPath) {
+		parsed, err := manifest.ParseManifest(manifestPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		m = parsed
+	}
+
+	m.Bundle = &on
+	if err := manifest.WriteManifest(manifestPath, m); err != nil {
+		return err
+	}
+
+	return RegenerateInitScript(repoPath, shellName)
+}
+
+// IsBundleMode reports whether a shell loads its eager features from a bundle on this machine
+func IsBundleMode(repoPath, shellName string) (bool, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse manifests: %w", err)
+	}
+	return merged.Bundle, nil
+}
+
+// RebuildBundle writes the bundle of eager features for a shell when bundle mode is on, and
+// removes any bundle left over when it is off. zsh bundles are also compiled with zcompile.
+func RebuildBundle(repoPath, shellName string) error {
+	bundlePath, err := GetBundlePath(repoPath, shellName)
+	if err != nil {
+		return err
+	}
+
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return fmt.Errorf("failed to parse manifests: %w", err)
+	}
+
+	// A compiled bundle must never outlive its source
+	if err := os.Remove(bundlePath + ".zwc"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove compiled bundle: %w", err)
+	}
+	if !merged.Bundle {
+		if err := os.Remove(bundlePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove bundle: %w", err)
+		}
+		return nil
+	}
+
+	content, err := generateBundle(repoPath, shellName, categorizeFeaturesMerged(merged).Eager)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(bundlePath, content); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if shellName == "zsh" {
+		if zsh, ok := FindShellExecutable("zsh"); ok {
+			if output, err := exec.Command(zsh, "-fc", `zcompile -- "$1"`, "zsh", bundlePath).CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to compile bundle: %w: %s", err, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+	return nil
+}
+
+// generateBundle concatenates the eager features of a shell in load order. Each feature runs in
+// its own function or block, so a return or error in one does not stop the others. A feature
+// the shell cannot parse is sourced on its own, so its error is reported as without a bundle.
+func generateBundle(repoPath, shellName string, eager []string) (string, error) {
+	config, ok := GetShellConfig(shellName)
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s", shellName)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - %s feature bundle
+# Auto-generated - do not edit manually
+# Rebuilt when a feature file or the manifest changes
+
+`, shellName))
+
+	for _, feature := range eager {
+		featurePath := filepath.Join(GetFeaturesDirectory(repoPath, shellName), feature+config.Extension)
+		data, err := os.ReadFile(featurePath)
+		if errors.Is(err, os.ErrNotExist) {
+			sb.WriteString(fmt.Sprintf("# --- feature: %s (missing) ---\n", feature))
+			sb.WriteString(bundleMissingWarning(shellName, feature))
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read feature '%s': %w", feature, err)
+		}
+
+		relativePath := "features/" + feature + config.Extension
+		if !featureParses(shellName, featurePath) {
+			sb.WriteString(fmt.Sprintf("# --- feature: %s (%s) does not parse; sourced on its own ---\n", feature, relativePath))
+			sb.WriteString(bundleSourceOnItsOwn(shellName, feature))
+			continue
+		}
+
+		content := strings.TrimRight(fileops.NormalizeLineEndings(string(data)), "\n")
+		sb.WriteString(fmt.Sprintf("# --- begin feature: %s (%s) ---\n", feature, relativePath))
+		switch shellName {
+		case "fish":
+			sb.WriteString("begin\n" + content + "\nend\n")
+		case "powershell":
+			sb.WriteString(fmt.Sprintf(`try {
+  . {
+%s
+  }
+} catch {
+  Write-Warning "oh-my-dot: feature '%s' failed: $_"
+}
+`, content, feature))
+		default:
+			sb.WriteString("_omd_bundled_feature() {\n  :\n" + content + "\n}\n_omd_bundled_feature\n")
+		}
+		sb.WriteString(fmt.Sprintf("# --- end feature: %s ---\n\n", feature))
+	}
+
+	if shellName != "fish" && shellName != "powershell" {
+		sb.WriteString("unset -f _omd_bundled_feature 2>/dev/null\n")
+	}
+	return sb.String(), nil
+}
+
+func bundleMissingWarning(shellName, feature string) string {
+	if shellName == "powershell" {
+		return fmt.Sprintf("Write-Warning \"oh-my-dot: feature '%s' not found\"\n\n", feature)
+	}
+	return fmt.Sprintf("echo \"oh-my-dot: warning: feature '%s' not found\" >&2\n\n", feature)
+}
+
+func bundleSourceOnItsOwn(shellName, feature string) string {
+	switch shellName {
+	case "fish":
+		return fmt.Sprintf("source \"$OMD_SHELL_ROOT/features/%s.fish\"\n\n", feature)
+	case "powershell":
+		return fmt.Sprintf(". (Join-Path $OMD_SHELL_ROOT \"features\\%s.ps1\")\n\n", feature)
+	case "zsh":
+		return fmt.Sprintf(". \"$OMD_SHELL_ROOT/features/%s.zsh\"\n\n", feature)
+	default:
+		return fmt.Sprintf(". \"$OMD_SHELL_ROOT/features/%s.sh\"\n\n", feature)
+	}
+}
+
+// featureParses reports whether a shell can parse a feature file. Without the shell, or for
+// PowerShell, which has no syntax-only mode, the feature is assumed to parse.
+func featureParses(shellName, path string) bool {
+	if shellName == "powershell" {
+		return true
+	}
+	executable, ok := FindShellExecutable(shellName)
+	if !ok {
+		return true
+	}
+	return exec.Command(executable, "-n", path).Run() == nil
+}
+
+// bundleFreshnessFiles lists the files, relative to the shell directory, that make a bundle stale when they change
+func bundleFreshnessFiles(eager []string, extension, separator string) []string {
+	files := []string{manifestFileName, localManifestFileName}
+	for _, feature := range eager {
+		files = append(files, "features"+separator+feature+extension)
+	}
+	return files
+}
+
+// bundleLoader returns the code an init script uses to load eager features in bundle mode: the
+// bundle while it is newer than every feature file and manifest, and otherwise the separate
+// feature files in loadFeatures while oh-my-dot rebuilds the bundle in the background. The
+// background rebuild holds the lock from LockBundle, so other shells starting meanwhile skip it.
+// The code is indented by indent; loadFeatures must already be indented one level deeper.
+func bundleLoader(shellName, loadFeatures, indent string) string {
+	var head, tail string
+	switch shellName {
+	case "bash", "posix":
+		head = fmt.Sprintf(`if _omd_bundle_fresh; then
+  . "$OMD_SHELL_ROOT/bundle.sh"
+else
+  command -v oh-my-dot >/dev/null 2>&1 && ( oh-my-dot shell bundle --shell %s --background >/dev/null 2>&1 & )
+`, shellName)
+		tail = "fi\n"
+	case "zsh":
+		head = `if _omd_bundle_fresh; then
+  . "$OMD_SHELL_ROOT/bundle.zsh"
+else
+  (( $+commands[oh-my-dot] )) && oh-my-dot shell bundle --shell zsh --background >/dev/null 2>&1 &!
+`
+		tail = "fi\n"
+	case "fish":
+		head = `if __omd_bundle_fresh $OMD_SHELL_ROOT
+  source "$OMD_SHELL_ROOT/bundle.fish"
+else
+  if command -q oh-my-dot
+    command oh-my-dot shell bundle --shell fish --background >/dev/null 2>&1 &
+    disown
+  end
+`
+		tail = "end\n"
+	case "powershell":
+		head = `if (__omd_bundle_fresh $OMD_SHELL_ROOT) {
+  . (Join-Path $OMD_SHELL_ROOT "bundle.ps1")
+} else {
+  if (Get-Command oh-my-dot -ErrorAction SilentlyContinue) {
+    Start-Process oh-my-dot -ArgumentList "shell", "bundle", "--shell", "powershell", "--background" -NoNewWindow
+  }
+`
+		tail = "}\n"
+	}
+
+	return indentLines(head, indent) + strings.TrimRight(loadFeatures, "\n") + "\n" + indentLines(tail, indent)
+}
+
+// indentLines prefixes every line of code with indent
+func indentLines(code, indent string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if strings.TrimSpace(line) != "" {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// bundleFreshCheck returns the definition of the function bundleLoader uses to tell whether the bundle is up to date.
+func bundleFreshCheck(shellName string, eager []string) string {
+	switch shellName {
+	case "bash":
+		return fmt.Sprintf(`_omd_bundle_fresh() {
+  local bundle="$OMD_SHELL_ROOT/bundle.sh" file
+  [ -r "$bundle" ] || return 1
+  for file in %s; do
+    [ "$OMD_SHELL_ROOT/$file" -nt "$bundle" ] && return 1
+  done
+  return 0
+}
+
+`, strings.Join(bundleFreshnessFiles(eager, ".sh", "/"), " "))
+	case "zsh":
+		return fmt.Sprintf(`_omd_bundle_fresh() {
+  local bundle="$OMD_SHELL_ROOT/bundle.zsh" file
+  [[ -r "$bundle" ]] || return 1
+  for file in %s; do
+    [[ "$OMD_SHELL_ROOT/$file" -nt "$bundle" ]] && return 1
+  done
+  return 0
+}
+
+`, strings.Join(bundleFreshnessFiles(eager, ".zsh", "/"), " "))
+	case "posix":
+		return fmt.Sprintf(`_omd_bundle_fresh() {
+  [ -r "$OMD_SHELL_ROOT/bundle.sh" ] || return 1
+  for _omd_file in %s; do
+    [ "$OMD_SHELL_ROOT/$_omd_file" -nt "$OMD_SHELL_ROOT/bundle.sh" ] && return 1
+  done
+  return 0
+}
+
+`, strings.Join(bundleFreshnessFiles(eager, ".sh", "/"), " "))
+	case "fish":
+		return fmt.Sprintf(`function __omd_bundle_fresh --argument-names root
+  set -l bundle "$root/bundle.fish"
+  test -r "$bundle"; or return 1
+  set -l built (path mtime "$bundle")
+  for file in %s
+    set -l changed (path mtime "$root/$file" 2>/dev/null); or continue
+    test $changed -gt $built; and return 1
+  end
+  return 0
+end
+
+`, strings.Join(bundleFreshnessFiles(eager, ".fish", "/"), " "))
+	case "powershell":
+		files := bundleFreshnessFiles(eager, ".ps1", "\\")
+		quoted := make([]string, len(files))
+		for i, file := range files {
+			quoted[i] = `"` + file + `"`
+		}
+		return fmt.Sprintf(`function __omd_bundle_fresh($root) {
+  $bundle = Join-Path $root "bundle.ps1"
+  if (-not (Test-Path $bundle)) { return $false }
+  $built = (Get-Item $bundle).LastWriteTimeUtc
+  foreach ($file in @(%s)) {
+    $path = Join-Path $root $file
+    if ((Test-Path $path) -and (Get-Item $path).LastWriteTimeUtc -gt $built) { return $false }
+  }
+  return $true
+}
+
+`, strings.Join(quoted, ", "))
+	default:
+		return ""
+	}
+}
+
+// writeFileAtomic replaces a file in one step, so a shell starting meanwhile never sources half of it
+func writeFileAtomic(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.WriteString(fileops.NormalizeLineEndings(content))
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr, os.Chmod(tmp.Name(), 0644)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
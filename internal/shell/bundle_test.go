@@ -0,0 +1,165 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func TestSetBundleMode(t *testing.T) {
+	repoPath := writeShellRepo(t, "bash", &manifest.FeatureManifest{Features: []manifest.FeatureConfig{{Name: "a"}, {Name: "b"}}},
+		map[string]string{"a": "A=1\n", "b": "B=1\n"})
+	bundlePath, err := GetBundlePath(repoPath, "bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetBundleMode(repoPath, "bash", true, false); err != nil {
+		t.Fatalf("SetBundleMode: %v", err)
+	}
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("expected a bundle: %v", err)
+	}
+	if strings.Index(string(bundle), "A=1") > strings.Index(string(bundle), "B=1") || !strings.Contains(string(bundle), "# --- begin feature: a (features/a.sh) ---") {
+		t.Fatalf("bundle should hold both features in load order:\n%s", bundle)
+	}
+	initPath, _ := GetInitScriptPath(repoPath, "bash")
+	script, _ := os.ReadFile(initPath)
+	if !strings.Contains(string(script), "_omd_bundle_fresh") || !strings.Contains(string(script), "features/a.sh") {
+		t.Fatalf("init script should load the bundle and fall back to the feature files:\n%s", script)
+	}
+
+	// Turning it off on this machine only removes the bundle
+	if err := SetBundleMode(repoPath, "bash", false, true); err != nil {
+		t.Fatalf("SetBundleMode local: %v", err)
+	}
+	if _, err := os.Stat(bundlePath); !os.IsNotExist(err) {
+		t.Fatalf("expected the bundle to be removed, got %v", err)
+	}
+	if bundled, _ := IsBundleMode(repoPath, "bash"); bundled {
+		t.Fatal("expected the local manifest to turn bundling off")
+	}
+	shared, _ := manifest.ParseManifest(GetManifestPath(repoPath, "bash"))
+	if shared.Bundle == nil || !*shared.Bundle {
+		t.Fatal("enabled.json should still turn bundling on")
+	}
+}
+
+func TestBundledInitScript(t *testing.T) {
+	if !IsShellExecutableAvailable("bash") {
+		t.Skip("bash is not available")
+	}
+
+	m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{{Name: "early-return"}, {Name: "broken"}, {Name: "after"}}}
+	repoPath := writeShellRepo(t, "bash", m, map[string]string{
+		"early-return": "OMD_TEST_EARLY=1\nreturn 0\nOMD_TEST_NOT_REACHED=1\n",
+		"after":        "OMD_TEST_AFTER=1\nomd_test_func() { :; }\n",
+		"broken":       "if then fi\n",
+	})
+	if err := SetBundleMode(repoPath, "bash", true, false); err != nil {
+		t.Fatalf("SetBundleMode: %v", err)
+	}
+	bundlePath, _ := GetBundlePath(repoPath, "bash")
+	bundle, _ := os.ReadFile(bundlePath)
+	if !strings.Contains(string(bundle), "# --- feature: broken (features/broken.sh) does not parse; sourced on its own ---") {
+		t.Fatalf("a feature that does not parse should be sourced on its own:\n%s", bundle)
+	}
+
+	initPath, _ := GetInitScriptPath(repoPath, "bash")
+	run := func() string {
+		t.Helper()
+		// PATH without oh-my-dot, so a stale bundle is not rebuilt in the background
+		cmd := exec.Command("bash", "--noprofile", "--norc", "-c",
+			`. "$1" 2>/dev/null; echo "early=$OMD_TEST_EARLY reached=$OMD_TEST_NOT_REACHED after=$OMD_TEST_AFTER func=$(type -t omd_test_func) fresh=$(_omd_bundle_fresh && echo yes)"`, "bash", initPath)
+		cmd.Env = append(os.Environ(), "PATH=/usr/bin:/bin")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("bash: %v", err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	if got, want := run(), "early=1 reached= after=1 func=function fresh=yes"; got != want {
+		t.Fatalf("bundled load = %q, want %q", got, want)
+	}
+
+	// A feature edited after the bundle was built is loaded from its file
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(filepath.Join(GetFeaturesDirectory(repoPath, "bash"), "after.sh"), []byte("OMD_TEST_AFTER=2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(GetFeaturesDirectory(repoPath, "bash"), "after.sh"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := run(), "early=1 reached= after=2 func= fresh="; got != want {
+		t.Fatalf("stale bundle load = %q, want %q", got, want)
+	}
+
+	if err := RebuildBundle(repoPath, "bash"); err != nil {
+		t.Fatalf("RebuildBundle: %v", err)
+	}
+	if err := os.Chtimes(bundlePath, later.Add(time.Second), later.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := run(), "early=1 reached= after=2 func= fresh=yes"; got != want {
+		t.Fatalf("rebuilt bundle load = %q, want %q", got, want)
+	}
+}
+
+func TestIsLocalOnlyFile(t *testing.T) {
+	tests := map[string]bool{
+		"omd-shells/bash/enabled.local.json": true,
+		"omd-shells/zsh/bundle.zsh":          true,
+		"omd-shells/zsh/bundle.zsh.zwc":      true,
+		"omd-shells/bash/bundle.sh":          true,
+		"omd-shells/bash/bundle.sh.lock":     true,
+		"omd-shells/bash/bundle.zsh":         false,
+		"omd-shells/bash/features/bundle.sh": false,
+		"omd-shells/bash/enabled.json":       false,
+		"omd-shells/powershell/bundle.ps1":   true,
+		"omd-shells/fish/bundle.fish":        true,
+		"omd-shells/lib/helpers.sh":          false,
+		"omd-shells/zsh/features/a.zsh.zwc":  false,
+		"omd-shells/unknown/bundle.sh":       false,
+	}
+	for path, want := range tests {
+		if got := IsLocalOnlyFile(path); got != want {
+			t.Errorf("IsLocalOnlyFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestLockBundle(t *testing.T) {
+	repoPath := writeShellRepo(t, "bash", &manifest.FeatureManifest{}, nil)
+
+	unlock, ok, err := LockBundle(repoPath, "bash")
+	if err != nil || !ok {
+		t.Fatalf("LockBundle: %v, %v", ok, err)
+	}
+	if _, ok, _ := LockBundle(repoPath, "bash"); ok {
+		t.Fatal("a second rebuild should not get the lock while the first holds it")
+	}
+	unlock()
+
+	unlock, ok, err = LockBundle(repoPath, "bash")
+	if err != nil || !ok {
+		t.Fatalf("LockBundle after unlock: %v, %v", ok, err)
+	}
+
+	// A lock left by a rebuild that did not finish is taken over
+	bundlePath, _ := GetBundlePath(repoPath, "bash")
+	old := time.Now().Add(-2 * bundleLockTimeout)
+	if err := os.Chtimes(bundlePath+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := LockBundle(repoPath, "bash"); err != nil || !ok {
+		t.Fatalf("LockBundle with a stale lock: %v, %v", ok, err)
+	}
+	unlock()
+}
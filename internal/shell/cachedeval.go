@@ -0,0 +1,329 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long omd_cached_eval keeps output when OMD_CACHE_TTL is not set
+const DefaultCacheTTL = 24 * time.Hour
+
+// CacheEntry is the cached output of one omd_cached_eval call
+type CacheEntry struct {
+	Shell   string
+	Key     string
+	Path    string
+	Binary  string    // Resolved path of the command's binary
+	Command string    // Command line whose output is cached
+	Created time.Time // When the output was cached
+	Size    int64
+}
+
+// CacheDir returns the directory omd_cached_eval keeps a shell's cached output in. It matches
+// the directory the generated function uses: $OMD_CACHE_DIR, else %LOCALAPPDATA%\oh-my-dot for
+// PowerShell on Windows, else $XDG_CACHE_HOME/oh-my-dot or ~/.cache/oh-my-dot.
+func CacheDir(shellName string) (string, error) {
+	base := os.Getenv("OMD_CACHE_DIR")
+	if base == "" && shellName == "powershell" && runtime.GOOS == "windows" {
+		base = filepath.Join(os.Getenv("LOCALAPPDATA"), "oh-my-dot")
+	}
+	if base == "" {
+		if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+			base = filepath.Join(xdg, "oh-my-dot")
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to get home directory: %w", err)
+			}
+			base = filepath.Join(home, ".cache", "oh-my-dot")
+		}
+	}
+	return filepath.Join(base, "eval", shellName), nil
+}
+
+// ListCache returns the cached output of the given shells, sorted by shell and key
+func ListCache(shells []string) ([]CacheEntry, error) {
+	var entries []CacheEntry
+	for _, shellName := range shells {
+		config, ok := GetShellConfig(shellName)
+		if !ok {
+			return nil, fmt.Errorf("unsupported shell: %s", shellName)
+		}
+		dir, err := CacheDir(shellName)
+		if err != nil {
+			return nil, err
+		}
+
+		files, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), config.Extension) {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			entry := CacheEntry{
+				Shell:   shellName,
+				Key:     strings.TrimSuffix(file.Name(), config.Extension),
+				Path:    filepath.Join(dir, file.Name()),
+				Created: info.ModTime(),
+				Size:    info.Size(),
+			}
+			readCacheStamp(&entry)
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Shell != entries[j].Shell {
+			return entries[i].Shell < entries[j].Shell
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// readCacheStamp fills in an entry from its stamp file, whose lines are the binary path, the
+// time the output was cached in Unix seconds and the command line
+func readCacheStamp(entry *CacheEntry) {
+	file, err := os.Open(entry.Path + ".stamp")
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() && len(lines) < 3 {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if len(lines) < 3 {
+		return
+	}
+	entry.Binary, entry.Command = lines[0], lines[2]
+	if seconds, err := strconv.ParseInt(lines[1], 10, 64); err == nil {
+		entry.Created = time.Unix(seconds, 0)
+	}
+}
+
+// ClearCache removes the cached output of the given shells, only for the given keys when any
+// are given, and returns the entries it removed
+func ClearCache(shells, keys []string) ([]CacheEntry, error) {
+	entries, err := ListCache(shells)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if len(wanted) > 0 && !wanted[entry.Key] {
+			continue
+		}
+		for _, path := range []string{entry.Path, entry.Path + ".stamp", entry.Path + ".ref"} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// cachedEvalFunction returns the definition of omd_cached_eval for a shell. It runs a command
+// and sources its output like eval "$(command)", but keeps the output in CacheDir and sources
+// that instead until the command's binary moves or changes, its arguments change or the cache
+// is older than OMD_CACHE_TTL seconds. Output of a failing command is sourced but not cached.
+func cachedEvalFunction(shellName string) string {
+	ttl := int(DefaultCacheTTL.Seconds())
+	switch shellName {
+	case "bash":
+		return shCachedEvalFunction("bash", `bin="$(type -P "$1")" || return 127`, `now="${EPOCHSECONDS:-$(date +%s)}"`, ttl)
+	case "zsh":
+		return shCachedEvalFunction("zsh", `bin="${commands[$1]}"; [ -n "$bin" ] || return 127`, `zmodload -F zsh/datetime p:EPOCHSECONDS 2>/dev/null; now="${EPOCHSECONDS:-$(date +%s)}"`, ttl)
+	case "fish":
+		return fmt.Sprintf(`# Source a command's output, cached until its binary or arguments change or the cache is
+# older than OMD_CACHE_TTL seconds
+# Usage: omd_cached_eval <key> <command> [args...]
+function omd_cached_eval
+  set -l key $argv[1]
+  set -l cmd $argv[2..-1]
+  set -l bin (command -s $cmd[1]); or return 127
+  set -l dir $HOME/.cache/oh-my-dot
+  set -q XDG_CACHE_HOME; and set dir $XDG_CACHE_HOME/oh-my-dot
+  set -q OMD_CACHE_DIR; and set dir $OMD_CACHE_DIR
+  set dir $dir/eval/fish
+  set -l file $dir/$key.fish
+  set -l mtime (path mtime $bin)
+  set -l now (date +%%s)
+  set -l ttl %d
+  set -q OMD_CACHE_TTL; and set ttl $OMD_CACHE_TTL
+  set -l stamp
+  if test -r $file; and test -r $file.stamp
+    while read -l line
+      set -a stamp $line
+    end < $file.stamp
+  end
+  if test (count $stamp) -ge 4; and test "$stamp[1]" = "$bin" -a "$stamp[3]" = "$cmd" -a "$stamp[4]" = "$mtime"; and test (math $now - $stamp[2]) -lt $ttl
+    source $file
+    return
+  end
+  if not mkdir -p $dir 2>/dev/null
+    $cmd | source
+    return
+  end
+  if $cmd > $file.$fish_pid
+    mv -f $file.$fish_pid $file
+    printf '%%s\n' $bin $now "$cmd" $mtime > $file.stamp
+    source $file
+  else
+    source $file.$fish_pid
+    rm -f $file.$fish_pid
+  end
+end
+
+`, ttl)
+	case "powershell":
+		return fmt.Sprintf(`# Return a command's output, cached until its binary or arguments change or the cache is
+# older than OMD_CACHE_TTL seconds. Dot-source the result so it runs in the caller's scope:
+#   . (omd_cached_eval <key> <command> [args...])
+function omd_cached_eval {
+  param([string]$Key, [string]$Command, [Parameter(ValueFromRemainingArguments = $true)][string[]]$Arguments)
+  $application = Get-Command $Command -CommandType Application -ErrorAction SilentlyContinue | Select-Object -First 1
+  if (-not $application) {
+    return {}
+  }
+  $cacheRoot = if ($env:OMD_CACHE_DIR) { $env:OMD_CACHE_DIR }
+    elseif ($env:OS -eq 'Windows_NT') { Join-Path $env:LOCALAPPDATA 'oh-my-dot' }
+    elseif ($env:XDG_CACHE_HOME) { Join-Path $env:XDG_CACHE_HOME 'oh-my-dot' }
+    else { Join-Path $HOME '.cache/oh-my-dot' }
+  $dir = Join-Path $cacheRoot 'eval/powershell'
+  $file = Join-Path $dir "$Key.ps1"
+  $binary = $application.Source
+  $mtime = (Get-Item -LiteralPath $binary).LastWriteTimeUtc.Ticks
+  $now = [DateTimeOffset]::UtcNow.ToUnixTimeSeconds()
+  $ttl = if ($env:OMD_CACHE_TTL) { [long]$env:OMD_CACHE_TTL } else { %d }
+  $commandLine = (@($Command) + @($Arguments)) -join ' '
+  if ((Test-Path -LiteralPath $file) -and (Test-Path -LiteralPath "$file.stamp")) {
+    $stamp = @(Get-Content -LiteralPath "$file.stamp")
+    if ($stamp.Count -ge 4 -and $stamp[0] -eq $binary -and ($now - [long]$stamp[1]) -lt $ttl -and $stamp[2] -eq $commandLine -and $stamp[3] -eq "$mtime") {
+      return $file
+    }
+  }
+  $output = & $binary @Arguments | Out-String
+  if ($LASTEXITCODE -ne 0) {
+    return [scriptblock]::Create($output)
+  }
+  try {
+    New-Item -ItemType Directory -Force -Path $dir -ErrorAction Stop | Out-Null
+    Set-Content -LiteralPath $file -Value $output -Encoding utf8 -ErrorAction Stop
+    Set-Content -LiteralPath "$file.stamp" -Value @($binary, $now, $commandLine, $mtime) -Encoding utf8 -ErrorAction Stop
+    return $file
+  } catch {
+    return [scriptblock]::Create($output)
+  }
+}
+
+`, ttl)
+	case "posix":
+		return fmt.Sprintf(`# Source a command's output, cached until its binary or arguments change or the cache is
+# older than OMD_CACHE_TTL seconds
+# Usage: omd_cached_eval <key> <command> [args...]
+omd_cached_eval() {
+  _omd_ce_key="$1"; shift
+  _omd_ce_bin="$(command -v "$1" 2>/dev/null)" || return 127
+  case "$_omd_ce_bin" in
+    /*) ;;
+    *) eval "$("$@")"; return ;;
+  esac
+  _omd_ce_file="${OMD_CACHE_DIR:-${XDG_CACHE_HOME:-$HOME/.cache}/oh-my-dot}/eval/posix/$_omd_ce_key.sh"
+  _omd_ce_now="$(date +%%s)"
+  if [ -r "$_omd_ce_file" ] && [ ! "$_omd_ce_bin" -nt "$_omd_ce_file.ref" ] && [ ! "$_omd_ce_bin" -ot "$_omd_ce_file.ref" ] &&
+    { read -r _omd_ce_cached_bin; read -r _omd_ce_cached_time; read -r _omd_ce_cached_cmd; } 2>/dev/null < "$_omd_ce_file.stamp" &&
+    [ "$_omd_ce_cached_bin" = "$_omd_ce_bin" ] && [ "$_omd_ce_cached_cmd" = "$*" ] &&
+    [ $(( _omd_ce_now - _omd_ce_cached_time )) -lt "${OMD_CACHE_TTL:-%d}" ]; then
+    unset _omd_ce_key _omd_ce_bin _omd_ce_now _omd_ce_cached_bin _omd_ce_cached_time _omd_ce_cached_cmd
+    . "$_omd_ce_file"
+    return
+  fi
+  unset _omd_ce_key _omd_ce_cached_bin _omd_ce_cached_time _omd_ce_cached_cmd
+  if ! mkdir -p "${_omd_ce_file%%/*}" 2>/dev/null; then
+    unset _omd_ce_bin _omd_ce_now
+    eval "$("$@")"
+    return
+  fi
+  if "$@" > "$_omd_ce_file.$$"; then
+    mv -f "$_omd_ce_file.$$" "$_omd_ce_file"
+    touch -r "$_omd_ce_bin" "$_omd_ce_file.ref"
+    printf '%%s\n%%s\n%%s\n' "$_omd_ce_bin" "$_omd_ce_now" "$*" > "$_omd_ce_file.stamp"
+    unset _omd_ce_bin _omd_ce_now
+    . "$_omd_ce_file"
+  else
+    unset _omd_ce_bin _omd_ce_now
+    . "$_omd_ce_file.$$"
+    rm -f "$_omd_ce_file.$$"
+  fi
+}
+
+`, ttl)
+	default:
+		return ""
+	}
+}
+
+// shCachedEvalFunction returns omd_cached_eval for bash or zsh. lookup must set bin to the path
+// of the command's binary and clock must set now to the current time in Unix seconds.
+// The binary's mtime is copied to a .ref file so it can be compared without forking stat.
+func shCachedEvalFunction(shellName, lookup, clock string, ttl int) string {
+	return fmt.Sprintf(`# Source a command's output, cached until its binary or arguments change or the cache is
+# older than OMD_CACHE_TTL seconds
+# Usage: omd_cached_eval <key> <command> [args...]
+omd_cached_eval() {
+  local key="$1"; shift
+  local bin now cached_bin cached_time cached_cmd
+  %s
+  local dir="${OMD_CACHE_DIR:-${XDG_CACHE_HOME:-$HOME/.cache}/oh-my-dot}/eval/%s"
+  local file="$dir/$key%s"
+  %s
+  if [ -r "$file" ] && [ ! "$bin" -nt "$file.ref" ] && [ ! "$bin" -ot "$file.ref" ] &&
+    { read -r cached_bin; read -r cached_time; read -r cached_cmd; } 2>/dev/null < "$file.stamp" &&
+    [ "$cached_bin" = "$bin" ] && [ "$cached_cmd" = "$*" ] &&
+    [ $(( now - cached_time )) -lt "${OMD_CACHE_TTL:-%d}" ]; then
+    . "$file"
+    return
+  fi
+  if ! mkdir -p "$dir" 2>/dev/null; then
+    eval "$("$@")"
+    return
+  fi
+  if "$@" > "$file.$$"; then
+    mv -f "$file.$$" "$file"
+    touch -r "$bin" "$file.ref"
+    printf '%%s\n%%s\n%%s\n' "$bin" "$now" "$*" > "$file.stamp"
+    . "$file"
+  else
+    . "$file.$$"
+    rm -f "$file.$$"
+  fi
+}
+
+`, lookup, shellName, SupportedShells()[shellName].Extension, clock, ttl)
+}
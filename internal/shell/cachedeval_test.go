@@ -0,0 +1,138 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedEval(t *testing.T) {
+	for _, shellName := range []string{"bash", "posix"} {
+		t.Run(shellName, func(t *testing.T) {
+			executable, ok := FindShellExecutable(shellName)
+			if !ok {
+				t.Skipf("%s is not available", shellName)
+			}
+
+			dir := t.TempDir()
+			cacheDir := filepath.Join(dir, "cache")
+			t.Setenv("OMD_CACHE_DIR", cacheDir)
+
+			// The tool counts its runs, so a cache hit shows as an unchanged count
+			binDir := filepath.Join(dir, "bin")
+			if err := os.MkdirAll(binDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			tool := filepath.Join(binDir, "omd-test-tool")
+			counter := filepath.Join(dir, "runs")
+			if err := os.WriteFile(tool, []byte("#!/bin/sh\necho x >> \""+counter+"\"\necho \"OMD_TEST_VALUE=$1\"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			scriptPath := filepath.Join(dir, "test.sh")
+			script := cachedEvalFunction(shellName) + `omd_cached_eval tool omd-test-tool "$1"
+echo "value=$OMD_TEST_VALUE"
+`
+			if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			run := func(arg string, env ...string) string {
+				t.Helper()
+				cmd := exec.Command(executable, scriptPath, arg)
+				cmd.Env = append(os.Environ(), append([]string{"PATH=" + binDir + ":/usr/bin:/bin"}, env...)...)
+				output, err := cmd.CombinedOutput()
+				if err != nil {
+					t.Fatalf("%s: %v\n%s", shellName, err, output)
+				}
+				runs, _ := os.ReadFile(counter)
+				return strings.TrimSpace(string(output)) + " runs=" + strings.Repeat("x", strings.Count(string(runs), "x"))
+			}
+
+			steps := []struct {
+				name string
+				arg  string
+				env  []string
+				want string
+			}{
+				{"first run caches", "a", nil, "value=a runs=x"},
+				{"second run uses the cache", "a", nil, "value=a runs=x"},
+				{"new arguments", "b", nil, "value=b runs=xx"},
+				{"expired", "b", []string{"OMD_CACHE_TTL=0"}, "value=b runs=xxx"},
+			}
+			for _, step := range steps {
+				if got := run(step.arg, step.env...); got != step.want {
+					t.Fatalf("%s: got %q, want %q", step.name, got, step.want)
+				}
+			}
+
+			// An upgraded binary has a different mtime
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(tool, later, later); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := run("b"), "value=b runs=xxxx"; got != want {
+				t.Fatalf("changed binary: got %q, want %q", got, want)
+			}
+			if got, want := run("b"), "value=b runs=xxxx"; got != want {
+				t.Fatalf("after changed binary: got %q, want %q", got, want)
+			}
+
+			entries, err := ListCache([]string{shellName})
+			if err != nil {
+				t.Fatalf("ListCache: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Key != "tool" || entries[0].Binary != tool || entries[0].Command != "omd-test-tool b" {
+				t.Fatalf("unexpected cache entries: %+v", entries)
+			}
+		})
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	t.Setenv("OMD_CACHE_DIR", t.TempDir())
+
+	for _, entry := range []struct{ shell, key string }{{"bash", "a"}, {"bash", "b"}, {"zsh", "a"}} {
+		dir, err := CacheDir(entry.shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, _ := GetShellConfig(entry.shell)
+		path := filepath.Join(dir, entry.key+config.Extension)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("true\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+".stamp", []byte("/usr/bin/tool\n1700000000\ntool init\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ClearCache([]string{"bash", "zsh"}, []string{"a"})
+	if err != nil {
+		t.Fatalf("ClearCache: %v", err)
+	}
+	if len(removed) != 2 || removed[0].Shell != "bash" || removed[1].Shell != "zsh" {
+		t.Fatalf("expected both a entries to be removed, got %+v", removed)
+	}
+	if !removed[0].Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected the creation time from the stamp, got %v", removed[0].Created)
+	}
+
+	left, _ := ListCache([]string{"bash", "zsh"})
+	if len(left) != 1 || left[0].Key != "b" {
+		t.Fatalf("expected only bash b to be left, got %+v", left)
+	}
+	if _, err := os.Stat(removed[0].Path + ".stamp"); !os.IsNotExist(err) {
+		t.Fatalf("expected the stamp to be removed, got %v", err)
+	}
+
+	if removed, err := ClearCache([]string{"bash"}, nil); err != nil || len(removed) != 1 {
+		t.Fatalf("ClearCache all = %+v, %v", removed, err)
+	}
+}
@@ -81,6 +81,12 @@ func normalizeShellName(name string) string {
 
 // IsShellExecutableAvailable reports whether the current environment can resolve a shell executable.
 func IsShellExecutableAvailable(shellName string) bool {
+	_, ok := FindShellExecutable(shellName)
+	return ok
+}
+
+// FindShellExecutable returns the path of the executable that runs a shell.
+func FindShellExecutable(shellName string) (string, bool) {
 	var candidates []string
 
 	switch shellName {
@@ -95,7 +101,7 @@ func IsShellExecutableAvailable(shellName string) bool {
 	case "posix":
 		candidates = []string{"sh", "sh.exe"}
 	default:
-		return false
+		return "", false
 	}
 
 	for _, candidate := range candidates {
@@ -103,11 +109,11 @@ func IsShellExecutableAvailable(shellName string) bool {
 			if shellName == "bash" && isWslBashLauncher(resolvedPath) {
 				continue
 			}
-			return true
+			return resolvedPath, true
 		}
 	}
 
-	return false
+	return "", false
 }
 
 func isWslBashLauncher(path string) bool {
@@ -198,6 +204,16 @@ func GetInitScriptPath(repoPath, shellName string) (string, error) {
 	return filepath.Join(GetShellDirectory(repoPath, shellName), config.InitScript), nil
 }
 
+// InitScriptShell returns the shell whose generated init script is at path, relative to the repository
+func InitScriptShell(path string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if len(parts) != 3 || parts[0] != "omd-shells" {
+		return "", false
+	}
+	config, ok := GetShellConfig(parts[1])
+	return parts[1], ok && parts[2] == config.InitScript
+}
+
 // GetManifestPath returns the path to enabled.json for a shell
 func GetManifestPath(repoPath, shellName string) string {
 	return filepath.Join(GetShellDirectory(repoPath, shellName), manifestFileName)
@@ -0,0 +1,508 @@
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// GetEnvPath returns the path of the env file shared by all shells
+func GetEnvPath(repoPath string) string {
+	return filepath.Join(repoPath, "omd-shells", "env.json")
+}
+
+// LoadEnv returns the environment a shell's init script sets: the shared env file followed by the
+// env sections of the shell's manifest and local manifest
+func LoadEnv(repoPath, shellName string) (manifest.EnvConfig, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return manifest.EnvConfig{}, fmt.Errorf("failed to parse manifests: %w", err)
+	}
+	return joinSharedEnv(repoPath, merged)
+}
+
+// joinSharedEnv returns the shared env file followed by the environment of a merged manifest
+func joinSharedEnv(repoPath string, merged *manifest.MergedManifest) (manifest.EnvConfig, error) {
+	shared, err := manifest.ParseEnvFile(GetEnvPath(repoPath))
+	if err != nil {
+		return manifest.EnvConfig{}, fmt.Errorf("failed to parse %s: %w", GetEnvPath(repoPath), err)
+	}
+	return manifest.JoinEnv(shared, &merged.Env), nil
+}
+
+// envSegment is a literal part of a value or, when ref is set, a variable reference
+type envSegment struct {
+	literal string
+	ref     string
+}
+
+// parseEnvValue splits a value into literals and references. A ~ at the start refers to HOME and
+// $NAME or ${NAME} to a variable; any other $ is literal.
+func parseEnvValue(value string) []envSegment {
+	var segments []envSegment
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, envSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		segments = append(segments, envSegment{ref: "HOME"})
+		value = value[1:]
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			literal.WriteByte(value[i])
+			continue
+		}
+		rest := value[i+1:]
+		if braced, ok := strings.CutPrefix(rest, "{"); ok {
+			if end := strings.IndexByte(braced, '}'); end > 0 && isEnvName(braced[:end]) {
+				flush()
+				segments = append(segments, envSegment{ref: braced[:end]})
+				i += end + 2
+				continue
+			}
+		}
+		end := 0
+		for end < len(rest) && isEnvNameByte(rest[end], end == 0) {
+			end++
+		}
+		if end == 0 {
+			literal.WriteByte('$')
+			continue
+		}
+		flush()
+		segments = append(segments, envSegment{ref: rest[:end]})
+		i += end
+	}
+	flush()
+	return segments
+}
+
+func isEnvName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isEnvNameByte(name[i], i == 0) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func isEnvNameByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// shEnvValue renders a value as a double-quoted string for POSIX-like shells
+func shEnvValue(value string) string {
+	var sb strings.Builder
+	sb.WriteString(`"`)
+	for _, segment := range parseEnvValue(value) {
+		if segment.ref != "" {
+			sb.WriteString("${" + segment.ref + "}")
+			continue
+		}
+		for _, c := range segment.literal {
+			if strings.ContainsRune("\\\"$`", c) {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteString(`"`)
+	return sb.String()
+}
+
+// fishEnvValue renders a value as a double-quoted string for fish. fish has no ${NAME}, so the
+// quotes are closed after a reference that a name character follows.
+func fishEnvValue(value string) string {
+	var sb strings.Builder
+	sb.WriteString(`"`)
+	afterRef := false
+	for _, segment := range parseEnvValue(value) {
+		if segment.ref != "" {
+			if afterRef {
+				sb.WriteString(`""`)
+			}
+			sb.WriteString("$" + segment.ref)
+			afterRef = true
+			continue
+		}
+		if afterRef && isEnvNameByte(segment.literal[0], false) {
+			sb.WriteString(`""`)
+		}
+		afterRef = false
+		for _, c := range segment.literal {
+			if strings.ContainsRune("\\\"$", c) {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteString(`"`)
+	return sb.String()
+}
+
+// psEnvValue renders a value as a double-quoted string for PowerShell. ~ and $HOME use the
+// PowerShell home directory, which is also set on Windows; other references are environment variables.
+func psEnvValue(value string) string {
+	var sb strings.Builder
+	sb.WriteString(`"`)
+	for _, segment := range parseEnvValue(value) {
+		if segment.ref == "HOME" {
+			sb.WriteString("${HOME}")
+			continue
+		}
+		if segment.ref != "" {
+			sb.WriteString("${env:" + segment.ref + "}")
+			continue
+		}
+		for _, c := range segment.literal {
+			if strings.ContainsRune("`\"$", c) {
+				sb.WriteRune('`')
+			}
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteString(`"`)
+	return sb.String()
+}
+
+// envStatement is a rendered statement with the conditions it runs under
+type envStatement struct {
+	code  string
+	os    []string
+	hosts []string
+}
+
+// envStatements returns the statements that set the variables and PATH entries, rendered by setVar
+// and addPath. Variables come first, so PATH entries can refer to them. Entries added to the front
+// are added in reverse, so PATH lists them in the declared order.
+func envStatements(env manifest.EnvConfig, setVar func(name, value string) string, addPath func(dir string, appendEntry bool) string) []envStatement {
+	var statements []envStatement
+	for _, v := range env.Vars {
+		statements = append(statements, envStatement{code: setVar(v.Name, v.Value), os: v.OS, hosts: v.Hosts})
+	}
+	for i := len(env.Path) - 1; i >= 0; i-- {
+		if p := env.Path[i]; !p.Append {
+			statements = append(statements, envStatement{code: addPath(p.Dir, false), os: p.OS, hosts: p.Hosts})
+		}
+	}
+	for _, p := range env.Path {
+		if p.Append {
+			statements = append(statements, envStatement{code: addPath(p.Dir, true), os: p.OS, hosts: p.Hosts})
+		}
+	}
+	return statements
+}
+
+// envConditions reports whether any statement depends on the operating system or the host
+func envConditions(env manifest.EnvConfig) (usesOS, usesHost bool) {
+	for _, v := range env.Vars {
+		usesOS = usesOS || len(v.OS) > 0
+		usesHost = usesHost || len(v.Hosts) > 0
+	}
+	for _, p := range env.Path {
+		usesOS = usesOS || len(p.OS) > 0
+		usesHost = usesHost || len(p.Hosts) > 0
+	}
+	return usesOS, usesHost
+}
+
+// envBlock returns the code that sets the declared environment in the shell's syntax
+func envBlock(shellName string, env manifest.EnvConfig) string {
+	if env.IsEmpty() {
+		return ""
+	}
+	switch shellName {
+	case "bash", "zsh", "posix":
+		return shEnvBlock(shellName, env)
+	case "fish":
+		return fishEnvBlock(env)
+	case "powershell":
+		return powerShellEnvBlock(env)
+	default:
+		return ""
+	}
+}
+
+// shEnvBlock returns the environment for bash, zsh and POSIX sh
+func shEnvBlock(shellName string, env manifest.EnvConfig) string {
+	var sb strings.Builder
+	sb.WriteString("# Environment from env.json and the manifest\n")
+
+	usesOS, usesHost := envConditions(env)
+	if usesOS {
+		if shellName == "posix" {
+			sb.WriteString(`case $(uname -s) in
+  Linux) _omd_os=linux ;;
+  Darwin) _omd_os=darwin ;;
+  MINGW*|MSYS*|CYGWIN*) _omd_os=windows ;;
+  *) _omd_os=unknown ;;
+esac
+`)
+		} else {
+			sb.WriteString(`case $OSTYPE in
+  linux*) _omd_os=linux ;;
+  darwin*) _omd_os=darwin ;;
+  msys*|cygwin*|win32*) _omd_os=windows ;;
+  *) _omd_os=unknown ;;
+esac
+`)
+		}
+	}
+	if usesHost {
+		switch shellName {
+		case "bash":
+			sb.WriteString("_omd_host=${HOSTNAME%%.*}\n")
+		case "zsh":
+			sb.WriteString("_omd_host=${HOST%%.*}\n")
+		default:
+			sb.WriteString("_omd_host=$(uname -n)\n_omd_host=${_omd_host%%.*}\n")
+		}
+	}
+
+	if len(env.Path) > 0 {
+		sb.WriteString(`# Adds a directory to the front or, with append, the end of PATH, removing other copies
+_omd_path_add() {
+  _omd_path=":$PATH:"
+  while :; do
+    case $_omd_path in
+      *":$1:"*) _omd_path="${_omd_path%%:"$1":*}:${_omd_path#*:"$1":}" ;;
+      *) break ;;
+    esac
+  done
+  _omd_path=${_omd_path#:}
+  _omd_path=${_omd_path%:}
+  if [ "$2" = append ]; then
+    PATH="${_omd_path:+$_omd_path:}$1"
+  else
+    PATH="$1${_omd_path:+:$_omd_path}"
+  fi
+}
+`)
+	}
+
+	statements := envStatements(env,
+		func(name, value string) string { return fmt.Sprintf("export %s=%s", name, shEnvValue(value)) },
+		func(dir string, appendEntry bool) string {
+			if appendEntry {
+				return fmt.Sprintf("_omd_path_add %s append", shEnvValue(dir))
+			}
+			return fmt.Sprintf("_omd_path_add %s", shEnvValue(dir))
+		})
+	for _, statement := range statements {
+		var conditions []string
+		if len(statement.os) > 0 {
+			conditions = append(conditions, shAnyEqual("$_omd_os", statement.os))
+		}
+		if len(statement.hosts) > 0 {
+			conditions = append(conditions, shAnyEqual("$_omd_host", statement.hosts))
+		}
+		if len(conditions) == 0 {
+			sb.WriteString(statement.code + "\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("if %s; then\n  %s\nfi\n", strings.Join(conditions, " && "), statement.code))
+	}
+
+	var cleanup []string
+	if len(env.Path) > 0 {
+		sb.WriteString("export PATH\n")
+		sb.WriteString("unset -f _omd_path_add\n")
+		cleanup = append(cleanup, "_omd_path")
+	}
+	if usesOS {
+		cleanup = append(cleanup, "_omd_os")
+	}
+	if usesHost {
+		cleanup = append(cleanup, "_omd_host")
+	}
+	if len(cleanup) > 0 {
+		sb.WriteString(fmt.Sprintf("unset %s\n", strings.Join(cleanup, " ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// shAnyEqual returns a test that variable is one of values
+func shAnyEqual(variable string, values []string) string {
+	tests := make([]string, len(values))
+	for i, value := range values {
+		tests[i] = fmt.Sprintf(`[ "%s" = %s ]`, variable, shQuote(value))
+	}
+	if len(tests) == 1 {
+		return tests[0]
+	}
+	return "{ " + strings.Join(tests, " || ") + "; }"
+}
+
+// fishEnvBlock returns the environment for fish
+func fishEnvBlock(env manifest.EnvConfig) string {
+	var sb strings.Builder
+	sb.WriteString("# Environment from env.json and the manifest\n")
+
+	usesOS, usesHost := envConditions(env)
+	if usesOS {
+		sb.WriteString(`set -l __omd_os unknown
+switch (uname -s)
+  case Linux
+    set __omd_os linux
+  case Darwin
+    set __omd_os darwin
+  case 'MINGW*' 'MSYS*' 'CYGWIN*'
+    set __omd_os windows
+end
+`)
+	}
+	if usesHost {
+		sb.WriteString("set -l __omd_host (string replace -r '\\..*' '' -- $hostname)\n")
+	}
+
+	if len(env.Path) > 0 {
+		sb.WriteString(`# Adds a directory to the front or, with append, the end of PATH, removing other copies
+function __omd_path_add --argument-names dir where
+  while contains -- $dir $PATH
+    set -e PATH[(contains -i -- $dir $PATH)]
+  end
+  if test "$where" = append
+    set -gx PATH $PATH $dir
+  else
+    set -gx PATH $dir $PATH
+  end
+end
+`)
+	}
+
+	statements := envStatements(env,
+		func(name, value string) string { return fmt.Sprintf("set -gx %s %s", name, fishEnvValue(value)) },
+		func(dir string, appendEntry bool) string {
+			if appendEntry {
+				return fmt.Sprintf("__omd_path_add %s append", fishEnvValue(dir))
+			}
+			return fmt.Sprintf("__omd_path_add %s", fishEnvValue(dir))
+		})
+	for _, statement := range statements {
+		var conditions []string
+		if len(statement.os) > 0 {
+			conditions = append(conditions, "contains -- $__omd_os "+strings.Join(quoteAll(statement.os, fishQuote), " "))
+		}
+		if len(statement.hosts) > 0 {
+			conditions = append(conditions, "contains -- $__omd_host "+strings.Join(quoteAll(statement.hosts, fishQuote), " "))
+		}
+		if len(conditions) == 0 {
+			sb.WriteString(statement.code + "\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("if %s\n  %s\nend\n", strings.Join(conditions, "; and "), statement.code))
+	}
+
+	if len(env.Path) > 0 {
+		sb.WriteString("functions -e __omd_path_add\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// powerShellEnvBlock returns the environment for PowerShell. $IsLinux and $IsMacOS are not set in
+// Windows PowerShell, which only runs on Windows.
+func powerShellEnvBlock(env manifest.EnvConfig) string {
+	var sb strings.Builder
+	sb.WriteString("# Environment from env.json and the manifest\n")
+
+	usesOS, usesHost := envConditions(env)
+	if usesOS {
+		sb.WriteString("$__omdOS = if ($IsMacOS) { 'darwin' } elseif ($IsLinux) { 'linux' } else { 'windows' }\n")
+	}
+	if usesHost {
+		sb.WriteString("$__omdHost = ([System.Net.Dns]::GetHostName() -split '\\.')[0]\n")
+	}
+
+	if len(env.Path) > 0 {
+		sb.WriteString(`# Adds a directory to the front or, with -Append, the end of PATH, removing other copies
+function __omd_path_add([string]$Dir, [switch]$Append) {
+  $separator = [System.IO.Path]::PathSeparator
+  $entries = @($env:PATH -split [regex]::Escape($separator) | Where-Object { $_ -and $_ -ne $Dir })
+  if ($Append) {
+    $entries += $Dir
+  } else {
+    $entries = @($Dir) + $entries
+  }
+  $env:PATH = $entries -join $separator
+}
+`)
+	}
+
+	statements := envStatements(env,
+		func(name, value string) string { return fmt.Sprintf("$env:%s = %s", name, psEnvValue(value)) },
+		func(dir string, appendEntry bool) string {
+			if appendEntry {
+				return fmt.Sprintf("__omd_path_add %s -Append", psEnvValue(dir))
+			}
+			return fmt.Sprintf("__omd_path_add %s", psEnvValue(dir))
+		})
+	for _, statement := range statements {
+		var conditions []string
+		if len(statement.os) > 0 {
+			conditions = append(conditions, fmt.Sprintf("@(%s) -contains $__omdOS", strings.Join(quoteAll(statement.os, psQuote), ", ")))
+		}
+		if len(statement.hosts) > 0 {
+			conditions = append(conditions, fmt.Sprintf("@(%s) -contains $__omdHost", strings.Join(quoteAll(statement.hosts, psQuote), ", ")))
+		}
+		if len(conditions) == 0 {
+			sb.WriteString(statement.code + "\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("if (%s) {\n  %s\n}\n", strings.Join(conditions, " -and "), statement.code))
+	}
+
+	var cleanup []string
+	if len(env.Path) > 0 {
+		sb.WriteString("Remove-Item Function:__omd_path_add\n")
+	}
+	if usesOS {
+		cleanup = append(cleanup, "__omdOS")
+	}
+	if usesHost {
+		cleanup = append(cleanup, "__omdHost")
+	}
+	if len(cleanup) > 0 {
+		sb.WriteString(fmt.Sprintf("Remove-Variable -Name %s\n", strings.Join(cleanup, ", ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// EnvSummary returns one line per declared variable and PATH entry, with its conditions
+func EnvSummary(env manifest.EnvConfig) []string {
+	conditions := func(osNames, hosts []string) string {
+		var parts []string
+		if len(osNames) > 0 {
+			parts = append(parts, "os: "+strings.Join(osNames, ", "))
+		}
+		if len(hosts) > 0 {
+			parts = append(parts, "hosts: "+strings.Join(hosts, ", "))
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		return " (" + strings.Join(parts, "; ") + ")"
+	}
+
+	var lines []string
+	for _, v := range env.Vars {
+		lines = append(lines, fmt.Sprintf("%s=%s%s", v.Name, v.Value, conditions(v.OS, v.Hosts)))
+	}
+	for _, p := range env.Path {
+		where := "PATH"
+		if p.Append {
+			where = "PATH (end)"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s%s", where, p.Dir, conditions(p.OS, p.Hosts)))
+	}
+	return lines
+}
@@ -0,0 +1,159 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func TestParseEnvValue(t *testing.T) {
+	tests := map[string][]envSegment{
+		"plain":          {{literal: "plain"}},
+		"~/bin":          {{ref: "HOME"}, {literal: "/bin"}},
+		"a~b":            {{literal: "a~b"}},
+		"$GOPATH/bin":    {{ref: "GOPATH"}, {literal: "/bin"}},
+		"${TOOL}s":       {{ref: "TOOL"}, {literal: "s"}},
+		"$5 ${bad-name}": {{literal: "$5 ${bad-name}"}},
+		"cost: $":        {{literal: "cost: $"}},
+	}
+	for value, want := range tests {
+		got := parseEnvValue(value)
+		if len(got) != len(want) {
+			t.Errorf("parseEnvValue(%q) = %+v, want %+v", value, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("parseEnvValue(%q) = %+v, want %+v", value, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestEnvValueQuoting(t *testing.T) {
+	value := "~/say \"hi\" `x` $TOOL${TOOL}s \\ $5"
+	if got, want := shEnvValue(value), "\"${HOME}/say \\\"hi\\\" \\`x\\` ${TOOL}${TOOL}s \\\\ \\$5\""; got != want {
+		t.Errorf("shEnvValue = %s, want %s", got, want)
+	}
+	if got, want := fishEnvValue(value), "\"$HOME/say \\\"hi\\\" `x` $TOOL\"\"$TOOL\"\"s \\\\ \\$5\""; got != want {
+		t.Errorf("fishEnvValue = %s, want %s", got, want)
+	}
+	if got, want := psEnvValue(value), "\"${HOME}/say `\"hi`\" ``x`` ${env:TOOL}${env:TOOL}s \\ `$5\""; got != want {
+		t.Errorf("psEnvValue = %s, want %s", got, want)
+	}
+}
+
+func TestEnvInInitScript(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, _, _ = strings.Cut(host, ".")
+
+	for _, shellName := range []string{"bash", "posix"} {
+		t.Run(shellName, func(t *testing.T) {
+			executable, ok := FindShellExecutable(shellName)
+			if !ok {
+				t.Skipf("%s is not available", shellName)
+			}
+
+			m := &manifest.FeatureManifest{Env: &manifest.EnvConfig{Path: []manifest.PathEntry{
+				{Dir: "$OMD_TOOLS/bin"},
+				{Dir: "/usr/bin"},
+				{Dir: "/opt/end", Append: true},
+				{Dir: "/opt/elsewhere", OS: []string{"windows"}},
+				{Dir: "~/bin"},
+			}}}
+			repoPath := writeShellRepo(t, shellName, m, nil)
+			shared := `{
+  "vars": [
+    {"name": "OMD_TOOLS", "value": "/tools"},
+    {"name": "OMD_QUOTED", "value": "say \"hi\" to $OMD_TOOLS` + "`x`" + ` $5"},
+    {"name": "OMD_THIS_OS", "value": "yes", "os": ["` + runtime.GOOS + `"]},
+    {"name": "OMD_OTHER_OS", "value": "yes", "os": ["windows"]},
+    {"name": "OMD_THIS_HOST", "value": "yes", "hosts": ["` + host + `"]},
+    {"name": "OMD_OTHER_HOST", "value": "yes", "hosts": ["not-this-host"]}
+  ],
+  "path": [{"dir": "~/bin"}]
+}`
+			if err := os.WriteFile(GetEnvPath(repoPath), []byte(shared), 0644); err != nil {
+				t.Fatal(err)
+			}
+			local := &manifest.FeatureManifest{Env: &manifest.EnvConfig{Vars: []manifest.EnvVar{{Name: "OMD_TOOLS", Value: "/local-tools"}}}}
+			if err := manifest.WriteManifest(GetLocalManifestPath(repoPath, shellName), local); err != nil {
+				t.Fatal(err)
+			}
+			if err := RegenerateInitScript(repoPath, shellName); err != nil {
+				t.Fatalf("RegenerateInitScript: %v", err)
+			}
+			initPath, _ := GetInitScriptPath(repoPath, shellName)
+
+			// ~/bin is already in PATH, at the end; it moves to the front instead of being listed twice
+			cmd := exec.Command(executable, "-c", `. ./init.sh
+echo "PATH=$PATH"
+echo "QUOTED=$OMD_QUOTED"
+echo "OS=$OMD_THIS_OS/$OMD_OTHER_OS HOST=$OMD_THIS_HOST/$OMD_OTHER_HOST"
+echo "left=$(set | grep -c '^_omd_\(os\|host\|path\)=')"`)
+			// The POSIX init script finds its directory from $0, so run it from the shell directory
+			cmd.Args[0] = filepath.Base(executable)
+			cmd.Dir = filepath.Dir(initPath)
+			cmd.Env = append(os.Environ(), "HOME=/home/omd", "PATH=/usr/local/bin:/usr/bin:/bin:/home/omd/bin")
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", shellName, err, output)
+			}
+
+			want := strings.Join([]string{
+				"PATH=/home/omd/bin:/local-tools/bin:/usr/bin:/usr/local/bin:/bin:/opt/end",
+				"QUOTED=say \"hi\" to /tools`x` $5",
+				"OS=yes/ HOST=yes/",
+				"left=0",
+			}, "\n")
+			if got := strings.TrimSpace(string(output)); got != want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestEnvBlocks(t *testing.T) {
+	env := manifest.EnvConfig{
+		Vars: []manifest.EnvVar{{Name: "EDITOR", Value: "nvim"}, {Name: "GOPATH", Value: "~/go", OS: []string{"linux", "darwin"}, Hosts: []string{"work"}}},
+		Path: []manifest.PathEntry{{Dir: "$GOPATH/bin"}, {Dir: "/opt/tools/bin", Append: true}},
+	}
+
+	fish := envBlock("fish", env)
+	for _, want := range []string{
+		`set -gx EDITOR "nvim"`,
+		"if contains -- $__omd_os 'linux' 'darwin'; and contains -- $__omd_host 'work'\n  set -gx GOPATH \"$HOME/go\"\nend",
+		`__omd_path_add "$GOPATH/bin"`,
+		`__omd_path_add "/opt/tools/bin" append`,
+	} {
+		if !strings.Contains(fish, want) {
+			t.Errorf("fish env should contain %q:\n%s", want, fish)
+		}
+	}
+
+	powershell := envBlock("powershell", env)
+	for _, want := range []string{
+		`$env:EDITOR = "nvim"`,
+		"if (@('linux', 'darwin') -contains $__omdOS -and @('work') -contains $__omdHost) {\n  $env:GOPATH = \"${HOME}/go\"\n}",
+		`__omd_path_add "${env:GOPATH}/bin"`,
+		`__omd_path_add "/opt/tools/bin" -Append`,
+		"Remove-Variable -Name __omdOS, __omdHost",
+	} {
+		if !strings.Contains(powershell, want) {
+			t.Errorf("PowerShell env should contain %q:\n%s", want, powershell)
+		}
+	}
+
+	if envBlock("zsh", manifest.EnvConfig{}) != "" {
+		t.Error("an empty env should not add anything")
+	}
+}
@@ -8,15 +8,32 @@ import (
 	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
 )
 
-// FeaturesByStrategy organizes features by their loading strategy
+// FeaturesByStrategy organizes features by their loading strategy, each in load order
 type FeaturesByStrategy struct {
-	Eager     []string
-	Defer     []string
-	OnCommand map[string][]string // feature name -> trigger commands
+	Eager        []string
+	Defer        []string
+	OnCommand    []OnCommandFeature
+	OnCompletion []OnCommandFeature // Loaded by the first completion of one of the commands
+	OnDirectory  []OnDirectoryFeature
+	Bundle       bool                   // Eager features load from the bundle built by RebuildBundle
+	Env          manifest.EnvConfig     // Variables and PATH entries set before any feature loads
+	Aliases      []manifest.AliasConfig // Defined after the eager features, so they win over aliases of features
+}
+
+// OnCommandFeature is a feature loaded by the first run of one of its commands
+type OnCommandFeature struct {
+	Name     string
+	Commands []string
+}
+
+// OnDirectoryFeature is a feature loaded the first time the shell is in a directory with one of its markers
+type OnDirectoryFeature struct {
+	Name    string
+	Markers []string
 }
 
 // GenerateInitScript generates a complete init script for a shell
-// Supports eager, defer, and on-command loading strategies (Phase 5)
+// Supports eager, defer, on-command, on-completion and on-directory loading strategies (Phase 5)
 // Supports local overrides via enabled.local.json (Phase 6)
 func GenerateInitScript(repoPath, shellName string) (string, error) {
 	// Parse manifest with local overrides
@@ -31,6 +48,11 @@ func GenerateInitScript(repoPath, shellName string) (string, error) {
 	// Organize features by strategy
 	features := categorizeFeaturesMerged(merged)
 
+	features.Env, err = joinSharedEnv(repoPath, merged)
+	if err != nil {
+		return "", err
+	}
+
 	// Generate shell-specific init script
 	switch shellName {
 	case "bash":
@@ -48,26 +70,34 @@ func GenerateInitScript(repoPath, shellName string) (string, error) {
 	}
 }
 
-// categorizeFeaturesMerged organizes enabled features from a merged manifest
+// categorizeFeaturesMerged organizes enabled features from a merged manifest in dependency order.
+// Dependency problems do not stop generation; doctor reports them.
 func categorizeFeaturesMerged(m *manifest.MergedManifest) FeaturesByStrategy {
-	features := FeaturesByStrategy{
-		OnCommand: make(map[string][]string),
-	}
+	features := FeaturesByStrategy{Bundle: m.Bundle, Aliases: m.Aliases}
 
-	for _, f := range m.GetEnabledFeatures() {
-		strategy := f.Strategy
-		if strategy == "" {
-			strategy = "eager" // Default to eager
-		}
+	all := make([]manifest.FeatureConfig, 0, len(m.Features))
+	for _, f := range m.Features {
+		all = append(all, f.FeatureConfig)
+	}
+	ordered, _ := OrderFeatures(all)
 
-		switch strategy {
+	for _, f := range ordered {
+		switch featureStrategy(f) {
 		case "eager":
 			features.Eager = append(features.Eager, f.Name)
 		case "defer":
 			features.Defer = append(features.Defer, f.Name)
 		case "on-command":
 			if len(f.OnCommand) > 0 {
-				features.OnCommand[f.Name] = f.OnCommand
+				features.OnCommand = append(features.OnCommand, OnCommandFeature{Name: f.Name, Commands: f.OnCommand})
+			}
+		case "on-completion":
+			if len(f.OnCommand) > 0 {
+				features.OnCompletion = append(features.OnCompletion, OnCommandFeature{Name: f.Name, Commands: f.OnCommand})
+			}
+		case "on-directory":
+			if len(f.OnDirectory) > 0 {
+				features.OnDirectory = append(features.OnDirectory, OnDirectoryFeature{Name: f.Name, Markers: f.OnDirectory})
 			}
 		}
 	}
@@ -105,12 +135,23 @@ fi
 
 `)
 
+	// Environment and PATH entries
+	sb.WriteString(envBlock("bash", features.Env))
+
+	// Cached eval helper for features
+	sb.WriteString(cachedEvalFunction("bash"))
+
 	// Eager loading
 	if len(features.Eager) > 0 {
+		if features.Bundle {
+			sb.WriteString("# Check whether the bundle of eager features is up to date\n")
+			sb.WriteString(bundleFreshCheck("bash", features.Eager))
+		}
 		sb.WriteString("# Load eager features\n")
 		sb.WriteString("_omd_load_eager_features() {\n")
+		var load strings.Builder
 		for _, feature := range features.Eager {
-			sb.WriteString(fmt.Sprintf(`  local feature_file="$OMD_SHELL_ROOT/features/%s.sh"
+			load.WriteString(fmt.Sprintf(`  local feature_file="$OMD_SHELL_ROOT/features/%s.sh"
   if [ -r "$feature_file" ]; then
     . "$feature_file"
   else
@@ -118,19 +159,31 @@ fi
   fi
 `, feature, feature))
 		}
+		if features.Bundle {
+			sb.WriteString(bundleLoader("bash", indentLines(load.String(), "  "), "  "))
+		} else {
+			sb.WriteString(load.String())
+		}
 		sb.WriteString("}\n\n")
 	}
 
 	// Defer loading
 	if len(features.Defer) > 0 {
-		sb.WriteString("# Load deferred features (background)\n")
-		sb.WriteString("_omd_load_deferred_features() {\n")
-		sb.WriteString("  if [[ $- == *i* ]]; then\n")
+		sb.WriteString("# Load deferred features in this shell at the first prompt, after the rest of the startup files.\n")
+		sb.WriteString("# Runs from PROMPT_COMMAND, removes itself and keeps $? for the prompt.\n")
+		sb.WriteString(`_omd_load_deferred_features() {
+  local last_status=$?
+  PROMPT_COMMAND="${PROMPT_COMMAND//_omd_load_deferred_features;/}"
+  PROMPT_COMMAND="${PROMPT_COMMAND//_omd_load_deferred_features/}"
+  unset -f _omd_load_deferred_features
+  local feature_file
+`)
 		for _, feature := range features.Defer {
-			sb.WriteString(fmt.Sprintf(`    ( [ -r "$OMD_SHELL_ROOT/features/%s.sh" ] && . "$OMD_SHELL_ROOT/features/%s.sh" ) &
-`, feature, feature))
+			sb.WriteString(fmt.Sprintf(`  feature_file="$OMD_SHELL_ROOT/features/%s.sh"
+  [ -r "$feature_file" ] && . "$feature_file"
+`, feature))
 		}
-		sb.WriteString("  fi\n")
+		sb.WriteString("  return $last_status\n")
 		sb.WriteString("}\n\n")
 	}
 
@@ -139,7 +192,8 @@ fi
 		sb.WriteString("# Register on-command features\n")
 		sb.WriteString("_omd_register_oncommand_features() {\n")
 
-		for featureName, commands := range features.OnCommand {
+		for _, feature := range features.OnCommand {
+			featureName, commands := feature.Name, feature.Commands
 			// Group commands that share the same feature
 			if len(commands) == 1 {
 				// Single command - simple wrapper
@@ -185,20 +239,35 @@ fi
 	if len(features.Eager) > 0 {
 		sb.WriteString("_omd_load_eager_features\n")
 	}
+	if len(features.Aliases) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(aliasBlock("bash", features.Aliases))
+	}
 	if len(features.OnCommand) > 0 {
 		sb.WriteString("_omd_register_oncommand_features\n")
 	}
+	if len(features.OnCompletion) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(onCompletionLoader("bash", features.OnCompletion))
+	}
+	if len(features.OnDirectory) > 0 {
+		// Registered first so the deferred features load before it at the first prompt
+		sb.WriteString("\n")
+		sb.WriteString(onDirectoryLoader("bash", features.OnDirectory))
+	}
 	if len(features.Defer) > 0 {
-		sb.WriteString("_omd_load_deferred_features\n")
+		sb.WriteString(`if [[ $- == *i* ]]; then
+  PROMPT_COMMAND="_omd_load_deferred_features${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+else
+  unset -f _omd_load_deferred_features
+fi
+`)
 	}
 
 	// Cleanup (keep _omd_load_eager_features for re-sourcing)
 	sb.WriteString("\n# Cleanup (keep _omd_load_eager_features for re-sourcing)\n")
 	funcsToClean := []string{}
 	// Don't clean up eager features function - needed for re-sourcing .bashrc
-	if len(features.Defer) > 0 {
-		funcsToClean = append(funcsToClean, "_omd_load_deferred_features")
-	}
 	if len(features.OnCommand) > 0 {
 		funcsToClean = append(funcsToClean, "_omd_register_oncommand_features")
 	}
@@ -234,12 +303,23 @@ fi
 
 `)
 
+	// Environment and PATH entries
+	sb.WriteString(envBlock("zsh", features.Env))
+
+	// Cached eval helper for features
+	sb.WriteString(cachedEvalFunction("zsh"))
+
 	// Eager loading
 	if len(features.Eager) > 0 {
+		if features.Bundle {
+			sb.WriteString("# Check whether the bundle of eager features is up to date\n")
+			sb.WriteString(bundleFreshCheck("zsh", features.Eager))
+		}
 		sb.WriteString("# Load eager features\n")
 		sb.WriteString("_omd_load_eager_features() {\n")
+		var load strings.Builder
 		for _, feature := range features.Eager {
-			sb.WriteString(fmt.Sprintf(`  local feature_file="$OMD_SHELL_ROOT/features/%s.zsh"
+			load.WriteString(fmt.Sprintf(`  local feature_file="$OMD_SHELL_ROOT/features/%s.zsh"
   if [[ -r "$feature_file" ]]; then
     . "$feature_file"
   else
@@ -247,20 +327,50 @@ fi
   fi
 `, feature, feature))
 		}
+		if features.Bundle {
+			sb.WriteString(bundleLoader("zsh", indentLines(load.String(), "  "), "  "))
+		} else {
+			sb.WriteString(load.String())
+		}
 		sb.WriteString("}\n\n")
 	}
 
 	// Defer loading
 	if len(features.Defer) > 0 {
-		sb.WriteString("# Load deferred features (background)\n")
+		sb.WriteString("# Load deferred features in this shell once the first prompt is drawn and the line editor is\n")
+		sb.WriteString("# idle: the first precmd watches an always-readable fd with zle -F. Without zle they load in precmd.\n")
 		sb.WriteString("_omd_load_deferred_features() {\n")
-		sb.WriteString("  if [[ -o interactive ]]; then\n")
+		sb.WriteString("  local feature_file\n")
 		for _, feature := range features.Defer {
-			sb.WriteString(fmt.Sprintf(`    ( [[ -r "$OMD_SHELL_ROOT/features/%s.zsh" ]] && . "$OMD_SHELL_ROOT/features/%s.zsh" ) &!
-`, feature, feature))
+			sb.WriteString(fmt.Sprintf(`  feature_file="$OMD_SHELL_ROOT/features/%s.zsh"
+  [[ -r "$feature_file" ]] && . "$feature_file"
+`, feature))
 		}
-		sb.WriteString("  fi\n")
 		sb.WriteString("}\n\n")
+		sb.WriteString(`_omd_deferred_ready() {
+  local fd=$1
+  zle -F $fd
+  exec {fd}<&-
+  zle -D _omd_deferred_ready
+  _omd_load_deferred_features
+  unset -f _omd_load_deferred_features _omd_deferred_ready
+  zle reset-prompt
+}
+
+_omd_deferred_precmd() {
+  add-zsh-hook -d precmd _omd_deferred_precmd
+  unset -f _omd_deferred_precmd
+  local fd
+  if [[ -o zle ]] && exec {fd}</dev/null; then
+    zle -N _omd_deferred_ready
+    zle -F -w $fd _omd_deferred_ready
+  else
+    _omd_load_deferred_features
+    unset -f _omd_load_deferred_features _omd_deferred_ready
+  fi
+}
+
+`)
 	}
 
 	// On-command loading
@@ -268,7 +378,8 @@ fi
 		sb.WriteString("# Register on-command features\n")
 		sb.WriteString("_omd_register_oncommand_features() {\n")
 
-		for featureName, commands := range features.OnCommand {
+		for _, feature := range features.OnCommand {
+			featureName, commands := feature.Name, feature.Commands
 			if len(commands) == 1 {
 				// Single command - simple wrapper
 				cmd := commands[0]
@@ -311,11 +422,29 @@ fi
 	if len(features.Eager) > 0 {
 		sb.WriteString("_omd_load_eager_features\n")
 	}
+	if len(features.Aliases) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(aliasBlock("zsh", features.Aliases))
+	}
 	if len(features.OnCommand) > 0 {
 		sb.WriteString("_omd_register_oncommand_features\n")
 	}
 	if len(features.Defer) > 0 {
-		sb.WriteString("_omd_load_deferred_features\n")
+		sb.WriteString(`if [[ -o interactive ]]; then
+  autoload -Uz add-zsh-hook
+  add-zsh-hook precmd _omd_deferred_precmd
+else
+  unset -f _omd_load_deferred_features _omd_deferred_ready _omd_deferred_precmd
+fi
+`)
+	}
+	if len(features.OnCompletion) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(onCompletionLoader("zsh", features.OnCompletion))
+	}
+	if len(features.OnDirectory) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(onDirectoryLoader("zsh", features.OnDirectory))
 	}
 
 	// Cleanup
@@ -323,9 +452,9 @@ fi
 	funcsToClean := []string{}
 	if len(features.Eager) > 0 {
 		funcsToClean = append(funcsToClean, "_omd_load_eager_features")
-	}
-	if len(features.Defer) > 0 {
-		funcsToClean = append(funcsToClean, "_omd_load_deferred_features")
+		if features.Bundle {
+			funcsToClean = append(funcsToClean, "_omd_bundle_fresh")
+		}
 	}
 	if len(features.OnCommand) > 0 {
 		funcsToClean = append(funcsToClean, "_omd_register_oncommand_features")
@@ -337,6 +466,25 @@ fi
 	return sb.String()
 }
 
+// fishSourceFeatureFunction defines the function fish loaders use to source a feature. fish makes a
+// variable set without a scope inside a function local to it, so the variables the feature leaves in
+// the function scope are copied to the global scope, as if the feature was sourced at the top level.
+const fishSourceFeatureFunction = `# Source a feature from a function, keeping the variables it sets
+function __omd_source_feature --argument-names __omd_feature_file
+  test -r "$__omd_feature_file"; or return 1
+  source "$__omd_feature_file"
+  for __omd_name in (set --local --names)
+    contains -- $__omd_name __omd_feature_file argv __omd_name; and continue
+    if set -qx $__omd_name
+      set -gx $__omd_name $$__omd_name
+    else
+      set -g $__omd_name $$__omd_name
+    end
+  end
+end
+
+`
+
 // generateFishInit generates a fish init script with all loading strategies
 func generateFishInit(features FeaturesByStrategy) string {
 	var sb strings.Builder
@@ -357,11 +505,27 @@ set -l OMD_SHELL_ROOT (dirname (status --current-filename))
 
 `)
 
+	// Environment and PATH entries
+	sb.WriteString(envBlock("fish", features.Env))
+
+	// Cached eval helper for features
+	sb.WriteString(cachedEvalFunction("fish"))
+
+	// Helper for the loaders that source features from a function
+	if len(features.Defer) > 0 || len(features.OnCommand) > 0 || len(features.OnCompletion) > 0 || len(features.OnDirectory) > 0 {
+		sb.WriteString(fishSourceFeatureFunction)
+	}
+
 	// Eager loading
 	if len(features.Eager) > 0 {
+		if features.Bundle {
+			sb.WriteString("# Check whether the bundle of eager features is up to date\n")
+			sb.WriteString(bundleFreshCheck("fish", features.Eager))
+		}
 		sb.WriteString("# Load eager features\n")
+		var load strings.Builder
 		for _, feature := range features.Eager {
-			sb.WriteString(fmt.Sprintf(`set -l feature_file "$OMD_SHELL_ROOT/features/%s.fish"
+			load.WriteString(fmt.Sprintf(`set -l feature_file "$OMD_SHELL_ROOT/features/%s.fish"
 if test -r "$feature_file"
   source "$feature_file"
 else
@@ -370,20 +534,25 @@ end
 
 `, feature, feature))
 		}
+		if features.Bundle {
+			sb.WriteString(bundleLoader("fish", indentLines(load.String(), "  "), ""))
+			sb.WriteString("functions -e __omd_bundle_fresh\n\n")
+		} else {
+			sb.WriteString(load.String())
+		}
 	}
 
+	// Aliases, after the eager features so they win over aliases the features define
+	sb.WriteString(aliasBlock("fish", features.Aliases))
+
 	// Defer loading (using fish_prompt event)
 	if len(features.Defer) > 0 {
-		sb.WriteString("# Load deferred features (on first prompt)\n")
-		sb.WriteString("function __omd_load_deferred --on-event fish_prompt\n")
+		sb.WriteString("# Load deferred features in this shell at the first prompt\n")
+		sb.WriteString("function __omd_load_deferred --on-event fish_prompt --inherit-variable OMD_SHELL_ROOT\n")
 		sb.WriteString("  # Remove this function after first run\n")
 		sb.WriteString("  functions -e __omd_load_deferred\n")
-		sb.WriteString("  \n")
-		sb.WriteString("  # Load deferred features in background\n")
 		for _, feature := range features.Defer {
-			sb.WriteString(fmt.Sprintf(`  set -l feature_file "$OMD_SHELL_ROOT/features/%s.fish"
-  test -r "$feature_file"; and source "$feature_file" &
-`, feature))
+			sb.WriteString(fmt.Sprintf("  __omd_source_feature \"$OMD_SHELL_ROOT/features/%s.fish\"\n", feature))
 		}
 		sb.WriteString("end\n\n")
 	}
@@ -391,14 +560,14 @@ end
 	// On-command loading
 	if len(features.OnCommand) > 0 {
 		sb.WriteString("# Register on-command features\n")
-		for featureName, commands := range features.OnCommand {
+		for _, feature := range features.OnCommand {
+			featureName, commands := feature.Name, feature.Commands
 			if len(commands) == 1 {
 				// Single command - simple wrapper
 				cmd := commands[0]
 				sb.WriteString(fmt.Sprintf(`function %s
   functions -e %s
-  set -l feature_file "$OMD_SHELL_ROOT/features/%s.fish"
-  test -r "$feature_file"; and source "$feature_file"
+  __omd_source_feature "$OMD_SHELL_ROOT/features/%s.fish"
   if type -q %s
     command %s $argv
   else
@@ -412,8 +581,7 @@ end
 				// Multiple commands - use helper function
 				loaderFunc := fmt.Sprintf("__omd_load_%s", featureName)
 				sb.WriteString(fmt.Sprintf(`function %s
-  set -l feature_file "$OMD_SHELL_ROOT/features/%s.fish"
-  test -r "$feature_file"; and source "$feature_file"
+  __omd_source_feature "$OMD_SHELL_ROOT/features/%s.fish"
 end
 
 `, loaderFunc, featureName))
@@ -433,6 +601,16 @@ end
 		}
 	}
 
+	// On-completion loading
+	if len(features.OnCompletion) > 0 {
+		sb.WriteString(onCompletionLoader("fish", features.OnCompletion))
+	}
+
+	// On-directory loading
+	if len(features.OnDirectory) > 0 {
+		sb.WriteString(onDirectoryLoader("fish", features.OnDirectory))
+	}
+
 	return sb.String()
 }
 
@@ -455,11 +633,22 @@ $OMD_SHELL_ROOT = Split-Path -Parent $PSCommandPath
 
 `)
 
+	// Environment and PATH entries
+	sb.WriteString(envBlock("powershell", features.Env))
+
+	// Cached eval helper for features
+	sb.WriteString(cachedEvalFunction("powershell"))
+
 	// Eager loading
 	if len(features.Eager) > 0 {
+		if features.Bundle {
+			sb.WriteString("# Check whether the bundle of eager features is up to date\n")
+			sb.WriteString(bundleFreshCheck("powershell", features.Eager))
+		}
 		sb.WriteString("# Load eager features\n")
+		var load strings.Builder
 		for _, feature := range features.Eager {
-			sb.WriteString(fmt.Sprintf(`$featureFile = Join-Path $OMD_SHELL_ROOT "features\%s.ps1"
+			load.WriteString(fmt.Sprintf(`$featureFile = Join-Path $OMD_SHELL_ROOT "features\%s.ps1"
 if (Test-Path $featureFile) {
   . $featureFile
 } else {
@@ -468,26 +657,54 @@ if (Test-Path $featureFile) {
 
 `, feature, feature))
 		}
+		if features.Bundle {
+			sb.WriteString(bundleLoader("powershell", indentLines(load.String(), "  "), ""))
+			sb.WriteString("Remove-Item Function:__omd_bundle_fresh\n\n")
+		} else {
+			sb.WriteString(load.String())
+		}
+	}
+
+	// Aliases, after the eager features so they win over aliases the features define
+	sb.WriteString(aliasBlock("powershell", features.Aliases))
+
+	// Event actions and completers run in their own scope and dot-source features into the global one
+	if len(features.Defer) > 0 || len(features.OnCompletion) > 0 || len(features.OnDirectory) > 0 {
+		sb.WriteString("# Global session state that features loaded later are dot-sourced into\n")
+		sb.WriteString("$global:__omdGlobalState = [psmoduleinfo]::new($false)\n")
+		sb.WriteString("$global:__omdGlobalState.SessionState = $ExecutionContext.SessionState\n\n")
 	}
 
-	// Defer loading (using background jobs)
+	// Defer loading (once the session is idle)
 	if len(features.Defer) > 0 {
-		sb.WriteString("# Load deferred features (background jobs)\n")
+		sb.WriteString("# Load deferred features in this session once it is idle after the first prompt. The OnIdle\n")
+		sb.WriteString("# action runs in its own scope, so the features are dot-sourced into the global session state.\n")
 		sb.WriteString("if ($Host.UI.RawUI) {  # Interactive shell check\n")
+		sb.WriteString("  $global:__omdDeferredFeatures = @(\n")
 		for _, feature := range features.Defer {
-			sb.WriteString(fmt.Sprintf(`  $featureFile = Join-Path $OMD_SHELL_ROOT "features\%s.ps1"
-  if (Test-Path $featureFile) {
-    Start-Job -ScriptBlock { param($f) . $f } -ArgumentList $featureFile | Out-Null
-  }
-`, feature))
+			sb.WriteString(fmt.Sprintf("    (Join-Path $OMD_SHELL_ROOT \"features\\%s.ps1\")\n", feature))
 		}
-		sb.WriteString("}\n\n")
+		sb.WriteString(`  )
+  Register-EngineEvent -SourceIdentifier PowerShell.OnIdle -MaxTriggerCount 1 -Action {
+    . $global:__omdGlobalState {
+      foreach ($featureFile in $global:__omdDeferredFeatures) {
+        if (Test-Path $featureFile) {
+          . $featureFile
+        }
+      }
+    }
+    Remove-Variable -Name __omdDeferredFeatures -Scope Global
+  } | Out-Null
+}
+
+`)
 	}
 
 	// On-command loading
 	if len(features.OnCommand) > 0 {
 		sb.WriteString("# Register on-command features\n")
-		for featureName, commands := range features.OnCommand {
+		for _, feature := range features.OnCommand {
+			featureName, commands := feature.Name, feature.Commands
 			if len(commands) == 1 {
 				// Single command - simple wrapper
 				cmd := commands[0]
@@ -537,6 +754,16 @@ if (Test-Path $featureFile) {
 		}
 	}
 
+	// On-completion loading
+	if len(features.OnCompletion) > 0 {
+		sb.WriteString(onCompletionLoader("powershell", features.OnCompletion))
+	}
+
+	// On-directory loading
+	if len(features.OnDirectory) > 0 {
+		sb.WriteString(onDirectoryLoader("powershell", features.OnDirectory))
+	}
+
 	return sb.String()
 }
 
@@ -565,11 +792,22 @@ fi
 
 `)
 
+	// Environment and PATH entries
+	sb.WriteString(envBlock("posix", features.Env))
+
+	// Cached eval helper for features
+	sb.WriteString(cachedEvalFunction("posix"))
+
 	// Eager loading
 	if len(features.Eager) > 0 {
+		if features.Bundle {
+			sb.WriteString("# Check whether the bundle of eager features is up to date\n")
+			sb.WriteString(bundleFreshCheck("posix", features.Eager))
+		}
 		sb.WriteString("# Load eager features\n")
+		var load strings.Builder
 		for _, feature := range features.Eager {
-			sb.WriteString(fmt.Sprintf(`feature_file="$OMD_SHELL_ROOT/features/%s.sh"
+			load.WriteString(fmt.Sprintf(`feature_file="$OMD_SHELL_ROOT/features/%s.sh"
 if [ -r "$feature_file" ]; then
   . "$feature_file"
 else
@@ -578,17 +816,27 @@ fi
 
 `, feature, feature))
 		}
+		if features.Bundle {
+			sb.WriteString(bundleLoader("posix", indentLines(load.String(), "  "), ""))
+			sb.WriteString("unset -f _omd_bundle_fresh; unset _omd_file\n\n")
+		} else {
+			sb.WriteString(load.String())
+		}
 	}
 
+	// Aliases, after the eager features so they win over aliases the features define
+	sb.WriteString(aliasBlock("posix", features.Aliases))
+
 	// Defer loading (basic background sourcing)
 	if len(features.Defer) > 0 {
-		sb.WriteString("# Load deferred features (background)\n")
-		sb.WriteString("# Check for interactive shell\n")
+		sb.WriteString("# Load deferred features in interactive shells. POSIX sh has no prompt hook to wait for, so\n")
+		sb.WriteString("# they load in this shell right after the eager features.\n")
 		sb.WriteString("case $- in\n")
 		sb.WriteString("  *i*)\n")
 		for _, feature := range features.Defer {
-			sb.WriteString(fmt.Sprintf(`    ( [ -r "$OMD_SHELL_ROOT/features/%s.sh" ] && . "$OMD_SHELL_ROOT/features/%s.sh" ) &
-`, feature, feature))
+			sb.WriteString(fmt.Sprintf(`    feature_file="$OMD_SHELL_ROOT/features/%s.sh"
+    [ -r "$feature_file" ] && . "$feature_file"
+`, feature))
 		}
 		sb.WriteString("    ;;\n")
 		sb.WriteString("esac\n\n")
@@ -597,7 +845,8 @@ fi
 	// On-command loading
 	if len(features.OnCommand) > 0 {
 		sb.WriteString("# Register on-command features\n")
-		for featureName, commands := range features.OnCommand {
+		for _, feature := range features.OnCommand {
+			featureName, commands := feature.Name, feature.Commands
 			if len(commands) == 1 {
 				// Single command - simple wrapper
 				cmd := commands[0]
@@ -637,10 +886,20 @@ fi
 		}
 	}
 
+	// On-completion loading
+	if len(features.OnCompletion) > 0 {
+		sb.WriteString("# POSIX sh has no programmable completion, so on-completion features are not loaded\n\n")
+	}
+
+	// On-directory loading
+	if len(features.OnDirectory) > 0 {
+		sb.WriteString(onDirectoryLoader("posix", features.OnDirectory))
+	}
+
 	return sb.String()
 }
 
-// RegenerateInitScript regenerates the init script for a shell
+// RegenerateInitScript regenerates the init script for a shell, and its bundle in bundle mode
 // This should be called after any manifest changes
 func RegenerateInitScript(repoPath, shellName string) error {
 	// Generate new init script content
@@ -656,7 +915,11 @@ func RegenerateInitScript(repoPath, shellName string) error {
 	}
 
 	// Write init script
-	return writeFile(initPath, content)
+	if err := writeFile(initPath, content); err != nil {
+		return err
+	}
+
+	return RebuildBundle(repoPath, shellName)
 }
 
 // TODO: Hook up somehow so the user can use it
@@ -0,0 +1,187 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// writeDeferredRepo writes a repo with an eager feature and a deferred one for a shell,
+// regenerates its init script and returns the path of the script
+// writeShellRepo writes a repository with the manifest and feature files, keyed by feature name,
+// for one shell, generates its init script and returns the repository path
+func writeShellRepo(t *testing.T, shellName string, m *manifest.FeatureManifest, files map[string]string) string {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	if err := os.MkdirAll(GetFeaturesDirectory(repoPath, shellName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.WriteManifest(GetManifestPath(repoPath, shellName), m); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := GetShellConfig(shellName)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(GetFeaturesDirectory(repoPath, shellName), name+config.Extension), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RegenerateInitScript(repoPath, shellName); err != nil {
+		t.Fatalf("RegenerateInitScript: %v", err)
+	}
+	return repoPath
+}
+
+// writeDeferredRepo writes a repository with an eager and a deferred feature and returns its init script path
+func writeDeferredRepo(t *testing.T, shellName string, eager, deferred string) string {
+	t.Helper()
+
+	repoPath := writeShellRepo(t, shellName, &manifest.FeatureManifest{Features: []manifest.FeatureConfig{
+		{Name: "now"},
+		{Name: "later", Strategy: "defer"},
+	}}, map[string]string{"now": eager, "later": deferred})
+	initPath, _ := GetInitScriptPath(repoPath, shellName)
+	return initPath
+}
+
+func TestDeferredFeaturesLoadInTheInteractiveShell(t *testing.T) {
+	tests := []struct {
+		shell string
+		args  []string
+		// Deferred definitions right after sourcing the init script and at the next prompt
+		atStartup, atPrompt string
+	}{
+		// bash and zsh wait for the first prompt
+		{"bash", []string{"--noprofile", "--norc", "-i"}, "func= value= alias=", "func=omd_later value=1 alias=ll"},
+		{"zsh", []string{"-f", "-i"}, "func= value= alias=", "func=omd_later value=1 alias=ll"},
+		// POSIX sh has no prompt hook, so deferred features load at the end of the init script
+		{"posix", []string{"-i"}, "func=omd_later value=1 alias=ll", "func=omd_later value=1 alias=ll"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			executable, ok := FindShellExecutable(tt.shell)
+			if !ok {
+				t.Skipf("%s is not available", tt.shell)
+			}
+			initPath := writeDeferredRepo(t, tt.shell, "OMD_NOW=1\n", "omd_later() { :; }\nOMD_LATER=1\nalias ll='ls -l'\n")
+
+			report := `echo "func=$(command -v omd_later) value=$OMD_LATER alias=$(alias ll >/dev/null 2>&1 && echo ll)"`
+			input := ". '" + initPath + "'; " + report + "\n" + report + "\nexit 0\n"
+			cmd := exec.Command(executable, tt.args...)
+			// The POSIX init script finds its directory from $0, which is the shell when sourced,
+			// so run the shell by name from the shell directory
+			cmd.Args[0] = filepath.Base(executable)
+			cmd.Dir = filepath.Dir(initPath)
+			cmd.Stdin = strings.NewReader(input)
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", tt.shell, err, output)
+			}
+
+			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected two reports, got %q", output)
+			}
+			if lines[0] != tt.atStartup {
+				t.Errorf("after the init script: got %q, want %q", lines[0], tt.atStartup)
+			}
+			if lines[1] != tt.atPrompt {
+				t.Errorf("at the next prompt: got %q, want %q", lines[1], tt.atPrompt)
+			}
+		})
+	}
+}
+
+func TestDeferredLoadingKeepsThePromptCommand(t *testing.T) {
+	if !IsShellExecutableAvailable("bash") {
+		t.Skip("bash is not available")
+	}
+	initPath := writeDeferredRepo(t, "bash", "", "OMD_LATER=1\n")
+
+	// The status of the last command reaches the rest of PROMPT_COMMAND and the hook removes itself
+	cmd := exec.Command("bash", "--noprofile", "--norc", "-i")
+	cmd.Stdin = strings.NewReader(`PROMPT_COMMAND='echo "status=$?"'
+. '` + initPath + `'; false
+echo "pc=$PROMPT_COMMAND later=$OMD_LATER"
+exit 0
+`)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash: %v\n%s", err, output)
+	}
+	if got, want := strings.TrimSpace(string(output)), "status=0\nstatus=1\npc=echo \"status=$?\" later=1\nstatus=0"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDeferredLoadingHooks(t *testing.T) {
+	features := FeaturesByStrategy{Defer: []string{"later"}}
+
+	fish := generateFishInit(features)
+	if !strings.Contains(fish, "function __omd_load_deferred --on-event fish_prompt --inherit-variable OMD_SHELL_ROOT") {
+		t.Errorf("fish should load deferred features from the first fish_prompt event:\n%s", fish)
+	}
+	if strings.Contains(fish, `source "$feature_file" &`) {
+		t.Errorf("fish should source deferred features in the session, not in the background:\n%s", fish)
+	}
+
+	powershell := generatePowerShellInit(features)
+	if !strings.Contains(powershell, "Register-EngineEvent -SourceIdentifier PowerShell.OnIdle -MaxTriggerCount 1") ||
+		!strings.Contains(powershell, ". $global:__omdGlobalState {") {
+		t.Errorf("PowerShell should dot-source deferred features into the global scope when idle:\n%s", powershell)
+	}
+	if strings.Contains(powershell, "Start-Job") {
+		t.Errorf("PowerShell should not load deferred features in a job:\n%s", powershell)
+	}
+
+	// The hooks run inside a function or event action; the variables a deferred feature sets must
+	// still be there afterwards, as they are for an eager feature
+	runtimes := []struct {
+		shell    string
+		deferred string
+		script   string
+		want     string
+	}{
+		{
+			shell:    "fish",
+			deferred: "set LATER loaded\nset -x LATER_EXPORTED yes\n",
+			script:   "source %s\nemit fish_prompt\necho \"later=$LATER\"\nenv | string match 'LATER_EXPORTED=*'",
+			want:     "later=loaded\nLATER_EXPORTED=yes",
+		},
+		{
+			shell:    "powershell",
+			deferred: "$later = 'loaded'\n",
+			script: `. '%s'
+New-Event -SourceIdentifier PowerShell.OnIdle | Out-Null
+for ($i = 0; $i -lt 50 -and -not $later; $i++) { Start-Sleep -Milliseconds 100 }
+"later=$later"`,
+			want: "later=loaded",
+		},
+	}
+	for _, tt := range runtimes {
+		t.Run(tt.shell, func(t *testing.T) {
+			executable, ok := FindShellExecutable(tt.shell)
+			if !ok {
+				t.Skipf("%s is not available", tt.shell)
+			}
+
+			initPath := writeDeferredRepo(t, tt.shell, "", tt.deferred)
+			args := []string{"--no-config", "-c"}
+			if tt.shell == "powershell" {
+				args = []string{"-NoProfile", "-NonInteractive", "-Command"}
+			}
+			output, err := exec.Command(executable, append(args, fmt.Sprintf(tt.script, initPath))...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", tt.shell, err, output)
+			}
+			if got := strings.TrimSpace(strings.ReplaceAll(string(output), "\r\n", "\n")); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
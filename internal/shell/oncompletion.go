@@ -0,0 +1,178 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// onCompletionLoader returns the code that registers a stub completer for the trigger commands of
+// each on-completion feature. The first completion of one of the commands loads the feature, which
+// registers the real completion, and hands the completion over to it.
+func onCompletionLoader(shellName string, features []OnCommandFeature) string {
+	switch shellName {
+	case "bash":
+		return bashOnCompletionLoader(features)
+	case "zsh":
+		return zshOnCompletionLoader(features)
+	case "fish":
+		return fishOnCompletionLoader(features)
+	case "powershell":
+		return powerShellOnCompletionLoader(features)
+	default:
+		return ""
+	}
+}
+
+// quoteAll quotes each value with quote
+func quoteAll(values []string, quote func(string) string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quote(value)
+	}
+	return quoted
+}
+
+// bashOnCompletionLoader returns the stubs for bash, registered with complete -F
+func bashOnCompletionLoader(features []OnCommandFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Register stub completers for on-completion features; the first completion loads the feature\n")
+	sb.WriteString(`# Completes with the compspec the feature registered for the command: its function is called
+# directly, other compspecs through exit status 124, which makes bash retry the completion.
+_omd_complete_dispatch() {
+  local spec
+  spec=$(complete -p -- "$1" 2>/dev/null) || return 0
+  if [[ $spec =~ -F\ ([^ ]+) ]]; then
+    "${BASH_REMATCH[1]}" "$@"
+    return
+  fi
+  return 124
+}
+
+`)
+	for _, feature := range features {
+		stub := fmt.Sprintf("_omd_complete_%s", feature.Name)
+		commands := strings.Join(quoteAll(feature.Commands, shQuote), " ")
+		sb.WriteString(fmt.Sprintf(`%s() {
+  complete -r %s 2>/dev/null
+  unset -f %s
+  local feature_file="$OMD_SHELL_ROOT/features/%s.sh"
+  [ -r "$feature_file" ] && . "$feature_file"
+  _omd_complete_dispatch "$@"
+}
+complete -F %s %s
+
+`, stub, commands, stub, feature.Name, stub, commands))
+	}
+
+	return sb.String()
+}
+
+// zshOnCompletionLoader returns the stubs for zsh, registered with compdef. compinit usually runs
+// after the init script in .zshrc, so without compdef the stubs are registered at the first prompt.
+func zshOnCompletionLoader(features []OnCommandFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Register stub completers for on-completion features; the first completion loads the feature\n")
+	sb.WriteString(`# Completes with the completer the feature registered for the command
+_omd_complete_dispatch() {
+  local completer=${_comps[$service]}
+  [[ -n $completer ]] && $completer "$@"
+}
+
+`)
+	var register strings.Builder
+	for _, feature := range features {
+		stub := fmt.Sprintf("_omd_complete_%s", feature.Name)
+		commands := strings.Join(quoteAll(feature.Commands, shQuote), " ")
+		sb.WriteString(fmt.Sprintf(`%s() {
+  compdef -d %s
+  unfunction %s
+  local feature_file="$OMD_SHELL_ROOT/features/%s.zsh"
+  [[ -r "$feature_file" ]] && . "$feature_file"
+  _omd_complete_dispatch "$@"
+}
+
+`, stub, commands, stub, feature.Name))
+		register.WriteString(fmt.Sprintf("  compdef %s %s\n", stub, commands))
+	}
+	sb.WriteString("_omd_register_oncompletion_features() {\n")
+	sb.WriteString(register.String())
+	sb.WriteString(`}
+
+if (( $+functions[compdef] )); then
+  _omd_register_oncompletion_features
+  unset -f _omd_register_oncompletion_features
+else
+  _omd_oncompletion_precmd() {
+    add-zsh-hook -d precmd _omd_oncompletion_precmd
+    (( $+functions[compdef] )) && _omd_register_oncompletion_features
+    unset -f _omd_oncompletion_precmd _omd_register_oncompletion_features
+  }
+  autoload -Uz add-zsh-hook
+  add-zsh-hook precmd _omd_oncompletion_precmd
+fi
+
+`)
+
+	return sb.String()
+}
+
+// fishOnCompletionLoader returns the stubs for fish, registered with complete -c. The stub erases
+// itself, loads the feature and prints the completions of the command line with complete -C.
+func fishOnCompletionLoader(features []OnCommandFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Register stub completers for on-completion features; the first completion loads the feature\n")
+	for _, feature := range features {
+		stub := fmt.Sprintf("__omd_complete_%s", feature.Name)
+		commands := quoteAll(feature.Commands, fishQuote)
+		erase := make([]string, len(commands))
+		for i, command := range commands {
+			erase[i] = fmt.Sprintf("complete -e -c %s", command)
+		}
+		sb.WriteString(fmt.Sprintf(`function %s --inherit-variable OMD_SHELL_ROOT
+  %s
+  functions -e %s
+  __omd_source_feature "$OMD_SHELL_ROOT/features/%s.fish"
+  complete -C (commandline -cp)
+end
+`, stub, strings.Join(erase, "; "), stub, feature.Name))
+		for _, command := range commands {
+			sb.WriteString(fmt.Sprintf("complete -c %s -f -a '(%s)'\n", command, stub))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// powerShellOnCompletionLoader returns the stubs for PowerShell, registered with
+// Register-ArgumentCompleter. A completer runs in its own scope, so the feature is dot-sourced into
+// the global session state; the feature replaces the completer and TabExpansion2 completes again.
+func powerShellOnCompletionLoader(features []OnCommandFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Register stub completers for on-completion features; the first completion loads the feature\n")
+	sb.WriteString("$global:__omdCompletionLoaded = @{}\n")
+	for _, feature := range features {
+		sb.WriteString(fmt.Sprintf(`Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {
+  param($wordToComplete, $commandAst, $cursorPosition)
+  # The feature did not replace this completer
+  if ($global:__omdCompletionLoaded[%s]) {
+    return
+  }
+  $global:__omdCompletionLoaded[%s] = $true
+  $featureFile = Join-Path $OMD_SHELL_ROOT "features\%s.ps1"
+  if (Test-Path $featureFile) {
+    . $global:__omdGlobalState { param($featureFile) . $featureFile } $featureFile
+  }
+  $inputScript = $commandAst.Extent.StartScriptPosition.GetFullScript()
+  (TabExpansion2 -inputScript $inputScript -cursorColumn $cursorPosition).CompletionMatches
+}
+
+`, strings.Join(quoteAll(feature.Commands, psQuote), ", "), psQuote(feature.Name), psQuote(feature.Name), feature.Name))
+	}
+
+	return sb.String()
+}
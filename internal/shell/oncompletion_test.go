@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func TestOnCompletionStubLoadsTheFeature(t *testing.T) {
+	if !IsShellExecutableAvailable("bash") {
+		t.Skip("bash is not available")
+	}
+
+	tests := []struct {
+		name    string
+		feature string
+		want    string
+	}{
+		{
+			name:    "hands over to the completion function",
+			feature: "_omd_test_complete() { COMPREPLY=(apply get); }\ncomplete -F _omd_test_complete omd-tool omd-t\n",
+			want:    "status=0 reply=apply get tool=complete -F _omd_test_complete omd-tool alias=complete -F _omd_test_complete omd-t stub=",
+		},
+		{
+			// bash retries the completion when the function returns 124
+			name:    "retries other completions",
+			feature: "complete -W 'apply get' omd-tool\n",
+			want:    "status=124 reply= tool=complete -W 'apply get' omd-tool alias= stub=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{
+				{Name: "tool-completion", Strategy: "on-completion", OnCommand: []string{"omd-tool", "omd-t"}},
+			}}
+			repoPath := writeShellRepo(t, "bash", m, map[string]string{"tool-completion": tt.feature})
+			initPath, _ := GetInitScriptPath(repoPath, "bash")
+
+			// Complete "omd-tool <TAB>" the way readline calls the compspec function
+			cmd := exec.Command("bash", "--noprofile", "--norc", "-c", `. "$1"
+[ "$(complete -p omd-tool)" = "complete -F _omd_complete_tool-completion omd-tool" ] || echo "no stub: $(complete -p omd-tool)"
+COMP_WORDS=(omd-tool ""); COMP_CWORD=1; COMP_LINE="omd-tool "; COMP_POINT=9
+_omd_complete_tool-completion omd-tool "" omd-tool
+echo "status=$? reply=${COMPREPLY[*]} tool=$(complete -p omd-tool) alias=$(complete -p omd-t 2>/dev/null) stub=$(type -t _omd_complete_tool-completion)"`, "bash", initPath)
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash: %v\n%s", err, output)
+			}
+			if got := strings.TrimSpace(string(output)); got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnCompletionStubs(t *testing.T) {
+	features := FeaturesByStrategy{OnCompletion: []OnCommandFeature{{Name: "kubectl-completion", Commands: []string{"kubectl", "k"}}}}
+
+	zsh := generateZshInit(features)
+	if !strings.Contains(zsh, "compdef _omd_complete_kubectl-completion 'kubectl' 'k'") ||
+		!strings.Contains(zsh, "add-zsh-hook precmd _omd_oncompletion_precmd") {
+		t.Errorf("zsh should register the stub with compdef, at the first prompt without compinit:\n%s", zsh)
+	}
+
+	fish := generateFishInit(features)
+	if !strings.Contains(fish, "complete -c 'kubectl' -f -a '(__omd_complete_kubectl-completion)'") ||
+		!strings.Contains(fish, "complete -C (commandline -cp)") {
+		t.Errorf("fish should register a stub that completes again after loading:\n%s", fish)
+	}
+
+	powershell := generatePowerShellInit(features)
+	if !strings.Contains(powershell, "Register-ArgumentCompleter -Native -CommandName 'kubectl', 'k' -ScriptBlock {") ||
+		!strings.Contains(powershell, "$global:__omdGlobalState = [psmoduleinfo]::new($false)") {
+		t.Errorf("PowerShell should register a stub completer that loads into the global scope:\n%s", powershell)
+	}
+
+	if posix := generatePosixInit(features); strings.Contains(posix, "kubectl") {
+		t.Errorf("POSIX sh has no completion to load:\n%s", posix)
+	}
+}
@@ -0,0 +1,255 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// onDirectoryLoader returns the code that loads on-directory features the first time the working
+// directory, or one of its parents, holds one of their marker files. Each feature loads once and
+// the hook removes itself when every feature has loaded. Only interactive shells are hooked.
+func onDirectoryLoader(shellName string, features []OnDirectoryFeature) string {
+	switch shellName {
+	case "bash":
+		return shOnDirectoryLoader(features, ".sh", true, `  PROMPT_COMMAND="${PROMPT_COMMAND//_omd_ondirectory_check;/}"
+  PROMPT_COMMAND="${PROMPT_COMMAND//_omd_ondirectory_check/}"
+  unset -f _omd_ondirectory_check _omd_find_marker
+`, `if [[ $- == *i* ]]; then
+  PROMPT_COMMAND="_omd_ondirectory_check${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+else
+  unset -f _omd_ondirectory_check _omd_find_marker
+  unset _omd_ondirectory_left _omd_ondirectory_pwd
+fi
+`)
+	case "zsh":
+		return shOnDirectoryLoader(features, ".zsh", true, `  add-zsh-hook -d chpwd _omd_ondirectory_check
+  unset -f _omd_ondirectory_check _omd_find_marker
+`, `if [[ -o interactive ]]; then
+  autoload -Uz add-zsh-hook
+  add-zsh-hook chpwd _omd_ondirectory_check
+  _omd_ondirectory_check
+else
+  unset -f _omd_ondirectory_check _omd_find_marker
+  unset _omd_ondirectory_left _omd_ondirectory_pwd
+fi
+`)
+	case "posix":
+		// POSIX sh has no directory or prompt hook, so cd is wrapped, and no local, so the
+		// check's variables are unset with it
+		return shOnDirectoryLoader(features, ".sh", false, `  unset -f cd _omd_ondirectory_check _omd_find_marker
+  unset _omd_marker _omd_marker_dir feature_file
+`, `case $- in
+  *i*)
+    cd() { command cd "$@" || return; _omd_ondirectory_check; }
+    _omd_ondirectory_check
+    ;;
+  *)
+    unset -f _omd_ondirectory_check _omd_find_marker
+    unset _omd_ondirectory_left _omd_ondirectory_pwd
+    ;;
+esac
+`)
+	case "fish":
+		return fishOnDirectoryLoader(features)
+	case "powershell":
+		return powerShellOnDirectoryLoader(features)
+	default:
+		return ""
+	}
+}
+
+// shOnDirectoryLoader returns the loader shared by bash, zsh and POSIX sh. hasLocal declares the
+// variables of the check and marker search local, which POSIX sh can't. unhook runs once every
+// feature has loaded and register hooks the check into the shell.
+func shOnDirectoryLoader(features []OnDirectoryFeature, ext string, hasLocal bool, unhook, register string) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Load on-directory features in directories that hold one of their markers, or below them\n")
+	sb.WriteString(fmt.Sprintf("_omd_ondirectory_left=%d\n", len(features)))
+	sb.WriteString(`_omd_ondirectory_pwd=
+
+# Succeeds when $PWD or one of its parents holds one of the marker files
+_omd_find_marker() {
+`)
+	if hasLocal {
+		sb.WriteString("  local _omd_marker _omd_marker_dir\n")
+	}
+	sb.WriteString(`  _omd_marker_dir=$PWD
+  while :; do
+    for _omd_marker in "$@"; do
+      [ -e "$_omd_marker_dir/$_omd_marker" ] && return 0
+    done
+    [ -n "$_omd_marker_dir" ] || return 1
+    _omd_marker_dir=${_omd_marker_dir%/*}
+  done
+}
+
+_omd_ondirectory_check() {
+`)
+	if hasLocal {
+		sb.WriteString("  local _omd_ondirectory_status=$? feature_file\n")
+	} else {
+		sb.WriteString("  _omd_ondirectory_status=$?\n")
+	}
+	sb.WriteString(`  [ "$PWD" = "$_omd_ondirectory_pwd" ] && return $_omd_ondirectory_status
+  _omd_ondirectory_pwd=$PWD
+`)
+	for i, feature := range features {
+		markers := make([]string, len(feature.Markers))
+		for j, marker := range feature.Markers {
+			markers[j] = shQuote(marker)
+		}
+		sb.WriteString(fmt.Sprintf(`  if [ -z "${_omd_ondirectory_%d:-}" ] && _omd_find_marker %s; then
+    _omd_ondirectory_%d=1
+    _omd_ondirectory_left=$((_omd_ondirectory_left - 1))
+    feature_file="$OMD_SHELL_ROOT/features/%s%s"
+    [ -r "$feature_file" ] && . "$feature_file"
+  fi
+`, i, strings.Join(markers, " "), i, feature.Name, ext))
+	}
+	flags := make([]string, len(features))
+	for i := range features {
+		flags[i] = fmt.Sprintf("_omd_ondirectory_%d", i)
+	}
+	sb.WriteString("  if [ \"$_omd_ondirectory_left\" -le 0 ]; then\n")
+	sb.WriteString(unhook)
+	sb.WriteString(fmt.Sprintf("  unset _omd_ondirectory_left _omd_ondirectory_pwd %s\n", strings.Join(flags, " ")))
+	sb.WriteString("  fi\n")
+	sb.WriteString("  return $_omd_ondirectory_status\n")
+	sb.WriteString("}\n\n")
+	sb.WriteString(register)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// fishOnDirectoryLoader returns the loader for fish, which runs the check when $PWD changes
+func fishOnDirectoryLoader(features []OnDirectoryFeature) string {
+	var sb strings.Builder
+
+	names := make([]string, len(features))
+	for i, feature := range features {
+		names[i] = feature.Name
+	}
+
+	sb.WriteString("# Load on-directory features in directories that hold one of their markers, or below them\n")
+	sb.WriteString(fmt.Sprintf("set -g __omd_ondirectory_pending %s\n", strings.Join(names, " ")))
+	sb.WriteString(`
+# Succeeds when $PWD or one of its parents holds one of the marker files
+function __omd_find_marker
+  set -l dir $PWD
+  while true
+    for marker in $argv
+      test -e "$dir/$marker"; and return 0
+    end
+    test -n "$dir"; or return 1
+    set dir (string replace -r '/[^/]*$' '' -- $dir)
+  end
+end
+
+function __omd_ondirectory_check --on-variable PWD --inherit-variable OMD_SHELL_ROOT
+`)
+	for _, feature := range features {
+		markers := make([]string, len(feature.Markers))
+		for j, marker := range feature.Markers {
+			markers[j] = fishQuote(marker)
+		}
+		sb.WriteString(fmt.Sprintf(`  if contains %s $__omd_ondirectory_pending; and __omd_find_marker %s
+    set -e __omd_ondirectory_pending[(contains -i %s $__omd_ondirectory_pending)]
+    __omd_source_feature "$OMD_SHELL_ROOT/features/%s.fish"
+  end
+`, feature.Name, strings.Join(markers, " "), feature.Name, feature.Name))
+	}
+	sb.WriteString(`  if not set -q __omd_ondirectory_pending[1]
+    functions -e __omd_ondirectory_check __omd_find_marker
+    set -e __omd_ondirectory_pending
+  end
+end
+
+if status is-interactive
+  __omd_ondirectory_check
+else
+  functions -e __omd_ondirectory_check __omd_find_marker
+  set -e __omd_ondirectory_pending
+end
+
+`)
+
+	return sb.String()
+}
+
+// powerShellOnDirectoryLoader returns the loader for PowerShell, which chains LocationChangedAction.
+// The action runs in its own scope, so the features are dot-sourced into the global session state.
+func powerShellOnDirectoryLoader(features []OnDirectoryFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Load on-directory features in directories that hold one of their markers, or below them\n")
+	sb.WriteString("if ($Host.UI.RawUI) {  # Interactive shell check\n")
+	sb.WriteString("  $global:__omdOnDirectoryFeatures = [ordered]@{\n")
+	for _, feature := range features {
+		markers := make([]string, len(feature.Markers))
+		for j, marker := range feature.Markers {
+			markers[j] = psQuote(marker)
+		}
+		sb.WriteString(fmt.Sprintf("    %s = @{ File = (Join-Path $OMD_SHELL_ROOT \"features\\%s.ps1\"); Markers = @(%s) }\n",
+			psQuote(feature.Name), feature.Name, strings.Join(markers, ", ")))
+	}
+	sb.WriteString(`  }
+  $global:__omdPreviousLocationChangedAction = $ExecutionContext.SessionState.InvokeCommand.LocationChangedAction
+
+  # Succeeds when the current file system location or one of its parents holds one of the marker files
+  function global:__omd_find_marker([string[]]$Markers) {
+    $location = Get-Location
+    if ($location.Provider.Name -ne 'FileSystem') {
+      return $false
+    }
+    $dir = $location.ProviderPath
+    while ($dir) {
+      foreach ($marker in $Markers) {
+        if (Test-Path -LiteralPath (Join-Path $dir $marker)) {
+          return $true
+        }
+      }
+      $dir = Split-Path -Parent $dir
+    }
+    return $false
+  }
+
+  function global:__omd_ondirectory_check {
+    foreach ($name in @($global:__omdOnDirectoryFeatures.Keys)) {
+      $feature = $global:__omdOnDirectoryFeatures[$name]
+      if (__omd_find_marker $feature.Markers) {
+        $global:__omdOnDirectoryFeatures.Remove($name)
+        if (Test-Path $feature.File) {
+          . $global:__omdGlobalState { param($featureFile) . $featureFile } $feature.File
+        }
+      }
+    }
+    if ($global:__omdOnDirectoryFeatures.Count -eq 0) {
+      # Put the previous action back unless another one was chained after this one
+      if ([object]::ReferenceEquals($ExecutionContext.SessionState.InvokeCommand.LocationChangedAction, $global:__omdOnDirectoryAction)) {
+        $ExecutionContext.SessionState.InvokeCommand.LocationChangedAction = $global:__omdPreviousLocationChangedAction
+        Remove-Variable -Name __omdPreviousLocationChangedAction -Scope Global
+      }
+      Remove-Variable -Name __omdOnDirectoryFeatures, __omdOnDirectoryAction -Scope Global
+      Remove-Item Function:__omd_find_marker, Function:__omd_ondirectory_check
+    }
+  }
+
+  $ExecutionContext.SessionState.InvokeCommand.LocationChangedAction = {
+    param($source, $locationArgs)
+    if ($global:__omdPreviousLocationChangedAction) {
+      $global:__omdPreviousLocationChangedAction.Invoke($source, $locationArgs)
+    }
+    if (Get-Command __omd_ondirectory_check -ErrorAction SilentlyContinue) {
+      __omd_ondirectory_check
+    }
+  }
+  $global:__omdOnDirectoryAction = $ExecutionContext.SessionState.InvokeCommand.LocationChangedAction
+  __omd_ondirectory_check
+}
+
+`)
+
+	return sb.String()
+}
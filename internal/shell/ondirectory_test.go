@@ -0,0 +1,120 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func TestOnDirectoryFeaturesLoadInMarkedDirectories(t *testing.T) {
+	for _, shellName := range []string{"bash", "posix"} {
+		t.Run(shellName, func(t *testing.T) {
+			executable, ok := FindShellExecutable(shellName)
+			if !ok {
+				t.Skipf("%s is not available", shellName)
+			}
+
+			m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{
+				{Name: "node", Strategy: "on-directory", OnDirectory: []string{".nvmrc", ".node-version"}},
+				{Name: "py", Strategy: "on-directory", OnDirectory: []string{"pyproject.toml"}},
+			}}
+			// Each feature counts its loads
+			files := map[string]string{}
+			for _, name := range []string{"node", "py"} {
+				variable := "OMD_" + strings.ToUpper(name)
+				files[name] = variable + "=$((${" + variable + ":-0} + 1))\n"
+			}
+			repoPath := writeShellRepo(t, shellName, m, files)
+			initPath, _ := GetInitScriptPath(repoPath, shellName)
+
+			projects := t.TempDir()
+			for _, path := range []string{"node/sub/deeper", "py"} {
+				if err := os.MkdirAll(filepath.Join(projects, path), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, path := range []string{"node/.node-version", "py/pyproject.toml"} {
+				if err := os.WriteFile(filepath.Join(projects, path), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report := `echo "node=$OMD_NODE py=$OMD_PY hook=$(command -v _omd_ondirectory_check)"`
+			input := strings.Join([]string{
+				". '" + initPath + "'",
+				report,
+				"cd '" + filepath.Join(projects, "node/sub/deeper") + "'",
+				report,
+				"cd ..; cd '" + projects + "'",
+				"cd node",
+				report,
+				"cd ../py",
+				report,
+				`echo "left=${_omd_marker-}${_omd_marker_dir-}${feature_file-}${_omd_ondirectory_left-}${_omd_ondirectory_pwd-}${_omd_ondirectory_0-}"`,
+				"exit 0",
+			}, "\n") + "\n"
+			cmd := exec.Command(executable, "-i")
+			// The POSIX init script finds its directory from $0, which is the shell when sourced,
+			// so run the shell by name from the shell directory
+			cmd.Args[0] = filepath.Base(executable)
+			cmd.Dir = filepath.Dir(initPath)
+			cmd.Stdin = strings.NewReader(input)
+			if shellName == "bash" {
+				cmd.Args = append(cmd.Args[:1], "--noprofile", "--norc", "-i")
+			}
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", shellName, err, output)
+			}
+
+			want := strings.Join([]string{
+				"node= py= hook=_omd_ondirectory_check",
+				// A marker in a parent directory counts
+				"node=1 py= hook=_omd_ondirectory_check",
+				// Entering the project again does not load the feature again
+				"node=1 py= hook=_omd_ondirectory_check",
+				// The hook removes itself once every feature has loaded
+				"node=1 py=1 hook=",
+				// and leaves none of its variables behind
+				"left=",
+			}, "\n")
+			if got := strings.TrimSpace(string(output)); got != want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestOnDirectoryHooks(t *testing.T) {
+	features := FeaturesByStrategy{OnDirectory: []OnDirectoryFeature{{Name: "node", Markers: []string{".nvmrc"}}}}
+
+	zsh := generateZshInit(features)
+	if !strings.Contains(zsh, "add-zsh-hook chpwd _omd_ondirectory_check") {
+		t.Errorf("zsh should check the markers when the directory changes:\n%s", zsh)
+	}
+	for name, script := range map[string]string{"bash": generateBashInit(features), "zsh": zsh} {
+		if !strings.Contains(script, "_omd_ondirectory_check() {\n  local _omd_ondirectory_status=$? feature_file\n") ||
+			!strings.Contains(script, "_omd_find_marker() {\n  local _omd_marker _omd_marker_dir\n") {
+			t.Errorf("%s should keep the check's variables local:\n%s", name, script)
+		}
+	}
+
+	fish := generateFishInit(features)
+	if !strings.Contains(fish, "function __omd_ondirectory_check --on-variable PWD --inherit-variable OMD_SHELL_ROOT") ||
+		!strings.Contains(fish, "__omd_find_marker '.nvmrc'") {
+		t.Errorf("fish should check the markers when PWD changes:\n%s", fish)
+	}
+
+	powershell := generatePowerShellInit(features)
+	if !strings.Contains(powershell, "$ExecutionContext.SessionState.InvokeCommand.LocationChangedAction = {") ||
+		!strings.Contains(powershell, "$global:__omdPreviousLocationChangedAction.Invoke($source, $locationArgs)") {
+		t.Errorf("PowerShell should chain LocationChangedAction:\n%s", powershell)
+	}
+	if !strings.Contains(powershell, `'node' = @{ File = (Join-Path $OMD_SHELL_ROOT "features\node.ps1"); Markers = @('.nvmrc') }`) {
+		t.Errorf("PowerShell should list the feature with its markers:\n%s", powershell)
+	}
+}
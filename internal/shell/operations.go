@@ -70,7 +70,7 @@ func InitializeShellDirectory(repoPath, shellName string) error {
 }
 
 // AddFeatureToShell adds a feature to a specific shell
-func AddFeatureToShell(repoPath, shellName, featureName string, strategy string, onCommand []string, disabled bool, options map[string]any) error {
+func AddFeatureToShell(repoPath, shellName, featureName string, strategy string, onCommand, onDirectory []string, disabled bool, options map[string]any) error {
 	// Check if shell directory exists, if not initialize it
 	if !ShellDirectoryExists(repoPath, shellName) {
 		if err := InitializeShellDirectory(repoPath, shellName); err != nil {
@@ -94,17 +94,23 @@ func AddFeatureToShell(repoPath, shellName, featureName string, strategy string,
 	}
 
 	// Determine onCommand (use provided or default from catalog)
-	if len(onCommand) == 0 && inCatalog && metadata.DefaultStrategy == "on-command" {
+	if len(onCommand) == 0 && inCatalog && (metadata.DefaultStrategy == "on-command" || strategy == "on-completion") {
 		onCommand = metadata.DefaultCommands
 	}
 
+	// Determine onDirectory (use provided or default from catalog)
+	if len(onDirectory) == 0 && inCatalog && strategy == "on-directory" {
+		onDirectory = metadata.DefaultMarkers
+	}
+
 	// Create feature config
 	featureConfig := manifest.FeatureConfig{
-		Name:      featureName,
-		Strategy:  strategy,
-		OnCommand: onCommand,
-		Disabled:  disabled,
-		Options:   options,
+		Name:        featureName,
+		Strategy:    strategy,
+		OnCommand:   onCommand,
+		OnDirectory: onDirectory,
+		Disabled:    disabled,
+		Options:     options,
 	}
 
 	// Add to manifest
@@ -175,7 +181,7 @@ func RefreshFeatureTemplate(repoPath, shellName, featureName string) error {
 		return fmt.Errorf("failed to refresh feature template: %w", err)
 	}
 
-	return nil
+	return RebuildBundle(repoPath, shellName)
 }
 
 // generateFeatureTemplate creates a template feature file
@@ -281,7 +287,7 @@ func CleanupShellDirectory(repoPath, shellName string) error {
 }
 
 // EnableFeatureWithOptions enables a disabled feature and optionally updates its configuration
-func EnableFeatureWithOptions(repoPath, shellName, featureName, strategy string, onCommand []string) error {
+func EnableFeatureWithOptions(repoPath, shellName, featureName, strategy string, onCommand, onDirectory []string) error {
 	manifestPath := GetManifestPath(repoPath, shellName)
 	m, err := manifest.ParseManifest(manifestPath)
 	if err != nil {
@@ -296,6 +302,19 @@ func EnableFeatureWithOptions(repoPath, shellName, featureName, strategy string,
 		if len(onCommand) > 0 {
 			f.OnCommand = onCommand
 		}
+		if len(onDirectory) > 0 {
+			f.OnDirectory = onDirectory
+		}
+		if f.Strategy == "on-completion" && len(f.OnCommand) == 0 {
+			if metadata, ok := catalog.GetFeature(featureName); ok {
+				f.OnCommand = metadata.DefaultCommands
+			}
+		}
+		if f.Strategy == "on-directory" && len(f.OnDirectory) == 0 {
+			if metadata, ok := catalog.GetFeature(featureName); ok {
+				f.OnDirectory = metadata.DefaultMarkers
+			}
+		}
 	})
 	if err != nil {
 		return err
@@ -355,6 +374,76 @@ func DisableFeature(repoPath, shellName, featureName string) error {
 	return RegenerateInitScript(repoPath, shellName)
 }
 
+// SetFeatureOrder reorders the features of a shell to follow names. With local set the order
+// is kept in enabled.local.json for this machine only; otherwise enabled.json is rewritten.
+func SetFeatureOrder(repoPath, shellName string, names []string, local bool) error {
+	if !local {
+		manifestPath := GetManifestPath(repoPath, shellName)
+		m, err := manifest.ParseManifest(manifestPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse manifest: %w", err)
+		}
+
+		m.SetOrder(names)
+		if err := manifest.WriteManifest(manifestPath, m); err != nil {
+			return err
+		}
+		return RegenerateInitScript(repoPath, shellName)
+	}
+
+	localPath := GetLocalManifestPath(repoPath, shellName)
+	localManifest := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{}}
+	if _, err := os.Stat(localPath); err == nil {
+		if err := manifest.ValidateLocalManifest(localPath); err != nil {
+			return fmt.Errorf("refusing to update %s: %w", localPath, err)
+		}
+		if localManifest, err = manifest.ParseManifest(localPath); err != nil {
+			return fmt.Errorf("failed to parse local manifest: %w", err)
+		}
+	}
+
+	localManifest.Order = names
+	if err := manifest.WriteManifest(localPath, localManifest); err != nil {
+		return err
+	}
+	return RegenerateInitScript(repoPath, shellName)
+}
+
+// FeatureOrder returns the names of a shell's features in manifest order, including local overrides
+func FeatureOrder(repoPath, shellName string) ([]string, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(merged.Features))
+	for i, f := range merged.Features {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// LoadOrder returns the names of a shell's enabled features in the order the init script loads them
+func LoadOrder(repoPath, shellName string) ([]string, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return nil, err
+	}
+
+	features := categorizeFeaturesMerged(merged)
+	names := append(append([]string{}, features.Eager...), features.Defer...)
+	for _, f := range features.OnCommand {
+		names = append(names, f.Name)
+	}
+	for _, f := range features.OnCompletion {
+		names = append(names, f.Name)
+	}
+	for _, f := range features.OnDirectory {
+		names = append(names, f.Name)
+	}
+	return names, nil
+}
+
 // ListShellsWithFeatures returns a list of shells that have been initialized
 func ListShellsWithFeatures(repoPath string) ([]string, error) {
 	omdShellsDir := filepath.Join(repoPath, "omd-shells")
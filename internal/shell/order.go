@@ -0,0 +1,181 @@
+package shell
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/catalog"
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// DependencyError reports a required feature that cannot be loaded before the feature needing it.
+type DependencyError struct {
+	Feature    string
+	Dependency string
+	Reason     string // e.g. "is disabled"
+	Missing    bool   // The dependency is not enabled at all, rather than loaded too late
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("feature '%s' requires '%s', which %s", e.Feature, e.Dependency, e.Reason)
+}
+
+// CycleError reports features whose requires and after declarations form a cycle.
+type CycleError struct {
+	Cycle []string // Features in the cycle, starting and ending with the same feature
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// strategyRank orders strategies by when they load; a feature may only require features of the same or a lower rank.
+// On-directory features load at startup when the shell starts in a marked directory, before deferred ones.
+// On-command and on-completion features share the last rank but each loads on its own, and a command
+// wrapper only loads the feature that registered it last, so no feature can require one of them,
+// even a feature with the same strategy and commands.
+var strategyRank = map[string]int{
+	"eager":         0,
+	"on-directory":  1,
+	"defer":         2,
+	"on-command":    3,
+	"on-completion": 3,
+}
+
+// featureStrategy returns the strategy of a feature, defaulting to eager.
+func featureStrategy(f manifest.FeatureConfig) string {
+	if f.Strategy == "" {
+		return "eager"
+	}
+	return f.Strategy
+}
+
+// featureDependencies returns the requires and after lists of a feature. A list set in the manifest,
+// even an empty one, replaces the one from the catalog, so a manifest can drop a catalog dependency.
+func featureDependencies(f manifest.FeatureConfig) (requires, after []string) {
+	requires, after = f.Requires, f.After
+	if metadata, ok := catalog.GetFeature(f.Name); ok {
+		if requires == nil {
+			requires = metadata.Requires
+		}
+		if after == nil {
+			after = metadata.After
+		}
+	}
+	return slices.Clone(requires), slices.Clone(after)
+}
+
+// OrderFeatures returns the enabled features so every feature comes after the features it requires
+// or is declared to load after. Otherwise the manifest order is kept, so the result is stable.
+// Missing dependencies and cycles are returned as errors; the order is still usable and breaks
+// cycles at the feature listed first.
+func OrderFeatures(features []manifest.FeatureConfig) ([]manifest.FeatureConfig, []error) {
+	var errs []error
+
+	known := make(map[string]manifest.FeatureConfig, len(features))
+	for _, f := range features {
+		known[f.Name] = f
+	}
+
+	var enabled []manifest.FeatureConfig
+	index := map[string]int{}
+	for _, f := range features {
+		if !f.Disabled {
+			index[f.Name] = len(enabled)
+			enabled = append(enabled, f)
+		}
+	}
+
+	// dependsOn[i] lists the enabled features that must come before enabled[i]
+	dependsOn := make([][]int, len(enabled))
+	for i, f := range enabled {
+		requires, after := featureDependencies(f)
+		for _, name := range requires {
+			dependency, exists := known[name]
+			switch {
+			case !exists:
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name, Reason: "is not in the manifest", Missing: true})
+				continue
+			case dependency.Disabled:
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name, Reason: "is disabled", Missing: true})
+				continue
+			case featureStrategy(dependency) == "on-command":
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name, Reason: "only loads when one of its commands runs"})
+			case featureStrategy(dependency) == "on-completion":
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name, Reason: "only loads when one of its commands is completed"})
+			case featureStrategy(dependency) == "on-directory" && featureStrategy(f) != "on-directory":
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name, Reason: "only loads in directories with one of its marker files"})
+			case strategyRank[featureStrategy(dependency)] > strategyRank[featureStrategy(f)]:
+				errs = append(errs, &DependencyError{Feature: f.Name, Dependency: name,
+					Reason: fmt.Sprintf("loads later (%s after %s)", featureStrategy(dependency), featureStrategy(f))})
+			}
+			dependsOn[i] = append(dependsOn[i], index[name])
+		}
+		for _, name := range after {
+			if j, ok := index[name]; ok && !slices.Contains(dependsOn[i], j) {
+				dependsOn[i] = append(dependsOn[i], j)
+			}
+		}
+	}
+
+	// Repeatedly take the first feature, in manifest order, whose dependencies are placed.
+	placed := make([]bool, len(enabled))
+	ordered := make([]manifest.FeatureConfig, 0, len(enabled))
+	for len(ordered) < len(enabled) {
+		next := -1
+		for i := range enabled {
+			if !placed[i] && allPlaced(dependsOn[i], placed) {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			// Every remaining feature waits on another: report a cycle and break it at its first feature.
+			cycle := findCycle(slices.Index(placed, false), dependsOn, placed)
+			names := make([]string, 0, len(cycle)+1)
+			for _, i := range cycle {
+				names = append(names, enabled[i].Name)
+			}
+			errs = append(errs, &CycleError{Cycle: append(names, names[0])})
+			next = slices.Min(cycle)
+		}
+
+		placed[next] = true
+		ordered = append(ordered, enabled[next])
+	}
+
+	return ordered, errs
+}
+
+func allPlaced(dependencies []int, placed []bool) bool {
+	for _, j := range dependencies {
+		if !placed[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCycle follows unplaced dependencies from start until a feature repeats, and returns that loop.
+// Every unplaced feature has an unplaced dependency when it is called, so the walk always ends.
+func findCycle(start int, dependsOn [][]int, placed []bool) []int {
+	var path []int
+	seen := map[int]int{}
+	current := start
+	for {
+		if at, ok := seen[current]; ok {
+			return path[at:]
+		}
+		seen[current] = len(path)
+		path = append(path, current)
+
+		for _, j := range dependsOn[current] {
+			if !placed[j] {
+				current = j
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+func featureNames(features []manifest.FeatureConfig) []string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func TestOrderFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		features []manifest.FeatureConfig
+		want     []string
+	}{
+		{
+			name:     "keeps manifest order without dependencies",
+			features: []manifest.FeatureConfig{{Name: "c"}, {Name: "a"}, {Name: "b"}},
+			want:     []string{"c", "a", "b"},
+		},
+		{
+			name:     "requires moves the dependency first",
+			features: []manifest.FeatureConfig{{Name: "prompt", Requires: []string{"tool"}}, {Name: "other"}, {Name: "tool"}},
+			want:     []string{"other", "tool", "prompt"},
+		},
+		{
+			name:     "after orders only enabled features",
+			features: []manifest.FeatureConfig{{Name: "prompt", After: []string{"tool", "absent"}}, {Name: "tool"}},
+			want:     []string{"tool", "prompt"},
+		},
+		{
+			name:     "disabled features are left out",
+			features: []manifest.FeatureConfig{{Name: "a", Disabled: true}, {Name: "b", After: []string{"a"}}},
+			want:     []string{"b"},
+		},
+		{
+			name: "chains resolve transitively",
+			features: []manifest.FeatureConfig{
+				{Name: "a", After: []string{"b"}},
+				{Name: "b", After: []string{"c"}},
+				{Name: "c"},
+			},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "catalog after applies to catalog features",
+			features: []manifest.FeatureConfig{
+				{Name: "oh-my-posh", Options: map[string]any{"theme": "pure"}},
+				{Name: "homebrew-path"},
+			},
+			want: []string{"homebrew-path", "oh-my-posh"},
+		},
+		{
+			name: "manifest after replaces the catalog's",
+			features: []manifest.FeatureConfig{
+				{Name: "oh-my-posh", After: []string{}, Options: map[string]any{"theme": "pure"}},
+				{Name: "homebrew-path"},
+			},
+			want: []string{"oh-my-posh", "homebrew-path"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := OrderFeatures(tt.features)
+			if len(errs) > 0 {
+				t.Fatalf("OrderFeatures errors: %v", errs)
+			}
+			if names := featureNames(got); strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("OrderFeatures = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderFeatures_Problems(t *testing.T) {
+	t.Run("missing and disabled requirements", func(t *testing.T) {
+		_, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "a", Requires: []string{"absent", "off"}},
+			{Name: "off", Disabled: true},
+		})
+		if len(errs) != 2 {
+			t.Fatalf("expected 2 errors, got %v", errs)
+		}
+		for _, err := range errs {
+			var dependencyErr *DependencyError
+			if !errors.As(err, &dependencyErr) || !dependencyErr.Missing {
+				t.Fatalf("expected a missing dependency error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("requirement that loads later", func(t *testing.T) {
+		_, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "a", Requires: []string{"b"}},
+			{Name: "b", Strategy: "defer"},
+		})
+		var dependencyErr *DependencyError
+		if len(errs) != 1 || !errors.As(errs[0], &dependencyErr) || dependencyErr.Missing {
+			t.Fatalf("expected one load order error, got %v", errs)
+		}
+	})
+
+	t.Run("on-directory requirements", func(t *testing.T) {
+		// On-directory features may load at startup, so they cannot rely on deferred features,
+		// and only other on-directory features can rely on them
+		_, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "tool", Strategy: "defer"},
+			{Name: "project", Strategy: "on-directory", OnDirectory: []string{".tool"}, Requires: []string{"tool"}},
+			{Name: "helper", Strategy: "on-directory", OnDirectory: []string{".tool"}, Requires: []string{"project"}},
+			{Name: "prompt", Strategy: "defer", Requires: []string{"project"}},
+		})
+		var failing []string
+		for _, err := range errs {
+			var dependencyErr *DependencyError
+			if !errors.As(err, &dependencyErr) {
+				t.Fatalf("expected dependency errors, got %v", err)
+			}
+			failing = append(failing, dependencyErr.Feature+"->"+dependencyErr.Dependency)
+		}
+		if got := strings.Join(failing, ","); got != "project->tool,prompt->project" {
+			t.Fatalf("dependency errors = %s", got)
+		}
+	})
+
+	t.Run("on-completion requirement", func(t *testing.T) {
+		_, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "a", Strategy: "on-completion", OnCommand: []string{"a"}, Requires: []string{"b"}},
+			{Name: "b", Strategy: "on-completion", OnCommand: []string{"b"}},
+		})
+		var dependencyErr *DependencyError
+		if len(errs) != 1 || !errors.As(errs[0], &dependencyErr) || dependencyErr.Dependency != "b" {
+			t.Fatalf("expected one load order error, got %v", errs)
+		}
+	})
+
+	t.Run("on-command requirement with the same commands", func(t *testing.T) {
+		_, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "a", Strategy: "on-command", OnCommand: []string{"kubectl"}, Requires: []string{"b"}},
+			{Name: "b", Strategy: "on-command", OnCommand: []string{"kubectl"}},
+		})
+		if len(errs) != 1 {
+			t.Fatalf("expected one load order error, got %v", errs)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		got, errs := OrderFeatures([]manifest.FeatureConfig{
+			{Name: "x"},
+			{Name: "a", Requires: []string{"b"}},
+			{Name: "b", After: []string{"c"}},
+			{Name: "c", Requires: []string{"a"}},
+		})
+		var cycleErr *CycleError
+		if len(errs) != 1 || !errors.As(errs[0], &cycleErr) {
+			t.Fatalf("expected one cycle error, got %v", errs)
+		}
+		if cycle := strings.Join(cycleErr.Cycle, " -> "); cycle != "a -> b -> c -> a" {
+			t.Fatalf("cycle = %s", cycle)
+		}
+		if names := strings.Join(featureNames(got), ","); names != "x,a,c,b" {
+			t.Fatalf("expected the cycle broken at a, got %s", names)
+		}
+	})
+}
+
+func TestGenerateInitScript_StableOrder(t *testing.T) {
+	repoPath := t.TempDir()
+	shellDir := filepath.Join(repoPath, "omd-shells", "bash")
+	if err := os.MkdirAll(shellDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{
+		{Name: "prompt", Requires: []string{"tool"}},
+		{Name: "tool"},
+	}}
+	for _, name := range []string{"zeta", "alpha", "mu", "beta", "omega"} {
+		m.Features = append(m.Features, manifest.FeatureConfig{Name: name, Strategy: "on-command", OnCommand: []string{name + "-cmd"}})
+	}
+	if err := manifest.WriteManifest(GetManifestPath(repoPath, "bash"), m); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := GenerateInitScript(repoPath, "bash")
+	if err != nil {
+		t.Fatalf("GenerateInitScript: %v", err)
+	}
+	if strings.Index(first, "features/tool.sh") > strings.Index(first, "features/prompt.sh") {
+		t.Fatal("expected tool to load before prompt")
+	}
+
+	last := -1
+	for _, name := range []string{"zeta", "alpha", "mu", "beta", "omega"} {
+		at := strings.Index(first, name+"-cmd() {")
+		if at < last {
+			t.Fatalf("expected on-command features in manifest order, %s is out of place", name)
+		}
+		last = at
+	}
+
+	for run := 0; run < 10; run++ {
+		again, err := GenerateInitScript(repoPath, "bash")
+		if err != nil {
+			t.Fatalf("GenerateInitScript: %v", err)
+		}
+		if again != first {
+			t.Fatal("expected the same init script on every run")
+		}
+	}
+}
+
+func TestSetFeatureOrder(t *testing.T) {
+	repoPath := t.TempDir()
+	if err := os.MkdirAll(GetShellDirectory(repoPath, "bash"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := &manifest.FeatureManifest{Features: []manifest.FeatureConfig{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+	if err := manifest.WriteManifest(GetManifestPath(repoPath, "bash"), base); err != nil {
+		t.Fatal(err)
+	}
+	initPath, err := GetInitScriptPath(repoPath, "bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadOrder := func() string {
+		t.Helper()
+		names, err := LoadOrder(repoPath, "bash")
+		if err != nil {
+			t.Fatalf("LoadOrder: %v", err)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if err := SetFeatureOrder(repoPath, "bash", []string{"c", "a", "b"}, false); err != nil {
+		t.Fatalf("SetFeatureOrder: %v", err)
+	}
+	if got := loadOrder(); got != "c,a,b" {
+		t.Fatalf("load order = %s, want c,a,b", got)
+	}
+	shared, err := manifest.ParseManifest(GetManifestPath(repoPath, "bash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.Features[0].Name != "c" {
+		t.Fatalf("expected enabled.json to start with c, got %+v", shared.Features)
+	}
+	script, err := os.ReadFile(initPath)
+	if err != nil {
+		t.Fatalf("expected the init script to be regenerated: %v", err)
+	}
+	if strings.Index(string(script), "features/c.sh") > strings.Index(string(script), "features/a.sh") {
+		t.Fatal("expected the init script to load c first")
+	}
+
+	// A local order changes this machine only
+	if err := SetFeatureOrder(repoPath, "bash", []string{"b", "c", "a"}, true); err != nil {
+		t.Fatalf("SetFeatureOrder local: %v", err)
+	}
+	if got := loadOrder(); got != "b,c,a" {
+		t.Fatalf("load order = %s, want b,c,a", got)
+	}
+	shared, err = manifest.ParseManifest(GetManifestPath(repoPath, "bash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.Features[0].Name != "c" {
+		t.Fatalf("expected enabled.json to keep the shared order, got %+v", shared.Features)
+	}
+	if _, err := os.Stat(filepath.Join(GetShellDirectory(repoPath, "bash"), "enabled.local.json")); err != nil {
+		t.Fatalf("expected enabled.local.json to be written: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PatrickMatthiesen/oh-my-dot/internal/manifest"
+)
+
+// ProfiledFeature is a feature timed by a profile script, in the order it is sourced
+type ProfiledFeature struct {
+	Name     string
+	Strategy string
+}
+
+// GenerateProfileScript generates an instrumented variant of the init script for a shell.
+// It sources every enabled feature in load order, whatever its strategy, and appends a
+// "name<TAB>start<TAB>end" line per feature to logPath. The first line of the log is
+// "#unit<TAB><unit>", naming the unit of the timestamps: ns, us or ticks (100ns).
+func GenerateProfileScript(repoPath, shellName, logPath string) (string, []ProfiledFeature, error) {
+	merged, err := manifest.ParseManifestWithLocal(GetManifestPath(repoPath, shellName), GetLocalManifestPath(repoPath, shellName))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse manifests: %w", err)
+	}
+
+	features := categorizeFeaturesMerged(merged)
+	var profiled []ProfiledFeature
+	for _, name := range features.Eager {
+		profiled = append(profiled, ProfiledFeature{Name: name, Strategy: "eager"})
+	}
+	for _, name := range features.Defer {
+		profiled = append(profiled, ProfiledFeature{Name: name, Strategy: "defer"})
+	}
+	for _, f := range features.OnCommand {
+		profiled = append(profiled, ProfiledFeature{Name: f.Name, Strategy: "on-command"})
+	}
+	for _, f := range features.OnCompletion {
+		profiled = append(profiled, ProfiledFeature{Name: f.Name, Strategy: "on-completion"})
+	}
+	for _, f := range features.OnDirectory {
+		profiled = append(profiled, ProfiledFeature{Name: f.Name, Strategy: "on-directory"})
+	}
+
+	shellRoot, err := filepath.Abs(GetShellDirectory(repoPath, shellName))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve shell directory: %w", err)
+	}
+
+	switch shellName {
+	case "bash":
+		return generateBashProfile(shellRoot, logPath, profiled), profiled, nil
+	case "zsh":
+		return generateZshProfile(shellRoot, logPath, profiled), profiled, nil
+	case "fish":
+		return generateFishProfile(shellRoot, logPath, profiled), profiled, nil
+	case "powershell":
+		return generatePowerShellProfile(shellRoot, logPath, profiled), profiled, nil
+	case "posix":
+		return generatePosixProfile(shellRoot, logPath, profiled), profiled, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported shell: %s", shellName)
+	}
+}
+
+// shQuote quotes a value for POSIX shells, bash and zsh
+func shQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes a value for fish
+func fishQuote(value string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", `\'`) + "'"
+}
+
+// psQuote quotes a value for PowerShell
+func psQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// writeShFeatureTimings writes the timed sourcing of each feature for POSIX-like shells.
+// _omd_profile_now must set _omd_profile_t to the current time.
+func writeShFeatureTimings(sb *strings.Builder, features []ProfiledFeature, ext string) {
+	for _, feature := range features {
+		sb.WriteString(fmt.Sprintf(`_omd_profile_now; _omd_profile_start=$_omd_profile_t
+[ -r "$OMD_SHELL_ROOT/features/%s%s" ] && . "$OMD_SHELL_ROOT/features/%s%s" >/dev/null
+_omd_profile_now
+printf '%%s\t%%s\t%%s\n' %s "$_omd_profile_start" "$_omd_profile_t" >> "$_omd_profile_log"
+`, feature.Name, ext, feature.Name, ext, shQuote(feature.Name)))
+	}
+}
+
+func generateBashProfile(shellRoot, logPath string, features []ProfiledFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - bash profile script
+# Auto-generated by 'oh-my-dot shell profile' - do not edit manually
+
+OMD_SHELL_ROOT=%s
+_omd_profile_log=%s
+
+# EPOCHREALTIME (bash 5+) avoids forking date for every timestamp
+if [ -n "${EPOCHREALTIME:-}" ]; then
+  _omd_profile_now() { _omd_profile_t=${EPOCHREALTIME/[.,]/}; }
+  printf '#unit\tus\n' > "$_omd_profile_log"
+else
+  _omd_profile_now() { _omd_profile_t=$(date +%%s%%N); }
+  printf '#unit\tns\n' > "$_omd_profile_log"
+fi
+
+if [ -r "$OMD_SHELL_ROOT/../lib/helpers.sh" ]; then
+  . "$OMD_SHELL_ROOT/../lib/helpers.sh"
+fi
+
+`, shQuote(shellRoot), shQuote(logPath)))
+
+	sb.WriteString(cachedEvalFunction("bash"))
+	writeShFeatureTimings(&sb, features, ".sh")
+	sb.WriteString("exit 0\n")
+	return sb.String()
+}
+
+func generateZshProfile(shellRoot, logPath string, features []ProfiledFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - zsh profile script
+# Auto-generated by 'oh-my-dot shell profile' - do not edit manually
+
+OMD_SHELL_ROOT=%s
+_omd_profile_log=%s
+
+zmodload zsh/datetime
+_omd_profile_now() {
+  local now
+  now=($epochtime)
+  _omd_profile_t=$(( now[1] * 1000000000 + now[2] ))
+}
+printf '#unit\tns\n' > "$_omd_profile_log"
+
+if [[ -r "$OMD_SHELL_ROOT/../lib/helpers.sh" ]]; then
+  . "$OMD_SHELL_ROOT/../lib/helpers.sh"
+fi
+
+`, shQuote(shellRoot), shQuote(logPath)))
+
+	sb.WriteString(cachedEvalFunction("zsh"))
+	writeShFeatureTimings(&sb, features, ".zsh")
+	sb.WriteString("exit 0\n")
+	return sb.String()
+}
+
+func generatePosixProfile(shellRoot, logPath string, features []ProfiledFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - POSIX sh profile script
+# Auto-generated by 'oh-my-dot shell profile' - do not edit manually
+
+OMD_SHELL_ROOT=%s
+_omd_profile_log=%s
+
+_omd_profile_now() { _omd_profile_t=$(date +%%s%%N); }
+printf '#unit\tns\n' > "$_omd_profile_log"
+
+if [ -r "$OMD_SHELL_ROOT/../lib/helpers.sh" ]; then
+  . "$OMD_SHELL_ROOT/../lib/helpers.sh"
+fi
+
+`, shQuote(shellRoot), shQuote(logPath)))
+
+	sb.WriteString(cachedEvalFunction("posix"))
+	writeShFeatureTimings(&sb, features, ".sh")
+	sb.WriteString("exit 0\n")
+	return sb.String()
+}
+
+func generateFishProfile(shellRoot, logPath string, features []ProfiledFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - fish profile script
+# Auto-generated by 'oh-my-dot shell profile' - do not edit manually
+
+set -g OMD_SHELL_ROOT %s
+set -g _omd_profile_log %s
+printf '#unit\tns\n' > $_omd_profile_log
+
+`, fishQuote(shellRoot), fishQuote(logPath)))
+	sb.WriteString(cachedEvalFunction("fish"))
+
+	for _, feature := range features {
+		sb.WriteString(fmt.Sprintf(`set -l start (date +%%s%%N)
+test -r "$OMD_SHELL_ROOT/features/%s.fish"; and source "$OMD_SHELL_ROOT/features/%s.fish" >/dev/null
+printf '%%s\t%%s\t%%s\n' %s $start (date +%%s%%N) >> $_omd_profile_log
+`, feature.Name, feature.Name, fishQuote(feature.Name)))
+	}
+	sb.WriteString("exit 0\n")
+	return sb.String()
+}
+
+func generatePowerShellProfile(shellRoot, logPath string, features []ProfiledFeature) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`# oh-my-dot shell framework - PowerShell profile script
+# Auto-generated by 'oh-my-dot shell profile' - do not edit manually
+
+$OMD_SHELL_ROOT = %s
+$omdProfileLog = %s
+$omdProfileClock = [System.Diagnostics.Stopwatch]::StartNew()
+Set-Content -Path $omdProfileLog -Value "#unit`+"`"+`tticks"
+
+`, psQuote(shellRoot), psQuote(logPath)))
+	sb.WriteString(cachedEvalFunction("powershell"))
+
+	for _, feature := range features {
+		sb.WriteString(fmt.Sprintf(`$omdProfileStart = $omdProfileClock.Elapsed.Ticks
+$featureFile = Join-Path $OMD_SHELL_ROOT "features\%s.ps1"
+if (Test-Path $featureFile) {
+  . $featureFile | Out-Null
+}
+Add-Content -Path $omdProfileLog -Value ("{0}`+"`"+`t{1}`+"`"+`t{2}" -f %s, $omdProfileStart, $omdProfileClock.Elapsed.Ticks)
+`, feature.Name, psQuote(feature.Name)))
+	}
+	sb.WriteString("exit 0\n")
+	return sb.String()
+}
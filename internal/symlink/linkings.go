@@ -45,8 +45,13 @@ func GetLinkings() (Linkings, error) {
 		return nil, fmt.Errorf("error reading links file: %w", err)
 	}
 
-	var links Linkings
-	err = json.Unmarshal(file, &links)
+	return ParseLinkings(file)
+}
+
+// ParseLinkings decodes the contents of a linkings.json file.
+func ParseLinkings(data []byte) (Linkings, error) {
+	links := Linkings{}
+	err := json.Unmarshal(data, &links)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling links: %w", err)
 	}
@@ -121,3 +121,23 @@ func Test_Config_Show_AllowGHAuth(t *testing.T) {
 		t.Fatalf("expected allow-gh-auth output to be true, got %q", output)
 	}
 }
+
+func Test_Config_Show_Remote_Shorthand(t *testing.T) {
+	setupTestConfig(t)
+	viper.Set("remote-url", "gh:username/dotfiles")
+	viper.Set("remote.protocol", "ssh")
+
+	if output := strings.TrimSpace(captureOutput(t, []string{"config", "remote-url"})); output != "git@github.com:username/dotfiles.git" {
+		t.Fatalf("expected the resolved SSH URL, got %q", output)
+	}
+	if output := strings.TrimSpace(captureOutput(t, []string{"config", "remote-shorthand"})); output != "gh:username/dotfiles" {
+		t.Fatalf("expected the shorthand, got %q", output)
+	}
+
+	output := captureOutput(t, []string{"config"})
+	for _, want := range []string{"remote-url: git@github.com:username/dotfiles.git", "remote-shorthand: gh:username/dotfiles", "remote-protocol: ssh"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected config output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
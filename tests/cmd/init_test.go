@@ -1,7 +1,10 @@
 package cmd_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,7 +14,9 @@ import (
 	internalgit "github.com/PatrickMatthiesen/oh-my-dot/internal/git"
 	"github.com/PatrickMatthiesen/oh-my-dot/tests/testutil"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"testing"
@@ -79,6 +84,57 @@ func Test_Init_Clone_Remote_With_Main_Branch(t *testing.T) {
 	}
 }
 
+// Test_Init_Create_Remote tests that --create-remote creates the repository through the
+// provider API, uses the returned clone URL as origin and pushes an initial commit to it
+func Test_Init_Create_Remote(t *testing.T) {
+	mockHomeDir(t)
+	remoteRepoPath := testutil.CreateBareRemoteRepo(t)
+
+	var created map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/user":
+			json.NewEncoder(w).Encode(map[string]any{"login": "me"})
+		case r.Method == http.MethodPost && r.URL.Path == "/user/repos":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{"clone_url": remoteRepoPath, "html_url": "https://git.example.com/me/dotfiles"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+	t.Setenv("GITEA_TOKEN", "test-token")
+
+	invokeCommand(t, []string{"init", "git.example.com/me/dotfiles", "--create-remote", "--provider", "gitea", "--api-url", api.URL})
+
+	if created["name"] != "dotfiles" || created["private"] != true {
+		t.Fatalf("create request = %v, want a private repository named dotfiles", created)
+	}
+	if got := viper.GetString("remote-url"); got != remoteRepoPath {
+		t.Fatalf("remote-url = %q, want the clone URL from the API", got)
+	}
+
+	remote, err := git.PlainOpen(remoteRepoPath)
+	testutil.TBErrorIfNotNil(t, err)
+	refs, err := remote.References()
+	testutil.TBErrorIfNotNil(t, err)
+	branches := 0
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() {
+			branches++
+		}
+		return nil
+	})
+	if branches == 0 {
+		t.Fatal("the initial commit was not pushed to the created repository")
+	}
+}
+
 // Fuzz_Init_With_Random_Branch_Names tests that the init command works correctly
 // with remote repositories that have various random branch names as their default branch
 func Fuzz_Init_With_Random_Branch_Names(f *testing.F) {
@@ -206,6 +262,20 @@ func invokeCommand(t *testing.T, args []string, remote ...string) {
 	// viper.AutomaticEnv()
 
 	cmd.Execute(func(c *cobra.Command) {
+		resetFlags(c)
 		c.SetArgs(args)
 	})
 }
+
+// resetFlags restores every flag to its default, since cobra keeps parsed values between executions.
+func resetFlags(c *cobra.Command) {
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+	})
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}